@@ -0,0 +1,102 @@
+// Command reencrypt 將平台資料庫中尚未加密、或不是以 active key 加密的 secret 以 active key 重新加密。
+//
+// 輪替 key 時先將新 key 設為 activeKeyID (舊 key 保留在設定中)，執行此命令後即可移除舊 key。
+//
+//	go run ./cmd/reencrypt [-dry-run]
+package main
+
+import (
+	"context"
+	"flag"
+	"log/slog"
+	"os"
+
+	"baas-api/internal/authsetting"
+	"baas-api/internal/config"
+	"baas-api/internal/database"
+	"baas-api/internal/encryption"
+
+	"github.com/samber/do/v2"
+)
+
+func main() {
+	dryRun := flag.Bool("dry-run", false, "Only report the rows that need re-encryption")
+	flag.Parse()
+
+	i := do.New()
+	config.Package(i)
+	database.Package(i)
+	encryption.Package(i)
+	authsetting.Package(i)
+
+	enc := do.MustInvokeAs[encryption.Service](i)
+	repo := do.MustInvokeAs[authsetting.Repository](i)
+	if enc.ActiveKeyID() == "" {
+		slog.Error("No active encryption key configured")
+		os.Exit(1)
+	}
+
+	ctx := context.Background()
+	failed := false
+
+	settings, err := repo.FindAll(ctx)
+	if err != nil {
+		os.Exit(1)
+	}
+	count := 0
+	for _, setting := range settings {
+		if !enc.NeedsReencrypt(setting.Secret) {
+			continue
+		}
+		count++
+		if *dryRun {
+			continue
+		}
+		if err := reencrypt(enc, setting.Secret, func(secret string) error {
+			return repo.UpdateSecret(ctx, setting.ProjectID, secret)
+		}); err != nil {
+			slog.Error("Failed to re-encrypt project auth secret", "error", err, "projectID", setting.ProjectID)
+			failed = true
+		}
+	}
+	slog.Info("Project auth secrets", "total", len(settings), "reencrypt", count, "dryRun", *dryRun)
+
+	providers, err := repo.FindAllOAuthProvidersWithSecret(ctx)
+	if err != nil {
+		os.Exit(1)
+	}
+	count = 0
+	for _, provider := range providers {
+		if !enc.NeedsReencrypt(*provider.ClientSecret) {
+			continue
+		}
+		count++
+		if *dryRun {
+			continue
+		}
+		if err := reencrypt(enc, *provider.ClientSecret, func(secret string) error {
+			return repo.UpdateOAuthProviderSecret(ctx, provider.ID, secret)
+		}); err != nil {
+			slog.Error("Failed to re-encrypt OAuth client secret", "error", err, "projectID", provider.ProjectID, "provider", provider.Name)
+			failed = true
+		}
+	}
+	slog.Info("OAuth client secrets", "total", len(providers), "reencrypt", count, "dryRun", *dryRun)
+
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// reencrypt 解密 value (未加密的舊資料原樣使用) 後以 active key 加密並寫回
+func reencrypt(enc encryption.Service, value string, update func(string) error) error {
+	plaintext, err := enc.Decrypt(value)
+	if err != nil {
+		return err
+	}
+	encrypted, err := enc.Encrypt(plaintext)
+	if err != nil {
+		return err
+	}
+	return update(encrypted)
+}
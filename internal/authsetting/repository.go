@@ -30,6 +30,11 @@ type Repository interface {
 	UpsertOAuthProviders(ctx context.Context, providers []*models.ProjectAuthProvider) error
 	FindAllOAuthProviders(ctx context.Context, projectID string) ([]*models.ProjectAuthProvider, error)
 	UpdateOrInsertOAuthProvider(ctx context.Context, provider *models.ProjectAuthProvider) error
+	// 重新加密用，只更新 secret 欄位
+	FindAll(ctx context.Context) ([]*models.ProjectAuthSettings, error)
+	UpdateSecret(ctx context.Context, projectID string, secret string) error
+	FindAllOAuthProvidersWithSecret(ctx context.Context) ([]*models.ProjectAuthProvider, error)
+	UpdateOAuthProviderSecret(ctx context.Context, id string, secret string) error
 }
 
 var _ Repository = (*repository)(nil)
@@ -147,3 +152,37 @@ func (r *repository) UpsertOAuthProviders(ctx context.Context, providers []*mode
 	}
 	return nil
 }
+
+func (r *repository) FindAll(ctx context.Context) ([]*models.ProjectAuthSettings, error) {
+	var settings []*models.ProjectAuthSettings
+	if err := r.db.WithContext(ctx).Find(&settings).Error; err != nil {
+		slog.ErrorContext(ctx, "Failed to find project auth settings", "error", err)
+		return nil, ErrDatabaseError
+	}
+	return settings, nil
+}
+
+func (r *repository) UpdateSecret(ctx context.Context, projectID string, secret string) error {
+	if err := r.db.WithContext(ctx).Model(&models.ProjectAuthSettings{}).Where("project_id = ?", projectID).UpdateColumn("secret", secret).Error; err != nil {
+		slog.ErrorContext(ctx, "Failed to update project auth secret", "error", err)
+		return ErrDatabaseError
+	}
+	return nil
+}
+
+func (r *repository) FindAllOAuthProvidersWithSecret(ctx context.Context) ([]*models.ProjectAuthProvider, error) {
+	var providers []*models.ProjectAuthProvider
+	if err := r.db.WithContext(ctx).Where("client_secret IS NOT NULL AND client_secret <> ''").Find(&providers).Error; err != nil {
+		slog.ErrorContext(ctx, "Failed to find OAuth providers", "error", err)
+		return nil, ErrDatabaseError
+	}
+	return providers, nil
+}
+
+func (r *repository) UpdateOAuthProviderSecret(ctx context.Context, id string, secret string) error {
+	if err := r.db.WithContext(ctx).Model(&models.ProjectAuthProvider{}).Where("id = ?", id).UpdateColumn("client_secret", secret).Error; err != nil {
+		slog.ErrorContext(ctx, "Failed to update OAuth provider secret", "error", err)
+		return ErrDatabaseError
+	}
+	return nil
+}
@@ -7,6 +7,7 @@ import (
 	"log/slog"
 	"net/url"
 	"strings"
+	"time"
 
 	// "github.com/go-viper/mapstructure/v2"
 	"github.com/go-viper/mapstructure/v2"
@@ -38,12 +39,204 @@ type PgRESTConfig struct {
 
 type KubeConfig struct {
 	ConfigPath string
-	Project    struct {
+	// Clusters 是可放置專案的叢集，未設定時只使用 ConfigPath (或 in-cluster) 的單一叢集
+	Clusters []KubeClusterConfig
+	// DefaultCluster 是未記錄叢集的既有專案所在的叢集，空字串代表 Clusters 的第一個
+	DefaultCluster string
+	DefaultPlan    string
+	Plans          map[string]PlanConfig
+	Ingress        IngressConfig
+	Project        struct {
 		Namespace     string
 		TLSSecretName string
+		Isolation     ProjectIsolationConfig
+		Backup        ProjectBackupConfig
+		Extensions    ProjectExtensionsConfig
+		Migration     ProjectMigrationConfig
+		JWKSRotation  ProjectJWKSRotationConfig
 	}
 }
 
+// KubeClusterConfig 是一個可放置專案的 Kubernetes 叢集
+type KubeClusterConfig struct {
+	Name   string
+	Region string
+	// ConfigPath 與 Context 指定 kubeconfig 與其中的 context，ConfigPath 為空時使用 in-cluster config
+	ConfigPath string
+	Context    string
+	// MaxProjects 是叢集可放置的專案數上限，0 代表不限制
+	MaxProjects int
+}
+
+// 專案對外路由使用的 ingress 實作
+const (
+	IngressProviderTraefik = "traefik"
+	IngressProviderGateway = "gateway"
+)
+
+// IngressConfig 選擇專案 API 與資料庫對外路由的實作
+type IngressConfig struct {
+	// Provider 是 IngressProviderTraefik 或 IngressProviderGateway
+	Provider string
+	Traefik  TraefikIngressConfig
+	Gateway  GatewayIngressConfig
+}
+
+// TraefikIngressConfig 是 IngressRoute 與 IngressRouteTCP 使用的 entry point 與 middleware
+type TraefikIngressConfig struct {
+	HTTPEntryPoint     string
+	PostgresEntryPoint string
+	// StripPrefixMiddleware 是共用 namespace 中移除 /api/rest 前綴的 Middleware
+	StripPrefixMiddleware string
+	// RealtimePrefixMiddleware 是共用 namespace 中將 /api/realtime 前綴改寫為 /socket 的 Middleware
+	RealtimePrefixMiddleware string
+}
+
+// GatewayIngressConfig 是 HTTPRoute 與 TLSRoute 附加的共用 Gateway
+type GatewayIngressConfig struct {
+	Name      string
+	Namespace string
+	// HTTPSListener 與 PostgresListener 是 Gateway 的 listener 名稱 (parentRef 的 sectionName)
+	HTTPSListener    string
+	PostgresListener string
+}
+
+// ProjectJWKSRotationConfig 控制專案 JWT 簽章金鑰的定期輪替
+type ProjectJWKSRotationConfig struct {
+	// Enabled 為 true 時背景排程會輪替超過 Interval 的金鑰；到期的舊金鑰 (包含手動輪替) 不論是否啟用都會移除
+	Enabled bool
+	// Interval 是兩次輪替之間的時間
+	Interval time.Duration
+	// RetireAfter 是切換簽章金鑰後保留舊金鑰的時間，應大於 JWT 的有效期限
+	RetireAfter time.Duration
+	// CheckInterval 是排程檢查到期專案的間隔
+	CheckInterval time.Duration
+}
+
+// ProjectMigrationConfig 控制專案 dbmate migration 的結果如何影響後續的佈建
+type ProjectMigrationConfig struct {
+	// BlockOnFailure 為 true 時 migration 失敗會中止後續的佈建步驟
+	BlockOnFailure bool
+}
+
+// ProjectExtensionsConfig 控制專案可以自行啟用的 Postgres extension
+type ProjectExtensionsConfig struct {
+	Allowlist []string
+}
+
+// ProjectBackupConfig 控制專案資料庫的 WAL 封存與排程備份 (CNPG barman object store)
+type ProjectBackupConfig struct {
+	Enabled         bool
+	Bucket          string
+	Schedule        string
+	RetentionPolicy string
+}
+
+// ProjectIsolationConfig 控制每個專案是否使用獨立的 namespace
+type ProjectIsolationConfig struct {
+	Enabled           bool
+	NamespacePrefix   string
+	IngressNamespace  string
+	OperatorNamespace string
+	PlatformNamespace string
+	ResourceQuota     struct {
+		RequestsCPU     string
+		RequestsMemory  string
+		LimitsCPU       string
+		LimitsMemory    string
+		RequestsStorage string
+		Pods            string
+	}
+	LimitRange struct {
+		DefaultCPU           string
+		DefaultMemory        string
+		DefaultRequestCPU    string
+		DefaultRequestMemory string
+	}
+}
+
+// PlanConfig 定義方案中專案 API 的預設 workload 設定與專案可以自行調整的上限
+type PlanConfig struct {
+	// Selectable 為 true 時使用者可以自行選擇此方案，其他方案只能由平台指派 (更新 dbo.projects.plan)
+	Selectable bool
+	AuthAPI    WorkloadConfig
+	RESTAPI    WorkloadConfig
+	Database   DatabaseClusterConfig
+	Limits     PlanLimits
+}
+
+// PlanLimits 是專案調整 workload 與資料庫時的上限，0 或空字串代表不限制
+type PlanLimits struct {
+	// MaxReplicas 限制 API 的副本數與 HPA 的 maxReplicas
+	MaxReplicas int32
+	// MaxCPU 與 MaxMemory 限制 API container 的 limits，設定時 limits 為必填
+	MaxCPU    string
+	MaxMemory string
+	// MaxInstances 限制 Postgres 的 instance 數
+	MaxInstances int32
+}
+
+// DatabaseClusterConfig 是專案 CNPG Cluster 的 instance 數與同步複寫設定
+type DatabaseClusterConfig struct {
+	Instances   int32
+	Synchronous DatabaseSynchronousConfig
+}
+
+// DatabaseSynchronousConfig 對應 CNPG 的 spec.postgresql.synchronous，Number 為 0 時使用非同步複寫
+type DatabaseSynchronousConfig struct {
+	// Method 是 any (quorum) 或 first (priority)
+	Method string
+	// Number 是需要確認 commit 的同步 standby 數量
+	Number int32
+	// DataDurability 為 required 時同步 standby 不足會暫停寫入，preferred 時會退回非同步
+	DataDurability string
+}
+
+// WorkloadConfig 是專案 API Deployment 的副本數、資源、HPA 與 PDB 設定
+type WorkloadConfig struct {
+	Replicas            int32
+	Resources           WorkloadResources
+	Autoscaling         WorkloadAutoscaling
+	PodDisruptionBudget WorkloadPodDisruptionBudget
+}
+
+type WorkloadResources struct {
+	RequestsCPU    string
+	RequestsMemory string
+	LimitsCPU      string
+	LimitsMemory   string
+}
+
+type WorkloadAutoscaling struct {
+	Enabled                        bool
+	MinReplicas                    int32
+	MaxReplicas                    int32
+	TargetCPUUtilizationPercentage int32
+}
+
+// WorkloadPodDisruptionBudget 只能設定 MinAvailable 或 MaxUnavailable 其中之一 (整數或百分比)
+type WorkloadPodDisruptionBudget struct {
+	MinAvailable   string
+	MaxUnavailable string
+}
+
+// PlanName 回傳方案名稱的正規化形式 (小寫)，名稱為空時使用 DefaultPlan
+func (c *KubeConfig) PlanName(name string) string {
+	if name == "" {
+		name = c.DefaultPlan
+	}
+	return strings.ToLower(name)
+}
+
+// Plan 回傳指定名稱的方案，名稱為空時使用 DefaultPlan
+func (c *KubeConfig) Plan(name string) (*PlanConfig, bool) {
+	plan, ok := c.Plans[c.PlanName(name)]
+	if !ok {
+		return nil, false
+	}
+	return &plan, true
+}
+
 type S3Config struct {
 	Endpoint        string
 	UseSSL          bool
@@ -56,14 +249,32 @@ type LoggingConfig struct {
 	Level string
 }
 
+// EncryptionConfig 是加密平台資料庫中 secret 欄位的 key-encryption key (KEK)
+type EncryptionConfig struct {
+	// ActiveKeyID 是加密新資料使用的 key，其他 key 只用於解密
+	ActiveKeyID string
+	Keys        []EncryptionKeyConfig
+	// KeysDir 中每個檔案是一把 key，檔名為 key ID (例如掛載的 Kubernetes Secret)
+	KeysDir string
+	// RevealMaxSessionAgeMinutes 是查看明文 secret 時 session 建立後的最長時間，0 表示不限制
+	RevealMaxSessionAgeMinutes int
+}
+
+type EncryptionKeyConfig struct {
+	ID string
+	// Key 是 base64 編碼的 32 bytes AES-256 key
+	Key string
+}
+
 type Config struct {
-	App      AppConfig
-	Database DatabaseConfig
-	Auth     AuthConfig
-	PgREST   PgRESTConfig
-	Kube     KubeConfig
-	S3       S3Config
-	Logging  LoggingConfig
+	App        AppConfig
+	Database   DatabaseConfig
+	Auth       AuthConfig
+	PgREST     PgRESTConfig
+	Kube       KubeConfig
+	S3         S3Config
+	Encryption EncryptionConfig
+	Logging    LoggingConfig
 }
 
 //go:embed config.yaml
@@ -88,6 +299,7 @@ func NewConfig(i do.Injector) (*Config, error) {
 	err := viper.Unmarshal(&c, viper.DecodeHook(
 		mapstructure.ComposeDecodeHookFunc(
 			mapstructure.StringToURLHookFunc(),
+			mapstructure.StringToTimeDurationHookFunc(),
 		),
 	))
 	if err != nil {
@@ -112,6 +324,32 @@ func NewConfig(i do.Injector) (*Config, error) {
 	return c, nil
 }
 
+// redactedConfig 是 LogValue 輸出的型別，避免 slog 再次呼叫 LogValue
+type redactedConfig Config
+
+// LogValue 回傳移除 secret (KEK、S3 secret key、資料庫連線字串的密碼) 的設定。
+//
+// slog 只對最外層的值呼叫 LogValue，因此在 Config 上遮蔽巢狀欄位。
+func (c *Config) LogValue() slog.Value {
+	redacted := redactedConfig(*c)
+	redacted.Encryption.Keys = make([]EncryptionKeyConfig, len(c.Encryption.Keys))
+	for i, key := range c.Encryption.Keys {
+		redacted.Encryption.Keys[i] = EncryptionKeyConfig{ID: key.ID, Key: redactedValue}
+	}
+	if redacted.S3.SecretAccessKey != "" {
+		redacted.S3.SecretAccessKey = redactedValue
+	}
+	// key=value 格式的連線字串無法只移除密碼，整個遮蔽
+	if u, err := url.Parse(c.Database.URL); err == nil && u.Scheme != "" {
+		redacted.Database.URL = u.Redacted()
+	} else if c.Database.URL != "" {
+		redacted.Database.URL = redactedValue
+	}
+	return slog.AnyValue(redacted)
+}
+
+const redactedValue = "[REDACTED]"
+
 var Package = do.Package(
 	do.Lazy(NewConfig),
 )
@@ -0,0 +1,63 @@
+package dto
+
+import "time"
+
+type ProjectBackup struct {
+	Name       string     `json:"name" doc:"Backup name"`
+	Phase      string     `json:"phase" example:"completed" doc:"Backup phase reported by the database operator"`
+	OnDemand   bool       `json:"onDemand" doc:"Whether the backup was triggered manually instead of by the schedule"`
+	ServerName string     `json:"serverName,omitempty" doc:"Archive server name the backup belongs to"`
+	BackupID   string     `json:"backupId,omitempty" doc:"Backup identifier in the object store"`
+	StartedAt  *time.Time `json:"startedAt,omitempty" doc:"Backup start time"`
+	StoppedAt  *time.Time `json:"stoppedAt,omitempty" doc:"Backup completion time"`
+	Error      string     `json:"error,omitempty" doc:"Error message if the backup failed"`
+}
+
+type ListProjectBackupsInput struct {
+	Ref string `query:"ref" example:"hisqrzwgndjcycmkwpnj" doc:"Project reference (20 lower characters [a-z])"`
+}
+
+type ListProjectBackupsOutput struct {
+	Body struct {
+		Backups []ProjectBackup `json:"backups" doc:"Backups of the project database, newest first"`
+	}
+}
+
+type CreateProjectBackupInput struct {
+	Body struct {
+		Ref string `json:"ref" example:"hisqrzwgndjcycmkwpnj" doc:"Project reference (20 lower characters [a-z])"`
+	}
+}
+
+type CreateProjectBackupOutput struct {
+	Body struct {
+		Backup ProjectBackup `json:"backup" doc:"The backup that was started"`
+	}
+}
+
+type RestoreProjectBackupInput struct {
+	Body struct {
+		Ref        string `json:"ref" example:"hisqrzwgndjcycmkwpnj" doc:"Project whose database is replaced by the restored data"`
+		SourceRef  string `json:"sourceRef,omitempty" required:"false" example:"hisqrzwgndjcycmkwpnj" doc:"Project that owns the backup (defaults to ref)"`
+		BackupName string `json:"backupName" doc:"Name of a completed backup of the source project"`
+	}
+}
+
+type RestoreProjectBackupOutput struct {
+	Body struct {
+		Accepted bool `json:"accepted" doc:"Indicates the restore was started; follow GET /project/backups/restore for progress"`
+	}
+}
+
+type GetProjectRestoreStatusInput struct {
+	Ref string `query:"ref" example:"hisqrzwgndjcycmkwpnj" doc:"Project reference (20 lower characters [a-z])"`
+}
+
+type GetProjectRestoreStatusOutput struct {
+	Body struct {
+		Status     string     `json:"status" enum:"none,running,succeeded,failed" doc:"Status of the latest database restore (none if the database was never restored)"`
+		StartedAt  *time.Time `json:"startedAt,omitempty" doc:"Restore start time"`
+		FinishedAt *time.Time `json:"finishedAt,omitempty" doc:"Restore completion time"`
+		Error      string     `json:"error,omitempty" doc:"Error message if the restore failed"`
+	}
+}
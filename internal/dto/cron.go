@@ -0,0 +1,103 @@
+package dto
+
+import "time"
+
+type ProjectCronJob struct {
+	Name               string             `json:"name" example:"nightly-cleanup" doc:"Cron job name"`
+	Schedule           string             `json:"schedule" example:"0 3 * * *" doc:"Cron schedule in UTC"`
+	Enabled            bool               `json:"enabled" doc:"Whether the schedule is active; disabled jobs can still be run manually"`
+	Type               string             `json:"type" enum:"sql,rpc" doc:"sql runs a SQL statement; rpc calls a function of the api schema"`
+	SQL                string             `json:"sql,omitempty" doc:"SQL run by an sql job"`
+	Function           string             `json:"function,omitempty" doc:"Function of the api schema called by an rpc job"`
+	Args               map[string]any     `json:"args,omitempty" doc:"Named arguments of the function"`
+	LastScheduleTime   *time.Time         `json:"lastScheduleTime,omitempty" doc:"Last time the job was scheduled"`
+	LastSuccessfulTime *time.Time         `json:"lastSuccessfulTime,omitempty" doc:"Last time a scheduled run succeeded"`
+	LastRun            *ProjectCronJobRun `json:"lastRun,omitempty" doc:"Latest run that is still kept in the history"`
+}
+
+type ProjectCronJobRun struct {
+	JobName     string     `json:"jobName" doc:"Name of the Job of the run"`
+	Trigger     string     `json:"trigger" enum:"scheduled,manual" doc:"Whether the run was scheduled or triggered manually"`
+	Status      string     `json:"status" enum:"pending,running,succeeded,failed" doc:"Run status"`
+	Message     string     `json:"message,omitempty" doc:"Failure reason reported by Kubernetes"`
+	StartedAt   *time.Time `json:"startedAt,omitempty" doc:"Time the run started"`
+	CompletedAt *time.Time `json:"completedAt,omitempty" doc:"Time the run succeeded or failed"`
+}
+
+type ListProjectCronJobsInput struct {
+	Ref string `query:"ref" example:"hisqrzwgndjcycmkwpnj" doc:"Project reference (20 lower characters [a-z])"`
+}
+
+type ListProjectCronJobsOutput struct {
+	Body struct {
+		CronJobs []ProjectCronJob `json:"cronJobs" doc:"Cron jobs of the project ordered by name"`
+	}
+}
+
+// PutProjectCronJobInput 建立或取代整個排程工作定義
+type PutProjectCronJobInput struct {
+	Body struct {
+		Ref      string         `json:"ref" example:"hisqrzwgndjcycmkwpnj" doc:"Project reference (20 lower characters [a-z])"`
+		Name     string         `json:"name" maxLength:"26" pattern:"^[a-z]([a-z0-9-]*[a-z0-9])?$" example:"nightly-cleanup" doc:"Cron job name"`
+		Schedule string         `json:"schedule" example:"0 3 * * *" doc:"Cron schedule in UTC (minute hour day-of-month month day-of-week, or @daily, @hourly, ...)"`
+		Enabled  bool           `json:"enabled" default:"true" doc:"Whether the schedule is active"`
+		Type     string         `json:"type" enum:"sql,rpc" doc:"sql runs a SQL statement; rpc calls a function of the api schema"`
+		SQL      string         `json:"sql,omitempty" example:"DELETE FROM api.sessions WHERE expires_at < now()" doc:"SQL run as the app role (type sql)"`
+		Function string         `json:"function,omitempty" example:"generate_daily_report" doc:"Function of the api schema to call (type rpc)"`
+		Args     map[string]any `json:"args,omitempty" doc:"Named arguments of the function (type rpc)"`
+	}
+}
+
+type PutProjectCronJobOutput struct {
+	Body struct {
+		CronJob ProjectCronJob `json:"cronJob" doc:"Saved cron job"`
+	}
+}
+
+type DeleteProjectCronJobInput struct {
+	Ref  string `query:"ref" example:"hisqrzwgndjcycmkwpnj" doc:"Project reference (20 lower characters [a-z])"`
+	Name string `query:"name" example:"nightly-cleanup" doc:"Cron job name"`
+}
+
+type DeleteProjectCronJobOutput struct {
+	Body struct {
+		Success bool `json:"success" doc:"Indicates if the cron job and its history were removed"`
+	}
+}
+
+type ListProjectCronJobRunsInput struct {
+	Ref  string `query:"ref" example:"hisqrzwgndjcycmkwpnj" doc:"Project reference (20 lower characters [a-z])"`
+	Name string `query:"name" example:"nightly-cleanup" doc:"Cron job name"`
+}
+
+type ListProjectCronJobRunsOutput struct {
+	Body struct {
+		Runs []ProjectCronJobRun `json:"runs" doc:"Kept runs, newest first"`
+	}
+}
+
+type TriggerProjectCronJobInput struct {
+	Body struct {
+		Ref  string `json:"ref" example:"hisqrzwgndjcycmkwpnj" doc:"Project reference (20 lower characters [a-z])"`
+		Name string `json:"name" example:"nightly-cleanup" doc:"Cron job name"`
+	}
+}
+
+type TriggerProjectCronJobOutput struct {
+	Body struct {
+		Run ProjectCronJobRun `json:"run" doc:"Started run"`
+	}
+}
+
+type GetProjectCronJobRunInput struct {
+	Ref     string `query:"ref" example:"hisqrzwgndjcycmkwpnj" doc:"Project reference (20 lower characters [a-z])"`
+	Name    string `query:"name" example:"nightly-cleanup" doc:"Cron job name"`
+	JobName string `query:"jobName" doc:"Name of the Job of the run"`
+}
+
+type GetProjectCronJobRunOutput struct {
+	Body struct {
+		Run  ProjectCronJobRun `json:"run" doc:"Run status"`
+		Logs string            `json:"logs" doc:"psql output; live output while the run is in progress"`
+	}
+}
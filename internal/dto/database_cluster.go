@@ -0,0 +1,42 @@
+package dto
+
+// ProjectDatabaseSynchronous 的欄位與 config.DatabaseSynchronousConfig 相同，可以直接轉型
+type ProjectDatabaseSynchronous struct {
+	Method         string `json:"method,omitempty" enum:"any,first" doc:"Quorum (any) or priority (first) based synchronous replication"`
+	Number         int32  `json:"number" minimum:"0" maximum:"4" doc:"Number of standbys that must confirm each commit, 0 uses asynchronous replication"`
+	DataDurability string `json:"dataDurability,omitempty" enum:"required,preferred" doc:"required blocks writes when not enough standbys are available, preferred falls back to asynchronous replication"`
+}
+
+type ProjectDatabaseConnection struct {
+	ReadWrite string `json:"readWrite" example:"postgresql://app@hisqrzwgndjcycmkwpnj.app.example.com:5432/app?sslmode=require" doc:"Connection string of the primary"`
+	ReadOnly  string `json:"readOnly,omitempty" example:"postgresql://app@hisqrzwgndjcycmkwpnj-ro.app.example.com:5432/app?sslmode=require" doc:"Connection string load balanced across the standbys, only available with more than one instance"`
+	Pooled    string `json:"pooled,omitempty" example:"postgresql://app@hisqrzwgndjcycmkwpnj-pooler.app.example.com:5432/app?sslmode=require" doc:"Connection string through the PgBouncer pooler, only available when pooling is enabled"`
+}
+
+type ProjectDatabaseCluster struct {
+	Instances      int32                      `json:"instances" doc:"Number of Postgres instances (one primary and the rest standbys)"`
+	Synchronous    ProjectDatabaseSynchronous `json:"synchronous" doc:"Synchronous replication settings"`
+	ReadyInstances int32                      `json:"readyInstances" doc:"Number of instances that are ready"`
+	CurrentPrimary string                     `json:"currentPrimary,omitempty" doc:"Name of the instance that is currently the primary"`
+	Connection     ProjectDatabaseConnection  `json:"connection" doc:"Connection strings without password"`
+}
+
+type GetProjectDatabaseClusterInput struct {
+	Ref string `query:"ref" example:"hisqrzwgndjcycmkwpnj" doc:"Project reference (20 lower characters [a-z])"`
+}
+
+type GetProjectDatabaseClusterOutput struct {
+	Body struct {
+		Cluster ProjectDatabaseCluster `json:"cluster" doc:"Postgres cluster settings and status"`
+	}
+}
+
+// UpdateProjectDatabaseClusterInput 以目前的設定 (或指定方案的預設值) 為基礎，覆寫有提供的欄位
+type UpdateProjectDatabaseClusterInput struct {
+	Body struct {
+		Ref         string                      `json:"ref" example:"hisqrzwgndjcycmkwpnj" doc:"Project reference (20 lower characters [a-z])"`
+		Plan        *string                     `json:"plan,omitempty" example:"pro" doc:"Reset to the defaults of this plan before applying the overrides; must be the project plan, the default plan or a selectable plan. The result must stay within the limits of the project plan"`
+		Instances   *int32                      `json:"instances,omitempty" minimum:"1" maximum:"5" doc:"Number of Postgres instances, scaled on the live cluster"`
+		Synchronous *ProjectDatabaseSynchronous `json:"synchronous,omitempty" doc:"Replaces the synchronous replication settings"`
+	}
+}
@@ -0,0 +1,45 @@
+package dto
+
+type ProjectDatabaseUser struct {
+	Username    string `json:"username" example:"app" doc:"Postgres role to log in as"`
+	Description string `json:"description" doc:"What the role is for"`
+	Password    string `json:"password,omitempty" doc:"Password of the role, only returned by the reveal endpoint"`
+}
+
+// ProjectDatabaseConnectionStrings 是以 app 角色連線到主要 instance 的連線字串，須將 CA 憑證存為 ca.crt。
+// 需要 direct TLS 時只提供 psql 與 JDBC
+type ProjectDatabaseConnectionStrings struct {
+	PSQL         string `json:"psql" example:"postgresql://app@hisqrzwgndjcycmkwpnj.app.example.com:5432/app?sslmode=verify-full&sslrootcert=ca.crt" doc:"libpq URI for psql and other libpq clients; includes sslnegotiation=direct (libpq 17+) when directTls is true"`
+	JDBC         string `json:"jdbc" example:"jdbc:postgresql://hisqrzwgndjcycmkwpnj.app.example.com:5432/app?user=app&sslmode=verify-full&sslrootcert=ca.crt" doc:"JDBC URL for the PostgreSQL JDBC driver; includes sslNegotiation=direct (pgJDBC 42.7.4+) when directTls is true"`
+	Prisma       string `json:"prisma,omitempty" example:"postgresql://app@hisqrzwgndjcycmkwpnj.app.example.com:5432/app?sslmode=require&sslaccept=strict&sslcert=ca.crt" doc:"DATABASE_URL for Prisma; sslcert is relative to the schema file. Omitted when directTls is true, Prisma cannot use direct TLS"`
+	NodePostgres string `json:"nodePostgres,omitempty" example:"postgresql://app@hisqrzwgndjcycmkwpnj.app.example.com:5432/app?sslmode=verify-full&sslrootcert=ca.crt" doc:"connectionString for node-postgres (pg). Omitted when directTls is true, node-postgres cannot use direct TLS"`
+}
+
+type ProjectDatabaseConnectionDetails struct {
+	Host              string                           `json:"host" example:"hisqrzwgndjcycmkwpnj.app.example.com" doc:"Host of the primary; also the TLS SNI the route matches"`
+	ReadOnlyHost      string                           `json:"readOnlyHost,omitempty" example:"hisqrzwgndjcycmkwpnj-ro.app.example.com" doc:"Host load balanced across the standbys, only available with more than one instance"`
+	PooledHost        string                           `json:"pooledHost,omitempty" example:"hisqrzwgndjcycmkwpnj-pooler.app.example.com" doc:"Host of the PgBouncer pooler, only available when pooling is enabled"`
+	Port              int32                            `json:"port" example:"5432" doc:"Port of every host"`
+	Database          string                           `json:"database" example:"app" doc:"Database name"`
+	SSLMode           string                           `json:"sslMode" example:"verify-full" doc:"Connections are routed by TLS SNI, so TLS is required and the client must send the host as SNI"`
+	DirectTLS         bool                             `json:"directTls" doc:"The route cannot see the SNI after the Postgres SSLRequest, so the client must start TLS directly (sslnegotiation=direct, libpq 17+)"`
+	CACertificate     string                           `json:"caCertificate" doc:"PEM encoded CA certificate that signed the server certificate"`
+	Users             []ProjectDatabaseUser            `json:"users" doc:"Roles that can log in to the database"`
+	ConnectionStrings ProjectDatabaseConnectionStrings `json:"connectionStrings" doc:"Ready-made connection strings for the app role"`
+}
+
+type GetProjectDatabaseConnectionInput struct {
+	Ref string `query:"ref" example:"hisqrzwgndjcycmkwpnj" doc:"Project reference (20 lower characters [a-z])"`
+}
+
+type RevealProjectDatabaseConnectionInput struct {
+	Body struct {
+		Ref string `json:"ref" example:"hisqrzwgndjcycmkwpnj" doc:"Project reference (20 lower characters [a-z])"`
+	}
+}
+
+type GetProjectDatabaseConnectionOutput struct {
+	Body struct {
+		Connection ProjectDatabaseConnectionDetails `json:"connection" doc:"How to connect to the project database"`
+	}
+}
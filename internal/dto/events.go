@@ -0,0 +1,27 @@
+package dto
+
+import "time"
+
+type ProjectKubernetesEvent struct {
+	Severity  string    `json:"severity" enum:"info,warning,error" doc:"Severity of the event"`
+	Reason    string    `json:"reason" example:"ImagePullBackOff" doc:"Kubernetes event reason"`
+	Message   string    `json:"message" doc:"User-readable description of the event"`
+	Detail    string    `json:"detail" doc:"Original Kubernetes event message"`
+	Kind      string    `json:"kind" example:"Pod" doc:"Kind of the project resource the event is about"`
+	Name      string    `json:"name" doc:"Name of the project resource the event is about"`
+	Count     int32     `json:"count" doc:"Number of times the event occurred"`
+	FirstSeen time.Time `json:"firstSeen" doc:"Time the event first occurred"`
+	LastSeen  time.Time `json:"lastSeen" doc:"Time the event last occurred"`
+}
+
+type ListProjectEventsInput struct {
+	Ref      string    `query:"ref" example:"hisqrzwgndjcycmkwpnj" doc:"Project reference (20 lower characters [a-z])"`
+	Since    time.Time `query:"since" doc:"Only return events that last occurred after this time (RFC 3339)"`
+	Severity string    `query:"severity" enum:"info,warning,error" doc:"Only return events with at least this severity"`
+}
+
+type ListProjectEventsOutput struct {
+	Body struct {
+		Events []ProjectKubernetesEvent `json:"events" doc:"Kubernetes events of the project resources, newest first"`
+	}
+}
@@ -0,0 +1,39 @@
+package dto
+
+type ProjectDatabaseExtension struct {
+	Name             string `json:"name" example:"vector" doc:"Extension name"`
+	Comment          string `json:"comment,omitempty" doc:"Extension description"`
+	Builtin          bool   `json:"builtin" doc:"Installed by the platform on every project and cannot be disabled"`
+	Available        bool   `json:"available" doc:"The extension is shipped in the Postgres image"`
+	Enabled          bool   `json:"enabled" doc:"The extension is requested on the project database"`
+	DefaultVersion   string `json:"defaultVersion,omitempty" doc:"Version installed when no version is requested"`
+	Version          string `json:"version,omitempty" doc:"Requested version"`
+	InstalledVersion string `json:"installedVersion,omitempty" doc:"Version currently installed in the database"`
+	Applied          bool   `json:"applied" doc:"The database operator applied the requested state"`
+	Message          string `json:"message,omitempty" doc:"Error reported by the database operator"`
+}
+
+type ListProjectDatabaseExtensionsInput struct {
+	Ref string `query:"ref" example:"hisqrzwgndjcycmkwpnj" doc:"Project reference (20 lower characters [a-z])"`
+}
+
+type ListProjectDatabaseExtensionsOutput struct {
+	Body struct {
+		Extensions []ProjectDatabaseExtension `json:"extensions" doc:"Built-in and allowlisted extensions of the project database"`
+	}
+}
+
+type UpdateProjectDatabaseExtensionInput struct {
+	Body struct {
+		Ref     string `json:"ref" example:"hisqrzwgndjcycmkwpnj" doc:"Project reference (20 lower characters [a-z])"`
+		Name    string `json:"name" example:"vector" doc:"Extension name"`
+		Enabled bool   `json:"enabled" doc:"Enable (CREATE EXTENSION) or disable (DROP EXTENSION) the extension"`
+		Version string `json:"version,omitempty" required:"false" example:"0.8.0" doc:"Extension version, defaults to the version shipped in the image"`
+	}
+}
+
+type UpdateProjectDatabaseExtensionOutput struct {
+	Body struct {
+		Extension ProjectDatabaseExtension `json:"extension" doc:"Requested extension state; applied updates once the operator reconciles the database"`
+	}
+}
@@ -0,0 +1,30 @@
+package dto
+
+import "time"
+
+type GetProjectJWKSRotationInput struct {
+	Ref string `query:"ref" example:"hisqrzwgndjcycmkwpnj" doc:"Project reference (20 lower characters [a-z])"`
+}
+
+type GetProjectJWKSRotationOutput struct {
+	Body struct {
+		RotatedAt     *time.Time `json:"rotatedAt,omitempty" doc:"Time the auth API last switched to a new signing key"`
+		RetireAt      *time.Time `json:"retireAt,omitempty" doc:"Time the previous keys will be removed; empty when no rotation is in progress"`
+		NextRotation  *time.Time `json:"nextRotation,omitempty" doc:"Time of the next scheduled rotation; empty when scheduled rotation is disabled"`
+		SigningKeyID  string     `json:"signingKeyId" doc:"Key ID (kid) the auth API signs new JWTs with"`
+		PublishedKeys []string   `json:"publishedKeys" doc:"Key IDs the REST API accepts JWTs from"`
+	}
+}
+
+type RotateProjectJWKSInput struct {
+	Body struct {
+		Ref string `json:"ref" example:"hisqrzwgndjcycmkwpnj" doc:"Project reference (20 lower characters [a-z])"`
+	}
+}
+
+type RotateProjectJWKSOutput struct {
+	Body struct {
+		Accepted bool      `json:"accepted" doc:"Indicates the rotation was started"`
+		RetireAt time.Time `json:"retireAt" doc:"Time the previous key will be removed"`
+	}
+}
@@ -0,0 +1,10 @@
+package dto
+
+import "time"
+
+type StreamProjectLogsInput struct {
+	Ref       string    `query:"ref" example:"hisqrzwgndjcycmkwpnj" doc:"Project reference (20 lower characters [a-z])"`
+	Container string    `query:"container" enum:"auth-api,pgrst,openapi" required:"true" doc:"Container to stream logs from"`
+	TailLines int64     `query:"tailLines" minimum:"0" maximum:"5000" default:"100" doc:"Number of most recent lines to start from for each pod"`
+	SinceTime time.Time `query:"sinceTime" doc:"Only return lines logged at or after this time (RFC 3339)"`
+}
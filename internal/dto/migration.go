@@ -0,0 +1,100 @@
+package dto
+
+import "time"
+
+type ProjectMigration struct {
+	JobName     string     `json:"jobName" doc:"Name of the dbmate migration Job"`
+	Action      string     `json:"action,omitempty" enum:"up,rollback" doc:"dbmate command of a user migration run"`
+	Status      string     `json:"status" enum:"pending,running,succeeded,failed" doc:"Migration status"`
+	Message     string     `json:"message,omitempty" doc:"Failure reason reported by Kubernetes"`
+	StartedAt   *time.Time `json:"startedAt,omitempty" doc:"Time the migration Job started"`
+	CompletedAt *time.Time `json:"completedAt,omitempty" doc:"Time the migration Job succeeded or failed"`
+}
+
+type GetProjectMigrationInput struct {
+	Ref string `query:"ref" example:"hisqrzwgndjcycmkwpnj" doc:"Project reference (20 lower characters [a-z])"`
+}
+
+type GetProjectMigrationOutput struct {
+	Body struct {
+		Migration ProjectMigration `json:"migration" doc:"Status of the project database migration"`
+	}
+}
+
+type GetProjectMigrationLogsInput struct {
+	Ref string `query:"ref" example:"hisqrzwgndjcycmkwpnj" doc:"Project reference (20 lower characters [a-z])"`
+}
+
+type GetProjectMigrationLogsOutput struct {
+	Body struct {
+		Migration ProjectMigration `json:"migration" doc:"Status of the project database migration"`
+		Logs      string           `json:"logs" doc:"dbmate output; live output while the migration is running"`
+	}
+}
+
+type ProjectUserMigration struct {
+	Version  string `json:"version" example:"20261018120000" doc:"Migration version (the number before the first underscore of the filename)"`
+	Name     string `json:"name" example:"create_todos" doc:"Migration name"`
+	Filename string `json:"filename,omitempty" example:"20261018120000_create_todos.sql" doc:"Uploaded file; empty when an applied version's file was removed"`
+	Applied  bool   `json:"applied" doc:"The version is recorded in the project's migration history"`
+	HasDown  bool   `json:"hasDown" doc:"The file has a -- migrate:down section and can be rolled back"`
+}
+
+type ListProjectUserMigrationsInput struct {
+	Ref string `query:"ref" example:"hisqrzwgndjcycmkwpnj" doc:"Project reference (20 lower characters [a-z])"`
+}
+
+type ListProjectUserMigrationsOutput struct {
+	Body struct {
+		Migrations []ProjectUserMigration `json:"migrations" doc:"Uploaded and applied migrations ordered by version"`
+		LastRun    *ProjectMigration      `json:"lastRun,omitempty" doc:"Status of the latest apply or rollback"`
+	}
+}
+
+type UploadProjectUserMigrationInput struct {
+	Body struct {
+		Ref      string `json:"ref" example:"hisqrzwgndjcycmkwpnj" doc:"Project reference (20 lower characters [a-z])"`
+		Filename string `json:"filename" maxLength:"200" pattern:"^[0-9]+_[a-z0-9_]+\\.sql$" example:"20261018120000_create_todos.sql" doc:"dbmate migration filename (<version>_<name>.sql)"`
+		Content  string `json:"content" example:"-- migrate:up\ncreate table todos (id serial primary key);\n\n-- migrate:down\ndrop table todos;\n" doc:"dbmate migration with -- migrate:up and optional -- migrate:down sections"`
+	}
+}
+
+type UploadProjectUserMigrationOutput struct {
+	Body struct {
+		Migration ProjectUserMigration `json:"migration" doc:"Uploaded migration"`
+	}
+}
+
+type DeleteProjectUserMigrationInput struct {
+	Ref      string `query:"ref" example:"hisqrzwgndjcycmkwpnj" doc:"Project reference (20 lower characters [a-z])"`
+	Filename string `query:"filename" example:"20261018120000_create_todos.sql" doc:"Migration filename"`
+}
+
+type DeleteProjectUserMigrationOutput struct {
+	Body struct {
+		Success bool `json:"success" doc:"Indicates if the migration was removed"`
+	}
+}
+
+type RunProjectUserMigrationsInput struct {
+	Body struct {
+		Ref string `json:"ref" example:"hisqrzwgndjcycmkwpnj" doc:"Project reference (20 lower characters [a-z])"`
+	}
+}
+
+type RunProjectUserMigrationsOutput struct {
+	Body struct {
+		Migration ProjectMigration `json:"migration" doc:"Status of the started run"`
+	}
+}
+
+type GetProjectUserMigrationRunInput struct {
+	Ref string `query:"ref" example:"hisqrzwgndjcycmkwpnj" doc:"Project reference (20 lower characters [a-z])"`
+}
+
+type GetProjectUserMigrationRunOutput struct {
+	Body struct {
+		Migration ProjectMigration `json:"migration" doc:"Status of the latest apply or rollback"`
+		Logs      string           `json:"logs" doc:"dbmate output; live output while the run is in progress"`
+	}
+}
@@ -0,0 +1,60 @@
+package dto
+
+import "time"
+
+type GetProjectRecoveryWindowInput struct {
+	Ref string `query:"ref" example:"hisqrzwgndjcycmkwpnj" doc:"Project reference (20 lower characters [a-z])"`
+}
+
+type GetProjectRecoveryWindowOutput struct {
+	Body struct {
+		From             time.Time `json:"from" doc:"Earliest time the database can be restored to"`
+		To               time.Time `json:"to" doc:"Latest time the database can be restored to; recent WAL may not be archived yet"`
+		ArchivingHealthy bool      `json:"archivingHealthy" doc:"Whether continuous WAL archiving is currently working"`
+	}
+}
+
+type RestoreProjectToPointInTimeInput struct {
+	Body struct {
+		Ref        string    `json:"ref" example:"hisqrzwgndjcycmkwpnj" doc:"Project reference (20 lower characters [a-z])"`
+		TargetTime time.Time `json:"targetTime" doc:"Time to recover the database to (RFC 3339)"`
+		Mode       string    `json:"mode" enum:"replace,inspect" default:"inspect" doc:"replace recovers into a new cluster and switches the project to it once healthy; inspect creates a separate cluster side by side"`
+	}
+}
+
+type RestoreProjectToPointInTimeOutput struct {
+	Body struct {
+		Accepted bool   `json:"accepted" doc:"Indicates the recovery was started; follow GET /project/backups/restore for progress in replace mode"`
+		Mode     string `json:"mode" doc:"Recovery mode that was started"`
+		Host     string `json:"host,omitempty" doc:"Database host of the inspection cluster (inspect mode only)"`
+	}
+}
+
+type ProjectPITRCluster struct {
+	Name       string     `json:"name" doc:"Inspection cluster name"`
+	Phase      string     `json:"phase" doc:"Cluster phase reported by the database operator"`
+	TargetTime *time.Time `json:"targetTime,omitempty" doc:"Time the cluster was recovered to"`
+	Host       string     `json:"host" doc:"Database host (TLS with SNI) on port 5432"`
+	Username   string     `json:"username" doc:"Database user"`
+	Password   string     `json:"password,omitempty" doc:"Database password, available once the cluster is ready"`
+}
+
+type GetProjectPITRClusterInput struct {
+	Ref string `query:"ref" example:"hisqrzwgndjcycmkwpnj" doc:"Project reference (20 lower characters [a-z])"`
+}
+
+type GetProjectPITRClusterOutput struct {
+	Body struct {
+		Cluster ProjectPITRCluster `json:"cluster" doc:"Point-in-time inspection cluster"`
+	}
+}
+
+type DeleteProjectPITRClusterInput struct {
+	Ref string `query:"ref" example:"hisqrzwgndjcycmkwpnj" doc:"Project reference (20 lower characters [a-z])"`
+}
+
+type DeleteProjectPITRClusterOutput struct {
+	Body struct {
+		Success bool `json:"success" doc:"Indicates if the inspection cluster was deleted"`
+	}
+}
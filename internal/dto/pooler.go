@@ -0,0 +1,35 @@
+package dto
+
+type ProjectPooler struct {
+	Enabled         bool                      `json:"enabled" doc:"Whether PgBouncer connection pooling is enabled"`
+	Instances       int32                     `json:"instances,omitempty" doc:"Number of PgBouncer pods"`
+	ReadyInstances  int32                     `json:"readyInstances,omitempty" doc:"Number of PgBouncer pods reported by the operator"`
+	PoolMode        string                    `json:"poolMode,omitempty" enum:"session,transaction" doc:"PgBouncer pool mode"`
+	DefaultPoolSize int32                     `json:"defaultPoolSize,omitempty" doc:"Server connections per user and database pair"`
+	MaxClientConn   int32                     `json:"maxClientConn,omitempty" doc:"Maximum client connections accepted by each PgBouncer pod"`
+	RESTAPI         bool                      `json:"restApi" doc:"Whether the REST API connects through the pooler"`
+	Connection      ProjectDatabaseConnection `json:"connection" doc:"Direct and pooled connection strings without password"`
+}
+
+type GetProjectPoolerInput struct {
+	Ref string `query:"ref" example:"hisqrzwgndjcycmkwpnj" doc:"Project reference (20 lower characters [a-z])"`
+}
+
+type GetProjectPoolerOutput struct {
+	Body struct {
+		Pooler ProjectPooler `json:"pooler" doc:"Connection pooling settings"`
+	}
+}
+
+// UpdateProjectPoolerInput 以目前的設定 (或預設值) 為基礎，覆寫有提供的欄位
+type UpdateProjectPoolerInput struct {
+	Body struct {
+		Ref             string  `json:"ref" example:"hisqrzwgndjcycmkwpnj" doc:"Project reference (20 lower characters [a-z])"`
+		Enabled         bool    `json:"enabled" doc:"Enable or disable PgBouncer connection pooling"`
+		Instances       *int32  `json:"instances,omitempty" minimum:"1" maximum:"3" doc:"Number of PgBouncer pods"`
+		PoolMode        *string `json:"poolMode,omitempty" enum:"session,transaction" doc:"PgBouncer pool mode; transaction mode disables prepared statements and schema reload notifications of the REST API"`
+		DefaultPoolSize *int32  `json:"defaultPoolSize,omitempty" minimum:"1" maximum:"500" doc:"Server connections per user and database pair"`
+		MaxClientConn   *int32  `json:"maxClientConn,omitempty" minimum:"1" maximum:"10000" doc:"Maximum client connections accepted by each PgBouncer pod"`
+		RESTAPI         *bool   `json:"restApi,omitempty" doc:"Connect the REST API through the pooler"`
+	}
+}
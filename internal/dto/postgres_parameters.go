@@ -0,0 +1,38 @@
+package dto
+
+type ProjectPostgresParameter struct {
+	Name            string   `json:"name" example:"work_mem" doc:"Parameter name"`
+	Type            string   `json:"type" enum:"integer,real,memory,duration,bool,enum" doc:"Value type; memory values need a unit (kB, MB, GB, TB), durations default to milliseconds"`
+	Values          []string `json:"values,omitempty" doc:"Allowed values of an enum parameter"`
+	RestartRequired bool     `json:"restartRequired" doc:"Changing the parameter restarts the database instances"`
+	Desired         string   `json:"desired,omitempty" example:"16MB" doc:"Value set on the project, empty when the default is used"`
+	Applied         string   `json:"applied,omitempty" example:"4MB" doc:"Value currently in effect on the database"`
+	PendingRestart  bool     `json:"pendingRestart" doc:"The database reports a changed value that waits for a restart"`
+	Pending         bool     `json:"pending" doc:"The desired value is not yet in effect"`
+}
+
+type GetProjectPostgresParametersInput struct {
+	Ref string `query:"ref" example:"hisqrzwgndjcycmkwpnj" doc:"Project reference (20 lower characters [a-z])"`
+}
+
+type GetProjectPostgresParametersOutput struct {
+	Body struct {
+		Phase      string                     `json:"phase" example:"Cluster in healthy state" doc:"Cluster phase reported by the database operator"`
+		Parameters []ProjectPostgresParameter `json:"parameters" doc:"Parameters that can be tuned on the project, sorted by name"`
+	}
+}
+
+type UpdateProjectPostgresParametersInput struct {
+	Body struct {
+		Ref string `json:"ref" example:"hisqrzwgndjcycmkwpnj" doc:"Project reference (20 lower characters [a-z])"`
+		// 值為 null 時移除參數並回到預設值
+		Parameters map[string]*string `json:"parameters" doc:"Parameters to change; null resets a parameter to its default"`
+	}
+}
+
+type UpdateProjectPostgresParametersOutput struct {
+	Body struct {
+		RestartRequired bool                       `json:"restartRequired" doc:"The change restarts the database instances to take effect"`
+		Parameters      []ProjectPostgresParameter `json:"parameters" doc:"Parameters after the change; applied values update once the operator reloads the configuration"`
+	}
+}
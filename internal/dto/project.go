@@ -23,6 +23,8 @@ type CreateProjectInput struct {
 		Name        string  `json:"name" maxLength:"100" example:"My Project" doc:"Project name"`
 		Description *string `json:"description" maxLength:"4000" required:"false" example:"This is my project" doc:"Project description"`
 		StorageSize string  `json:"storageSize" hidden:"true" default:"1Gi" example:"1Gi" doc:"Storage size for the project"`
+		Plan        string  `json:"plan,omitempty" required:"false" example:"free" doc:"Plan that provides the default API workload settings and the limits of later changes (uses the platform default when empty); only the default plan and selectable plans can be chosen"`
+		Region      string  `json:"region,omitempty" required:"false" example:"tw-1" doc:"Region of the cluster to place the project in (any region when empty)"`
 	}
 }
 
@@ -90,7 +92,21 @@ type ProjectAuthSettings struct {
 type ProjectAuthProviderInfo struct {
 	Enabled      bool    `json:"enabled" doc:"Whether this OAuth provider is enabled"`
 	ClientID     *string `json:"clientId,omitempty" doc:"OAuth Client ID"`
-	ClientSecret *string `json:"clientSecret,omitempty" doc:"OAuth Client Secret"`
+	ClientSecret *string `json:"clientSecret,omitempty" doc:"Masked OAuth Client Secret, use the reveal endpoint to get the plaintext"`
+}
+
+type RevealProjectAuthSecretInput struct {
+	Body struct {
+		Ref      string `json:"ref" example:"hisqrzwgndjcycmkwpnj" doc:"Project reference (20 lower characters [a-z])"`
+		Provider string `json:"provider" enum:"google,github,discord" doc:"OAuth provider name"`
+	}
+}
+
+type RevealProjectAuthSecretOutput struct {
+	Body struct {
+		Provider     string `json:"provider" doc:"OAuth provider name"`
+		ClientSecret string `json:"clientSecret" doc:"Plaintext OAuth Client Secret"`
+	}
 }
 
 type GetProjectSettingsOutput struct {
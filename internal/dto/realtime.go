@@ -0,0 +1,26 @@
+package dto
+
+type ProjectRealtime struct {
+	Enabled bool     `json:"enabled" doc:"Whether the realtime service is deployed"`
+	Ready   bool     `json:"ready" doc:"Whether the realtime service accepts connections"`
+	URL     string   `json:"url" example:"wss://hisqrzwgndjcycmkwpnj.example.com/api/realtime/websocket" doc:"WebSocket endpoint of the realtime service"`
+	Tables  []string `json:"tables" example:"[\"dbo.classes\",\"dbo.objects\"]" doc:"Tables (schema.table) whose changes are broadcast"`
+}
+
+type GetProjectRealtimeInput struct {
+	Ref string `query:"ref" example:"hisqrzwgndjcycmkwpnj" doc:"Project reference (20 lower characters [a-z])"`
+}
+
+type GetProjectRealtimeOutput struct {
+	Body struct {
+		Realtime ProjectRealtime `json:"realtime" doc:"Realtime service of the project"`
+	}
+}
+
+type UpdateProjectRealtimeInput struct {
+	Body struct {
+		Ref     string   `json:"ref" example:"hisqrzwgndjcycmkwpnj" doc:"Project reference (20 lower characters [a-z])"`
+		Enabled bool     `json:"enabled" doc:"Deploy or remove the realtime service"`
+		Tables  []string `json:"tables,omitempty" maxItems:"100" example:"[\"api.todos\"]" doc:"Tables (schema.table) to broadcast in addition to dbo.classes and dbo.objects; omit to keep the current tables"`
+	}
+}
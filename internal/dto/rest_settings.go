@@ -0,0 +1,35 @@
+package dto
+
+type ProjectRESTSettings struct {
+	Schemas                []string `json:"schemas" doc:"Schemas exposed by the REST API; the first one is used when a request does not select a profile"`
+	MaxRows                *int32   `json:"maxRows,omitempty" doc:"Maximum rows returned per request; unlimited when omitted"`
+	PreRequest             string   `json:"preRequest,omitempty" doc:"Function called at the start of every request"`
+	PoolSize               int32    `json:"poolSize" doc:"Database connections of each REST API pod"`
+	PoolAcquisitionTimeout int32    `json:"poolAcquisitionTimeout" doc:"Seconds a request waits for a database connection"`
+	JWTAudience            string   `json:"jwtAudience,omitempty" doc:"Audience JWTs must contain"`
+	OpenAPIMode            string   `json:"openApiMode" enum:"follow-privileges,ignore-privileges,disabled" doc:"Which objects the OpenAPI output lists"`
+}
+
+type GetProjectRESTSettingsInput struct {
+	Ref string `query:"ref" example:"hisqrzwgndjcycmkwpnj" doc:"Project reference (20 lower characters [a-z])"`
+}
+
+type GetProjectRESTSettingsOutput struct {
+	Body struct {
+		Settings ProjectRESTSettings `json:"settings" doc:"REST API (PostgREST) settings"`
+	}
+}
+
+// UpdateProjectRESTSettingsInput 以目前的設定為基礎，覆寫有提供的欄位
+type UpdateProjectRESTSettingsInput struct {
+	Body struct {
+		Ref                    string   `json:"ref" example:"hisqrzwgndjcycmkwpnj" doc:"Project reference (20 lower characters [a-z])"`
+		Schemas                []string `json:"schemas,omitempty" minItems:"1" maxItems:"10" doc:"Schemas to expose; they must exist in the project database"`
+		MaxRows                *int32   `json:"maxRows,omitempty" minimum:"0" maximum:"1000000" doc:"Maximum rows returned per request; 0 removes the limit"`
+		PreRequest             *string  `json:"preRequest,omitempty" maxLength:"127" doc:"Function called at the start of every request, optionally schema-qualified; empty removes it"`
+		PoolSize               *int32   `json:"poolSize,omitempty" minimum:"1" maximum:"100" doc:"Database connections of each REST API pod"`
+		PoolAcquisitionTimeout *int32   `json:"poolAcquisitionTimeout,omitempty" minimum:"1" maximum:"60" doc:"Seconds a request waits for a database connection"`
+		JWTAudience            *string  `json:"jwtAudience,omitempty" maxLength:"255" doc:"Audience JWTs must contain; empty accepts any audience"`
+		OpenAPIMode            *string  `json:"openApiMode,omitempty" enum:"follow-privileges,ignore-privileges,disabled" doc:"Which objects the OpenAPI output lists"`
+	}
+}
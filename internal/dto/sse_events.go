@@ -1,5 +1,7 @@
 package dto
 
+import "time"
+
 type MessageEvent struct {
 	Message string `json:"message"`
 }
@@ -13,3 +15,10 @@ type ProjectStatusEvent struct {
 	Step      int    `json:"step"`
 	TotalStep int    `json:"totalStep"`
 }
+
+type ProjectLogEvent struct {
+	Pod       string     `json:"pod"`
+	Container string     `json:"container"`
+	Timestamp *time.Time `json:"timestamp,omitempty"`
+	Line      string     `json:"line"`
+}
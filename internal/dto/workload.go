@@ -0,0 +1,53 @@
+package dto
+
+// ProjectWorkloadResources 的欄位與 config.WorkloadResources 相同，可以直接轉型
+type ProjectWorkloadResources struct {
+	RequestsCPU    string `json:"requestsCPU,omitempty" example:"100m" doc:"CPU request of the API container"`
+	RequestsMemory string `json:"requestsMemory,omitempty" example:"256Mi" doc:"Memory request of the API container"`
+	LimitsCPU      string `json:"limitsCPU,omitempty" example:"1" doc:"CPU limit of the API container"`
+	LimitsMemory   string `json:"limitsMemory,omitempty" example:"512Mi" doc:"Memory limit of the API container"`
+}
+
+type ProjectWorkloadAutoscaling struct {
+	Enabled                        bool  `json:"enabled" doc:"Enable the HorizontalPodAutoscaler"`
+	MinReplicas                    int32 `json:"minReplicas,omitempty" minimum:"1" maximum:"20" doc:"Minimum replicas when autoscaling"`
+	MaxReplicas                    int32 `json:"maxReplicas,omitempty" minimum:"1" maximum:"20" doc:"Maximum replicas when autoscaling"`
+	TargetCPUUtilizationPercentage int32 `json:"targetCPUUtilizationPercentage,omitempty" minimum:"1" maximum:"100" doc:"Average CPU utilization (of requests) to scale at"`
+}
+
+type ProjectWorkloadPodDisruptionBudget struct {
+	MinAvailable   string `json:"minAvailable,omitempty" example:"1" doc:"Minimum available pods during voluntary disruptions (integer or percentage)"`
+	MaxUnavailable string `json:"maxUnavailable,omitempty" example:"1" doc:"Maximum unavailable pods during voluntary disruptions (integer or percentage)"`
+}
+
+type ProjectWorkload struct {
+	Replicas            int32                              `json:"replicas" doc:"Replicas when autoscaling is disabled"`
+	Resources           ProjectWorkloadResources           `json:"resources" doc:"Container resource requests and limits"`
+	Autoscaling         ProjectWorkloadAutoscaling         `json:"autoscaling" doc:"HorizontalPodAutoscaler settings"`
+	PodDisruptionBudget ProjectWorkloadPodDisruptionBudget `json:"podDisruptionBudget" doc:"PodDisruptionBudget settings, empty disables the budget"`
+}
+
+type GetProjectWorkloadInput struct {
+	Ref       string `query:"ref" example:"hisqrzwgndjcycmkwpnj" doc:"Project reference (20 lower characters [a-z])"`
+	Component string `query:"component" enum:"auth-api,rest-api" doc:"Project API component"`
+}
+
+type GetProjectWorkloadOutput struct {
+	Body struct {
+		Component string          `json:"component" doc:"Project API component"`
+		Workload  ProjectWorkload `json:"workload" doc:"Currently applied workload settings"`
+	}
+}
+
+// UpdateProjectWorkloadInput 以目前的設定 (或指定方案的預設值) 為基礎，覆寫有提供的區塊
+type UpdateProjectWorkloadInput struct {
+	Body struct {
+		Ref                 string                              `json:"ref" example:"hisqrzwgndjcycmkwpnj" doc:"Project reference (20 lower characters [a-z])"`
+		Component           string                              `json:"component" enum:"auth-api,rest-api" doc:"Project API component"`
+		Plan                *string                             `json:"plan,omitempty" example:"pro" doc:"Reset to the defaults of this plan before applying the overrides; must be the project plan, the default plan or a selectable plan. The result must stay within the limits of the project plan"`
+		Replicas            *int32                              `json:"replicas,omitempty" minimum:"1" maximum:"20" doc:"Replicas when autoscaling is disabled"`
+		Resources           *ProjectWorkloadResources           `json:"resources,omitempty" doc:"Replaces the container resource settings"`
+		Autoscaling         *ProjectWorkloadAutoscaling         `json:"autoscaling,omitempty" doc:"Replaces the HorizontalPodAutoscaler settings"`
+		PodDisruptionBudget *ProjectWorkloadPodDisruptionBudget `json:"podDisruptionBudget,omitempty" doc:"Replaces the PodDisruptionBudget settings"`
+	}
+}
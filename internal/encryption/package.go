@@ -0,0 +1,7 @@
+package encryption
+
+import "github.com/samber/do/v2"
+
+var Package = do.Package(
+	do.Lazy(NewService),
+)
@@ -0,0 +1,205 @@
+// Package encryption implements envelope encryption of secrets stored in the platform database.
+package encryption
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"baas-api/internal/config"
+
+	"github.com/samber/do/v2"
+)
+
+var (
+	ErrNoActiveKey   = errors.New("encryption key not configured")
+	ErrUnknownKey    = errors.New("unknown encryption key")
+	ErrInvalidCipher = errors.New("invalid encrypted value")
+)
+
+// envelopePrefix 標記已加密的值，沒有此前綴的值視為尚未加密的舊資料
+const envelopePrefix = "enc:v1:"
+
+// keySize 是 AES-256 的 key 長度，KEK 與 DEK 相同
+const keySize = 32
+
+type Service interface {
+	// Encrypt 以新的 data key (DEK) 加密 plaintext，再以 active KEK 加密 DEK。
+	//
+	// 回傳值格式為 "enc:v1:<key ID>:<加密的 DEK>:<加密的資料>"，可直接存入 text 欄位。
+	Encrypt(plaintext string) (string, error)
+	// Decrypt 解密 Encrypt 的結果，未加密的舊資料原樣回傳
+	Decrypt(value string) (string, error)
+	// IsEncrypted 回傳值是否已加密
+	IsEncrypted(value string) bool
+	// NeedsReencrypt 回傳值是否尚未加密或不是以 active KEK 加密
+	NeedsReencrypt(value string) bool
+	ActiveKeyID() string
+}
+
+type service struct {
+	activeKeyID string
+	keys        map[string]cipher.AEAD
+}
+
+var _ Service = (*service)(nil)
+
+func NewService(i do.Injector) (*service, error) {
+	cfg := do.MustInvoke[*config.Config](i)
+	return newService(&cfg.Encryption)
+}
+
+func newService(cfg *config.EncryptionConfig) (*service, error) {
+	rawKeys := map[string]string{}
+	for _, key := range cfg.Keys {
+		rawKeys[key.ID] = key.Key
+	}
+	if cfg.KeysDir != "" {
+		entries, err := os.ReadDir(cfg.KeysDir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read encryption keys dir: %w", err)
+		}
+		for _, entry := range entries {
+			// 掛載的 Kubernetes Secret 會有 "..data" 等隱藏檔案
+			if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+				continue
+			}
+			data, err := os.ReadFile(filepath.Join(cfg.KeysDir, entry.Name()))
+			if err != nil {
+				return nil, fmt.Errorf("failed to read encryption key %s: %w", entry.Name(), err)
+			}
+			rawKeys[entry.Name()] = strings.TrimSpace(string(data))
+		}
+	}
+
+	s := &service{activeKeyID: cfg.ActiveKeyID, keys: map[string]cipher.AEAD{}}
+	for id, raw := range rawKeys {
+		if id == "" || strings.Contains(id, ":") {
+			return nil, fmt.Errorf("invalid encryption key ID %q", id)
+		}
+		key, err := base64.StdEncoding.DecodeString(raw)
+		if err != nil || len(key) != keySize {
+			return nil, fmt.Errorf("encryption key %s must be %d bytes encoded in base64", id, keySize)
+		}
+		aead, err := newAEAD(key)
+		if err != nil {
+			return nil, err
+		}
+		s.keys[id] = aead
+	}
+
+	if s.activeKeyID == "" {
+		slog.Warn("No active encryption key configured, secrets are stored unencrypted")
+	} else if _, ok := s.keys[s.activeKeyID]; !ok {
+		return nil, fmt.Errorf("%w: active key %s", ErrUnknownKey, s.activeKeyID)
+	}
+	return s, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// seal 加密 plaintext，回傳 nonce 與 ciphertext 串接後的 base64
+func seal(aead cipher.AEAD, plaintext, additionalData []byte) (string, error) {
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, plaintext, additionalData)
+	return base64.RawURLEncoding.EncodeToString(sealed), nil
+}
+
+func open(aead cipher.AEAD, encoded string, additionalData []byte) ([]byte, error) {
+	sealed, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil || len(sealed) < aead.NonceSize() {
+		return nil, ErrInvalidCipher
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, additionalData)
+	if err != nil {
+		return nil, ErrInvalidCipher
+	}
+	return plaintext, nil
+}
+
+func (s *service) Encrypt(plaintext string) (string, error) {
+	kek, ok := s.keys[s.activeKeyID]
+	if !ok {
+		return "", ErrNoActiveKey
+	}
+
+	dek := make([]byte, keySize)
+	if _, err := rand.Read(dek); err != nil {
+		return "", err
+	}
+	dekAEAD, err := newAEAD(dek)
+	if err != nil {
+		return "", err
+	}
+
+	// key ID 作為 additional data，避免加密的 DEK 被搬到其他 key ID 下
+	additionalData := []byte(s.activeKeyID)
+	wrappedDEK, err := seal(kek, dek, additionalData)
+	if err != nil {
+		return "", err
+	}
+	data, err := seal(dekAEAD, []byte(plaintext), additionalData)
+	if err != nil {
+		return "", err
+	}
+	return envelopePrefix + s.activeKeyID + ":" + wrappedDEK + ":" + data, nil
+}
+
+func (s *service) Decrypt(value string) (string, error) {
+	if !s.IsEncrypted(value) {
+		return value, nil
+	}
+	parts := strings.Split(strings.TrimPrefix(value, envelopePrefix), ":")
+	if len(parts) != 3 {
+		return "", ErrInvalidCipher
+	}
+	keyID, wrappedDEK, data := parts[0], parts[1], parts[2]
+
+	kek, ok := s.keys[keyID]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrUnknownKey, keyID)
+	}
+	additionalData := []byte(keyID)
+	dek, err := open(kek, wrappedDEK, additionalData)
+	if err != nil {
+		return "", err
+	}
+	dekAEAD, err := newAEAD(dek)
+	if err != nil {
+		return "", ErrInvalidCipher
+	}
+	plaintext, err := open(dekAEAD, data, additionalData)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+func (s *service) IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, envelopePrefix)
+}
+
+func (s *service) NeedsReencrypt(value string) bool {
+	return !strings.HasPrefix(value, envelopePrefix+s.activeKeyID+":")
+}
+
+func (s *service) ActiveKeyID() string {
+	return s.activeKeyID
+}
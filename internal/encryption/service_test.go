@@ -0,0 +1,212 @@
+package encryption
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"baas-api/internal/config"
+)
+
+func newTestKey(t *testing.T) string {
+	t.Helper()
+	key := make([]byte, keySize)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatal(err)
+	}
+	return base64.StdEncoding.EncodeToString(key)
+}
+
+func newTestService(t *testing.T, activeKeyID string, keys map[string]string) *service {
+	t.Helper()
+	cfg := &config.EncryptionConfig{ActiveKeyID: activeKeyID}
+	for id, key := range keys {
+		cfg.Keys = append(cfg.Keys, config.EncryptionKeyConfig{ID: id, Key: key})
+	}
+	s, err := newService(cfg)
+	if err != nil {
+		t.Fatalf("newService: %v", err)
+	}
+	return s
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	s := newTestService(t, "k1", map[string]string{"k1": newTestKey(t)})
+
+	tests := []struct {
+		name      string
+		plaintext string
+	}{
+		{name: "empty", plaintext: ""},
+		{name: "ascii", plaintext: "client-secret"},
+		{name: "contains separator", plaintext: "a:b:c"},
+		{name: "unicode", plaintext: "密碼 🔑"},
+		{name: "long", plaintext: strings.Repeat("x", 4096)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encrypted, err := s.Encrypt(tt.plaintext)
+			if err != nil {
+				t.Fatalf("Encrypt: %v", err)
+			}
+			if !s.IsEncrypted(encrypted) || s.NeedsReencrypt(encrypted) {
+				t.Errorf("IsEncrypted = %v, NeedsReencrypt = %v", s.IsEncrypted(encrypted), s.NeedsReencrypt(encrypted))
+			}
+			if !strings.HasPrefix(encrypted, envelopePrefix+"k1:") {
+				t.Errorf("encrypted = %q, want key ID k1", encrypted)
+			}
+			if tt.plaintext != "" && strings.Contains(encrypted, tt.plaintext) {
+				t.Errorf("encrypted value contains the plaintext")
+			}
+
+			again, err := s.Encrypt(tt.plaintext)
+			if err != nil {
+				t.Fatalf("Encrypt: %v", err)
+			}
+			if again == encrypted {
+				t.Error("encrypting twice returned the same value")
+			}
+
+			decrypted, err := s.Decrypt(encrypted)
+			if err != nil || decrypted != tt.plaintext {
+				t.Errorf("Decrypt = %q, %v, want %q", decrypted, err, tt.plaintext)
+			}
+		})
+	}
+}
+
+func TestDecryptInvalid(t *testing.T) {
+	s := newTestService(t, "k1", map[string]string{"k1": newTestKey(t), "k2": newTestKey(t)})
+	encrypted, err := s.Encrypt("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts := strings.Split(strings.TrimPrefix(encrypted, envelopePrefix), ":")
+	_, wrappedDEK, data := parts[0], parts[1], parts[2]
+
+	tests := []struct {
+		name    string
+		value   string
+		want    string
+		wantErr error
+	}{
+		{name: "legacy plaintext", value: "plain-secret", want: "plain-secret"},
+		{name: "unknown key", value: envelopePrefix + "k9:" + wrappedDEK + ":" + data, wantErr: ErrUnknownKey},
+		{name: "missing part", value: envelopePrefix + "k1:" + wrappedDEK, wantErr: ErrInvalidCipher},
+		{name: "invalid base64", value: envelopePrefix + "k1:" + wrappedDEK + ":!!!", wantErr: ErrInvalidCipher},
+		{name: "tampered data", value: envelopePrefix + "k1:" + wrappedDEK + ":" + tamper(data), wantErr: ErrInvalidCipher},
+		{name: "tampered DEK", value: envelopePrefix + "k1:" + tamper(wrappedDEK) + ":" + data, wantErr: ErrInvalidCipher},
+		// key ID 是 additional data，DEK 搬到其他 key 下無法解密
+		{name: "moved to another key", value: envelopePrefix + "k2:" + wrappedDEK + ":" + data, wantErr: ErrInvalidCipher},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.Decrypt(tt.value)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Decrypt error = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Decrypt = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// tamper 改變 base64 字串中的一個字元
+func tamper(encoded string) string {
+	b := []byte(encoded)
+	if b[len(b)/2] == 'A' {
+		b[len(b)/2] = 'B'
+	} else {
+		b[len(b)/2] = 'A'
+	}
+	return string(b)
+}
+
+func TestKeyRotation(t *testing.T) {
+	oldKey, newKey := newTestKey(t), newTestKey(t)
+	before := newTestService(t, "old", map[string]string{"old": oldKey})
+	encrypted, err := before.Encrypt("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 新的 active key 加入後，舊 key 加密的值仍可解密，但需要重新加密
+	after := newTestService(t, "new", map[string]string{"old": oldKey, "new": newKey})
+	if !after.NeedsReencrypt(encrypted) || !after.NeedsReencrypt("plain-secret") {
+		t.Error("values encrypted with the old key or unencrypted should need re-encryption")
+	}
+	plaintext, err := after.Decrypt(encrypted)
+	if err != nil || plaintext != "secret" {
+		t.Fatalf("Decrypt = %q, %v", plaintext, err)
+	}
+	reencrypted, err := after.Encrypt(plaintext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if after.NeedsReencrypt(reencrypted) || !strings.HasPrefix(reencrypted, envelopePrefix+"new:") {
+		t.Errorf("re-encrypted value = %q", reencrypted)
+	}
+
+	// 舊 key 移除後無法解密舊的值
+	retired := newTestService(t, "new", map[string]string{"new": newKey})
+	if _, err := retired.Decrypt(encrypted); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("Decrypt with retired key error = %v, want %v", err, ErrUnknownKey)
+	}
+	if got, err := retired.Decrypt(reencrypted); err != nil || got != "secret" {
+		t.Errorf("Decrypt = %q, %v", got, err)
+	}
+}
+
+func TestNoActiveKey(t *testing.T) {
+	s := newTestService(t, "", nil)
+	if _, err := s.Encrypt("secret"); !errors.Is(err, ErrNoActiveKey) {
+		t.Errorf("Encrypt error = %v, want %v", err, ErrNoActiveKey)
+	}
+	if got, err := s.Decrypt("plain-secret"); err != nil || got != "plain-secret" {
+		t.Errorf("Decrypt = %q, %v", got, err)
+	}
+}
+
+func TestNewServiceConfig(t *testing.T) {
+	validKey := newTestKey(t)
+
+	keysDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(keysDir, "mounted"), []byte(validKey+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	// 掛載的 Kubernetes Secret 中的隱藏檔案與目錄
+	if err := os.WriteFile(filepath.Join(keysDir, "..data"), []byte("not a key"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(keysDir, "subdir"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		cfg     config.EncryptionConfig
+		wantErr bool
+	}{
+		{name: "inline key", cfg: config.EncryptionConfig{ActiveKeyID: "k1", Keys: []config.EncryptionKeyConfig{{ID: "k1", Key: validKey}}}},
+		{name: "keys dir", cfg: config.EncryptionConfig{ActiveKeyID: "mounted", KeysDir: keysDir}},
+		{name: "no keys", cfg: config.EncryptionConfig{}},
+		{name: "unknown active key", cfg: config.EncryptionConfig{ActiveKeyID: "k2", Keys: []config.EncryptionKeyConfig{{ID: "k1", Key: validKey}}}, wantErr: true},
+		{name: "short key", cfg: config.EncryptionConfig{ActiveKeyID: "k1", Keys: []config.EncryptionKeyConfig{{ID: "k1", Key: base64.StdEncoding.EncodeToString([]byte("short"))}}}, wantErr: true},
+		{name: "invalid base64", cfg: config.EncryptionConfig{ActiveKeyID: "k1", Keys: []config.EncryptionKeyConfig{{ID: "k1", Key: "not base64!"}}}, wantErr: true},
+		{name: "separator in key ID", cfg: config.EncryptionConfig{Keys: []config.EncryptionKeyConfig{{ID: "k:1", Key: validKey}}}, wantErr: true},
+		{name: "missing keys dir", cfg: config.EncryptionConfig{KeysDir: filepath.Join(keysDir, "missing")}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newService(&tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("newService error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
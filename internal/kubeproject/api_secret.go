@@ -0,0 +1,158 @@
+package kubeproject
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// AnnotationSecretChecksum 記錄 Pod 使用的 secret 值的雜湊，值改變時 Deployment 會 rolling update
+const AnnotationSecretChecksum = "baas.wke/secret-checksum"
+
+// API Secret 中的 key，與 container 環境變數名稱相同
+const (
+	SecretKeyBetterAuthSecret = "BETTER_AUTH_SECRET"
+	SecretKeyPGRSTJWTSecret   = "PGRST_JWT_SECRET"
+
+	SecretKeyRealtimeSecretKeyBase = "SECRET_KEY_BASE"
+	SecretKeyRealtimeDBEncKey      = "DB_ENC_KEY"
+	SecretKeyRealtimeAPIJWTSecret  = "API_JWT_SECRET"
+)
+
+// isAPISecretEnvName 回傳環境變數是否應該放在 API Secret 中
+func isAPISecretEnvName(name string) bool {
+	return name == SecretKeyBetterAuthSecret || name == SecretKeyPGRSTJWTSecret || isClientSecretKey(name)
+}
+
+// isClientSecretKey 回傳 key 是否為 OAuth provider 的 client secret
+func isClientSecretKey(key string) bool {
+	return strings.HasSuffix(key, "_CLIENT_SECRET")
+}
+
+// secretEnvVar 回傳以 secretKeyRef 引用 API Secret 中同名 key 的環境變數
+func (s *service) secretEnvVar(ref string, name string) corev1.EnvVar {
+	return corev1.EnvVar{
+		Name: name,
+		ValueFrom: &corev1.EnvVarSource{
+			SecretKeyRef: &corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: s.GetAPISecretName(ref)},
+				Key:                  name,
+			},
+		},
+	}
+}
+
+// applyAPISecret 將 values 合併到專案的 API Secret 後套用，回傳合併後的完整內容。
+//
+// prune 不為 nil 時，目前符合 prune 但不在 values 中的 key 會被移除 (例如已移除的 provider 的 client secret)。
+// Secret 不以 Cluster 為 owner，還原資料庫時不會被刪除。
+func (s *service) applyAPISecret(ctx context.Context, ref string, values map[string]string, prune func(key string) bool) (map[string]string, error) {
+	name := s.GetAPISecretName(ref)
+	data := map[string]string{}
+	removed := map[string]any{}
+	current, err := s.clientset.CoreV1().Secrets(s.GetProjectNamespace(ref)).Get(ctx, name, metav1.GetOptions{})
+	switch {
+	case err == nil:
+		for key, value := range current.Data {
+			if _, ok := values[key]; !ok && prune != nil && prune(key) {
+				removed[key] = nil
+				continue
+			}
+			data[key] = string(value)
+		}
+	case !apierrors.IsNotFound(err):
+		slog.ErrorContext(ctx, "Failed to get API secret", "error", err, "ref", ref)
+		return nil, errors.New("failed to get API secret")
+	}
+	for key, value := range values {
+		data[key] = value
+	}
+
+	// stringData 套用後會合併到 data，server-side apply 不會移除省略的 key，因此以 merge patch 明確移除
+	if len(removed) > 0 {
+		patch, err := json.Marshal(map[string]any{"data": removed})
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed to marshal API secret prune patch", "error", err, "ref", ref)
+			return nil, errors.New("failed to marshal API secret prune patch")
+		}
+		_, err = s.clientset.CoreV1().Secrets(s.GetProjectNamespace(ref)).Patch(
+			ctx,
+			name,
+			types.MergePatchType,
+			patch,
+			metav1.PatchOptions{FieldManager: FieldManager},
+		)
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed to prune API secret", "error", err, "ref", ref)
+			return nil, errors.New("failed to prune API secret")
+		}
+	}
+
+	secret := &corev1.Secret{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "v1",
+			Kind:       "Secret",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: s.GetProjectNamespace(ref),
+			Labels:    projectLabels(ref, APISecretComponent),
+		},
+		Type:       corev1.SecretTypeOpaque,
+		StringData: data,
+	}
+	payload, err := json.Marshal(secret)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to marshal API secret", "error", err, "ref", ref)
+		return nil, errors.New("failed to marshal API secret")
+	}
+	_, err = s.clientset.CoreV1().Secrets(s.GetProjectNamespace(ref)).Patch(
+		ctx,
+		name,
+		types.ApplyPatchType,
+		payload,
+		applyPatchOptions(FieldManager),
+	)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to apply API secret", "error", err, "ref", ref)
+		return nil, errors.New("failed to apply API secret")
+	}
+	return data, nil
+}
+
+// secretChecksum 計算 Deployment 使用的 secret key 的雜湊
+func secretChecksum(data map[string]string, keys []string) string {
+	keys = slices.Clone(keys)
+	slices.Sort(keys)
+	hash := sha256.New()
+	for _, key := range lo.Uniq(keys) {
+		hash.Write([]byte(key))
+		hash.Write([]byte{0})
+		hash.Write([]byte(data[key]))
+		hash.Write([]byte{0})
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// secretEnvKeys 回傳環境變數中引用 API Secret 的 key
+func (s *service) secretEnvKeys(ref string, envVars []corev1.EnvVar) []string {
+	secretName := s.GetAPISecretName(ref)
+	var keys []string
+	for _, env := range envVars {
+		if env.ValueFrom != nil && env.ValueFrom.SecretKeyRef != nil && env.ValueFrom.SecretKeyRef.Name == secretName {
+			keys = append(keys, env.ValueFrom.SecretKeyRef.Key)
+		}
+	}
+	return keys
+}
@@ -0,0 +1,51 @@
+package kubeproject
+
+import (
+	"github.com/samber/lo"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// FieldManager 是所有專案資源在 server-side apply 時使用的 field manager
+	FieldManager = "baas-api"
+	// SettingsFieldManager 用於專案設定變更 (PatchAuthAPIDeployment)，
+	// 與建立時的欄位分開管理，避免重新套用時互相覆蓋
+	SettingsFieldManager = "baas-api-settings"
+	// WorkloadFieldManager 用於 API Deployment 的副本數、resources 以及 HPA/PDB (ApplyAPIWorkload)
+	WorkloadFieldManager = "baas-api-workload"
+	// ClusterFieldManager 用於 CNPG Cluster 的 instance 數與同步複寫設定 (ApplyDatabaseCluster)
+	ClusterFieldManager = "baas-api-cluster"
+	// ParametersFieldManager 用於專案調整的 Postgres 參數 (ApplyPostgresParameters)
+	ParametersFieldManager = "baas-api-parameters"
+	// PoolerFieldManager 用於 PostgREST 經由 pooler 連線的設定 (ApplyRESTAPIPooler)
+	PoolerFieldManager = "baas-api-pooler"
+	// JWKSFieldManager 用於輪替 JWKS 時更新 REST API Pod 的 secret checksum (ApplyRESTAPIJWKS)
+	JWKSFieldManager = "baas-api-jwks"
+	// RESTSettingsFieldManager 用於專案調整的 PostgREST 設定 (ApplyRESTAPISettings)
+	RESTSettingsFieldManager = "baas-api-rest-settings"
+)
+
+// applyPatchOptions returns the PatchOptions for a server-side apply via the typed clientset.
+func applyPatchOptions(fieldManager string) metav1.PatchOptions {
+	return metav1.PatchOptions{
+		FieldManager: fieldManager,
+		Force:        lo.ToPtr(true),
+	}
+}
+
+// applyOptions returns the ApplyOptions for a server-side apply via the dynamic client.
+func applyOptions(fieldManager string) metav1.ApplyOptions {
+	return metav1.ApplyOptions{
+		FieldManager: fieldManager,
+		Force:        true,
+	}
+}
+
+// ignoreNotFound 讓刪除已不存在的資源視為成功，使刪除步驟可以重複執行
+func ignoreNotFound(err error) error {
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
@@ -6,7 +6,9 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"maps"
 	"net/url"
+	"slices"
 	"strings"
 
 	"baas-api/internal/dto"
@@ -17,8 +19,10 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
+// APIDeploymentOption 是 auth API 的設定，PatchAuthAPIDeployment 需要完整的設定
 type APIDeploymentOption struct {
 	BetterAuthSecret *string
 	TrustedOrigins   []string
@@ -31,16 +35,23 @@ func (s *service) CreateAuthAPIDeployment(ctx context.Context, ref string, opt *
 		return errors.New("BetterAuthSecret is required when creating Auth API deployment")
 	}
 
+	// secret 值放在專案的 API Secret 中，環境變數以 secretKeyRef 引用
+	secretValues := map[string]string{
+		SecretKeyBetterAuthSecret: *opt.BetterAuthSecret,
+	}
+
+	appSecretName, err := s.databaseRoleSecretName(ctx, ref, RoleApp)
+	if err != nil {
+		return err
+	}
+
 	// Build environment variables dynamically
 	envVars := []corev1.EnvVar{
 		{
 			Name:  "BETTER_AUTH_URL",
 			Value: fmt.Sprintf("https://%s.%s", ref, s.config.App.ExternalDomain),
 		},
-		{
-			Name:  "BETTER_AUTH_SECRET",
-			Value: *opt.BetterAuthSecret,
-		},
+		s.secretEnvVar(ref, SecretKeyBetterAuthSecret),
 		{
 			Name:  "TRUSTED_ORIGINS",
 			Value: strings.Join(opt.TrustedOrigins, ","),
@@ -54,7 +65,7 @@ func (s *service) CreateAuthAPIDeployment(ctx context.Context, ref string, opt *
 			ValueFrom: &corev1.EnvVarSource{
 				SecretKeyRef: &corev1.SecretKeySelector{
 					LocalObjectReference: corev1.LocalObjectReference{
-						Name: fmt.Sprintf("%s-app", ref),
+						Name: appSecretName,
 					},
 					Key: "uri",
 				},
@@ -63,33 +74,34 @@ func (s *service) CreateAuthAPIDeployment(ctx context.Context, ref string, opt *
 	}
 
 	// Add OAuth provider environment variables dynamically from AuthProviders
-	for providerName, provider := range opt.AuthProviders {
-		upperProviderName := strings.ToUpper(providerName)
-		envVars = append(envVars,
-			corev1.EnvVar{Name: upperProviderName + "_ENABLED", Value: utils.BoolToString(provider.Enabled)},
-		)
-		if provider.ClientID != nil {
-			envVars = append(envVars,
-				corev1.EnvVar{Name: upperProviderName + "_CLIENT_ID", Value: *provider.ClientID},
-			)
-		}
-		if provider.ClientSecret != nil {
-			envVars = append(envVars,
-				corev1.EnvVar{Name: upperProviderName + "_CLIENT_SECRET", Value: *provider.ClientSecret},
-			)
-		}
+	envVars = append(envVars, s.authProviderEnvVars(ref, opt.AuthProviders, secretValues)...)
+
+	secretData, err := s.applyAPISecret(ctx, ref, secretValues, nil)
+	if err != nil {
+		return err
 	}
 
 	deploymentName := s.GetAuthAPIDeploymentName(ref)
 	authContainerName := s.GetAuthAPIContainerName(ref)
+	ownerRef, err := s.clusterOwnerReference(ctx, ref)
+	if err != nil {
+		return err
+	}
+
 	// Create the deployment object
 	deployment := &appsv1.Deployment{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "apps/v1",
+			Kind:       "Deployment",
+		},
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      deploymentName,
-			Namespace: s.namespace,
+			Name:            deploymentName,
+			Namespace:       s.GetProjectNamespace(ref),
+			Labels:          projectLabels(ref, AuthAPIComponent),
+			OwnerReferences: []metav1.OwnerReference{*ownerRef},
 		},
+		// Replicas 與 resources 由 ApplyAPIWorkload 管理
 		Spec: appsv1.DeploymentSpec{
-			Replicas: lo.ToPtr(int32(1)),
 			Selector: &metav1.LabelSelector{
 				MatchLabels: map[string]string{
 					"app": deploymentName,
@@ -97,8 +109,11 @@ func (s *service) CreateAuthAPIDeployment(ctx context.Context, ref string, opt *
 			},
 			Template: corev1.PodTemplateSpec{
 				ObjectMeta: metav1.ObjectMeta{
-					Labels: map[string]string{
+					Labels: lo.Assign(projectLabels(ref, AuthAPIComponent), map[string]string{
 						"app": deploymentName,
+					}),
+					Annotations: map[string]string{
+						AnnotationSecretChecksum: secretChecksum(secretData, s.secretEnvKeys(ref, envVars)),
 					},
 				},
 				Spec: corev1.PodSpec{
@@ -112,6 +127,26 @@ func (s *service) CreateAuthAPIDeployment(ctx context.Context, ref string, opt *
 								},
 							},
 							Env: envVars,
+							ReadinessProbe: &corev1.Probe{
+								ProbeHandler: corev1.ProbeHandler{
+									HTTPGet: &corev1.HTTPGetAction{
+										Path: "/api/auth/ok",
+										Port: intstr.FromInt32(3000),
+									},
+								},
+								PeriodSeconds:    5,
+								FailureThreshold: 3,
+							},
+							LivenessProbe: &corev1.Probe{
+								ProbeHandler: corev1.ProbeHandler{
+									TCPSocket: &corev1.TCPSocketAction{
+										Port: intstr.FromInt32(3000),
+									},
+								},
+								InitialDelaySeconds: 10,
+								PeriodSeconds:       10,
+								FailureThreshold:    3,
+							},
 						},
 					},
 				},
@@ -119,8 +154,20 @@ func (s *service) CreateAuthAPIDeployment(ctx context.Context, ref string, opt *
 		},
 	}
 
-	// Create the deployment
-	_, err := s.clientset.AppsV1().Deployments(s.namespace).Create(ctx, deployment, metav1.CreateOptions{})
+	data, err := json.Marshal(deployment)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to marshal API deployment", "error", err)
+		return errors.New("failed to marshal API deployment")
+	}
+
+	// Apply the deployment
+	_, err = s.clientset.AppsV1().Deployments(s.GetProjectNamespace(ref)).Patch(
+		ctx,
+		deploymentName,
+		types.ApplyPatchType,
+		data,
+		applyPatchOptions(FieldManager),
+	)
 	if err != nil {
 		slog.ErrorContext(ctx, "Failed to create API deployment", "error", err)
 		return errors.New("failed to create API deployment")
@@ -129,68 +176,116 @@ func (s *service) CreateAuthAPIDeployment(ctx context.Context, ref string, opt *
 	return nil
 }
 
+// PatchAuthAPIDeployment 以 opt 的完整設定更新 auth API 的環境變數。
+//
+// opt 必須包含專案目前所有的 provider、trusted origins 與 proxy URL：SettingsFieldManager 以 server-side apply
+// 套用完整的 env 清單，先前套用但這次沒有送出的環境變數 (例如已移除的 provider) 會被移除，
+// API Secret 中不再使用的 client secret 也會一併移除。
 func (s *service) PatchAuthAPIDeployment(ctx context.Context, ref string, opt *APIDeploymentOption) error {
 	deploymentName := s.GetAuthAPIDeploymentName(ref)
 	authContainerName := s.GetAuthAPIContainerName(ref)
-	envVars := []corev1.EnvVar{}
+	secretValues := map[string]string{}
 
-	if opt.ProxyURL != nil {
+	betterAuthURL := fmt.Sprintf("https://%s.%s", ref, s.config.App.ExternalDomain)
+	var cookieDomain string
+	if opt.ProxyURL != nil && *opt.ProxyURL != "" {
 		proxyURL, err := url.Parse(*opt.ProxyURL)
 		if err != nil {
 			slog.ErrorContext(ctx, "Invalid ProxyURL", "error", err)
 			return errors.New("invalid ProxyURL")
 		}
-		envVars = append(envVars, corev1.EnvVar{
-			Name:  "BETTER_AUTH_URL",
-			Value: *opt.ProxyURL,
-		})
-		envVars = append(envVars, corev1.EnvVar{
-			Name:  "COOKIE_DOMAIN",
-			Value: proxyURL.Hostname(),
-		})
+		betterAuthURL = *opt.ProxyURL
+		cookieDomain = proxyURL.Hostname()
 	}
 
-	if opt.BetterAuthSecret != nil {
-		envVars = append(envVars, corev1.EnvVar{
-			Name:  "BETTER_AUTH_SECRET",
-			Value: *opt.BetterAuthSecret,
-		})
+	// 沒有 email 設定的專案維持建立時的預設值 (啟用)
+	emailEnabled := true
+	if provider, ok := opt.AuthProviders["email"]; ok {
+		emailEnabled = provider.Enabled
 	}
-	if len(opt.TrustedOrigins) > 0 {
-		envVars = append(envVars, corev1.EnvVar{
+
+	envVars := []corev1.EnvVar{
+		{
+			Name:  "BETTER_AUTH_URL",
+			Value: betterAuthURL,
+		},
+		{
 			Name:  "TRUSTED_ORIGINS",
 			Value: strings.Join(opt.TrustedOrigins, ","),
-		})
+		},
+		{
+			Name:  "EMAIL_AND_PASSWORD_ENABLED",
+			Value: utils.BoolToString(emailEnabled),
+		},
 	}
-	if provider, ok := opt.AuthProviders["email"]; ok {
+	if cookieDomain != "" {
 		envVars = append(envVars, corev1.EnvVar{
-			Name:  "EMAIL_AND_PASSWORD_ENABLED",
-			Value: utils.BoolToString(provider.Enabled),
+			Name:  "COOKIE_DOMAIN",
+			Value: cookieDomain,
 		})
 	}
+	if opt.BetterAuthSecret != nil {
+		secretValues[SecretKeyBetterAuthSecret] = *opt.BetterAuthSecret
+		envVars = append(envVars, s.secretEnvVar(ref, SecretKeyBetterAuthSecret))
+	}
+	envVars = append(envVars, s.authProviderEnvVars(ref, opt.AuthProviders, secretValues)...)
 
-	// Add OAuth provider environment variables dynamically from AuthProviders
-	for providerName, provider := range opt.AuthProviders {
-		upperProviderName := strings.ToUpper(providerName)
-		envVars = append(envVars,
-			corev1.EnvVar{Name: upperProviderName + "_ENABLED", Value: utils.BoolToString(provider.Enabled)},
-		)
-		if provider.ClientID != nil {
-			envVars = append(envVars,
-				corev1.EnvVar{Name: upperProviderName + "_CLIENT_ID", Value: *provider.ClientID},
-			)
+	// 舊版 Deployment 以明文設定的 secret 環境變數移到 API Secret，
+	// 目前的 Deployment 只用來找出這些變數，不與要套用的 env 合併
+	current, err := s.clientset.AppsV1().Deployments(s.GetProjectNamespace(ref)).Get(ctx, deploymentName, metav1.GetOptions{})
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to get API deployment", "error", err)
+		return errors.New("failed to get API deployment")
+	}
+	var legacyEnvVars []corev1.EnvVar
+	for _, container := range current.Spec.Template.Spec.Containers {
+		if container.Name != authContainerName {
+			continue
 		}
-		if provider.ClientSecret != nil {
-			envVars = append(envVars,
-				corev1.EnvVar{Name: upperProviderName + "_CLIENT_SECRET", Value: *provider.ClientSecret},
-			)
+		for _, env := range container.Env {
+			if !isAPISecretEnvName(env.Name) || env.ValueFrom != nil {
+				continue
+			}
+			desired := slices.ContainsFunc(envVars, func(e corev1.EnvVar) bool { return e.Name == env.Name })
+			// 已移除的 provider 的 client secret 不移到 API Secret，env 由 server-side apply 移除
+			if !desired && env.Name != SecretKeyBetterAuthSecret {
+				continue
+			}
+			if _, ok := secretValues[env.Name]; !ok {
+				secretValues[env.Name] = env.Value
+			}
+			secretEnv := s.secretEnvVar(ref, env.Name)
+			legacyEnvVars = append(legacyEnvVars, secretEnv)
+			if !desired {
+				envVars = append(envVars, secretEnv)
+			}
 		}
 	}
 
-	// Prepare JSON merge patch payload instead of YAML to avoid "invalid character" errors
+	secretData, err := s.applyAPISecret(ctx, ref, secretValues, isClientSecretKey)
+	if err != nil {
+		return err
+	}
+	if err := s.migrateLegacySecretEnv(ctx, ref, deploymentName, authContainerName, legacyEnvVars); err != nil {
+		return err
+	}
+
+	// Apply configuration 只包含要管理的欄位
 	payload := map[string]any{
+		"apiVersion": "apps/v1",
+		"kind":       "Deployment",
+		"metadata": map[string]any{
+			"name":      deploymentName,
+			"namespace": s.GetProjectNamespace(ref),
+		},
 		"spec": map[string]any{
 			"template": map[string]any{
+				// secret 值改變時更新 checksum，觸發 rolling update 讓 Pod 讀取新的值
+				"metadata": map[string]any{
+					"annotations": map[string]string{
+						AnnotationSecretChecksum: secretChecksum(secretData, s.secretEnvKeys(ref, envVars)),
+					},
+				},
 				"spec": map[string]any{
 					"containers": []map[string]any{
 						{
@@ -208,13 +303,13 @@ func (s *service) PatchAuthAPIDeployment(ctx context.Context, ref string, opt *A
 		return errors.New("failed to marshal patch data")
 	}
 
-	// Patch the deployment
-	_, err = s.clientset.AppsV1().Deployments(s.namespace).Patch(
+	// Apply the settings
+	_, err = s.clientset.AppsV1().Deployments(s.GetProjectNamespace(ref)).Patch(
 		ctx,
 		deploymentName,
-		types.StrategicMergePatchType,
+		types.ApplyPatchType,
 		data,
-		metav1.PatchOptions{},
+		applyPatchOptions(SettingsFieldManager),
 	)
 	if err != nil {
 		slog.ErrorContext(ctx, "Failed to patch API deployment", "error", err)
@@ -224,15 +319,90 @@ func (s *service) PatchAuthAPIDeployment(ctx context.Context, ref string, opt *A
 	return nil
 }
 
+// authProviderEnvVars 回傳 OAuth provider 的環境變數，client secret 放到 secretValues 並以 secretKeyRef 引用。
+//
+// 依 provider 名稱排序，避免每次套用的 env 順序不同而觸發 rolling update。
+func (s *service) authProviderEnvVars(ref string, providers map[string]dto.AuthProvider, secretValues map[string]string) []corev1.EnvVar {
+	var envVars []corev1.EnvVar
+	for _, providerName := range slices.Sorted(maps.Keys(providers)) {
+		provider := providers[providerName]
+		upperProviderName := strings.ToUpper(providerName)
+		envVars = append(envVars,
+			corev1.EnvVar{Name: upperProviderName + "_ENABLED", Value: utils.BoolToString(provider.Enabled)},
+		)
+		if provider.ClientID != nil {
+			envVars = append(envVars,
+				corev1.EnvVar{Name: upperProviderName + "_CLIENT_ID", Value: *provider.ClientID},
+			)
+		}
+		if provider.ClientSecret != nil {
+			secretValues[upperProviderName+"_CLIENT_SECRET"] = *provider.ClientSecret
+			envVars = append(envVars, s.secretEnvVar(ref, upperProviderName+"_CLIENT_SECRET"))
+		}
+	}
+	return envVars
+}
+
 func (s *service) DeleteAuthAPIDeployment(ctx context.Context, ref string) error {
 	deploymentName := s.GetAuthAPIDeploymentName(ref)
 
 	// Delete the deployment
-	err := s.clientset.AppsV1().Deployments(s.namespace).Delete(ctx, deploymentName, metav1.DeleteOptions{})
-	if err != nil {
+	err := s.clientset.AppsV1().Deployments(s.GetProjectNamespace(ref)).Delete(ctx, deploymentName, metav1.DeleteOptions{})
+	if ignoreNotFound(err) != nil {
 		slog.ErrorContext(ctx, "Failed to delete API deployment", "error", err)
 		return errors.New("failed to delete API deployment")
 	}
 
 	return nil
 }
+
+// migrateLegacySecretEnv 將明文的 secret 環境變數改為 secretKeyRef。
+//
+// 明文的 value 欄位由建立時的 FieldManager 擁有，SettingsFieldManager 套用 valueFrom 時不會移除它，
+// 因此先以 strategic merge patch 移除 value，避免 value 與 valueFrom 同時存在。
+func (s *service) migrateLegacySecretEnv(ctx context.Context, ref string, deploymentName string, containerName string, envVars []corev1.EnvVar) error {
+	if len(envVars) == 0 {
+		return nil
+	}
+
+	env := make([]map[string]any, 0, len(envVars))
+	for _, envVar := range envVars {
+		env = append(env, map[string]any{
+			"name":      envVar.Name,
+			"value":     nil,
+			"valueFrom": envVar.ValueFrom,
+		})
+	}
+	patch := map[string]any{
+		"spec": map[string]any{
+			"template": map[string]any{
+				"spec": map[string]any{
+					"containers": []map[string]any{
+						{
+							"name": containerName,
+							"env":  env,
+						},
+					},
+				},
+			},
+		},
+	}
+	data, err := json.Marshal(patch)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to marshal secret env migration patch", "error", err)
+		return errors.New("failed to marshal secret env migration patch")
+	}
+
+	_, err = s.clientset.AppsV1().Deployments(s.GetProjectNamespace(ref)).Patch(
+		ctx,
+		deploymentName,
+		types.StrategicMergePatchType,
+		data,
+		metav1.PatchOptions{FieldManager: SettingsFieldManager},
+	)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to migrate secret env of API deployment", "error", err, "deployment", deploymentName)
+		return errors.New("failed to migrate secret env of API deployment")
+	}
+	return nil
+}
@@ -2,21 +2,34 @@ package kubeproject
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"log/slog"
 
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
 func (s *service) CreateAuthAPIService(ctx context.Context, ref string) error {
 	serviceName := s.GetAuthAPIServiceName(ref)
 	deploymentName := s.GetAuthAPIDeploymentName(ref)
+	ownerRef, err := s.clusterOwnerReference(ctx, ref)
+	if err != nil {
+		return err
+	}
+
 	service := &corev1.Service{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "v1",
+			Kind:       "Service",
+		},
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      serviceName,
-			Namespace: s.namespace,
+			Name:            serviceName,
+			Namespace:       s.GetProjectNamespace(ref),
+			Labels:          projectLabels(ref, AuthAPIComponent),
+			OwnerReferences: []metav1.OwnerReference{*ownerRef},
 		},
 		Spec: corev1.ServiceSpec{
 			Type: corev1.ServiceTypeClusterIP,
@@ -34,7 +47,19 @@ func (s *service) CreateAuthAPIService(ctx context.Context, ref string) error {
 		},
 	}
 
-	_, err := s.clientset.CoreV1().Services(s.namespace).Create(ctx, service, metav1.CreateOptions{})
+	data, err := json.Marshal(service)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to marshal Auth API service", "error", err)
+		return errors.New("failed to marshal Auth API service")
+	}
+
+	_, err = s.clientset.CoreV1().Services(s.GetProjectNamespace(ref)).Patch(
+		ctx,
+		serviceName,
+		types.ApplyPatchType,
+		data,
+		applyPatchOptions(FieldManager),
+	)
 	if err != nil {
 		slog.ErrorContext(ctx, "Failed to create Auth API service", "error", err)
 		return errors.New("failed to create Auth API service")
@@ -46,8 +71,8 @@ func (s *service) CreateAuthAPIService(ctx context.Context, ref string) error {
 func (s *service) DeleteAuthAPIService(ctx context.Context, ref string) error {
 	serviceName := s.GetAuthAPIServiceName(ref)
 
-	err := s.clientset.CoreV1().Services(s.namespace).Delete(ctx, serviceName, metav1.DeleteOptions{})
-	if err != nil {
+	err := s.clientset.CoreV1().Services(s.GetProjectNamespace(ref)).Delete(ctx, serviceName, metav1.DeleteOptions{})
+	if ignoreNotFound(err) != nil {
 		slog.ErrorContext(ctx, "Failed to delete Auth API service", "error", err)
 		return errors.New("failed to delete Auth API service")
 	}
@@ -0,0 +1,267 @@
+package kubeproject
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"slices"
+	"time"
+
+	"baas-api/internal/minio"
+
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	utilrand "k8s.io/apimachinery/pkg/util/rand"
+)
+
+// CNPG Backup 的 status.phase
+const (
+	BackupPhaseCompleted = "completed"
+	BackupPhaseFailed    = "failed"
+)
+
+// BackupInfo 是 CNPG Backup 資源的摘要
+type BackupInfo struct {
+	Name       string
+	Phase      string
+	Method     string
+	OnDemand   bool
+	ServerName string
+	BackupID   string
+	StartedAt  *time.Time
+	StoppedAt  *time.Time
+	Error      string
+}
+
+// GetBackupDestinationPath 回傳專案備份在 object store 中的位置
+func (s *service) GetBackupDestinationPath(ref string) string {
+	return "s3://" + s.config.Kube.Project.Backup.Bucket + "/" + minio.GetBackupPrefixByRef(ref)
+}
+
+func (s *service) getBackupEndpointURL() string {
+	scheme := lo.Ternary(s.config.S3.UseSSL, "https", "http")
+	return scheme + "://" + s.config.S3.Endpoint
+}
+
+// buildBarmanObjectStore 建立 CNPG barmanObjectStore 設定。
+//
+// ref 決定使用哪個專案的憑證 Secret，sourceRef 決定讀寫哪個專案的路徑前綴；
+// 從其他專案的備份還原時兩者不同。
+func (s *service) buildBarmanObjectStore(ref string, sourceRef string, serverName string) map[string]any {
+	secretName := s.GetBackupCredentialsSecretName(ref)
+	return map[string]any{
+		"destinationPath": s.GetBackupDestinationPath(sourceRef),
+		"endpointURL":     s.getBackupEndpointURL(),
+		"serverName":      serverName,
+		"s3Credentials": map[string]any{
+			"accessKeyId": map[string]any{
+				"name": secretName,
+				"key":  "ACCESS_KEY_ID",
+			},
+			"secretAccessKey": map[string]any{
+				"name": secretName,
+				"key":  "ACCESS_SECRET_KEY",
+			},
+		},
+		"wal": map[string]any{
+			"compression": "gzip",
+		},
+		"data": map[string]any{
+			"compression": "gzip",
+		},
+	}
+}
+
+// CreateBackupCredentialsSecret 建立 barman 存取 object store 使用的憑證
+func (s *service) CreateBackupCredentialsSecret(ctx context.Context, ref string, accessKeyID string, secretAccessKey string) error {
+	secret := &corev1.Secret{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "v1",
+			Kind:       "Secret",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      s.GetBackupCredentialsSecretName(ref),
+			Namespace: s.GetProjectNamespace(ref),
+			Labels:    projectLabels(ref, BackupComponent),
+		},
+		StringData: map[string]string{
+			"ACCESS_KEY_ID":     accessKeyID,
+			"ACCESS_SECRET_KEY": secretAccessKey,
+		},
+	}
+
+	data, err := json.Marshal(secret)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to marshal backup credentials secret", "error", err)
+		return errors.New("failed to marshal backup credentials secret")
+	}
+
+	_, err = s.clientset.CoreV1().Secrets(secret.Namespace).Patch(
+		ctx,
+		secret.Name,
+		types.ApplyPatchType,
+		data,
+		applyPatchOptions(FieldManager),
+	)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to create backup credentials secret", "error", err, "ref", ref)
+		return errors.New("failed to create backup credentials secret")
+	}
+	return nil
+}
+
+// CreateScheduledBackup 依設定的排程建立 CNPG ScheduledBackup，建立後會立即執行第一次備份
+func (s *service) CreateScheduledBackup(ctx context.Context, ref string) error {
+	if !s.config.Kube.Project.Backup.Enabled {
+		return nil
+	}
+
+	ownerRef, err := s.clusterOwnerReference(ctx, ref)
+	if err != nil {
+		return err
+	}
+
+	scheduledBackup := &unstructured.Unstructured{}
+	scheduledBackup.SetAPIVersion(scheduledBackupGVR.GroupVersion().String())
+	scheduledBackup.SetKind("ScheduledBackup")
+	scheduledBackup.SetName(s.GetScheduledBackupName(ref))
+	scheduledBackup.SetNamespace(s.GetProjectNamespace(ref))
+	scheduledBackup.SetLabels(projectLabels(ref, BackupComponent))
+	scheduledBackup.SetOwnerReferences([]metav1.OwnerReference{*ownerRef})
+	scheduledBackup.Object["spec"] = map[string]any{
+		"schedule":             s.config.Kube.Project.Backup.Schedule,
+		"immediate":            true,
+		"method":               "barmanObjectStore",
+		"backupOwnerReference": "self",
+		"cluster": map[string]any{
+			"name": ownerRef.Name,
+		},
+	}
+
+	_, err = s.dynamicClient.Resource(scheduledBackupGVR).
+		Namespace(s.GetProjectNamespace(ref)).
+		Apply(ctx, scheduledBackup.GetName(), scheduledBackup, applyOptions(FieldManager))
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to create scheduled backup", "error", err, "ref", ref)
+		return errors.New("failed to create scheduled backup")
+	}
+	return nil
+}
+
+// CreateBackup 建立一次性的 on-demand 備份
+func (s *service) CreateBackup(ctx context.Context, ref string) (*BackupInfo, error) {
+	if !s.config.Kube.Project.Backup.Enabled {
+		return nil, ErrBackupNotEnabled
+	}
+
+	ownerRef, err := s.clusterOwnerReference(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	backup := &unstructured.Unstructured{}
+	backup.SetAPIVersion(backupGVR.GroupVersion().String())
+	backup.SetKind("Backup")
+	// 以 generateName 產生名稱，同一秒內的多次備份不會衝突
+	backup.SetGenerateName(ref + "-")
+	backup.SetNamespace(s.GetProjectNamespace(ref))
+	backup.SetLabels(projectLabels(ref, BackupComponent))
+	backup.SetOwnerReferences([]metav1.OwnerReference{*ownerRef})
+	backup.Object["spec"] = map[string]any{
+		"method": "barmanObjectStore",
+		"cluster": map[string]any{
+			"name": ownerRef.Name,
+		},
+	}
+
+	created, err := s.dynamicClient.Resource(backupGVR).
+		Namespace(s.GetProjectNamespace(ref)).
+		Create(ctx, backup, metav1.CreateOptions{FieldManager: FieldManager})
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to create backup", "error", err, "ref", ref)
+		return nil, errors.New("failed to create backup")
+	}
+
+	return toBackupInfo(created), nil
+}
+
+// ListBackups 列出專案 cluster (包含還原前的 Cluster) 的所有備份，依開始時間由新到舊排序
+func (s *service) ListBackups(ctx context.Context, ref string) ([]BackupInfo, error) {
+	list, err := s.dynamicClient.Resource(backupGVR).
+		Namespace(s.GetProjectNamespace(ref)).
+		List(ctx, metav1.ListOptions{})
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to list backups", "error", err, "ref", ref)
+		return nil, ErrFailedToListBackups
+	}
+
+	backups := []BackupInfo{}
+	for _, item := range list.Items {
+		cluster, _, _ := unstructured.NestedString(item.Object, "spec", "cluster", "name")
+		if !isProjectClusterName(ref, cluster) {
+			continue
+		}
+		backups = append(backups, *toBackupInfo(&item))
+	}
+
+	slices.SortFunc(backups, func(a, b BackupInfo) int {
+		return lo.FromPtr(b.StartedAt).Compare(lo.FromPtr(a.StartedAt))
+	})
+	return backups, nil
+}
+
+// FindBackup 取得專案的指定備份
+func (s *service) FindBackup(ctx context.Context, ref string, name string) (*BackupInfo, error) {
+	backup, err := s.dynamicClient.Resource(backupGVR).
+		Namespace(s.GetProjectNamespace(ref)).
+		Get(ctx, name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, ErrBackupNotFound
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to get backup", "error", err, "ref", ref, "name", name)
+		return nil, ErrFailedToGetBackup
+	}
+
+	cluster, _, _ := unstructured.NestedString(backup.Object, "spec", "cluster", "name")
+	if !isProjectClusterName(ref, cluster) {
+		return nil, ErrBackupNotFound
+	}
+	return toBackupInfo(backup), nil
+}
+
+func toBackupInfo(backup *unstructured.Unstructured) *BackupInfo {
+	nestedString := func(fields ...string) string {
+		value, _, _ := unstructured.NestedString(backup.Object, fields...)
+		return value
+	}
+	nestedTime := func(fields ...string) *time.Time {
+		value, err := time.Parse(time.RFC3339, nestedString(fields...))
+		if err != nil {
+			return nil
+		}
+		return &value
+	}
+
+	return &BackupInfo{
+		Name:       backup.GetName(),
+		Phase:      nestedString("status", "phase"),
+		Method:     lo.CoalesceOrEmpty(nestedString("status", "method"), nestedString("spec", "method")),
+		OnDemand:   !slices.ContainsFunc(backup.GetOwnerReferences(), func(o metav1.OwnerReference) bool { return o.Kind == "ScheduledBackup" }),
+		ServerName: nestedString("status", "serverName"),
+		BackupID:   nestedString("status", "backupId"),
+		StartedAt:  nestedTime("status", "startedAt"),
+		StoppedAt:  nestedTime("status", "stoppedAt"),
+		Error:      nestedString("status", "error"),
+	}
+}
+
+// newBackupServerName 產生還原後新 cluster 的 serverName，讓新的 WAL 封存寫入獨立的目錄
+func newBackupServerName(ref string) string {
+	return generateResourceName(ref, utilrand.String(8))
+}
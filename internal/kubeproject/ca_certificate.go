@@ -0,0 +1,46 @@
+package kubeproject
+
+import (
+	"bytes"
+	"context"
+	"encoding/pem"
+	"errors"
+	"log/slog"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// FindDatabaseCACertificate 回傳簽發專案 TLS 憑證 (資料庫 SNI 路由使用) 的 CA 憑證 (PEM)。
+//
+// cert-manager 以 CA issuer 簽發時 secret 中有 ca.crt；否則回傳 tls.crt 憑證鏈中最後一張憑證。
+func (s *service) FindDatabaseCACertificate(ctx context.Context, ref string) (string, error) {
+	secretName := s.config.Kube.Project.TLSSecretName
+	secret, err := s.clientset.CoreV1().Secrets(s.namespace).Get(ctx, secretName, metav1.GetOptions{})
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to read shared TLS secret", "error", err, "secretName", secretName, "ref", ref)
+		return "", errors.New("failed to read shared TLS secret")
+	}
+
+	if ca := bytes.TrimSpace(secret.Data["ca.crt"]); len(ca) > 0 {
+		return string(ca) + "\n", nil
+	}
+
+	var last *pem.Block
+	rest := secret.Data[corev1.TLSCertKey]
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type == "CERTIFICATE" {
+			last = block
+		}
+	}
+	if last == nil {
+		slog.ErrorContext(ctx, "Shared TLS secret has no certificate", "secretName", secretName)
+		return "", errors.New("shared TLS secret has no certificate")
+	}
+	return string(pem.EncodeToMemory(last)), nil
+}
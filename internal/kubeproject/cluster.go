@@ -11,17 +11,34 @@ import (
 	"log/slog"
 	"strings"
 	"text/template"
-	"time"
 
+	"github.com/samber/lo"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/util/yaml"
 )
 
 //go:embed kube-files/project-cnpg-cluster.yaml
 var clusterYAML string
 
-func (s *service) CreateCluster(ctx context.Context, ref string, storageSize string) error {
+type ClusterOption struct {
+	// Name 是 Cluster 名稱，空白時使用 ref
+	Name string
+	// Component 是 Cluster 資源本身的 component label，空白時使用 DBComponent
+	Component   string
+	StorageSize string
+	// BackupServerName 是 WAL 封存使用的 serverName，空白時使用 ref。
+	// 從備份還原到同一個路徑前綴時必須使用新的名稱，避免覆寫原本的封存。
+	BackupServerName string
+	// Recovery 不為 nil 時以 bootstrap.recovery 從備份建立 cluster
+	Recovery *ClusterRecoveryOption
+	// DisableBackup 不設定 WAL 封存 (用於暫時性的 cluster)
+	DisableBackup bool
+}
+
+func (s *service) CreateCluster(ctx context.Context, ref string, opt *ClusterOption) error {
 	clusterData := map[string]any{
 		"RoleAuthenticatorSecretName": s.GetDatabaseRoleSecretName(ref, RoleAuthenticator),
 	}
@@ -44,19 +61,47 @@ func (s *service) CreateCluster(ctx context.Context, ref string, storageSize str
 	}
 
 	// set metadata
-	cluster.SetName(ref)
-	cluster.SetNamespace(s.namespace)
+	name := lo.CoalesceOrEmpty(opt.Name, ref)
+	cluster.SetName(name)
+	cluster.SetNamespace(s.GetProjectNamespace(ref))
+	cluster.SetLabels(projectLabels(ref, lo.CoalesceOrEmpty(opt.Component, DBComponent)))
+
+	// CNPG 會將 inheritedMetadata 傳遞到它所建立的 Pod、Service 與 PVC
+	if err := unstructured.SetNestedStringMap(cluster.Object, projectLabels(ref, DBComponent), "spec", "inheritedMetadata", "labels"); err != nil {
+		slog.Error("Failed to set inherited labels in Postgres cluster spec", "error", err)
+		return ErrFailedToSetSpecInheritedLabels
+	}
 
 	// set spec.storage.size
-	if err := unstructured.SetNestedField(cluster.Object, storageSize, "spec", "storage", "size"); err != nil {
+	if err := unstructured.SetNestedField(cluster.Object, opt.StorageSize, "spec", "storage", "size"); err != nil {
 		slog.Error("Failed to set storage size in Postgres cluster spec", "error", err)
 		return ErrFailedToSetSpecStorageSize
 	}
 
-	// 使用 dynamicClient 創建資源
+	// set spec.backup (WAL 封存與 base backup 目的地)
+	if s.config.Kube.Project.Backup.Enabled && !opt.DisableBackup {
+		serverName := lo.CoalesceOrEmpty(opt.BackupServerName, ref)
+		backup := map[string]any{
+			"retentionPolicy":   s.config.Kube.Project.Backup.RetentionPolicy,
+			"barmanObjectStore": s.buildBarmanObjectStore(ref, ref, serverName),
+		}
+		if err := unstructured.SetNestedMap(cluster.Object, backup, "spec", "backup"); err != nil {
+			slog.Error("Failed to set backup in Postgres cluster spec", "error", err)
+			return ErrFailedToSetSpecBackup
+		}
+	}
+
+	// set spec.bootstrap.recovery
+	if opt.Recovery != nil {
+		if err := s.setClusterRecovery(cluster, ref, opt.Recovery); err != nil {
+			return err
+		}
+	}
+
+	// 使用 dynamicClient 以 server-side apply 建立或更新資源
 	_, err = s.dynamicClient.Resource(clusterGVR).
-		Namespace(s.namespace).
-		Create(ctx, cluster, metav1.CreateOptions{})
+		Namespace(s.GetProjectNamespace(ref)).
+		Apply(ctx, name, cluster, applyOptions(FieldManager))
 	if err != nil {
 		slog.Error("Failed to create Postgres cluster", "error", err)
 		return ErrFailedToCreatePostgresCluster
@@ -66,51 +111,134 @@ func (s *service) CreateCluster(ctx context.Context, ref string, storageSize str
 }
 
 func (s *service) DeleteCluster(ctx context.Context, ref string) error {
-	// 使用 dynamicClient 刪除資源
-	err := s.dynamicClient.Resource(clusterGVR).
-		Namespace(s.namespace).
-		Delete(ctx, ref, metav1.DeleteOptions{})
+	name, err := s.databaseClusterName(ctx, ref)
 	if err != nil {
-		slog.Error("Failed to delete Postgres cluster", "error", err)
-		return ErrFailedToDeletePostgresCluster
+		return err
 	}
-
-	return nil
+	return s.deleteNamedCluster(ctx, ref, name)
 }
 
+// ClusterHealthyPhase 是 CNPG Cluster 可以正常服務時的 status.phase
+const ClusterHealthyPhase = "Cluster in healthy state"
+
+// FindClusterStatus 回傳 Cluster 的 status.phase，Cluster 尚未建立時回傳 nil。
+//
+// informer cache 同步後直接讀取 cache，不會對 API server 發出請求。
 func (s *service) FindClusterStatus(ctx context.Context, ref string) (*string, error) {
-	deployment, err := s.dynamicClient.Resource(clusterGVR).
-		Namespace(s.namespace).
-		Get(ctx, ref, metav1.GetOptions{})
+	name, err := s.databaseClusterName(ctx, ref)
 	if err != nil {
-		slog.Error("Failed to get postgres cluster", "error", err)
+		return nil, err
+	}
+	return s.findNamedClusterStatus(ctx, ref, name)
+}
+
+// findNamedClusterStatus 回傳專案 namespace 中指定名稱 Cluster 的 status.phase
+func (s *service) findNamedClusterStatus(ctx context.Context, ref string, name string) (*string, error) {
+	cluster, cached, err := s.getCachedCluster(ref, name)
+	if !cached {
+		cluster, err = s.dynamicClient.Resource(clusterGVR).
+			Namespace(s.GetProjectNamespace(ref)).
+			Get(ctx, name, metav1.GetOptions{})
+	}
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to get postgres cluster", "error", err, "ref", ref)
 		return nil, errors.New("failed to get postgres cluster")
 	}
 
-	status := deployment.Object["status"].(map[string]any)
-	phase, ok := status["phase"].(string)
-	if !ok {
+	phase, _, _ := unstructured.NestedString(cluster.Object, "status", "phase")
+	if phase == "" {
 		phase = "Initializing Postgres cluster"
-		return &phase, nil
 	}
-
 	return &phase, nil
 }
 
+// WaitClusterHealthy 等待 Cluster 進入 healthy 狀態，狀態變更由共用的 informer 通知
 func (s *service) WaitClusterHealthy(ctx context.Context, ref string) error {
-	ticker := time.NewTicker(5 * time.Second)
+	name, err := s.databaseClusterName(ctx, ref)
+	if err != nil {
+		return err
+	}
+	return s.waitNamedClusterHealthy(ctx, ref, name)
+}
+
+// waitNamedClusterHealthy 等待專案 namespace 中指定名稱的 Cluster 進入 healthy 狀態
+func (s *service) waitNamedClusterHealthy(ctx context.Context, ref string, name string) error {
+	changes, err := s.WatchProject(ctx, ref)
+	if err != nil {
+		return err
+	}
 	for {
 		select {
 		case <-ctx.Done():
 			return ctx.Err()
-		case <-ticker.C:
-			status, err := s.FindClusterStatus(ctx, ref)
+		case _, ok := <-changes:
+			if !ok {
+				return ctx.Err()
+			}
+			status, err := s.findNamedClusterStatus(ctx, ref, name)
 			if err != nil {
 				return err
 			}
-			if *status == "Cluster in healthy state" {
+			if status != nil && *status == ClusterHealthyPhase {
 				return nil
 			}
 		}
 	}
 }
+
+// databaseClusterName 回傳專案目前服務中的 CNPG Cluster 名稱。
+//
+// 專案建立時 Cluster 名稱為 ref，從備份還原後改為還原時建立的 Cluster (<ref>-db-<suffix>)。
+// 服務中的 Cluster 帶有 DBComponent label，切換途中有多個時使用最新建立的；
+// 沒有帶 label 的 Cluster 時 (labels 導入前建立的專案) 使用 ref。
+func (s *service) databaseClusterName(ctx context.Context, ref string) (string, error) {
+	namespace := s.GetProjectNamespace(ref)
+	selector := labels.SelectorFromSet(projectLabels(ref, DBComponent))
+
+	var clusters []*unstructured.Unstructured
+	if s.watcher.isSynced() {
+		objs, err := s.watcher.factory.ForResource(clusterGVR).Lister().ByNamespace(namespace).List(selector)
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed to list cached postgres clusters", "error", err, "ref", ref)
+			return "", errors.New("failed to list postgres clusters")
+		}
+		for _, obj := range objs {
+			if cluster, ok := obj.(*unstructured.Unstructured); ok {
+				clusters = append(clusters, cluster)
+			}
+		}
+	} else {
+		list, err := s.dynamicClient.Resource(clusterGVR).Namespace(namespace).List(ctx, metav1.ListOptions{
+			LabelSelector: selector.String(),
+		})
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed to list postgres clusters", "error", err, "ref", ref)
+			return "", errors.New("failed to list postgres clusters")
+		}
+		for i := range list.Items {
+			clusters = append(clusters, &list.Items[i])
+		}
+	}
+
+	if len(clusters) == 0 {
+		return ref, nil
+	}
+	newest := lo.MaxBy(clusters, func(a, b *unstructured.Unstructured) bool {
+		return a.GetCreationTimestamp().After(b.GetCreationTimestamp().Time)
+	})
+	return newest.GetName(), nil
+}
+
+// getDatabaseCluster 取得專案目前服務中的 Cluster，錯誤由呼叫端記錄
+func (s *service) getDatabaseCluster(ctx context.Context, ref string) (*unstructured.Unstructured, error) {
+	name, err := s.databaseClusterName(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	return s.dynamicClient.Resource(clusterGVR).
+		Namespace(s.GetProjectNamespace(ref)).
+		Get(ctx, name, metav1.GetOptions{})
+}
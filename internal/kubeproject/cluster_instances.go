@@ -0,0 +1,164 @@
+package kubeproject
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"baas-api/internal/config"
+
+	"github.com/samber/lo"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// MaxClusterInstances 是單一專案 cluster 允許的最大 instance 數
+const MaxClusterInstances = 5
+
+// DatabaseClusterInfo 是專案 cluster 目前的 instance 設定與狀態
+type DatabaseClusterInfo struct {
+	config.DatabaseClusterConfig
+	ReadyInstances int32
+	CurrentPrimary string
+	// ReadOnlyHost 只有在有 standby 時才會有值
+	ReadOnlyHost string
+}
+
+// ValidateDatabaseCluster 檢查 instance 數與同步複寫設定是否合法
+func ValidateDatabaseCluster(cluster config.DatabaseClusterConfig) error {
+	if cluster.Instances < 1 || cluster.Instances > MaxClusterInstances {
+		return fmt.Errorf("instances must be between 1 and %d", MaxClusterInstances)
+	}
+
+	sync := cluster.Synchronous
+	if sync.Number == 0 {
+		return nil
+	}
+	if sync.Number < 0 || sync.Number >= cluster.Instances {
+		return errors.New("synchronous number must be less than the number of instances")
+	}
+	if sync.Method != "any" && sync.Method != "first" {
+		return errors.New("synchronous method must be any or first")
+	}
+	if sync.DataDurability != "" && sync.DataDurability != "required" && sync.DataDurability != "preferred" {
+		return errors.New("synchronous dataDurability must be required or preferred")
+	}
+	return nil
+}
+
+// ValidateDatabaseClusterLimits 檢查 cluster 設定是否在方案的上限內
+func ValidateDatabaseClusterLimits(cluster config.DatabaseClusterConfig, limits config.PlanLimits) error {
+	if limits.MaxInstances > 0 && cluster.Instances > limits.MaxInstances {
+		return fmt.Errorf("instances must not exceed %d", limits.MaxInstances)
+	}
+	return nil
+}
+
+// ApplyDatabaseCluster 套用 cluster 的 instance 數與同步複寫設定，並依是否有 standby 建立或移除唯讀的資料庫路由。
+//
+// CNPG 會在線上逐一新增或移除 standby，不需要重建 cluster。
+// 這些欄位使用獨立的 ClusterFieldManager，不會與 CreateCluster 的欄位互相覆蓋。
+func (s *service) ApplyDatabaseCluster(ctx context.Context, ref string, cluster config.DatabaseClusterConfig) error {
+	name, err := s.databaseClusterName(ctx, ref)
+	if err != nil {
+		return err
+	}
+	if err := s.applyClusterInstances(ctx, ref, name, cluster); err != nil {
+		return err
+	}
+	return s.applyReadOnlyDBRoute(ctx, ref, name, cluster.Instances > 1)
+}
+
+// applyClusterInstances 套用指定名稱 Cluster 的 instance 數與同步複寫設定
+func (s *service) applyClusterInstances(ctx context.Context, ref string, name string, cluster config.DatabaseClusterConfig) error {
+	if err := ValidateDatabaseCluster(cluster); err != nil {
+		slog.ErrorContext(ctx, "Invalid database cluster configuration", "error", err, "ref", ref)
+		return err
+	}
+
+	spec := map[string]any{
+		"instances": cluster.Instances,
+	}
+	// 未宣告 synchronous 時，先前由此 field manager 設定的同步複寫會被移除
+	if cluster.Synchronous.Number > 0 {
+		synchronous := map[string]any{
+			"method": cluster.Synchronous.Method,
+			"number": cluster.Synchronous.Number,
+		}
+		if cluster.Synchronous.DataDurability != "" {
+			synchronous["dataDurability"] = cluster.Synchronous.DataDurability
+		}
+		spec["postgresql"] = map[string]any{"synchronous": synchronous}
+	}
+
+	obj := &unstructured.Unstructured{}
+	obj.SetAPIVersion(clusterGVR.GroupVersion().String())
+	obj.SetKind("Cluster")
+	obj.SetName(name)
+	obj.SetNamespace(s.GetProjectNamespace(ref))
+	obj.Object["spec"] = spec
+
+	_, err := s.dynamicClient.Resource(clusterGVR).
+		Namespace(s.GetProjectNamespace(ref)).
+		Apply(ctx, name, obj, applyOptions(ClusterFieldManager))
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to apply postgres cluster instances", "error", err, "ref", ref)
+		return errors.New("failed to apply postgres cluster instances")
+	}
+	return nil
+}
+
+// applyReadOnlyDBRoute 以 <ref>-ro.<domain> 將唯讀連線導向 Cluster 的 -ro service (只包含 standby)
+func (s *service) applyReadOnlyDBRoute(ctx context.Context, ref string, clusterName string, enabled bool) error {
+	name := s.GetDBReadOnlyIngressRouteTCPName(ref)
+	if !enabled {
+		return s.ingress.DeleteDBRoute(ctx, ref, name)
+	}
+
+	ownerRef, err := s.namedClusterOwnerReference(ctx, ref, clusterName)
+	if err != nil {
+		return err
+	}
+	return s.ingress.ApplyDBRoute(ctx, ref, dbRouteOption{
+		Name:        name,
+		Component:   DBReadOnlyComponent,
+		Host:        s.GetProjectReadOnlyHost(ref),
+		ServiceName: s.GetDatabaseROServiceName(clusterName),
+		OwnerRef:    ownerRef,
+	})
+}
+
+// FindDatabaseCluster 從 Cluster 資源讀取目前的 instance 數、同步複寫設定與狀態
+func (s *service) FindDatabaseCluster(ctx context.Context, ref string) (*DatabaseClusterInfo, error) {
+	cluster, err := s.getDatabaseCluster(ctx, ref)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to get postgres cluster", "error", err, "ref", ref)
+		return nil, errors.New("failed to get postgres cluster")
+	}
+
+	nestedInt32 := func(fields ...string) int32 {
+		value, _, _ := unstructured.NestedInt64(cluster.Object, fields...)
+		return int32(value)
+	}
+	nestedString := func(fields ...string) string {
+		value, _, _ := unstructured.NestedString(cluster.Object, fields...)
+		return value
+	}
+
+	info := &DatabaseClusterInfo{
+		DatabaseClusterConfig: config.DatabaseClusterConfig{
+			Instances: lo.CoalesceOrEmpty(nestedInt32("spec", "instances"), 1),
+			Synchronous: config.DatabaseSynchronousConfig{
+				Method:         nestedString("spec", "postgresql", "synchronous", "method"),
+				Number:         nestedInt32("spec", "postgresql", "synchronous", "number"),
+				DataDurability: nestedString("spec", "postgresql", "synchronous", "dataDurability"),
+			},
+		},
+		ReadyInstances: nestedInt32("status", "readyInstances"),
+		CurrentPrimary: nestedString("status", "currentPrimary"),
+	}
+	if info.Instances > 1 {
+		info.ReadOnlyHost = s.GetProjectReadOnlyHost(ref)
+	}
+	return info, nil
+}
@@ -0,0 +1,581 @@
+package kubeproject
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/samber/lo"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// 排程工作執行的內容
+const (
+	// CronTaskSQL 以 app 角色執行 SQL
+	CronTaskSQL = "sql"
+	// CronTaskRPC 呼叫 api schema 中的函式，與 REST API 的 /rpc/<function> 相同
+	CronTaskRPC = "rpc"
+)
+
+// 排程工作執行的觸發方式
+const (
+	CronTriggerScheduled = "scheduled"
+	CronTriggerManual    = "manual"
+)
+
+const (
+	// LabelCronJob 標示 Job 所屬的排程工作名稱
+	LabelCronJob = "baas.wke/cron-job"
+	// AnnotationCronTask 保存排程工作的 CronJobTask (JSON)
+	AnnotationCronTask = "baas.wke/cron-task"
+	// annotationCronInstantiate 與 kubectl create job --from=cronjob 相同，標示手動觸發的 Job
+	annotationCronInstantiate = "cronjob.kubernetes.io/instantiate"
+
+	// cronContainerName 是執行 psql 的 container 名稱
+	cronContainerName = "psql"
+)
+
+// CronJobLimit 是每個專案的排程工作數量上限
+const CronJobLimit = 20
+
+// CronRunHistoryLimit 是保留的成功與失敗執行紀錄數 (各自計算)
+const CronRunHistoryLimit = 5
+
+// CronRunTimeoutSeconds 是單次執行的時間上限
+const CronRunTimeoutSeconds = 3600
+
+// CronSQLLimitBytes 是 SQL 的大小上限，SQL 以環境變數傳入 Pod
+const CronSQLLimitBytes = 16 * 1024
+
+// CronJobTask 是排程工作執行的內容
+type CronJobTask struct {
+	Type string `json:"type"`
+	// SQL 是 CronTaskSQL 執行的 SQL
+	SQL string `json:"sql,omitempty"`
+	// Function 是 CronTaskRPC 呼叫的 api schema 函式名稱
+	Function string `json:"function,omitempty"`
+	// Args 是傳給函式的 JSON 物件，以具名參數呼叫
+	Args map[string]any `json:"args,omitempty"`
+}
+
+// CronJobOption 是專案的排程工作定義
+type CronJobOption struct {
+	Name string
+	// Schedule 是 cron 格式的排程 (UTC)
+	Schedule string
+	Enabled  bool
+	Task     CronJobTask
+}
+
+// CronJobInfo 是排程工作的定義與最近的執行狀態
+type CronJobInfo struct {
+	CronJobOption
+	LastScheduleTime   *time.Time
+	LastSuccessfulTime *time.Time
+	// LastRun 是最近一次執行，尚未執行過 (或紀錄已被清除) 時為 nil
+	LastRun *CronJobRun
+}
+
+// CronJobRun 是排程工作的一次執行 (Job)，Status 與 migration Job 相同
+type CronJobRun struct {
+	// Name 是排程工作的名稱
+	Name        string
+	JobName     string
+	Trigger     string
+	Status      string
+	Message     string
+	StartedAt   *time.Time
+	CompletedAt *time.Time
+	Logs        string
+}
+
+var (
+	cronJobNameRegex    = regexp.MustCompile(`^[a-z]([a-z0-9-]{0,24}[a-z0-9])?$`)
+	cronArgNameRegex    = regexp.MustCompile(`^[a-z_][a-z0-9_]{0,62}$`)
+	cronScheduleMacros  = []string{"@yearly", "@annually", "@monthly", "@weekly", "@daily", "@midnight", "@hourly"}
+	cronMonthNames      = []string{"jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec"}
+	cronDayOfWeekNames  = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+	cronScheduleColumns = []struct {
+		name     string
+		min, max int
+		names    []string
+	}{
+		{"minute", 0, 59, nil},
+		{"hour", 0, 23, nil},
+		{"day of month", 1, 31, nil},
+		{"month", 1, 12, cronMonthNames},
+		{"day of week", 0, 7, cronDayOfWeekNames},
+	}
+)
+
+// ValidateCronJob 檢查排程工作定義是否合法
+func ValidateCronJob(opt CronJobOption) error {
+	if !cronJobNameRegex.MatchString(opt.Name) {
+		return errors.New("name must be 1-26 lower case letters, digits or '-', starting with a letter")
+	}
+	if err := validateCronSchedule(opt.Schedule); err != nil {
+		return err
+	}
+
+	switch opt.Task.Type {
+	case CronTaskSQL:
+		if strings.TrimSpace(opt.Task.SQL) == "" {
+			return errors.New("SQL must not be empty")
+		}
+		if len(opt.Task.SQL) > CronSQLLimitBytes {
+			return fmt.Errorf("SQL must be at most %d bytes", CronSQLLimitBytes)
+		}
+	case CronTaskRPC:
+		if !identifierRegex.MatchString(opt.Task.Function) {
+			return fmt.Errorf("invalid function name %q", opt.Task.Function)
+		}
+		for name := range opt.Task.Args {
+			if !cronArgNameRegex.MatchString(name) {
+				return fmt.Errorf("invalid argument name %q", name)
+			}
+		}
+		if len(cronStatement(opt.Task)) > CronSQLLimitBytes {
+			return fmt.Errorf("arguments must be at most %d bytes", CronSQLLimitBytes)
+		}
+	default:
+		return errors.New("task type must be sql or rpc")
+	}
+	return nil
+}
+
+// validateCronSchedule 檢查 Kubernetes CronJob 接受的 cron 格式 (5 個欄位或 @daily 等縮寫)
+func validateCronSchedule(schedule string) error {
+	if slices.Contains(cronScheduleMacros, schedule) {
+		return nil
+	}
+	fields := strings.Fields(schedule)
+	if len(fields) != len(cronScheduleColumns) {
+		return errors.New("schedule must have 5 fields: minute hour day-of-month month day-of-week")
+	}
+	for i, field := range fields {
+		column := cronScheduleColumns[i]
+		if err := validateCronField(field, column.min, column.max, column.names); err != nil {
+			return fmt.Errorf("invalid %s %q: %w", column.name, field, err)
+		}
+	}
+	return nil
+}
+
+func validateCronField(field string, min, max int, names []string) error {
+	value := func(s string) (int, error) {
+		if i := slices.Index(names, strings.ToLower(s)); i >= 0 {
+			return i + min, nil
+		}
+		n, err := strconv.Atoi(s)
+		if err != nil || n < min || n > max {
+			return 0, fmt.Errorf("values must be between %d and %d", min, max)
+		}
+		return n, nil
+	}
+
+	for _, part := range strings.Split(field, ",") {
+		rangePart, step, hasStep := strings.Cut(part, "/")
+		if hasStep {
+			if n, err := strconv.Atoi(step); err != nil || n < 1 {
+				return errors.New("step must be a positive number")
+			}
+		}
+		if rangePart == "*" || rangePart == "?" {
+			continue
+		}
+		low, high, isRange := strings.Cut(rangePart, "-")
+		lowValue, err := value(low)
+		if err != nil {
+			return err
+		}
+		if !isRange {
+			continue
+		}
+		highValue, err := value(high)
+		if err != nil {
+			return err
+		}
+		if lowValue > highValue {
+			return errors.New("range start must not be after its end")
+		}
+	}
+	return nil
+}
+
+// cronStatement 回傳排程工作執行的 SQL。
+//
+// RPC 的參數以不指定型別的字串常值傳入，由 Postgres 依函式簽章轉型；物件與陣列以 JSON 文字傳入。
+func cronStatement(task CronJobTask) string {
+	if task.Type == CronTaskSQL {
+		return task.SQL
+	}
+
+	names := slices.Sorted(maps.Keys(task.Args))
+	params := lo.Map(names, func(name string, _ int) string {
+		return name + " => " + cronArgLiteral(task.Args[name])
+	})
+	return "SELECT api." + task.Function + "(" + strings.Join(params, ", ") + ")"
+}
+
+func cronArgLiteral(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return "NULL"
+	case string:
+		return quoteLiteral(v)
+	default:
+		data, _ := json.Marshal(v)
+		return quoteLiteral(string(data))
+	}
+}
+
+// escapeEnvValue 跳脫環境變數值中的 $，避免 kubelet 將 $(VAR) 展開或將 $$ 縮減為 $
+// (例如 dollar-quoted 字串 $$...$$ 或 $1)
+func escapeEnvValue(s string) string {
+	return strings.ReplaceAll(s, "$", "$$")
+}
+
+// quoteLiteral 將字串轉成 SQL 字串常值 (standard_conforming_strings 為 on)
+func quoteLiteral(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// ListCronJobs 回傳專案的排程工作與最近的執行狀態
+func (s *service) ListCronJobs(ctx context.Context, ref string) ([]CronJobInfo, error) {
+	list, err := s.clientset.BatchV1().CronJobs(s.GetProjectNamespace(ref)).List(ctx, metav1.ListOptions{
+		LabelSelector: labels.SelectorFromSet(projectLabels(ref, CronComponent)).String(),
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to list cron jobs", "error", err, "ref", ref)
+		return nil, errors.New("failed to list cron jobs")
+	}
+
+	runs, err := s.listCronJobRuns(ctx, ref, "")
+	if err != nil {
+		return nil, err
+	}
+
+	jobs := make([]CronJobInfo, 0, len(list.Items))
+	for _, cronJob := range list.Items {
+		info := cronJobInfo(&cronJob)
+		if i := slices.IndexFunc(runs, func(run CronJobRun) bool { return run.Name == info.Name }); i >= 0 {
+			info.LastRun = &runs[i]
+		}
+		jobs = append(jobs, info)
+	}
+	slices.SortFunc(jobs, func(a, b CronJobInfo) int { return strings.Compare(a.Name, b.Name) })
+	return jobs, nil
+}
+
+// FindCronJob 回傳排程工作，不存在時回傳 ErrCronJobNotFound
+func (s *service) FindCronJob(ctx context.Context, ref string, name string) (*CronJobInfo, error) {
+	cronJob, err := s.findCronJob(ctx, ref, name)
+	if err != nil {
+		return nil, err
+	}
+	info := cronJobInfo(cronJob)
+
+	runs, err := s.listCronJobRuns(ctx, ref, name)
+	if err != nil {
+		return nil, err
+	}
+	if len(runs) > 0 {
+		info.LastRun = &runs[0]
+	}
+	return &info, nil
+}
+
+func (s *service) findCronJob(ctx context.Context, ref string, name string) (*batchv1.CronJob, error) {
+	cronJobName := s.GetCronJobName(ref, name)
+	cronJob, err := s.clientset.BatchV1().CronJobs(s.GetProjectNamespace(ref)).Get(ctx, cronJobName, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, ErrCronJobNotFound
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to get cron job", "error", err, "cronJob", cronJobName)
+		return nil, errors.New("failed to get cron job")
+	}
+	return cronJob, nil
+}
+
+// ApplyCronJob 建立或更新排程工作。
+//
+// CronJob 不設定 ownerReference，以備份還原切換 Cluster 時不會被移除；刪除專案時依 label 移除。
+func (s *service) ApplyCronJob(ctx context.Context, ref string, opt CronJobOption) error {
+	if err := ValidateCronJob(opt); err != nil {
+		slog.ErrorContext(ctx, "Invalid cron job", "error", err, "ref", ref)
+		return err
+	}
+
+	task, err := json.Marshal(opt.Task)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to marshal cron task", "error", err, "ref", ref)
+		return errors.New("failed to marshal cron task")
+	}
+
+	appSecretName, err := s.databaseRoleSecretName(ctx, ref, RoleApp)
+	if err != nil {
+		return err
+	}
+
+	cronJobName := s.GetCronJobName(ref, opt.Name)
+	jobLabels := lo.Assign(projectLabels(ref, CronComponent), map[string]string{LabelCronJob: opt.Name})
+	cronJob := &batchv1.CronJob{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "batch/v1",
+			Kind:       "CronJob",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        cronJobName,
+			Namespace:   s.GetProjectNamespace(ref),
+			Labels:      jobLabels,
+			Annotations: map[string]string{AnnotationCronTask: string(task)},
+		},
+		Spec: batchv1.CronJobSpec{
+			Schedule:                   opt.Schedule,
+			TimeZone:                   lo.ToPtr("Etc/UTC"),
+			Suspend:                    lo.ToPtr(!opt.Enabled),
+			ConcurrencyPolicy:          batchv1.ForbidConcurrent,
+			SuccessfulJobsHistoryLimit: lo.ToPtr(int32(CronRunHistoryLimit)),
+			FailedJobsHistoryLimit:     lo.ToPtr(int32(CronRunHistoryLimit)),
+			JobTemplate: batchv1.JobTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: jobLabels,
+				},
+				Spec: batchv1.JobSpec{
+					// SQL 不一定是冪等的，失敗時不重試
+					BackoffLimit:          lo.ToPtr(int32(0)),
+					ActiveDeadlineSeconds: lo.ToPtr(int64(CronRunTimeoutSeconds)),
+					Template: corev1.PodTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{
+							Labels: jobLabels,
+						},
+						Spec: corev1.PodSpec{
+							Containers: []corev1.Container{
+								{
+									Name:    cronContainerName,
+									Image:   "postgres:18-alpine",
+									Command: []string{"psql"},
+									// $(VAR) 由 Kubernetes 展開，不經過 shell
+									Args: []string{"$(DATABASE_URL)", "--no-psqlrc", "--set", "ON_ERROR_STOP=1", "--command", "$(CRON_SQL)"},
+									Env: []corev1.EnvVar{
+										{
+											Name: "DATABASE_URL", ValueFrom: &corev1.EnvVarSource{
+												SecretKeyRef: &corev1.SecretKeySelector{
+													Key:                  "uri",
+													LocalObjectReference: corev1.LocalObjectReference{Name: appSecretName},
+												},
+											},
+										},
+										{Name: "CRON_SQL", Value: escapeEnvValue(cronStatement(opt.Task))},
+										{Name: "PGAPPNAME", Value: generateResourceName("cron", opt.Name)},
+									},
+								},
+							},
+							RestartPolicy: corev1.RestartPolicyNever,
+						},
+					},
+				},
+			},
+		},
+	}
+
+	data, err := json.Marshal(cronJob)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to marshal cron job", "error", err, "cronJob", cronJobName)
+		return errors.New("failed to marshal cron job")
+	}
+	_, err = s.clientset.BatchV1().CronJobs(s.GetProjectNamespace(ref)).Patch(
+		ctx,
+		cronJobName,
+		types.ApplyPatchType,
+		data,
+		applyPatchOptions(FieldManager),
+	)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to apply cron job", "error", err, "cronJob", cronJobName)
+		return errors.New("failed to apply cron job")
+	}
+	return nil
+}
+
+// DeleteCronJob 移除排程工作與其執行紀錄
+func (s *service) DeleteCronJob(ctx context.Context, ref string, name string) error {
+	cronJobName := s.GetCronJobName(ref, name)
+	err := s.clientset.BatchV1().CronJobs(s.GetProjectNamespace(ref)).Delete(ctx, cronJobName, metav1.DeleteOptions{
+		PropagationPolicy: lo.ToPtr(metav1.DeletePropagationBackground),
+	})
+	if apierrors.IsNotFound(err) {
+		return ErrCronJobNotFound
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to delete cron job", "error", err, "cronJob", cronJobName)
+		return errors.New("failed to delete cron job")
+	}
+	return nil
+}
+
+// TriggerCronJob 立即執行一次排程工作，與 kubectl create job --from=cronjob 相同。
+//
+// Job 由 CronJob 擁有，與排程的執行共用紀錄數上限；上一次執行尚未結束時回傳 ErrCronJobRunning。
+func (s *service) TriggerCronJob(ctx context.Context, ref string, name string) (*CronJobRun, error) {
+	cronJob, err := s.findCronJob(ctx, ref, name)
+	if err != nil {
+		return nil, err
+	}
+	runs, err := s.listCronJobRuns(ctx, ref, name)
+	if err != nil {
+		return nil, err
+	}
+	if slices.ContainsFunc(runs, func(run CronJobRun) bool {
+		return run.Status == MigrationPending || run.Status == MigrationRunning
+	}) {
+		return nil, ErrCronJobRunning
+	}
+
+	jobName := generateResourceName(cronJob.Name, strconv.FormatInt(time.Now().Unix(), 36))
+	job := &batchv1.Job{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "batch/v1",
+			Kind:       "Job",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        jobName,
+			Namespace:   cronJob.Namespace,
+			Labels:      cronJob.Spec.JobTemplate.Labels,
+			Annotations: map[string]string{annotationCronInstantiate: CronTriggerManual},
+			OwnerReferences: []metav1.OwnerReference{{
+				APIVersion:         "batch/v1",
+				Kind:               "CronJob",
+				Name:               cronJob.Name,
+				UID:                cronJob.UID,
+				Controller:         lo.ToPtr(true),
+				BlockOwnerDeletion: lo.ToPtr(false),
+			}},
+		},
+		Spec: cronJob.Spec.JobTemplate.Spec,
+	}
+
+	data, err := json.Marshal(job)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to marshal cron run", "error", err, "jobName", jobName)
+		return nil, errors.New("failed to marshal cron run")
+	}
+	_, err = s.clientset.BatchV1().Jobs(cronJob.Namespace).Patch(
+		ctx,
+		jobName,
+		types.ApplyPatchType,
+		data,
+		applyPatchOptions(FieldManager),
+	)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to trigger cron job", "error", err, "jobName", jobName)
+		return nil, errors.New("failed to trigger cron job")
+	}
+
+	return &CronJobRun{
+		Name:    name,
+		JobName: jobName,
+		Trigger: CronTriggerManual,
+		Status:  MigrationPending,
+	}, nil
+}
+
+// ListCronJobRuns 回傳排程工作保留的執行紀錄 (由新到舊)
+func (s *service) ListCronJobRuns(ctx context.Context, ref string, name string) ([]CronJobRun, error) {
+	if _, err := s.findCronJob(ctx, ref, name); err != nil {
+		return nil, err
+	}
+	return s.listCronJobRuns(ctx, ref, name)
+}
+
+// FindCronJobRunLogs 回傳一次執行的 psql 輸出，紀錄已被清除時回傳 ErrCronJobRunNotFound
+func (s *service) FindCronJobRunLogs(ctx context.Context, ref string, name string, jobName string) (*CronJobRun, error) {
+	runs, err := s.listCronJobRuns(ctx, ref, name)
+	if err != nil {
+		return nil, err
+	}
+	i := slices.IndexFunc(runs, func(run CronJobRun) bool { return run.JobName == jobName })
+	if i < 0 {
+		return nil, ErrCronJobRunNotFound
+	}
+
+	run := runs[i]
+	if run.Status != MigrationPending {
+		run.Logs, err = s.jobLogs(ctx, ref, jobName, cronContainerName, MigrationLogLimitBytes)
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed to get cron run logs", "error", err, "jobName", jobName)
+			return nil, errors.New("failed to get cron run logs")
+		}
+	}
+	return &run, nil
+}
+
+// listCronJobRuns 列出排程工作的 Job (由新到舊)，name 為空時列出專案所有排程工作的 Job
+func (s *service) listCronJobRuns(ctx context.Context, ref string, name string) ([]CronJobRun, error) {
+	selector := labels.Set(projectLabels(ref, CronComponent))
+	if name != "" {
+		selector[LabelCronJob] = name
+	}
+	list, err := s.clientset.BatchV1().Jobs(s.GetProjectNamespace(ref)).List(ctx, metav1.ListOptions{
+		LabelSelector: selector.String(),
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to list cron runs", "error", err, "ref", ref)
+		return nil, errors.New("failed to list cron runs")
+	}
+
+	slices.SortFunc(list.Items, func(a, b batchv1.Job) int {
+		return b.CreationTimestamp.Compare(a.CreationTimestamp.Time)
+	})
+	runs := make([]CronJobRun, 0, len(list.Items))
+	for _, job := range list.Items {
+		result := migrationJobResult(&job)
+		run := CronJobRun{
+			Name:        job.Labels[LabelCronJob],
+			JobName:     job.Name,
+			Trigger:     CronTriggerScheduled,
+			Status:      result.Status,
+			Message:     result.Message,
+			StartedAt:   result.StartedAt,
+			CompletedAt: result.CompletedAt,
+		}
+		if job.Annotations[annotationCronInstantiate] == CronTriggerManual {
+			run.Trigger = CronTriggerManual
+		}
+		runs = append(runs, run)
+	}
+	return runs, nil
+}
+
+func cronJobInfo(cronJob *batchv1.CronJob) CronJobInfo {
+	info := CronJobInfo{
+		CronJobOption: CronJobOption{
+			Name:     cronJob.Labels[LabelCronJob],
+			Schedule: cronJob.Spec.Schedule,
+			Enabled:  !lo.FromPtr(cronJob.Spec.Suspend),
+		},
+	}
+	// annotation 由 ApplyCronJob 寫入，無法解析時只回傳排程
+	_ = json.Unmarshal([]byte(cronJob.Annotations[AnnotationCronTask]), &info.Task)
+	if cronJob.Status.LastScheduleTime != nil {
+		info.LastScheduleTime = lo.ToPtr(cronJob.Status.LastScheduleTime.Time)
+	}
+	if cronJob.Status.LastSuccessfulTime != nil {
+		info.LastSuccessfulTime = lo.ToPtr(cronJob.Status.LastSuccessfulTime.Time)
+	}
+	return info
+}
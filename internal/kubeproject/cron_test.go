@@ -0,0 +1,143 @@
+package kubeproject
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateCronJob(t *testing.T) {
+	sqlTask := CronJobTask{Type: CronTaskSQL, SQL: "DELETE FROM logs WHERE created_at < now() - interval '7 days'"}
+
+	tests := []struct {
+		name    string
+		opt     CronJobOption
+		wantErr string
+	}{
+		{name: "sql", opt: CronJobOption{Name: "cleanup", Schedule: "*/5 * * * *", Task: sqlTask}},
+		{name: "macro", opt: CronJobOption{Name: "daily-report", Schedule: "@daily", Task: sqlTask}},
+		{name: "names and ranges", opt: CronJobOption{Name: "a1", Schedule: "0 9-17/2 1,15 jan-jun mon-fri", Task: sqlTask}},
+		{name: "sunday as 7", opt: CronJobOption{Name: "weekly", Schedule: "0 0 * * 7", Task: sqlTask}},
+		{name: "rpc", opt: CronJobOption{Name: "refresh", Schedule: "0 * * * *", Task: CronJobTask{Type: CronTaskRPC, Function: "refresh_stats", Args: map[string]any{"days": 7}}}},
+
+		{name: "upper case name", opt: CronJobOption{Name: "Cleanup", Schedule: "@daily", Task: sqlTask}, wantErr: "name must be"},
+		{name: "name ends with dash", opt: CronJobOption{Name: "cleanup-", Schedule: "@daily", Task: sqlTask}, wantErr: "name must be"},
+		{name: "name too long", opt: CronJobOption{Name: "a" + strings.Repeat("b", 26), Schedule: "@daily", Task: sqlTask}, wantErr: "name must be"},
+		{name: "four fields", opt: CronJobOption{Name: "cleanup", Schedule: "* * * *", Task: sqlTask}, wantErr: "5 fields"},
+		{name: "seconds field", opt: CronJobOption{Name: "cleanup", Schedule: "0 0 0 * * *", Task: sqlTask}, wantErr: "5 fields"},
+		{name: "minute out of range", opt: CronJobOption{Name: "cleanup", Schedule: "60 * * * *", Task: sqlTask}, wantErr: "invalid minute"},
+		{name: "day of month zero", opt: CronJobOption{Name: "cleanup", Schedule: "0 0 0 * *", Task: sqlTask}, wantErr: "invalid day of month"},
+		{name: "reversed range", opt: CronJobOption{Name: "cleanup", Schedule: "0 17-9 * * *", Task: sqlTask}, wantErr: "range start"},
+		{name: "zero step", opt: CronJobOption{Name: "cleanup", Schedule: "*/0 * * * *", Task: sqlTask}, wantErr: "step"},
+		{name: "unknown month name", opt: CronJobOption{Name: "cleanup", Schedule: "0 0 1 foo *", Task: sqlTask}, wantErr: "invalid month"},
+		{name: "unknown macro", opt: CronJobOption{Name: "cleanup", Schedule: "@every 5m", Task: sqlTask}, wantErr: "5 fields"},
+		{name: "empty sql", opt: CronJobOption{Name: "cleanup", Schedule: "@daily", Task: CronJobTask{Type: CronTaskSQL, SQL: " \n"}}, wantErr: "SQL must not be empty"},
+		{name: "sql too large", opt: CronJobOption{Name: "cleanup", Schedule: "@daily", Task: CronJobTask{Type: CronTaskSQL, SQL: strings.Repeat("x", CronSQLLimitBytes+1)}}, wantErr: "SQL must be at most"},
+		{name: "schema-qualified function", opt: CronJobOption{Name: "refresh", Schedule: "@daily", Task: CronJobTask{Type: CronTaskRPC, Function: "public.refresh"}}, wantErr: "invalid function name"},
+		{name: "function injection", opt: CronJobOption{Name: "refresh", Schedule: "@daily", Task: CronJobTask{Type: CronTaskRPC, Function: "f(); DROP TABLE x; --"}}, wantErr: "invalid function name"},
+		{name: "argument injection", opt: CronJobOption{Name: "refresh", Schedule: "@daily", Task: CronJobTask{Type: CronTaskRPC, Function: "f", Args: map[string]any{"a => 1); --": 1}}}, wantErr: "invalid argument name"},
+		{name: "arguments too large", opt: CronJobOption{Name: "refresh", Schedule: "@daily", Task: CronJobTask{Type: CronTaskRPC, Function: "f", Args: map[string]any{"a": strings.Repeat("x", CronSQLLimitBytes)}}}, wantErr: "arguments must be at most"},
+		{name: "unknown task type", opt: CronJobOption{Name: "cleanup", Schedule: "@daily", Task: CronJobTask{Type: "http"}}, wantErr: "task type"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateCronJob(tt.opt)
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("ValidateCronJob: %v", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Errorf("ValidateCronJob error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCronStatement(t *testing.T) {
+	tests := []struct {
+		name string
+		task CronJobTask
+		want string
+	}{
+		{
+			name: "sql is used as is",
+			task: CronJobTask{Type: CronTaskSQL, SQL: "SELECT 'it''s';"},
+			want: "SELECT 'it''s';",
+		},
+		{
+			name: "no arguments",
+			task: CronJobTask{Type: CronTaskRPC, Function: "refresh"},
+			want: "SELECT api.refresh()",
+		},
+		{
+			name: "arguments sorted by name",
+			task: CronJobTask{Type: CronTaskRPC, Function: "f", Args: map[string]any{"b": "x", "a": "y"}},
+			want: "SELECT api.f(a => 'y', b => 'x')",
+		},
+		{
+			name: "quotes escaped",
+			task: CronJobTask{Type: CronTaskRPC, Function: "f", Args: map[string]any{"name": "O'Brien'); DROP TABLE x; --"}},
+			want: "SELECT api.f(name => 'O''Brien''); DROP TABLE x; --')",
+		},
+		{
+			name: "backslash is literal",
+			task: CronJobTask{Type: CronTaskRPC, Function: "f", Args: map[string]any{"path": `C:\temp\'`}},
+			want: `SELECT api.f(path => 'C:\temp\''')`,
+		},
+		{
+			name: "null, numbers and booleans",
+			task: CronJobTask{Type: CronTaskRPC, Function: "f", Args: map[string]any{"a": nil, "b": 1.5, "c": true}},
+			want: "SELECT api.f(a => NULL, b => '1.5', c => 'true')",
+		},
+		{
+			name: "objects and arrays as JSON text",
+			task: CronJobTask{Type: CronTaskRPC, Function: "f", Args: map[string]any{"o": map[string]any{"k": "it's"}, "l": []any{1, "two"}}},
+			want: `SELECT api.f(l => '[1,"two"]', o => '{"k":"it''s"}')`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := cronStatement(tt.task); got != tt.want {
+				t.Errorf("cronStatement = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEscapeEnvValue(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  string
+	}{
+		{name: "no dollar", value: "SELECT 1", want: "SELECT 1"},
+		{name: "variable reference", value: "SELECT '$(HOME)'", want: "SELECT '$$(HOME)'"},
+		{name: "dollar quoting", value: "DO $$ BEGIN PERFORM 1; END $$", want: "DO $$$$ BEGIN PERFORM 1; END $$$$"},
+		{name: "tagged dollar quoting", value: "SELECT $fn$a$fn$", want: "SELECT $$fn$$a$$fn$$"},
+		{name: "positional parameter", value: "EXECUTE p($1)", want: "EXECUTE p($$1)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := escapeEnvValue(tt.value)
+			if got != tt.want {
+				t.Errorf("escapeEnvValue = %q, want %q", got, tt.want)
+			}
+			// kubelet 展開後應還原為原本的值
+			if expanded := expandEnvValue(got); expanded != tt.value {
+				t.Errorf("expanded value = %q, want %q", expanded, tt.value)
+			}
+		})
+	}
+}
+
+// expandEnvValue 模擬 kubelet 對環境變數值的展開：$$ 縮減為 $，$(VAR) 在變數不存在時保留原樣
+func expandEnvValue(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '$' && i+1 < len(s) && s[i+1] == '$' {
+			b.WriteByte('$')
+			i++
+			continue
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
@@ -18,6 +18,13 @@ import (
 var pgDatabaseYAMLBuf []byte
 
 func (s *service) CreateDatabase(ctx context.Context, ref string) error {
+	return s.applyDatabase(ctx, ref, nil)
+}
+
+// applyDatabase 以 template 套用 Database 資源，extensions 會附加在 template 的 extension 之後。
+//
+// spec.extensions 是整個 list 由 FieldManager 管理，每次套用都必須包含專案啟用的 extension。
+func (s *service) applyDatabase(ctx context.Context, ref string, extensions []DatabaseExtensionSpec) error {
 	pgDatabaseUnstructured := &unstructured.Unstructured{}
 
 	reader := bytes.NewReader(pgDatabaseYAMLBuf)
@@ -28,20 +35,39 @@ func (s *service) CreateDatabase(ctx context.Context, ref string) error {
 		return ErrFailedToDecodePostgresDatabaseYAML
 	}
 
+	ownerRef, err := s.clusterOwnerReference(ctx, ref)
+	if err != nil {
+		return err
+	}
+
 	// set metadata
 	pgDatabaseUnstructured.SetName(ref)
-	pgDatabaseUnstructured.SetNamespace(s.namespace)
+	pgDatabaseUnstructured.SetNamespace(s.GetProjectNamespace(ref))
+	pgDatabaseUnstructured.SetLabels(projectLabels(ref, DBComponent))
+	pgDatabaseUnstructured.SetOwnerReferences([]metav1.OwnerReference{*ownerRef})
 
 	// set spec.cluster.name
-	if err := unstructured.SetNestedField(pgDatabaseUnstructured.Object, ref, "spec", "cluster", "name"); err != nil {
+	if err := unstructured.SetNestedField(pgDatabaseUnstructured.Object, ownerRef.Name, "spec", "cluster", "name"); err != nil {
 		slog.Error("Failed to set name in Postgres database spec", "error", err)
 		return ErrFailedToSetSpecClusterName
 	}
 
-	// 使用 dynamicClient 創建資源
-	_, err := s.dynamicClient.Resource(databaseGVR).
-		Namespace(s.namespace).
-		Create(ctx, pgDatabaseUnstructured, metav1.CreateOptions{})
+	// append spec.extensions
+	if len(extensions) > 0 {
+		items, _, _ := unstructured.NestedSlice(pgDatabaseUnstructured.Object, "spec", "extensions")
+		for _, extension := range extensions {
+			items = append(items, extension.toUnstructured())
+		}
+		if err := unstructured.SetNestedSlice(pgDatabaseUnstructured.Object, items, "spec", "extensions"); err != nil {
+			slog.Error("Failed to set extensions in Postgres database spec", "error", err)
+			return ErrFailedToSetSpecExtensions
+		}
+	}
+
+	// 使用 dynamicClient 以 server-side apply 建立或更新資源
+	_, err = s.dynamicClient.Resource(databaseGVR).
+		Namespace(s.GetProjectNamespace(ref)).
+		Apply(ctx, ref, pgDatabaseUnstructured, applyOptions(FieldManager))
 	if err != nil {
 		slog.Error("Failed to create Postgres database", "error", err)
 		return ErrFeiledToCreatePostgresDatabase
@@ -53,9 +79,9 @@ func (s *service) CreateDatabase(ctx context.Context, ref string) error {
 func (s *service) DeleteDatabase(ctx context.Context, ref string) error {
 	// 使用 dynamicClient 刪除資源
 	err := s.dynamicClient.Resource(databaseGVR).
-		Namespace(s.namespace).
+		Namespace(s.GetProjectNamespace(ref)).
 		Delete(ctx, ref, metav1.DeleteOptions{})
-	if err != nil {
+	if ignoreNotFound(err) != nil {
 		slog.Error("Failed to delete Postgres database", "error", err)
 		return ErrFailedToDeletePostgresDatabase
 	}
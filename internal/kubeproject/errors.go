@@ -13,12 +13,16 @@ var (
 	ErrFailedToGetSpecFromPostgresClusterYAML = errors.New("failed to get spec from Postgres cluster YAML")
 	ErrSpecNotFoundInPostgresClusterYAML      = errors.New("spec not found in Postgres cluster YAML")
 	ErrFailedToSetSpecStorageSize             = errors.New("failed to set storage size in Postgres cluster spec")
+	ErrFailedToSetSpecInheritedLabels         = errors.New("failed to set inherited labels in Postgres cluster spec")
+	ErrFailedToSetSpecBackup                  = errors.New("failed to set backup in Postgres cluster spec")
+	ErrFailedToSetSpecRecovery                = errors.New("failed to set recovery bootstrap in Postgres cluster spec")
 	ErrFailedToCreatePostgresCluster          = errors.New("failed to create Postgres cluster")
 	ErrFailedToDeletePostgresCluster          = errors.New("failed to delete Postgres cluster")
 	// database errors
 	ErrFailedToOpenPostgresDatabaseYAML   = errors.New("failed to open Postgres database YAML file")
 	ErrFailedToDecodePostgresDatabaseYAML = errors.New("failed to decode Postgres database YAML")
 	ErrFailedToSetSpecClusterName         = errors.New("failed to set cluster name in Postgres database spec")
+	ErrFailedToSetSpecExtensions          = errors.New("failed to set extensions in Postgres database spec")
 	ErrFeiledToCreatePostgresDatabase     = errors.New("failed to create Postgres database")
 	ErrFailedToDeletePostgresDatabase     = errors.New("failed to delete Postgres database")
 	ErrFailedToReadDatabaseSecret         = errors.New("failed to read Postgres database secret")
@@ -31,4 +35,31 @@ var (
 	ErrFailedToSetSpecTLSSecretName       = errors.New("failed to set TLS secret name in IngressRouteTCP spec")
 	ErrFailedToCreateIngressRouteTCP      = errors.New("failed to create IngressRouteTCP")
 	ErrFailedToDeleteIngressRouteTCP      = errors.New("failed to delete IngressRouteTCP")
+	// backup errors
+	ErrBackupNotEnabled    = errors.New("database backups are not enabled")
+	ErrBackupNotFound      = errors.New("backup not found")
+	ErrBackupNotCompleted  = errors.New("backup is not completed")
+	ErrFailedToListBackups = errors.New("failed to list backups")
+	ErrFailedToGetBackup   = errors.New("failed to get backup")
+	// point-in-time recovery errors
+	ErrNoRecoverabilityPoint = errors.New("no recoverability point available")
+	ErrPITRClusterNotFound   = errors.New("point-in-time recovery cluster not found")
+	// migration errors
+	ErrMigrationNotFound = errors.New("migration not found")
+	ErrMigrationFailed   = errors.New("migration failed")
+	ErrMigrationRunning  = errors.New("a migration is already running")
+	// user migration errors
+	ErrNoUserMigrations       = errors.New("no migrations have been uploaded")
+	ErrUserMigrationsTooLarge = errors.New("migrations exceed the size limit")
+	// cron job errors
+	ErrCronJobNotFound    = errors.New("cron job not found")
+	ErrCronJobRunning     = errors.New("cron job is already running")
+	ErrCronJobRunNotFound = errors.New("cron job run not found")
+	// REST API errors
+	ErrRESTAPIDeploymentNotFound = errors.New("REST API deployment not found")
+	// placement errors
+	ErrNoClusterInRegion   = errors.New("no cluster in the requested region")
+	ErrNoClusterAvailable  = errors.New("no cluster has capacity for a new project")
+	ErrUnknownCluster      = errors.New("project is placed in an unknown cluster")
+	ErrFailedToFindCluster = errors.New("failed to find the cluster of the project")
 )
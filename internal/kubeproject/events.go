@@ -0,0 +1,171 @@
+package kubeproject
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"baas-api/internal/models"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// 專案事件的嚴重程度
+const (
+	EventSeverityInfo    = "info"
+	EventSeverityWarning = "warning"
+	EventSeverityError   = "error"
+)
+
+// ProjectEvent 是轉換為使用者可讀訊息的 Kubernetes Event
+type ProjectEvent struct {
+	Severity string
+	Reason   string
+	// Message 是給使用者看的說明，Detail 是 Kubernetes 的原始訊息
+	Message   string
+	Detail    string
+	Kind      string
+	Name      string
+	Count     int32
+	FirstSeen time.Time
+	LastSeen  time.Time
+}
+
+// eventTranslation 是 Event reason 對應的說明與嚴重程度
+type eventTranslation struct {
+	Severity string
+	Message  string
+}
+
+// eventTranslations 涵蓋專案資源常見的失敗原因，其他 reason 使用 Kubernetes 的原始訊息
+var eventTranslations = map[string]eventTranslation{
+	"Failed":                  {EventSeverityError, "A container failed to start"},
+	"ErrImagePull":            {EventSeverityError, "A container image could not be pulled"},
+	"ImagePullBackOff":        {EventSeverityError, "A container image could not be pulled and will be retried"},
+	"InspectFailed":           {EventSeverityError, "A container image could not be inspected"},
+	"BackOff":                 {EventSeverityError, "A container keeps crashing and is being restarted"},
+	"CrashLoopBackOff":        {EventSeverityError, "A container keeps crashing and is being restarted"},
+	"OOMKilling":              {EventSeverityError, "A container ran out of memory and was stopped"},
+	"FailedScheduling":        {EventSeverityWarning, "A pod is waiting for a node with enough resources"},
+	"FailedCreate":            {EventSeverityError, "Pods could not be created; the project may have reached its resource quota"},
+	"FailedMount":             {EventSeverityError, "A storage volume could not be mounted"},
+	"FailedAttachVolume":      {EventSeverityError, "A storage volume could not be attached"},
+	"ProvisioningFailed":      {EventSeverityError, "The database storage volume could not be provisioned"},
+	"FailedBinding":           {EventSeverityError, "The database storage volume could not be bound"},
+	"ExternalProvisioning":    {EventSeverityInfo, "Waiting for the database storage volume to be provisioned"},
+	"VolumeResizeFailed":      {EventSeverityError, "The database storage volume could not be resized"},
+	"Unhealthy":               {EventSeverityWarning, "A health check failed"},
+	"Evicted":                 {EventSeverityWarning, "A pod was evicted from its node"},
+	"Preempting":              {EventSeverityWarning, "A pod was preempted by a higher priority workload"},
+	"BackoffLimitExceeded":    {EventSeverityError, "The database migration failed too many times"},
+	"DeadlineExceeded":        {EventSeverityError, "The database migration took too long"},
+	"FailedGetResourceMetric": {EventSeverityWarning, "Autoscaling could not read the pod resource usage"},
+}
+
+// refFromResourceName 從資源名稱取得所屬的專案。
+//
+// 專案的資源 (包含 operator 產生的 Pod 與 PVC) 都以 ref 或 "<ref>-" 開頭命名。
+func refFromResourceName(name string) string {
+	if len(name) < 20 || (len(name) > 20 && name[20] != '-') {
+		return ""
+	}
+	if ref := name[:20]; models.IsValidReference(ref) {
+		return ref
+	}
+	return ""
+}
+
+// translateEvent 將 Kubernetes Event 轉換為使用者可讀的 ProjectEvent
+func translateEvent(event *corev1.Event) ProjectEvent {
+	translation, ok := eventTranslations[event.Reason]
+	if !ok {
+		translation = eventTranslation{Severity: EventSeverityInfo, Message: event.Message}
+		if event.Type == corev1.EventTypeWarning {
+			translation.Severity = EventSeverityWarning
+		}
+	}
+
+	lastSeen := event.LastTimestamp.Time
+	if lastSeen.IsZero() {
+		lastSeen = event.EventTime.Time
+	}
+	if lastSeen.IsZero() {
+		lastSeen = event.CreationTimestamp.Time
+	}
+	firstSeen := event.FirstTimestamp.Time
+	if firstSeen.IsZero() {
+		firstSeen = lastSeen
+	}
+	count := event.Count
+	if event.Series != nil {
+		count = event.Series.Count
+	}
+
+	return ProjectEvent{
+		Severity:  translation.Severity,
+		Reason:    event.Reason,
+		Message:   fmt.Sprintf("%s (%s %s)", translation.Message, strings.ToLower(event.InvolvedObject.Kind), event.InvolvedObject.Name),
+		Detail:    event.Message,
+		Kind:      event.InvolvedObject.Kind,
+		Name:      event.InvolvedObject.Name,
+		Count:     max(count, 1),
+		FirstSeen: firstSeen,
+		LastSeen:  lastSeen,
+	}
+}
+
+// ListProjectEvents 回傳 involvedObject 屬於專案資源、且在 since 之後發生的 Event，依時間由新到舊排列。
+//
+// namespace 有訂閱中的 Event informer 且已同步時直接讀取 cache，不會對 API server 發出請求。
+func (s *service) ListProjectEvents(ctx context.Context, ref string, since time.Time) ([]ProjectEvent, error) {
+	var events []corev1.Event
+	if factory := s.watcher.cachedEvents(s.GetProjectNamespace(ref)); factory != nil {
+		objs, err := factory.ForResource(eventGVR).Lister().ByNamespace(s.GetProjectNamespace(ref)).List(labels.Everything())
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed to list cached events", "error", err, "ref", ref)
+			return nil, errors.New("failed to list project events")
+		}
+		for _, obj := range objs {
+			u, ok := obj.(*unstructured.Unstructured)
+			if !ok || involvedObjectProjectRef(u) != ref {
+				continue
+			}
+			var event corev1.Event
+			if err := runtime.DefaultUnstructuredConverter.FromUnstructured(u.Object, &event); err != nil {
+				continue
+			}
+			events = append(events, event)
+		}
+	} else {
+		list, err := s.clientset.CoreV1().Events(s.GetProjectNamespace(ref)).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed to list events", "error", err, "ref", ref)
+			return nil, errors.New("failed to list project events")
+		}
+		for _, event := range list.Items {
+			if refFromResourceName(event.InvolvedObject.Name) == ref {
+				events = append(events, event)
+			}
+		}
+	}
+
+	result := make([]ProjectEvent, 0, len(events))
+	for i := range events {
+		event := translateEvent(&events[i])
+		if event.LastSeen.After(since) {
+			result = append(result, event)
+		}
+	}
+	slices.SortFunc(result, func(a, b ProjectEvent) int {
+		return b.LastSeen.Compare(a.LastSeen)
+	})
+	return result, nil
+}
@@ -0,0 +1,138 @@
+package kubeproject
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/yaml"
+)
+
+var ErrExtensionNotAllowed = errors.New("extension is not allowed")
+
+// DatabaseExtensionSpec 是專案在 Database spec.extensions 中管理的 extension
+type DatabaseExtensionSpec struct {
+	Name string
+	// Enabled 為 false 時以 ensure: absent 讓 CNPG 移除 extension
+	Enabled bool
+	Version string
+}
+
+func (e DatabaseExtensionSpec) toUnstructured() map[string]any {
+	item := map[string]any{
+		"name":   e.Name,
+		"ensure": "present",
+	}
+	if !e.Enabled {
+		item["ensure"] = "absent"
+	}
+	if e.Version != "" {
+		item["version"] = e.Version
+	}
+	return item
+}
+
+// DatabaseExtension 是 Database 資源中 extension 的設定與 CNPG 回報的狀態
+type DatabaseExtension struct {
+	DatabaseExtensionSpec
+	// Builtin 表示由平台的 Database template 建立，不能停用
+	Builtin bool
+	Applied bool
+	Message string
+}
+
+// builtinExtensionNames 回傳 Database template 中固定建立的 extension
+var builtinExtensionNames = sync.OnceValue(func() []string {
+	database := &unstructured.Unstructured{}
+	if err := yaml.NewYAMLOrJSONDecoder(bytes.NewReader(pgDatabaseYAMLBuf), 1024).Decode(database); err != nil {
+		slog.Error("Failed to decode Postgres database YAML", "error", err)
+		return nil
+	}
+	items, _, _ := unstructured.NestedSlice(database.Object, "spec", "extensions")
+	names := []string{}
+	for _, item := range items {
+		if extension, ok := item.(map[string]any); ok {
+			if name, ok := extension["name"].(string); ok {
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+})
+
+// IsBuiltinExtension 回傳 extension 是否由平台固定建立
+func IsBuiltinExtension(name string) bool {
+	return slices.Contains(builtinExtensionNames(), name)
+}
+
+// IsExtensionAllowed 回傳 extension 是否在平台設定的允許清單中
+func (s *service) IsExtensionAllowed(name string) bool {
+	return !IsBuiltinExtension(name) && slices.Contains(s.config.Kube.Project.Extensions.Allowlist, name)
+}
+
+// FindDatabaseExtensions 回傳 Database 資源中所有 extension 的設定與套用狀態
+func (s *service) FindDatabaseExtensions(ctx context.Context, ref string) ([]DatabaseExtension, error) {
+	database, err := s.dynamicClient.Resource(databaseGVR).
+		Namespace(s.GetProjectNamespace(ref)).
+		Get(ctx, ref, metav1.GetOptions{})
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to get postgres database", "error", err, "ref", ref)
+		return nil, errors.New("failed to get postgres database")
+	}
+
+	statuses := map[string]map[string]any{}
+	items, _, _ := unstructured.NestedSlice(database.Object, "status", "extensions")
+	for _, item := range items {
+		if status, ok := item.(map[string]any); ok {
+			if name, ok := status["name"].(string); ok {
+				statuses[name] = status
+			}
+		}
+	}
+
+	extensions := []DatabaseExtension{}
+	items, _, _ = unstructured.NestedSlice(database.Object, "spec", "extensions")
+	for _, item := range items {
+		spec, ok := item.(map[string]any)
+		if !ok {
+			continue
+		}
+		name, _ := spec["name"].(string)
+		ensure, _ := spec["ensure"].(string)
+		version, _ := spec["version"].(string)
+
+		extension := DatabaseExtension{
+			DatabaseExtensionSpec: DatabaseExtensionSpec{
+				Name:    name,
+				Enabled: ensure != "absent",
+				Version: version,
+			},
+			Builtin: IsBuiltinExtension(name),
+		}
+		if status, ok := statuses[name]; ok {
+			extension.Applied, _ = status["applied"].(bool)
+			extension.Message, _ = status["message"].(string)
+		}
+		extensions = append(extensions, extension)
+	}
+
+	return extensions, nil
+}
+
+// ApplyDatabaseExtensions 套用專案管理的 extension (不含平台固定建立的 extension)。
+//
+// 停用的 extension 需保留 ensure: absent，CNPG 才會執行 DROP EXTENSION。
+func (s *service) ApplyDatabaseExtensions(ctx context.Context, ref string, extensions []DatabaseExtensionSpec) error {
+	for _, extension := range extensions {
+		if !s.IsExtensionAllowed(extension.Name) {
+			return fmt.Errorf("%w: %s", ErrExtensionNotAllowed, extension.Name)
+		}
+	}
+	return s.applyDatabase(ctx, ref, extensions)
+}
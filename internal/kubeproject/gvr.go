@@ -18,6 +18,24 @@ var databaseGVR = schema.GroupVersionResource{
 	Resource: "databases",
 }
 
+var scheduledBackupGVR = schema.GroupVersionResource{
+	Group:    "postgresql.cnpg.io",
+	Version:  "v1",
+	Resource: "scheduledbackups",
+}
+
+var backupGVR = schema.GroupVersionResource{
+	Group:    "postgresql.cnpg.io",
+	Version:  "v1",
+	Resource: "backups",
+}
+
+var poolerGVR = schema.GroupVersionResource{
+	Group:    "postgresql.cnpg.io",
+	Version:  "v1",
+	Resource: "poolers",
+}
+
 var ingressRouteTCPGVR = schema.GroupVersionResource{
 	Group:    "traefik.io",
 	Version:  "v1alpha1",
@@ -29,3 +47,40 @@ var ingressRouteGVR = schema.GroupVersionResource{
 	Version:  "v1alpha1",
 	Resource: "ingressroutes",
 }
+
+var middlewareGVR = schema.GroupVersionResource{
+	Group:    "traefik.io",
+	Version:  "v1alpha1",
+	Resource: "middlewares",
+}
+
+var httpRouteGVR = schema.GroupVersionResource{
+	Group:    "gateway.networking.k8s.io",
+	Version:  "v1",
+	Resource: "httproutes",
+}
+
+// TLSRoute 目前只在 Gateway API 的 experimental channel
+var tlsRouteGVR = schema.GroupVersionResource{
+	Group:    "gateway.networking.k8s.io",
+	Version:  "v1alpha2",
+	Resource: "tlsroutes",
+}
+
+var deploymentGVR = schema.GroupVersionResource{
+	Group:    "apps",
+	Version:  "v1",
+	Resource: "deployments",
+}
+
+var jobGVR = schema.GroupVersionResource{
+	Group:    "batch",
+	Version:  "v1",
+	Resource: "jobs",
+}
+
+var eventGVR = schema.GroupVersionResource{
+	Group:    "",
+	Version:  "v1",
+	Resource: "events",
+}
@@ -5,130 +5,79 @@ package kubeproject
 
 import (
 	"context"
-	_ "embed"
-	"errors"
-	"log/slog"
-	"strings"
-	"text/template"
+	"fmt"
+
+	"baas-api/internal/config"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
-	"k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 )
 
-//go:embed kube-files/project-ingressroute.yaml
-var ingressRouteYAMLStr string
-
-//go:embed kube-files/project-ingressroutetcp.yaml
-var ingressRouteTCPYAMLStr string
+// ingressProvider 建立專案 API 與資料庫對外的路由資源，由 config.Kube.Ingress.Provider 選擇實作
+type ingressProvider interface {
+	// ApplyAPIRoute 將 <ref>.<domain>/api/auth 與 /api/rest 導向專案的 Auth API 與 PostgREST
+	ApplyAPIRoute(ctx context.Context, ref string, ownerRef *metav1.OwnerReference) error
+	DeleteAPIRoute(ctx context.Context, ref string) error
+	// ApplyRealtimeRoute 將 <ref>.<domain>/api/realtime 導向專案的 Realtime 的 /socket
+	ApplyRealtimeRoute(ctx context.Context, ref string, ownerRef *metav1.OwnerReference) error
+	DeleteRealtimeRoute(ctx context.Context, ref string) error
+	// ApplyDBRoute 以 SNI host 將 Postgres 連線導向指定的 service
+	ApplyDBRoute(ctx context.Context, ref string, opt dbRouteOption) error
+	DeleteDBRoute(ctx context.Context, ref string, name string) error
+	// GVRs 是 provider 建立的資源，專案刪除與還原時一併處理
+	GVRs() []schema.GroupVersionResource
+}
 
-func (s *service) CreateIngressRoute(ctx context.Context, ref string) error {
-	ingressData := map[string]any{
-		"ProjectHost":     s.GetProjectHost(ref),
-		"AuthServiceName": s.GetAuthAPIServiceName(ref),
-		"RESTServiceName": s.GetRESTAPIServiceName(ref),
-		"TLSSecretName":   s.config.Kube.Project.TLSSecretName,
-	}
-	ingressTmpl, err := template.New("yaml").Parse(ingressRouteYAMLStr)
-	if err != nil {
-		slog.Error("Failed to parse IngressRoute YAML template", "error", err)
-		return errors.New("failed to parse IngressRoute YAML template")
-	}
-	var ingressRendered strings.Builder
-	if err := ingressTmpl.Execute(&ingressRendered, ingressData); err != nil {
-		slog.Error("Failed to execute IngressRoute YAML template", "error", err)
-		return errors.New("failed to execute IngressRoute YAML template")
-	}
+type dbRouteOption struct {
+	Name        string
+	Component   string
+	Host        string
+	ServiceName string
+	OwnerRef    *metav1.OwnerReference
+}
 
-	ingressRoute := &unstructured.Unstructured{}
-	decoder := yaml.NewYAMLOrJSONDecoder(strings.NewReader(ingressRendered.String()), 1024)
-	if err := decoder.Decode(ingressRoute); err != nil {
-		slog.Error("Failed to decode IngressRoute YAML", "error", err)
-		return errors.New("failed to decode IngressRoute YAML")
+func newIngressProvider(s *service) (ingressProvider, error) {
+	switch s.config.Kube.Ingress.Provider {
+	case config.IngressProviderTraefik, "":
+		return &traefikIngress{s: s}, nil
+	case config.IngressProviderGateway:
+		return &gatewayIngress{s: s}, nil
+	default:
+		return nil, fmt.Errorf("unknown ingress provider %q", s.config.Kube.Ingress.Provider)
 	}
+}
 
-	// set metadata
-	ingressRouteName := s.GetAPIIngressRouteName(ref)
-	ingressRoute.SetName(ingressRouteName)
-	ingressRoute.SetNamespace(s.namespace)
-
-	// 使用 dynamicClient 創建資源
-	_, err = s.dynamicClient.Resource(ingressRouteGVR).
-		Namespace(s.namespace).
-		Create(ctx, ingressRoute, metav1.CreateOptions{})
+func (s *service) CreateIngressRoute(ctx context.Context, ref string) error {
+	ownerRef, err := s.clusterOwnerReference(ctx, ref)
 	if err != nil {
-		slog.Error("Failed to create IngressRoute", "error", err)
-		return errors.New("failed to create IngressRoute")
+		return err
 	}
-
-	return nil
+	return s.ingress.ApplyAPIRoute(ctx, ref, ownerRef)
 }
 
 func (s *service) DeleteIngressRoute(ctx context.Context, ref string) error {
-	// 使用 dynamicClient 刪除資源
-	target := s.GetAPIIngressRouteName(ref)
-	err := s.dynamicClient.Resource(ingressRouteGVR).
-		Namespace(s.namespace).
-		Delete(ctx, target, metav1.DeleteOptions{})
-	if err != nil {
-		slog.Error("Failed to delete IngressRoute", "error", err)
-		return errors.New("failed to delete IngressRoute")
-	}
-
-	return nil
+	return s.ingress.DeleteAPIRoute(ctx, ref)
 }
 
 func (s *service) CreateIngressRouteTCP(ctx context.Context, ref string) error {
-	ingressData := map[string]any{
-		"ProjectHost":          s.GetProjectHost(ref),
-		"ProjectDBServiceName": s.GetDatabaseRWServiceName(ref),
-		"TLSSecretName":        s.config.Kube.Project.TLSSecretName,
-	}
-	ingressTmpl, err := template.New("yaml").Parse(ingressRouteTCPYAMLStr)
-	if err != nil {
-		slog.Error("Failed to parse IngressRouteTCP YAML template", "error", err)
-		return errors.New("failed to parse IngressRouteTCP YAML template")
-	}
-	var ingressRendered strings.Builder
-	if err := ingressTmpl.Execute(&ingressRendered, ingressData); err != nil {
-		slog.Error("Failed to execute IngressRouteTCP YAML template", "error", err)
-		return errors.New("failed to execute IngressRouteTCP YAML template")
-	}
-
-	ingressRouteTCPUnstructured := &unstructured.Unstructured{}
-	decoder := yaml.NewYAMLOrJSONDecoder(strings.NewReader(ingressRendered.String()), 1024)
-	if err := decoder.Decode(ingressRouteTCPUnstructured); err != nil {
-		slog.Error("Failed to decode IngressRouteTCP YAML", "error", err)
-		return ErrFailedToDecodeIngressRouteTCPYAML
-	}
-
-	// set metadata
-	dbIngressRouteTCPName := s.GetDBIngressRouteTCPName(ref)
-	ingressRouteTCPUnstructured.SetName(dbIngressRouteTCPName)
-	ingressRouteTCPUnstructured.SetNamespace(s.namespace)
-
-	// 使用 dynamicClient 創建資源
-	_, err = s.dynamicClient.Resource(ingressRouteTCPGVR).
-		Namespace(s.namespace).
-		Create(ctx, ingressRouteTCPUnstructured, metav1.CreateOptions{})
+	ownerRef, err := s.clusterOwnerReference(ctx, ref)
 	if err != nil {
-		slog.Error("Failed to create IngressRouteTCP", "error", err)
-		return ErrFailedToCreateIngressRouteTCP
+		return err
 	}
+	return s.applyPrimaryDBRoute(ctx, ref, ownerRef)
+}
 
-	return nil
+// applyPrimaryDBRoute 以 <ref>.<domain> 將資料庫連線導向 ownerRef Cluster 的 -rw service
+func (s *service) applyPrimaryDBRoute(ctx context.Context, ref string, ownerRef *metav1.OwnerReference) error {
+	return s.ingress.ApplyDBRoute(ctx, ref, dbRouteOption{
+		Name:        s.GetDBIngressRouteTCPName(ref),
+		Component:   DBComponent,
+		Host:        s.GetProjectHost(ref),
+		ServiceName: s.GetDatabaseRWServiceName(ownerRef.Name),
+		OwnerRef:    ownerRef,
+	})
 }
 
 func (s *service) DeleteIngressRouteTCP(ctx context.Context, ref string) error {
-	// 使用 dynamicClient 刪除資源
-	target := s.GetDBIngressRouteTCPName(ref)
-	err := s.dynamicClient.Resource(ingressRouteTCPGVR).
-		Namespace(s.namespace).
-		Delete(ctx, target, metav1.DeleteOptions{})
-	if err != nil {
-		slog.Error("Failed to delete IngressRouteTCP", "error", err)
-		return ErrFailedToDeleteIngressRouteTCP
-	}
-
-	return nil
+	return s.ingress.DeleteDBRoute(ctx, ref, s.GetDBIngressRouteTCPName(ref))
 }
@@ -0,0 +1,215 @@
+package kubeproject
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// gatewayIngress 以 Gateway API 的 HTTPRoute 與 TLSRoute 建立路由 (例如 Envoy Gateway)。
+//
+// TLS 由共用 Gateway 的 listener 終止，route 只以 hostname 比對，不需要專案的 TLS secret。
+type gatewayIngress struct {
+	s *service
+}
+
+var _ ingressProvider = (*gatewayIngress)(nil)
+
+func (p *gatewayIngress) GVRs() []schema.GroupVersionResource {
+	return []schema.GroupVersionResource{httpRouteGVR, tlsRouteGVR}
+}
+
+// parentRefs 回傳附加到共用 Gateway 指定 listener 的 parentRefs
+func (p *gatewayIngress) parentRefs(listener string) []any {
+	gateway := p.s.config.Kube.Ingress.Gateway
+	parentRef := map[string]any{
+		"name":      gateway.Name,
+		"namespace": gateway.Namespace,
+	}
+	if listener != "" {
+		parentRef["sectionName"] = listener
+	}
+	return []any{parentRef}
+}
+
+// replacePrefix 是將比對到的路徑前綴改寫為 prefix 的 URLRewrite filter，取代 Traefik 的 strip-prefix middleware
+func replacePrefix(prefix string) []any {
+	return []any{
+		map[string]any{
+			"type": "URLRewrite",
+			"urlRewrite": map[string]any{
+				"path": map[string]any{
+					"type":               "ReplacePrefixMatch",
+					"replacePrefixMatch": prefix,
+				},
+			},
+		},
+	}
+}
+
+func pathPrefixMatch(path string) []any {
+	return []any{
+		map[string]any{
+			"path": map[string]any{
+				"type":  "PathPrefix",
+				"value": path,
+			},
+		},
+	}
+}
+
+func backendRef(name string, port int64) []any {
+	return []any{
+		map[string]any{
+			"name": name,
+			"port": port,
+		},
+	}
+}
+
+func (p *gatewayIngress) ApplyAPIRoute(ctx context.Context, ref string, ownerRef *metav1.OwnerReference) error {
+	s := p.s
+	name := s.GetAPIIngressRouteName(ref)
+	route := &unstructured.Unstructured{}
+	route.SetAPIVersion(httpRouteGVR.GroupVersion().String())
+	route.SetKind("HTTPRoute")
+	route.SetName(name)
+	route.SetNamespace(s.GetProjectNamespace(ref))
+	route.SetLabels(projectLabels(ref, APIIngressComponent))
+	route.SetOwnerReferences([]metav1.OwnerReference{*ownerRef})
+	route.Object["spec"] = map[string]any{
+		"parentRefs": p.parentRefs(s.config.Kube.Ingress.Gateway.HTTPSListener),
+		"hostnames":  []any{s.GetProjectHost(ref)},
+		// Gateway API 以最長的路徑前綴優先，規則順序不影響比對
+		"rules": []any{
+			map[string]any{
+				"matches":     pathPrefixMatch("/api/auth"),
+				"backendRefs": backendRef(s.GetAuthAPIServiceName(ref), 3000),
+			},
+			map[string]any{
+				"matches":     pathPrefixMatch("/api/rest/docs"),
+				"filters":     replacePrefix("/docs"),
+				"backendRefs": backendRef(s.GetRESTAPIServiceName(ref), 8080),
+			},
+			map[string]any{
+				"matches":     pathPrefixMatch("/api/rest"),
+				"filters":     replacePrefix("/"),
+				"backendRefs": backendRef(s.GetRESTAPIServiceName(ref), 3000),
+			},
+		},
+	}
+
+	_, err := s.dynamicClient.Resource(httpRouteGVR).
+		Namespace(s.GetProjectNamespace(ref)).
+		Apply(ctx, name, route, applyOptions(FieldManager))
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to apply HTTPRoute", "error", err, "ref", ref)
+		return errors.New("failed to create HTTPRoute")
+	}
+	return nil
+}
+
+func (p *gatewayIngress) DeleteAPIRoute(ctx context.Context, ref string) error {
+	s := p.s
+	err := s.dynamicClient.Resource(httpRouteGVR).
+		Namespace(s.GetProjectNamespace(ref)).
+		Delete(ctx, s.GetAPIIngressRouteName(ref), metav1.DeleteOptions{})
+	if ignoreNotFound(err) != nil {
+		slog.ErrorContext(ctx, "Failed to delete HTTPRoute", "error", err, "ref", ref)
+		return errors.New("failed to delete HTTPRoute")
+	}
+	return nil
+}
+
+func (p *gatewayIngress) ApplyRealtimeRoute(ctx context.Context, ref string, ownerRef *metav1.OwnerReference) error {
+	s := p.s
+	name := s.GetRealtimeIngressRouteName(ref)
+	route := &unstructured.Unstructured{}
+	route.SetAPIVersion(httpRouteGVR.GroupVersion().String())
+	route.SetKind("HTTPRoute")
+	route.SetName(name)
+	route.SetNamespace(s.GetProjectNamespace(ref))
+	route.SetLabels(projectLabels(ref, RealtimeComponent))
+	route.SetOwnerReferences([]metav1.OwnerReference{*ownerRef})
+	route.Object["spec"] = map[string]any{
+		"parentRefs": p.parentRefs(s.config.Kube.Ingress.Gateway.HTTPSListener),
+		"hostnames":  []any{s.GetProjectHost(ref)},
+		"rules": []any{
+			map[string]any{
+				"matches":     pathPrefixMatch("/api/realtime"),
+				"filters":     replacePrefix("/socket"),
+				"backendRefs": backendRef(s.GetRealtimeServiceName(ref), 4000),
+			},
+		},
+	}
+
+	_, err := s.dynamicClient.Resource(httpRouteGVR).
+		Namespace(s.GetProjectNamespace(ref)).
+		Apply(ctx, name, route, applyOptions(FieldManager))
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to apply realtime HTTPRoute", "error", err, "ref", ref)
+		return errors.New("failed to create realtime HTTPRoute")
+	}
+	return nil
+}
+
+func (p *gatewayIngress) DeleteRealtimeRoute(ctx context.Context, ref string) error {
+	s := p.s
+	err := s.dynamicClient.Resource(httpRouteGVR).
+		Namespace(s.GetProjectNamespace(ref)).
+		Delete(ctx, s.GetRealtimeIngressRouteName(ref), metav1.DeleteOptions{})
+	if ignoreNotFound(err) != nil {
+		slog.ErrorContext(ctx, "Failed to delete realtime HTTPRoute", "error", err, "ref", ref)
+		return errors.New("failed to delete realtime HTTPRoute")
+	}
+	return nil
+}
+
+// ApplyDBRoute 建立依 SNI 路由的 TLSRoute。
+//
+// Postgres 預設先送出 SSLRequest 再進行 TLS handshake，Gateway 看不到 SNI，
+// 用戶端必須使用 direct TLS (libpq 17+ 的 sslnegotiation=direct)。
+func (p *gatewayIngress) ApplyDBRoute(ctx context.Context, ref string, opt dbRouteOption) error {
+	s := p.s
+	route := &unstructured.Unstructured{}
+	route.SetAPIVersion(tlsRouteGVR.GroupVersion().String())
+	route.SetKind("TLSRoute")
+	route.SetName(opt.Name)
+	route.SetNamespace(s.GetProjectNamespace(ref))
+	route.SetLabels(projectLabels(ref, opt.Component))
+	route.SetOwnerReferences([]metav1.OwnerReference{*opt.OwnerRef})
+	route.Object["spec"] = map[string]any{
+		"parentRefs": p.parentRefs(s.config.Kube.Ingress.Gateway.PostgresListener),
+		"hostnames":  []any{opt.Host},
+		"rules": []any{
+			map[string]any{
+				"backendRefs": backendRef(opt.ServiceName, 5432),
+			},
+		},
+	}
+
+	_, err := s.dynamicClient.Resource(tlsRouteGVR).
+		Namespace(s.GetProjectNamespace(ref)).
+		Apply(ctx, opt.Name, route, applyOptions(FieldManager))
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to apply TLSRoute", "error", err, "ref", ref, "name", opt.Name)
+		return errors.New("failed to create TLSRoute")
+	}
+	return nil
+}
+
+func (p *gatewayIngress) DeleteDBRoute(ctx context.Context, ref string, name string) error {
+	s := p.s
+	err := s.dynamicClient.Resource(tlsRouteGVR).
+		Namespace(s.GetProjectNamespace(ref)).
+		Delete(ctx, name, metav1.DeleteOptions{})
+	if ignoreNotFound(err) != nil {
+		slog.ErrorContext(ctx, "Failed to delete TLSRoute", "error", err, "ref", ref, "name", name)
+		return errors.New("failed to delete TLSRoute")
+	}
+	return nil
+}
@@ -0,0 +1,257 @@
+package kubeproject
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"text/template"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/yaml"
+)
+
+//go:embed kube-files/project-ingressroute.yaml
+var ingressRouteYAMLStr string
+
+//go:embed kube-files/project-ingressroute-realtime.yaml
+var realtimeIngressRouteYAMLStr string
+
+//go:embed kube-files/project-ingressroutetcp.yaml
+var ingressRouteTCPYAMLStr string
+
+// traefikIngress 以 Traefik 的 IngressRoute 與 IngressRouteTCP 建立路由
+type traefikIngress struct {
+	s *service
+}
+
+var _ ingressProvider = (*traefikIngress)(nil)
+
+func (p *traefikIngress) GVRs() []schema.GroupVersionResource {
+	return []schema.GroupVersionResource{ingressRouteGVR, ingressRouteTCPGVR, middlewareGVR}
+}
+
+// applyProjectMiddleware 回傳 IngressRoute 引用的 middleware 所在的 namespace。
+//
+// 啟用 isolation 時將共用 namespace 中的 middleware 複製到專案 namespace：
+// Traefik 預設不允許 IngressRoute 引用其他 namespace 的 middleware (providers.kubernetesCRD.allowCrossNamespace)。
+// 共用的 middleware 變更後，重新套用專案的路由才會更新複本。
+func (p *traefikIngress) applyProjectMiddleware(ctx context.Context, ref string, name string, component string, ownerRef *metav1.OwnerReference) (string, error) {
+	s := p.s
+	namespace := s.GetProjectNamespace(ref)
+	if namespace == s.namespace {
+		return namespace, nil
+	}
+
+	source, err := s.dynamicClient.Resource(middlewareGVR).Namespace(s.namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to read shared middleware", "error", err, "middleware", name, "namespace", s.namespace)
+		return "", fmt.Errorf("failed to read middleware %s in namespace %s", name, s.namespace)
+	}
+	spec, _, err := unstructured.NestedMap(source.Object, "spec")
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to read shared middleware spec", "error", err, "middleware", name)
+		return "", fmt.Errorf("failed to read middleware %s in namespace %s", name, s.namespace)
+	}
+
+	middleware := &unstructured.Unstructured{Object: map[string]any{"spec": spec}}
+	middleware.SetAPIVersion(middlewareGVR.GroupVersion().String())
+	middleware.SetKind("Middleware")
+	middleware.SetName(name)
+	middleware.SetNamespace(namespace)
+	middleware.SetLabels(projectLabels(ref, component))
+	middleware.SetOwnerReferences([]metav1.OwnerReference{*ownerRef})
+
+	_, err = s.dynamicClient.Resource(middlewareGVR).
+		Namespace(namespace).
+		Apply(ctx, name, middleware, applyOptions(FieldManager))
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to copy middleware", "error", err, "middleware", name, "namespace", namespace)
+		return "", errors.New("failed to copy middleware")
+	}
+	return namespace, nil
+}
+
+// deleteProjectMiddleware 刪除複製到專案 namespace 的 middleware，共用 namespace 中的 middleware 不刪除
+func (p *traefikIngress) deleteProjectMiddleware(ctx context.Context, ref string, name string) error {
+	s := p.s
+	namespace := s.GetProjectNamespace(ref)
+	if namespace == s.namespace {
+		return nil
+	}
+	err := s.dynamicClient.Resource(middlewareGVR).Namespace(namespace).Delete(ctx, name, metav1.DeleteOptions{})
+	if ignoreNotFound(err) != nil {
+		slog.ErrorContext(ctx, "Failed to delete middleware", "error", err, "middleware", name, "namespace", namespace)
+		return errors.New("failed to delete middleware")
+	}
+	return nil
+}
+
+// render 以 data 執行 YAML 範本並解析為 unstructured 物件
+func (p *traefikIngress) render(kind string, tmpl string, data map[string]any) (*unstructured.Unstructured, error) {
+	parsed, err := template.New("yaml").Parse(tmpl)
+	if err != nil {
+		slog.Error("Failed to parse YAML template", "kind", kind, "error", err)
+		return nil, errors.New("failed to parse " + kind + " YAML template")
+	}
+	var rendered strings.Builder
+	if err := parsed.Execute(&rendered, data); err != nil {
+		slog.Error("Failed to execute YAML template", "kind", kind, "error", err)
+		return nil, errors.New("failed to execute " + kind + " YAML template")
+	}
+
+	obj := &unstructured.Unstructured{}
+	decoder := yaml.NewYAMLOrJSONDecoder(strings.NewReader(rendered.String()), 1024)
+	if err := decoder.Decode(obj); err != nil {
+		slog.Error("Failed to decode YAML", "kind", kind, "error", err)
+		return nil, errors.New("failed to decode " + kind + " YAML")
+	}
+	return obj, nil
+}
+
+func (p *traefikIngress) ApplyAPIRoute(ctx context.Context, ref string, ownerRef *metav1.OwnerReference) error {
+	s := p.s
+	middlewareName := s.config.Kube.Ingress.Traefik.StripPrefixMiddleware
+	middlewareNamespace, err := p.applyProjectMiddleware(ctx, ref, middlewareName, APIIngressComponent, ownerRef)
+	if err != nil {
+		return err
+	}
+	ingressRoute, err := p.render("IngressRoute", ingressRouteYAMLStr, map[string]any{
+		"ProjectHost":         s.GetProjectHost(ref),
+		"AuthServiceName":     s.GetAuthAPIServiceName(ref),
+		"RESTServiceName":     s.GetRESTAPIServiceName(ref),
+		"TLSSecretName":       s.config.Kube.Project.TLSSecretName,
+		"EntryPoint":          s.config.Kube.Ingress.Traefik.HTTPEntryPoint,
+		"MiddlewareName":      middlewareName,
+		"MiddlewareNamespace": middlewareNamespace,
+	})
+	if err != nil {
+		return err
+	}
+
+	// set metadata
+	ingressRouteName := s.GetAPIIngressRouteName(ref)
+	ingressRoute.SetName(ingressRouteName)
+	ingressRoute.SetNamespace(s.GetProjectNamespace(ref))
+	ingressRoute.SetLabels(projectLabels(ref, APIIngressComponent))
+	ingressRoute.SetOwnerReferences([]metav1.OwnerReference{*ownerRef})
+
+	// 使用 dynamicClient 以 server-side apply 建立或更新資源
+	_, err = s.dynamicClient.Resource(ingressRouteGVR).
+		Namespace(s.GetProjectNamespace(ref)).
+		Apply(ctx, ingressRouteName, ingressRoute, applyOptions(FieldManager))
+	if err != nil {
+		slog.Error("Failed to create IngressRoute", "error", err)
+		return errors.New("failed to create IngressRoute")
+	}
+
+	return nil
+}
+
+func (p *traefikIngress) DeleteAPIRoute(ctx context.Context, ref string) error {
+	s := p.s
+	target := s.GetAPIIngressRouteName(ref)
+	err := s.dynamicClient.Resource(ingressRouteGVR).
+		Namespace(s.GetProjectNamespace(ref)).
+		Delete(ctx, target, metav1.DeleteOptions{})
+	if ignoreNotFound(err) != nil {
+		slog.Error("Failed to delete IngressRoute", "error", err)
+		return errors.New("failed to delete IngressRoute")
+	}
+
+	return p.deleteProjectMiddleware(ctx, ref, s.config.Kube.Ingress.Traefik.StripPrefixMiddleware)
+}
+
+func (p *traefikIngress) ApplyRealtimeRoute(ctx context.Context, ref string, ownerRef *metav1.OwnerReference) error {
+	s := p.s
+	middlewareName := s.config.Kube.Ingress.Traefik.RealtimePrefixMiddleware
+	middlewareNamespace, err := p.applyProjectMiddleware(ctx, ref, middlewareName, RealtimeComponent, ownerRef)
+	if err != nil {
+		return err
+	}
+	ingressRoute, err := p.render("IngressRoute", realtimeIngressRouteYAMLStr, map[string]any{
+		"ProjectHost":         s.GetProjectHost(ref),
+		"RealtimeServiceName": s.GetRealtimeServiceName(ref),
+		"TLSSecretName":       s.config.Kube.Project.TLSSecretName,
+		"EntryPoint":          s.config.Kube.Ingress.Traefik.HTTPEntryPoint,
+		"MiddlewareName":      middlewareName,
+		"MiddlewareNamespace": middlewareNamespace,
+	})
+	if err != nil {
+		return err
+	}
+
+	name := s.GetRealtimeIngressRouteName(ref)
+	ingressRoute.SetName(name)
+	ingressRoute.SetNamespace(s.GetProjectNamespace(ref))
+	ingressRoute.SetLabels(projectLabels(ref, RealtimeComponent))
+	ingressRoute.SetOwnerReferences([]metav1.OwnerReference{*ownerRef})
+
+	_, err = s.dynamicClient.Resource(ingressRouteGVR).
+		Namespace(s.GetProjectNamespace(ref)).
+		Apply(ctx, name, ingressRoute, applyOptions(FieldManager))
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to create realtime IngressRoute", "error", err, "ref", ref)
+		return errors.New("failed to create realtime IngressRoute")
+	}
+	return nil
+}
+
+func (p *traefikIngress) DeleteRealtimeRoute(ctx context.Context, ref string) error {
+	s := p.s
+	err := s.dynamicClient.Resource(ingressRouteGVR).
+		Namespace(s.GetProjectNamespace(ref)).
+		Delete(ctx, s.GetRealtimeIngressRouteName(ref), metav1.DeleteOptions{})
+	if ignoreNotFound(err) != nil {
+		slog.ErrorContext(ctx, "Failed to delete realtime IngressRoute", "error", err, "ref", ref)
+		return errors.New("failed to delete realtime IngressRoute")
+	}
+	return p.deleteProjectMiddleware(ctx, ref, s.config.Kube.Ingress.Traefik.RealtimePrefixMiddleware)
+}
+
+func (p *traefikIngress) ApplyDBRoute(ctx context.Context, ref string, opt dbRouteOption) error {
+	s := p.s
+	ingressRouteTCP, err := p.render("IngressRouteTCP", ingressRouteTCPYAMLStr, map[string]any{
+		"ProjectHost":          opt.Host,
+		"ProjectDBServiceName": opt.ServiceName,
+		"TLSSecretName":        s.config.Kube.Project.TLSSecretName,
+		"EntryPoint":           s.config.Kube.Ingress.Traefik.PostgresEntryPoint,
+	})
+	if err != nil {
+		return ErrFailedToDecodeIngressRouteTCPYAML
+	}
+
+	// set metadata
+	ingressRouteTCP.SetName(opt.Name)
+	ingressRouteTCP.SetNamespace(s.GetProjectNamespace(ref))
+	ingressRouteTCP.SetLabels(projectLabels(ref, opt.Component))
+	ingressRouteTCP.SetOwnerReferences([]metav1.OwnerReference{*opt.OwnerRef})
+
+	// 使用 dynamicClient 以 server-side apply 建立或更新資源
+	_, err = s.dynamicClient.Resource(ingressRouteTCPGVR).
+		Namespace(s.GetProjectNamespace(ref)).
+		Apply(ctx, opt.Name, ingressRouteTCP, applyOptions(FieldManager))
+	if err != nil {
+		slog.Error("Failed to create IngressRouteTCP", "error", err)
+		return ErrFailedToCreateIngressRouteTCP
+	}
+
+	return nil
+}
+
+func (p *traefikIngress) DeleteDBRoute(ctx context.Context, ref string, name string) error {
+	s := p.s
+	err := s.dynamicClient.Resource(ingressRouteTCPGVR).
+		Namespace(s.GetProjectNamespace(ref)).
+		Delete(ctx, name, metav1.DeleteOptions{})
+	if ignoreNotFound(err) != nil {
+		slog.ErrorContext(ctx, "Failed to delete IngressRouteTCP", "error", err, "ref", ref, "name", name)
+		return ErrFailedToDeleteIngressRouteTCP
+	}
+
+	return nil
+}
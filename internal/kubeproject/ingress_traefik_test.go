@@ -0,0 +1,117 @@
+package kubeproject
+
+import (
+	"context"
+	"testing"
+
+	"baas-api/internal/config"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// handleApply 讓 fake dynamic client 以建立或取代處理 server-side apply
+func handleApply(client *dynamicfake.FakeDynamicClient) {
+	client.PrependReactor("patch", "*", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		patch := action.(k8stesting.PatchAction)
+		if patch.GetPatchType() != types.ApplyPatchType {
+			return false, nil, nil
+		}
+		obj := &unstructured.Unstructured{}
+		if err := obj.UnmarshalJSON(patch.GetPatch()); err != nil {
+			return true, nil, err
+		}
+		tracker := client.Tracker()
+		if _, err := tracker.Get(patch.GetResource(), patch.GetNamespace(), patch.GetName()); err != nil {
+			return true, obj, tracker.Create(patch.GetResource(), obj, patch.GetNamespace())
+		}
+		return true, obj, tracker.Update(patch.GetResource(), obj, patch.GetNamespace())
+	})
+}
+
+func TestTraefikAPIRouteMiddleware(t *testing.T) {
+	ref := testRefs("t", 1)[0]
+	ownerRef := &metav1.OwnerReference{APIVersion: clusterGVR.GroupVersion().String(), Kind: "Cluster", Name: ref, UID: "uid"}
+	stripPrefix := &unstructured.Unstructured{Object: map[string]any{
+		"spec": map[string]any{"stripPrefix": map[string]any{"prefixes": []any{"/api/rest"}}},
+	}}
+	stripPrefix.SetAPIVersion(middlewareGVR.GroupVersion().String())
+	stripPrefix.SetKind("Middleware")
+	stripPrefix.SetNamespace(testProjectNamespace)
+	stripPrefix.SetName("strip-prefix")
+
+	tests := []struct {
+		name          string
+		isolation     bool
+		objects       []runtime.Object
+		wantNamespace string
+		wantErr       bool
+	}{
+		{name: "shared namespace", objects: []runtime.Object{stripPrefix}, wantNamespace: testProjectNamespace},
+		{name: "isolated namespace", isolation: true, objects: []runtime.Object{stripPrefix}, wantNamespace: "baas-" + ref},
+		{name: "isolated namespace without shared middleware", isolation: true, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			svc := newTestClusterWithObjects(t, config.KubeClusterConfig{}, tt.objects...)
+			handleApply(svc.dynamicClient.(*dynamicfake.FakeDynamicClient))
+			svc.config.Kube.Project.Isolation.Enabled = tt.isolation
+			svc.config.Kube.Project.Isolation.NamespacePrefix = "baas-"
+			svc.config.Kube.Ingress.Traefik.StripPrefixMiddleware = "strip-prefix"
+
+			err := svc.ingress.ApplyAPIRoute(ctx, ref, ownerRef)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("ApplyAPIRoute succeeded, want error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ApplyAPIRoute: %v", err)
+			}
+
+			route, err := svc.dynamicClient.Resource(ingressRouteGVR).Namespace(svc.GetProjectNamespace(ref)).Get(ctx, svc.GetAPIIngressRouteName(ref), metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("get IngressRoute: %v", err)
+			}
+			routes, _, _ := unstructured.NestedSlice(route.Object, "spec", "routes")
+			for _, r := range routes {
+				middlewares, _, _ := unstructured.NestedSlice(r.(map[string]any), "middlewares")
+				for _, m := range middlewares {
+					if namespace := m.(map[string]any)["namespace"]; namespace != tt.wantNamespace {
+						t.Errorf("middleware namespace = %v, want %s", namespace, tt.wantNamespace)
+					}
+				}
+			}
+
+			copied, err := svc.dynamicClient.Resource(middlewareGVR).Namespace(tt.wantNamespace).Get(ctx, "strip-prefix", metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("get middleware: %v", err)
+			}
+			prefixes, _, _ := unstructured.NestedSlice(copied.Object, "spec", "stripPrefix", "prefixes")
+			if len(prefixes) != 1 || prefixes[0] != "/api/rest" {
+				t.Errorf("middleware prefixes = %v, want [/api/rest]", prefixes)
+			}
+
+			// 刪除路由時只刪除專案 namespace 中的複本
+			if err := svc.ingress.DeleteAPIRoute(ctx, ref); err != nil {
+				t.Fatalf("DeleteAPIRoute: %v", err)
+			}
+			_, err = svc.dynamicClient.Resource(middlewareGVR).Namespace(testProjectNamespace).Get(ctx, "strip-prefix", metav1.GetOptions{})
+			if err != nil {
+				t.Errorf("shared middleware deleted: %v", err)
+			}
+			if tt.isolation {
+				_, err = svc.dynamicClient.Resource(middlewareGVR).Namespace(tt.wantNamespace).Get(ctx, "strip-prefix", metav1.GetOptions{})
+				if err == nil {
+					t.Error("middleware copy not deleted")
+				}
+			}
+		})
+	}
+}
@@ -2,12 +2,14 @@ package kubeproject
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"log/slog"
 
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 )
 
 const InsertJwksSQLFilename = "001001_insert_jwks.sql"
@@ -22,9 +24,14 @@ type CreateJWKSConfigMapOption struct {
 func (s *service) CreateJWKSConfigMap(ctx context.Context, opt CreateJWKSConfigMapOption) error {
 	configMapName := s.GetJWKSConfigMapName(opt.Ref)
 	configMap := &corev1.ConfigMap{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "v1",
+			Kind:       "ConfigMap",
+		},
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      configMapName,
-			Namespace: s.namespace,
+			Namespace: s.GetProjectNamespace(opt.Ref),
+			Labels:    projectLabels(opt.Ref, JWKSComponent),
 		},
 		Data: map[string]string{
 			InsertJwksSQLFilename: fmt.Sprintf(`-- migrate:up
@@ -33,7 +40,18 @@ INSERT INTO auth.jwks (id, public_key, private_key) VALUES ('%s', '%s', '%s');
 `, opt.KID, opt.PublicKey, opt.PrivateKey),
 		},
 	}
-	_, err := s.clientset.CoreV1().ConfigMaps(s.namespace).Create(ctx, configMap, metav1.CreateOptions{})
+	data, err := json.Marshal(configMap)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to marshal JWKS ConfigMap", "error", err, "configMapName", configMapName)
+		return errors.New("failed to marshal JWKS ConfigMap")
+	}
+	_, err = s.clientset.CoreV1().ConfigMaps(s.GetProjectNamespace(opt.Ref)).Patch(
+		ctx,
+		configMapName,
+		types.ApplyPatchType,
+		data,
+		applyPatchOptions(FieldManager),
+	)
 	if err != nil {
 		slog.ErrorContext(ctx, "Failed to create JWKS ConfigMap", "error", err, "configMapName", configMapName)
 		return errors.New("failed to create JWKS ConfigMap")
@@ -43,8 +61,8 @@ INSERT INTO auth.jwks (id, public_key, private_key) VALUES ('%s', '%s', '%s');
 
 func (s *service) DeleteJWKSConfigMap(ctx context.Context, ref string) error {
 	configMapName := s.GetJWKSConfigMapName(ref)
-	err := s.clientset.CoreV1().ConfigMaps(s.namespace).Delete(ctx, configMapName, metav1.DeleteOptions{})
-	if err != nil {
+	err := s.clientset.CoreV1().ConfigMaps(s.GetProjectNamespace(ref)).Delete(ctx, configMapName, metav1.DeleteOptions{})
+	if ignoreNotFound(err) != nil {
 		slog.Error("Failed to delete JWKS ConfigMap", "error", err, "configMapName", configMapName)
 		return errors.New("failed to delete JWKS ConfigMap")
 	}
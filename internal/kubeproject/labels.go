@@ -0,0 +1,163 @@
+package kubeproject
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"github.com/samber/lo"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// 所有專案資源共用的 labels
+const (
+	LabelProjectRef = "baas.wke/project-ref"
+	LabelComponent  = "baas.wke/component"
+	LabelManagedBy  = "app.kubernetes.io/managed-by"
+
+	ManagedByValue = "baas-api"
+)
+
+// projectLabels 回傳專案資源的標準 label 組合
+func projectLabels(ref string, component string) map[string]string {
+	return map[string]string{
+		LabelProjectRef: ref,
+		LabelComponent:  component,
+		LabelManagedBy:  ManagedByValue,
+	}
+}
+
+// projectSelector 回傳選取專案所有資源的 label selector
+func projectSelector(ref string) string {
+	return labels.SelectorFromSet(labels.Set{
+		LabelProjectRef: ref,
+		LabelManagedBy:  ManagedByValue,
+	}).String()
+}
+
+// clusterOwnerReference 取得專案 CNPG Cluster 的 OwnerReference。
+//
+// Cluster 是專案的根資源，其餘在 Cluster 之後建立的資源都以它為 owner，
+// 刪除 Cluster 時由 Kubernetes GC 一併清除。
+func (s *service) clusterOwnerReference(ctx context.Context, ref string) (*metav1.OwnerReference, error) {
+	name, err := s.databaseClusterName(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	return s.namedClusterOwnerReference(ctx, ref, name)
+}
+
+// namedClusterOwnerReference 取得專案 namespace 中指定名稱 Cluster 的 OwnerReference
+func (s *service) namedClusterOwnerReference(ctx context.Context, ref string, name string) (*metav1.OwnerReference, error) {
+	cluster, err := s.dynamicClient.Resource(clusterGVR).
+		Namespace(s.GetProjectNamespace(ref)).
+		Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to get postgres cluster for owner reference", "error", err, "ref", ref)
+		return nil, errors.New("failed to get postgres cluster for owner reference")
+	}
+
+	return &metav1.OwnerReference{
+		APIVersion:         clusterGVR.GroupVersion().String(),
+		Kind:               "Cluster",
+		Name:               cluster.GetName(),
+		UID:                cluster.GetUID(),
+		BlockOwnerDeletion: lo.ToPtr(false),
+	}, nil
+}
+
+// projectCustomResources 是專案使用的 CRD 資源，依刪除順序排列
+func (s *service) projectCustomResources() []schema.GroupVersionResource {
+	return append(s.ingress.GVRs(),
+		poolerGVR,
+		scheduledBackupGVR,
+		backupGVR,
+		databaseGVR,
+		clusterGVR,
+	)
+}
+
+// projectResources 是專案的所有資源，依刪除順序排列
+func (s *service) projectResources() []schema.GroupVersionResource {
+	return append(s.projectCustomResources(),
+		deploymentGVR,
+		schema.GroupVersionResource{Group: "autoscaling", Version: "v2", Resource: "horizontalpodautoscalers"},
+		schema.GroupVersionResource{Group: "policy", Version: "v1", Resource: "poddisruptionbudgets"},
+		schema.GroupVersionResource{Group: "batch", Version: "v1", Resource: "cronjobs"},
+		jobGVR,
+		schema.GroupVersionResource{Group: "", Version: "v1", Resource: "services"},
+		schema.GroupVersionResource{Group: "", Version: "v1", Resource: "configmaps"},
+		schema.GroupVersionResource{Group: "", Version: "v1", Resource: "secrets"},
+	)
+}
+
+// namedResource 是以名稱指定的單一資源
+type namedResource struct {
+	gvr  schema.GroupVersionResource
+	name string
+}
+
+// legacyProjectResources 是 labels 導入前建立的專案資源，這些資源沒有專案 labels，只能依名稱刪除
+func (s *service) legacyProjectResources(ref string) []namedResource {
+	return []namedResource{
+		{ingressRouteGVR, s.GetAPIIngressRouteName(ref)},
+		{ingressRouteTCPGVR, s.GetDBIngressRouteTCPName(ref)},
+		{databaseGVR, ref},
+		{clusterGVR, ref},
+		{deploymentGVR, s.GetAuthAPIDeploymentName(ref)},
+		{deploymentGVR, s.GetRESTAPIDeploymentName(ref)},
+		{jobGVR, s.GetMigrationJobName(ref)},
+		{schema.GroupVersionResource{Group: "", Version: "v1", Resource: "services"}, s.GetAuthAPIServiceName(ref)},
+		{schema.GroupVersionResource{Group: "", Version: "v1", Resource: "services"}, s.GetRESTAPIServiceName(ref)},
+		{schema.GroupVersionResource{Group: "", Version: "v1", Resource: "configmaps"}, s.GetJWKSConfigMapName(ref)},
+		{schema.GroupVersionResource{Group: "", Version: "v1", Resource: "secrets"}, s.GetDatabaseRoleSecretName(ref, RoleAuthenticator)},
+	}
+}
+
+// DeleteAllForProject 刪除專案的所有 Kubernetes 資源。
+//
+// 資源依 label selector 刪除；labels 導入前建立的資源沒有專案 labels，另外依名稱刪除。
+func (s *service) DeleteAllForProject(ctx context.Context, ref string) error {
+	namespace := s.GetProjectNamespace(ref)
+	selector := projectSelector(ref)
+	listOpts := metav1.ListOptions{LabelSelector: selector}
+	deleteOpts := metav1.DeleteOptions{PropagationPolicy: lo.ToPtr(metav1.DeletePropagationBackground)}
+
+	var errs []error
+	for _, gvr := range s.projectResources() {
+		client := s.dynamicClient.Resource(gvr).Namespace(namespace)
+		list, err := client.List(ctx, listOpts)
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed to list project resources", "resource", gvr.Resource, "selector", selector, "error", err)
+			errs = append(errs, err)
+			continue
+		}
+		for _, item := range list.Items {
+			if err := ignoreNotFound(client.Delete(ctx, item.GetName(), deleteOpts)); err != nil {
+				slog.ErrorContext(ctx, "Failed to delete project resource", "resource", gvr.Resource, "name", item.GetName(), "error", err)
+				errs = append(errs, err)
+			}
+		}
+	}
+
+	// 已依 label 刪除或未安裝的 CRD 會回傳 NotFound
+	for _, resource := range s.legacyProjectResources(ref) {
+		err := s.dynamicClient.Resource(resource.gvr).Namespace(namespace).Delete(ctx, resource.name, deleteOpts)
+		if err := ignoreNotFound(err); err != nil {
+			slog.ErrorContext(ctx, "Failed to delete legacy project resource", "resource", resource.gvr.Resource, "name", resource.name, "error", err)
+			errs = append(errs, err)
+		}
+	}
+
+	// 獨立 namespace 模式下，最後移除整個 namespace (包含 NetworkPolicy、ResourceQuota 等)
+	if err := s.DeleteProjectNamespace(ctx, ref); err != nil {
+		errs = append(errs, err)
+	}
+
+	if len(errs) > 0 {
+		return errors.New("failed to delete project resources")
+	}
+	return nil
+}
@@ -0,0 +1,389 @@
+package kubeproject
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/samber/lo"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/tools/cache"
+)
+
+var ErrLogContainerNotFound = errors.New("log container not found")
+
+// RedactedValue 取代 log 中出現的 secret 值
+const RedactedValue = "[REDACTED]"
+
+// minRedactLength 以下的值 (例如 username) 太短，取代後會破壞 log 的可讀性
+const minRedactLength = 8
+
+// LogStreamOption 是串流專案 container log 的選項
+type LogStreamOption struct {
+	// Container 是 AuthAPIComponent、PGRSTComponent 或 OpenAPIComponent
+	Container string
+	// TailLines 是每個 Pod 從最後幾行開始，nil 時從頭開始
+	TailLines *int64
+	SinceTime *time.Time
+}
+
+// LogLine 是一行已遮蔽 secret 的 container log
+type LogLine struct {
+	Pod       string
+	Container string
+	Timestamp *time.Time
+	Line      string
+}
+
+// logTarget 回傳 container 所屬的 Deployment 與實際的 container 名稱
+func (s *service) logTarget(ref string, container string) (string, string, error) {
+	switch container {
+	case AuthAPIComponent:
+		return s.GetAuthAPIDeploymentName(ref), s.GetAuthAPIContainerName(ref), nil
+	case PGRSTComponent, OpenAPIComponent:
+		return s.GetRESTAPIDeploymentName(ref), s.GetRESTAPIContainerName(ref, container), nil
+	default:
+		return "", "", fmt.Errorf("%w: %s", ErrLogContainerNotFound, container)
+	}
+}
+
+// StreamProjectLogs 以 follow 模式串流 Deployment 所有 Pod 中指定 container 的 log 到 lines，直到 ctx 結束。
+//
+// Pod 以 informer 監看，rolling update、scale out 或重新啟動後的 Pod 也會開始串流；
+// 每一行在送出前都會遮蔽專案的 secret 值。
+func (s *service) StreamProjectLogs(ctx context.Context, ref string, opt LogStreamOption, lines chan<- LogLine) error {
+	deploymentName, containerName, err := s.logTarget(ref, opt.Container)
+	if err != nil {
+		return err
+	}
+	namespace := s.GetProjectNamespace(ref)
+
+	deployment, err := s.clientset.AppsV1().Deployments(namespace).Get(ctx, deploymentName, metav1.GetOptions{})
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to get deployment for logs", "error", err, "ref", ref, "deployment", deploymentName)
+		return errors.New("failed to get deployment")
+	}
+
+	// CNPG 產生的 app role secret 沒有專案 labels，依名稱監看
+	appSecretName, err := s.databaseRoleSecretName(ctx, ref, RoleApp)
+	if err != nil {
+		return err
+	}
+	redactValues, err := s.logRedactValues(ctx, ref, deployment)
+	if err != nil {
+		return err
+	}
+	var redactor atomic.Pointer[strings.Replacer]
+	redactor.Store(newLogReplacer(redactValues))
+
+	pods := make(chan *corev1.Pod)
+	notify := func(obj any) {
+		pod, ok := obj.(*corev1.Pod)
+		if !ok {
+			return
+		}
+		select {
+		case pods <- pod:
+		case <-ctx.Done():
+		}
+	}
+	factory := informers.NewSharedInformerFactoryWithOptions(s.clientset, watchResync,
+		informers.WithNamespace(namespace),
+		informers.WithTweakListOptions(func(opts *metav1.ListOptions) {
+			opts.LabelSelector = labels.SelectorFromSet(deployment.Spec.Selector.MatchLabels).String()
+		}),
+	)
+	_, err = factory.Core().V1().Pods().Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    notify,
+		UpdateFunc: func(_, newObj any) { notify(newObj) },
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to watch pods for logs", "error", err, "ref", ref, "deployment", deploymentName)
+		return errors.New("failed to watch pods")
+	}
+
+	// secret 在串流期間可能變更 (JWKS 輪替、API Secret 重新套用、app role 密碼輪替)，變更後重新收集要遮蔽的值
+	refresh := make(chan struct{}, 1)
+	secretFactories, err := s.watchLogRedactSources(ref, namespace, appSecretName, refresh)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to watch secrets for logs", "error", err, "ref", ref)
+		return errors.New("failed to watch secrets")
+	}
+
+	var wg sync.WaitGroup
+	stopCh := make(chan struct{})
+	defer wg.Wait()
+	defer close(stopCh)
+	// 遮蔽的值無法更新時結束串流，同時停止所有 Pod 的串流
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	factory.Start(stopCh)
+	for _, secretFactory := range secretFactories {
+		secretFactory.Start(stopCh)
+	}
+
+	type streamEnd struct {
+		pod  string
+		last *time.Time
+	}
+	ended := make(chan streamEnd)
+	streaming := map[string]bool{}
+	// 每個 Pod 已送出的最後一行時間，container 重新啟動後從這之後繼續串流
+	lastSent := map[string]*time.Time{}
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-refresh:
+			deployment, err := s.clientset.AppsV1().Deployments(namespace).Get(ctx, deploymentName, metav1.GetOptions{})
+			if err != nil {
+				slog.ErrorContext(ctx, "Failed to get deployment for log redaction", "error", err, "ref", ref, "deployment", deploymentName)
+				return errors.New("failed to get deployment")
+			}
+			values, err := s.logRedactValues(ctx, ref, deployment)
+			if err != nil {
+				return err
+			}
+			// 已遮蔽的值繼續遮蔽，輪替前的 secret 仍可能出現在舊 Pod 的 log 中
+			redactValues = lo.Uniq(append(redactValues, values...))
+			redactor.Store(newLogReplacer(redactValues))
+		case end := <-ended:
+			delete(streaming, end.pod)
+			if end.last != nil {
+				lastSent[end.pod] = end.last
+			}
+		case pod := <-pods:
+			if pod.Status.Phase != corev1.PodRunning || pod.DeletionTimestamp != nil || streaming[pod.Name] {
+				continue
+			}
+			streaming[pod.Name] = true
+
+			logOpts := &corev1.PodLogOptions{
+				Container:  containerName,
+				Follow:     true,
+				Timestamps: true,
+				TailLines:  opt.TailLines,
+			}
+			if opt.SinceTime != nil {
+				logOpts.SinceTime = lo.ToPtr(metav1.NewTime(*opt.SinceTime))
+			}
+			after := lastSent[pod.Name]
+			if after != nil {
+				logOpts.TailLines = nil
+				logOpts.SinceTime = lo.ToPtr(metav1.NewTime(*after))
+			}
+
+			wg.Add(1)
+			go func(podName string) {
+				defer wg.Done()
+				last := s.streamPodLogs(ctx, ref, namespace, podName, opt.Container, logOpts, &redactor, after, lines)
+				select {
+				case ended <- streamEnd{pod: podName, last: last}:
+				case <-ctx.Done():
+				}
+			}(pod.Name)
+		}
+	}
+}
+
+// streamPodLogs 串流一個 Pod 的 log，直到串流結束 (例如 container 停止) 或 ctx 結束，回傳最後一行的時間。
+//
+// after 不為 nil 時略過該時間之前的行；SinceTime 只精確到秒，重新串流時會收到已送出的行。
+func (s *service) streamPodLogs(ctx context.Context, ref string, namespace string, podName string, container string, logOpts *corev1.PodLogOptions, redactor *atomic.Pointer[strings.Replacer], after *time.Time, lines chan<- LogLine) *time.Time {
+	last := after
+	stream, err := s.clientset.CoreV1().Pods(namespace).GetLogs(podName, logOpts).Stream(ctx)
+	if err != nil {
+		slog.WarnContext(ctx, "Failed to stream pod logs", "error", err, "ref", ref, "pod", podName)
+		return last
+	}
+	defer stream.Close()
+
+	scanner := bufio.NewScanner(stream)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := LogLine{Pod: podName, Container: container}
+		// Timestamps 開啟時每行的開頭是 RFC3339Nano 時間
+		timestamp, text, _ := strings.Cut(scanner.Text(), " ")
+		if t, err := time.Parse(time.RFC3339Nano, timestamp); err == nil {
+			if after != nil && !t.After(*after) {
+				continue
+			}
+			line.Timestamp = &t
+			line.Line = redactor.Load().Replace(text)
+			last = &t
+		} else {
+			line.Line = redactor.Load().Replace(scanner.Text())
+		}
+
+		select {
+		case lines <- line:
+		case <-ctx.Done():
+			return last
+		}
+	}
+	return last
+}
+
+// isRedactedKey 回傳 Secret key 或環境變數名稱是否為需要遮蔽的值 (password、連線 URI 與 secret)。
+//
+// username、host 與憑證等其他值不遮蔽，避免 log 中一般的字串也被取代。
+func isRedactedKey(name string) bool {
+	name = strings.ToUpper(name)
+	return strings.Contains(name, "PASSWORD") || strings.Contains(name, "PGPASS") || strings.Contains(name, "URI") || strings.Contains(name, "SECRET")
+}
+
+// watchLogRedactSources 監看專案的 Secret、ConfigMap 與 app role secret，變更時送出通知到 refresh。
+//
+// 回傳的 informer factory 由呼叫者啟動；informer 啟動時列出的既有物件不送出通知。
+func (s *service) watchLogRedactSources(ref string, namespace string, appSecretName string, refresh chan<- struct{}) ([]informers.SharedInformerFactory, error) {
+	notify := func() {
+		select {
+		case refresh <- struct{}{}:
+		default:
+		}
+	}
+	handler := cache.ResourceEventHandlerDetailedFuncs{
+		AddFunc: func(_ any, isInInitialList bool) {
+			if !isInInitialList {
+				notify()
+			}
+		},
+		UpdateFunc: func(oldObj, newObj any) {
+			// resync 送出的通知內容沒有變更
+			oldMeta, oldErr := meta.Accessor(oldObj)
+			newMeta, newErr := meta.Accessor(newObj)
+			if oldErr == nil && newErr == nil && oldMeta.GetResourceVersion() == newMeta.GetResourceVersion() {
+				return
+			}
+			notify()
+		},
+	}
+
+	projectFactory := informers.NewSharedInformerFactoryWithOptions(s.clientset, watchResync,
+		informers.WithNamespace(namespace),
+		informers.WithTweakListOptions(func(opts *metav1.ListOptions) {
+			opts.LabelSelector = projectSelector(ref)
+		}),
+	)
+	appSecretFactory := informers.NewSharedInformerFactoryWithOptions(s.clientset, watchResync,
+		informers.WithNamespace(namespace),
+		informers.WithTweakListOptions(func(opts *metav1.ListOptions) {
+			opts.FieldSelector = fields.OneTermEqualSelector("metadata.name", appSecretName).String()
+		}),
+	)
+	for _, informer := range []cache.SharedIndexInformer{
+		projectFactory.Core().V1().Secrets().Informer(),
+		projectFactory.Core().V1().ConfigMaps().Informer(),
+		appSecretFactory.Core().V1().Secrets().Informer(),
+	} {
+		if _, err := informer.AddEventHandler(handler); err != nil {
+			return nil, err
+		}
+	}
+	return []informers.SharedInformerFactory{projectFactory, appSecretFactory}, nil
+}
+
+// newLogReplacer 建立將 values 取代為 RedactedValue 的 Replacer
+func newLogReplacer(values []string) *strings.Replacer {
+	values = lo.Filter(values, func(value string, _ int) bool {
+		return len(value) >= minRedactLength
+	})
+	// 較長的值優先取代，避免 URI 中的 password 先被取代後 URI 無法比對
+	slices.SortFunc(values, func(a, b string) int { return len(b) - len(a) })
+
+	oldnew := make([]string, 0, len(values)*2)
+	for _, value := range values {
+		oldnew = append(oldnew, value, RedactedValue)
+	}
+	return strings.NewReplacer(oldnew...)
+}
+
+// logRedactValues 收集專案的 secret 值：
+// 專案 Secret 中的 password、URI 與 secret、API Secret 的所有值、JWKS 的私鑰，以及 Deployment 中以明文設定的 secret 環境變數。
+//
+// 任一來源讀取失敗時回傳錯誤，不以缺少部分值的結果串流 log。
+func (s *service) logRedactValues(ctx context.Context, ref string, deployment *appsv1.Deployment) ([]string, error) {
+	namespace := s.GetProjectNamespace(ref)
+	secrets, err := s.clientset.CoreV1().Secrets(namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: projectSelector(ref),
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to list project secrets for log redaction", "error", err, "ref", ref)
+		return nil, errors.New("failed to list project secrets")
+	}
+
+	var values []string
+	for _, secret := range secrets.Items {
+		// API Secret 只存放 secret 值 (包含 Realtime 的加密金鑰)
+		apiSecret := secret.Name == s.GetAPISecretName(ref)
+		for key, value := range secret.Data {
+			if apiSecret || isRedactedKey(key) {
+				values = append(values, string(value))
+			}
+		}
+	}
+	// CNPG 產生的 app role secret 沒有專案 labels；Cluster 尚未建立 secret 時沒有值需要遮蔽
+	appSecretName, err := s.databaseRoleSecretName(ctx, ref, RoleApp)
+	if err != nil {
+		return nil, err
+	}
+	appSecret, err := s.clientset.CoreV1().Secrets(namespace).Get(ctx, appSecretName, metav1.GetOptions{})
+	if ignoreNotFound(err) != nil {
+		slog.ErrorContext(ctx, "Failed to read database secret for log redaction", "error", err, "ref", ref, "secretName", appSecretName)
+		return nil, ErrFailedToReadDatabaseSecret
+	}
+	if err == nil {
+		for key, value := range appSecret.Data {
+			if isRedactedKey(key) {
+				values = append(values, string(value))
+			}
+		}
+	}
+	// JWKS 的私鑰只存在於 migration 使用的 ConfigMap
+	jwks, err := s.clientset.CoreV1().ConfigMaps(namespace).Get(ctx, s.GetJWKSConfigMapName(ref), metav1.GetOptions{})
+	if ignoreNotFound(err) != nil {
+		slog.ErrorContext(ctx, "Failed to read JWKS ConfigMap for log redaction", "error", err, "ref", ref)
+		return nil, errors.New("failed to read JWKS ConfigMap")
+	}
+	if err == nil {
+		values = append(values, jwksPrivateValues(jwks.Data[InsertJwksSQLFilename])...)
+	}
+	for _, container := range deployment.Spec.Template.Spec.Containers {
+		for _, env := range container.Env {
+			if isRedactedKey(env.Name) {
+				values = append(values, env.Value)
+			}
+		}
+	}
+	return lo.Uniq(values), nil
+}
+
+// jwksPrivateValues 從 JWKS migration 的 SQL 取出私鑰 JWK 與其中的私鑰 (d)
+func jwksPrivateValues(sql string) []string {
+	var values []string
+	// SQL 中的值以單引號包住，JWK 的 JSON 不含單引號
+	for _, quoted := range strings.Split(sql, "'") {
+		var jwk struct {
+			D string `json:"d"`
+		}
+		if err := json.Unmarshal([]byte(quoted), &jwk); err != nil || jwk.D == "" {
+			continue
+		}
+		values = append(values, quoted, jwk.D)
+	}
+	return values
+}
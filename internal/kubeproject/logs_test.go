@@ -0,0 +1,166 @@
+package kubeproject
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"baas-api/internal/config"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestLogRedactValues(t *testing.T) {
+	ref := testRefs("g", 1)[0]
+	svc := newTestCluster(t, config.KubeClusterConfig{})
+	privateJWK := `{"kty":"EC","d":"private-key-value"}`
+
+	apiSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: svc.GetAPISecretName(ref), Namespace: testProjectNamespace, Labels: projectLabels(ref, APISecretComponent)},
+		Data:       map[string][]byte{"JWT_KEY": []byte("api-secret-value")},
+	}
+	appSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: svc.GetDatabaseRoleSecretName(ref, RoleApp), Namespace: testProjectNamespace},
+		Data:       map[string][]byte{"password": []byte("app-password-value"), "username": []byte("app-username")},
+	}
+	jwks := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: svc.GetJWKSConfigMapName(ref), Namespace: testProjectNamespace, Labels: projectLabels(ref, JWKSComponent)},
+		Data:       map[string]string{InsertJwksSQLFilename: "INSERT INTO auth.jwks VALUES ('kid', '{}', '" + privateJWK + "');"},
+	}
+	deployment := &appsv1.Deployment{}
+	deployment.Spec.Template.Spec.Containers = []corev1.Container{{Env: []corev1.EnvVar{
+		{Name: "CLIENT_SECRET", Value: "plaintext-secret"},
+		{Name: "SITE_URL", Value: "https://example.com"},
+	}}}
+
+	tests := []struct {
+		name     string
+		objects  []runtime.Object
+		failKind string
+		want     []string
+		wantErr  bool
+	}{
+		{
+			name:    "all sources",
+			objects: []runtime.Object{apiSecret, appSecret, jwks},
+			want:    []string{"api-secret-value", "app-password-value", privateJWK, "private-key-value", "plaintext-secret"},
+		},
+		{
+			name:    "app secret and JWKS not created yet",
+			objects: []runtime.Object{apiSecret},
+			want:    []string{"api-secret-value", "plaintext-secret"},
+		},
+		{name: "app secret read fails", objects: []runtime.Object{apiSecret, appSecret, jwks}, failKind: "secrets", wantErr: true},
+		{name: "JWKS read fails", objects: []runtime.Object{apiSecret, appSecret, jwks}, failKind: "configmaps", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clientset := k8sfake.NewSimpleClientset(tt.objects...)
+			if tt.failKind != "" {
+				clientset.PrependReactor("get", tt.failKind, func(k8stesting.Action) (bool, runtime.Object, error) {
+					return true, nil, errors.New("connection refused")
+				})
+			}
+			svc.clientset = clientset
+
+			values, err := svc.logRedactValues(context.Background(), ref, deployment)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("logRedactValues = %v, want error", values)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("logRedactValues: %v", err)
+			}
+			slices.Sort(values)
+			want := slices.Clone(tt.want)
+			slices.Sort(want)
+			if !slices.Equal(values, want) {
+				t.Errorf("logRedactValues = %v, want %v", values, want)
+			}
+		})
+	}
+}
+
+func TestNewLogReplacer(t *testing.T) {
+	replacer := newLogReplacer([]string{"secret-password", "postgres://app:secret-password@db", "short"})
+	got := replacer.Replace("connect postgres://app:secret-password@db with secret-password as short")
+	want := "connect " + RedactedValue + " with " + RedactedValue + " as short"
+	if got != want {
+		t.Errorf("Replace = %q, want %q", got, want)
+	}
+}
+
+func TestWatchLogRedactSources(t *testing.T) {
+	ctx := context.Background()
+	ref := testRefs("w", 1)[0]
+	svc := newTestCluster(t, config.KubeClusterConfig{})
+	apiSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: svc.GetAPISecretName(ref), Namespace: testProjectNamespace, Labels: projectLabels(ref, APISecretComponent)},
+		Data:       map[string][]byte{"JWT_KEY": []byte("api-secret-value")},
+	}
+	clientset := k8sfake.NewSimpleClientset(apiSecret)
+	svc.clientset = clientset
+
+	refresh := make(chan struct{}, 1)
+	factories, err := svc.watchLogRedactSources(ref, testProjectNamespace, svc.GetDatabaseRoleSecretName(ref, RoleApp), refresh)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	for _, factory := range factories {
+		factory.Start(stopCh)
+		factory.WaitForCacheSync(stopCh)
+	}
+
+	// 啟動時已存在的 Secret 不需要重新收集
+	select {
+	case <-refresh:
+		t.Fatal("refresh for an existing secret")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	changes := []struct {
+		name   string
+		change func() error
+	}{
+		{name: "API secret re-applied", change: func() error {
+			secret := apiSecret.DeepCopy()
+			secret.ResourceVersion = "2"
+			secret.Data["JWT_KEY"] = []byte("rotated-secret-value")
+			_, err := clientset.CoreV1().Secrets(testProjectNamespace).Update(ctx, secret, metav1.UpdateOptions{})
+			return err
+		}},
+		{name: "JWKS rotated", change: func() error {
+			_, err := clientset.CoreV1().ConfigMaps(testProjectNamespace).Create(ctx, &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: svc.GetJWKSConfigMapName(ref), Namespace: testProjectNamespace, Labels: projectLabels(ref, JWKSComponent)},
+			}, metav1.CreateOptions{})
+			return err
+		}},
+		{name: "app password rotated", change: func() error {
+			_, err := clientset.CoreV1().Secrets(testProjectNamespace).Create(ctx, &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: svc.GetDatabaseRoleSecretName(ref, RoleApp), Namespace: testProjectNamespace},
+			}, metav1.CreateOptions{})
+			return err
+		}},
+	}
+	for _, tt := range changes {
+		if err := tt.change(); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		select {
+		case <-refresh:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("%s: no refresh", tt.name)
+		}
+	}
+}
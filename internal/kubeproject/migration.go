@@ -2,49 +2,97 @@ package kubeproject
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"log/slog"
+	"slices"
+	"time"
 
 	"github.com/samber/lo"
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
-
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 )
 
 const MigrationFinishedTTLSeconds = 300
 
 func (s *service) CreateMigrationJob(ctx context.Context, ref string) error {
 	migJobName := s.GetMigrationJobName(ref)
-	roleAppSecretName := s.GetDatabaseRoleSecretName(ref, RoleApp)
-	jwkConfigMapName := s.GetJWKSConfigMapName(ref)
 
+	ownerRef, err := s.clusterOwnerReference(ctx, ref)
+	if err != nil {
+		return err
+	}
+
+	job := s.dbmateJob(ref, migJobName, MigrationComponent, []string{"--wait", "up"}, nil, []corev1.VolumeProjection{
+		{ConfigMap: &corev1.ConfigMapProjection{LocalObjectReference: corev1.LocalObjectReference{Name: "migrations"}}},
+		{ConfigMap: &corev1.ConfigMapProjection{LocalObjectReference: corev1.LocalObjectReference{Name: s.GetJWKSConfigMapName(ref)}}},
+	}, ownerRef)
+
+	data, err := json.Marshal(job)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to marshal migration job", "error", err, "jobName", migJobName)
+		return errors.New("failed to marshal migration job")
+	}
+
+	// Job 的 Pod template 不可變更，重新套用相同內容時不會有任何變化；
+	// 若 Job 已因 TTL 被清除，則會重新建立並再次執行 (dbmate up 為冪等)
+	_, err = s.clientset.BatchV1().Jobs(s.GetProjectNamespace(ref)).Patch(
+		ctx,
+		migJobName,
+		types.ApplyPatchType,
+		data,
+		applyPatchOptions(FieldManager),
+	)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to create migration job", "error", err, "jobName", migJobName)
+		return errors.New("failed to create migration job")
+	}
+
+	return nil
+}
+
+// dbmateJob 建立以 app 角色連線執行 dbmate 的 Job，sources 投影到 /migrations
+func (s *service) dbmateJob(ref, name, component string, args []string, env []corev1.EnvVar, sources []corev1.VolumeProjection, ownerRef *metav1.OwnerReference) *batchv1.Job {
 	job := &batchv1.Job{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "batch/v1",
+			Kind:       "Job",
+		},
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      migJobName,
-			Namespace: s.namespace,
+			Name:      name,
+			Namespace: s.GetProjectNamespace(ref),
+			Labels:    projectLabels(ref, component),
 		},
 		Spec: batchv1.JobSpec{
-			TTLSecondsAfterFinished: lo.ToPtr(int32(300)),
+			TTLSecondsAfterFinished: lo.ToPtr(int32(MigrationFinishedTTLSeconds)),
 			BackoffLimit:            lo.ToPtr(int32(4)),
 			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: projectLabels(ref, component),
+				},
 				Spec: corev1.PodSpec{
 					Containers: []corev1.Container{
 						{
-							Name:  migJobName,
+							Name:  name,
 							Image: "ghcr.io/amacneil/dbmate:2",
-							Args:  []string{"--wait", "up"},
-							Env: []corev1.EnvVar{
+							Args:  args,
+							Env: append([]corev1.EnvVar{
 								{
 									Name: "DATABASE_URL", ValueFrom: &corev1.EnvVarSource{
 										SecretKeyRef: &corev1.SecretKeySelector{
 											Key:                  "uri",
-											LocalObjectReference: corev1.LocalObjectReference{Name: roleAppSecretName},
+											LocalObjectReference: corev1.LocalObjectReference{Name: s.GetDatabaseRoleSecretName(ownerRef.Name, RoleApp)},
 										},
 									},
 								},
 								{Name: "DBMATE_MIGRATIONS_DIR", Value: "/migrations"},
-							},
+							}, env...),
 							VolumeMounts: []corev1.VolumeMount{
 								{Name: "migrations", MountPath: "/migrations", ReadOnly: true},
 							},
@@ -54,12 +102,7 @@ func (s *service) CreateMigrationJob(ctx context.Context, ref string) error {
 						{
 							Name: "migrations",
 							VolumeSource: corev1.VolumeSource{
-								Projected: &corev1.ProjectedVolumeSource{
-									Sources: []corev1.VolumeProjection{
-										{ConfigMap: &corev1.ConfigMapProjection{LocalObjectReference: corev1.LocalObjectReference{Name: "migrations"}}},
-										{ConfigMap: &corev1.ConfigMapProjection{LocalObjectReference: corev1.LocalObjectReference{Name: jwkConfigMapName}}},
-									},
-								},
+								Projected: &corev1.ProjectedVolumeSource{Sources: sources},
 							},
 						},
 					},
@@ -68,12 +111,321 @@ func (s *service) CreateMigrationJob(ctx context.Context, ref string) error {
 			},
 		},
 	}
+	if ownerRef != nil {
+		job.OwnerReferences = []metav1.OwnerReference{*ownerRef}
+	}
+	return job
+}
+
+// Migration Job 的狀態
+const (
+	MigrationPending   = "pending"
+	MigrationRunning   = "running"
+	MigrationSucceeded = "succeeded"
+	MigrationFailed    = "failed"
+)
+
+// MigrationLogLimitBytes 是保存的 dbmate 輸出上限，避免超過 ConfigMap 的 1MiB 限制
+const MigrationLogLimitBytes = 256 * 1024
 
-	_, err := s.clientset.BatchV1().Jobs(s.namespace).Create(ctx, job, metav1.CreateOptions{})
+// MigrationResult 是 migration Job 的執行結果。
+//
+// Job 結束後會被 TTL 清除，結果與 dbmate 的輸出保存在 <ref>-migration-result ConfigMap 中。
+type MigrationResult struct {
+	JobName string
+	// Action 是使用者 migration 執行的 dbmate 命令 (MigrationActionUp 或 MigrationActionRollback)，平台 migration 為空
+	Action      string
+	Status      string
+	Message     string
+	StartedAt   *time.Time
+	CompletedAt *time.Time
+	Logs        string
+}
+
+// IsFinished 回傳 migration 是否已經結束 (成功或失敗)
+func (r *MigrationResult) IsFinished() bool {
+	return r.Status == MigrationSucceeded || r.Status == MigrationFailed
+}
+
+// migrationTarget 是一次 migration 的 Job 與保存結果的 ConfigMap
+type migrationTarget struct {
+	component  string
+	jobName    string
+	resultName string
+}
+
+// platformMigration 是建立專案時執行共用 migrations 的 Job，名稱固定
+func (s *service) platformMigration(ref string) migrationTarget {
+	return migrationTarget{
+		component:  MigrationComponent,
+		jobName:    s.GetMigrationJobName(ref),
+		resultName: s.GetMigrationResultConfigMapName(ref),
+	}
+}
+
+// findMigrationJob 取得 migration Job，informer cache 同步後直接讀取 cache
+func (s *service) findMigrationJob(ctx context.Context, ref string, name string) (*batchv1.Job, error) {
+	if !s.watcher.isSynced() {
+		return s.clientset.BatchV1().Jobs(s.GetProjectNamespace(ref)).Get(ctx, name, metav1.GetOptions{})
+	}
+
+	obj, err := s.watcher.factory.ForResource(jobGVR).Lister().ByNamespace(s.GetProjectNamespace(ref)).Get(name)
 	if err != nil {
-		slog.ErrorContext(ctx, "Failed to create migration job", "error", err, "jobName", migJobName)
-		return errors.New("failed to create migration job")
+		return nil, err
+	}
+	u, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return nil, apierrors.NewNotFound(jobGVR.GroupResource(), name)
+	}
+	job := &batchv1.Job{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(u.Object, job); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// migrationJobResult 依 Job 的 conditions 判斷狀態 (不包含 logs)
+func migrationJobResult(job *batchv1.Job) *MigrationResult {
+	result := &MigrationResult{
+		JobName: job.Name,
+		Status:  MigrationPending,
+	}
+	if job.Status.StartTime != nil {
+		result.StartedAt = lo.ToPtr(job.Status.StartTime.Time)
+	}
+	if job.Status.Active > 0 || job.Status.StartTime != nil {
+		result.Status = MigrationRunning
+	}
+	for _, condition := range job.Status.Conditions {
+		if condition.Status != corev1.ConditionTrue {
+			continue
+		}
+		switch condition.Type {
+		case batchv1.JobComplete:
+			result.Status = MigrationSucceeded
+			result.CompletedAt = lo.ToPtr(condition.LastTransitionTime.Time)
+		case batchv1.JobFailed:
+			result.Status = MigrationFailed
+			result.Message = condition.Message
+			result.CompletedAt = lo.ToPtr(condition.LastTransitionTime.Time)
+		}
+	}
+	return result
+}
+
+// WaitMigrationJob 等待 migration Job 結束，擷取 dbmate 的輸出並保存結果。
+//
+// Job 的變更由共用的 informer 通知；Job 在結束前就被移除時視為失敗。
+func (s *service) WaitMigrationJob(ctx context.Context, ref string) (*MigrationResult, error) {
+	return s.waitMigrationJob(ctx, ref, s.platformMigration(ref), "")
+}
+
+func (s *service) waitMigrationJob(ctx context.Context, ref string, target migrationTarget, action string) (*MigrationResult, error) {
+	changes, err := s.WatchProject(ctx, ref)
+	if err != nil {
+		return nil, err
 	}
 
+	var seen *MigrationResult
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case _, ok := <-changes:
+			if !ok {
+				return nil, ctx.Err()
+			}
+		}
+
+		job, err := s.findMigrationJob(ctx, ref, target.jobName)
+		if apierrors.IsNotFound(err) {
+			if seen == nil {
+				// Job 剛建立，cache 尚未收到
+				continue
+			}
+			seen.Status = MigrationFailed
+			seen.Message = "migration job was removed before it finished"
+			seen.CompletedAt = lo.ToPtr(time.Now())
+			return seen, s.recordMigrationResult(ctx, ref, target, seen)
+		}
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed to get migration job", "error", err, "ref", ref, "jobName", target.jobName)
+			return nil, errors.New("failed to get migration job")
+		}
+
+		seen = migrationJobResult(job)
+		seen.Action = action
+		if !seen.IsFinished() {
+			continue
+		}
+		// Job 在 TTL 後會連同 Pod 一起被清除，必須在此時擷取輸出
+		seen.Logs, err = s.jobLogs(ctx, ref, target.jobName, target.jobName, MigrationLogLimitBytes)
+		if err != nil {
+			slog.WarnContext(ctx, "Failed to capture migration logs", "error", err, "ref", ref)
+		}
+		return seen, s.recordMigrationResult(ctx, ref, target, seen)
+	}
+}
+
+// jobLogs 讀取 Job 最後一次嘗試的 Pod 中 container 的輸出 (migration Job 的 container 與 Job 同名)
+func (s *service) jobLogs(ctx context.Context, ref string, jobName string, container string, limitBytes int64) (string, error) {
+	namespace := s.GetProjectNamespace(ref)
+	pods, err := s.clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: labels.SelectorFromSet(labels.Set{batchv1.JobNameLabel: jobName}).String(),
+	})
+	if err != nil {
+		return "", err
+	}
+	if len(pods.Items) == 0 {
+		return "", nil
+	}
+
+	latest := slices.MaxFunc(pods.Items, func(a, b corev1.Pod) int {
+		return a.CreationTimestamp.Compare(b.CreationTimestamp.Time)
+	})
+	logs, err := s.clientset.CoreV1().Pods(namespace).GetLogs(latest.Name, &corev1.PodLogOptions{
+		Container:  container,
+		LimitBytes: lo.ToPtr(limitBytes),
+	}).DoRaw(ctx)
+	if err != nil {
+		return "", err
+	}
+	return string(logs), nil
+}
+
+// recordMigrationResult 將 migration 結果保存到 ConfigMap，Job 被清除後仍可查詢
+func (s *service) recordMigrationResult(ctx context.Context, ref string, target migrationTarget, result *MigrationResult) error {
+	ownerRef, err := s.clusterOwnerReference(ctx, ref)
+	if err != nil {
+		return err
+	}
+
+	data := map[string]string{
+		"jobName": result.JobName,
+		"status":  result.Status,
+		"message": result.Message,
+		"logs":    result.Logs,
+	}
+	if result.Action != "" {
+		data["action"] = result.Action
+	}
+	if result.StartedAt != nil {
+		data["startedAt"] = result.StartedAt.UTC().Format(time.RFC3339)
+	}
+	if result.CompletedAt != nil {
+		data["completedAt"] = result.CompletedAt.UTC().Format(time.RFC3339)
+	}
+	configMap := &corev1.ConfigMap{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "v1",
+			Kind:       "ConfigMap",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            target.resultName,
+			Namespace:       s.GetProjectNamespace(ref),
+			Labels:          projectLabels(ref, target.component),
+			OwnerReferences: []metav1.OwnerReference{*ownerRef},
+		},
+		Data: data,
+	}
+	payload, err := json.Marshal(configMap)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to marshal migration result", "error", err, "ref", ref)
+		return errors.New("failed to marshal migration result")
+	}
+	_, err = s.clientset.CoreV1().ConfigMaps(s.GetProjectNamespace(ref)).Patch(
+		ctx,
+		target.resultName,
+		types.ApplyPatchType,
+		payload,
+		applyPatchOptions(FieldManager),
+	)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to record migration result", "error", err, "ref", ref)
+		return errors.New("failed to record migration result")
+	}
 	return nil
 }
+
+// findRecordedMigrationResult 讀取保存的 migration 結果，不存在時回傳 nil
+func (s *service) findRecordedMigrationResult(ctx context.Context, ref string, target migrationTarget) (*MigrationResult, error) {
+	configMap, err := s.clientset.CoreV1().ConfigMaps(s.GetProjectNamespace(ref)).Get(ctx, target.resultName, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to get migration result", "error", err, "ref", ref)
+		return nil, errors.New("failed to get migration result")
+	}
+
+	parseTime := func(key string) *time.Time {
+		t, err := time.Parse(time.RFC3339, configMap.Data[key])
+		if err != nil {
+			return nil
+		}
+		return &t
+	}
+	return &MigrationResult{
+		JobName:     configMap.Data["jobName"],
+		Action:      configMap.Data["action"],
+		Status:      configMap.Data["status"],
+		Message:     configMap.Data["message"],
+		StartedAt:   parseTime("startedAt"),
+		CompletedAt: parseTime("completedAt"),
+		Logs:        configMap.Data["logs"],
+	}, nil
+}
+
+// FindMigrationResult 回傳專案 migration 的狀態。
+//
+// 執行中的 Job 以 Job 的狀態為準，結束後以保存的結果為準；兩者都不存在時回傳 ErrMigrationNotFound。
+func (s *service) FindMigrationResult(ctx context.Context, ref string) (*MigrationResult, error) {
+	return s.findMigrationResult(ctx, ref, s.platformMigration(ref))
+}
+
+func (s *service) findMigrationResult(ctx context.Context, ref string, target migrationTarget) (*MigrationResult, error) {
+	job, err := s.findMigrationJob(ctx, ref, target.jobName)
+	if ignoreNotFound(err) != nil {
+		slog.ErrorContext(ctx, "Failed to get migration job", "error", err, "ref", ref)
+		return nil, errors.New("failed to get migration job")
+	}
+	recorded, err := s.findRecordedMigrationResult(ctx, ref, target)
+	if err != nil {
+		return nil, err
+	}
+
+	if job != nil {
+		result := migrationJobResult(job)
+		// Job 已結束但尚未保存結果時也以 Job 的狀態為準
+		if !result.IsFinished() || recorded == nil || recorded.JobName != job.Name || !recorded.IsFinished() {
+			if recorded != nil {
+				result.Action = recorded.Action
+			}
+			return result, nil
+		}
+	}
+	if recorded == nil {
+		return nil, ErrMigrationNotFound
+	}
+	return recorded, nil
+}
+
+// FindMigrationLogs 回傳 dbmate 的輸出，執行中時直接讀取 Pod 目前的輸出
+func (s *service) FindMigrationLogs(ctx context.Context, ref string) (*MigrationResult, error) {
+	return s.findMigrationLogs(ctx, ref, s.platformMigration(ref))
+}
+
+func (s *service) findMigrationLogs(ctx context.Context, ref string, target migrationTarget) (*MigrationResult, error) {
+	result, err := s.findMigrationResult(ctx, ref, target)
+	if err != nil {
+		return nil, err
+	}
+	if result.Logs == "" && result.Status != MigrationPending {
+		result.Logs, err = s.jobLogs(ctx, ref, result.JobName, result.JobName, MigrationLogLimitBytes)
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed to get migration logs", "error", err, "ref", ref)
+			return nil, errors.New("failed to get migration logs")
+		}
+	}
+	return result, nil
+}
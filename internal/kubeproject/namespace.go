@@ -0,0 +1,414 @@
+package kubeproject
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/tools/cache"
+)
+
+// namespaceNameLabel 是 Kubernetes 自動加在每個 namespace 上的 label
+const namespaceNameLabel = "kubernetes.io/metadata.name"
+
+// GetProjectNamespace 回傳專案資源所在的 namespace。
+// 啟用 isolation 時每個專案使用 `<prefix><ref>`，否則使用共用的 namespace。
+func (s *service) GetProjectNamespace(ref string) string {
+	isolation := s.config.Kube.Project.Isolation
+	if !isolation.Enabled {
+		return s.namespace
+	}
+	return isolation.NamespacePrefix + ref
+}
+
+func (*service) GetNetworkPolicyName(ref string, policy string) string {
+	return generateResourceName(ref, policy)
+}
+
+func (*service) GetResourceQuotaName(ref string) string {
+	return generateResourceName(ref, "quota")
+}
+
+func (*service) GetLimitRangeName(ref string) string {
+	return generateResourceName(ref, "limits")
+}
+
+// EnsureProjectNamespace 建立專案的 namespace 以及隔離所需的 NetworkPolicy、
+// ResourceQuota、LimitRange，並複製共用的 TLS secret。未啟用 isolation 時不做任何事。
+func (s *service) EnsureProjectNamespace(ctx context.Context, ref string) error {
+	if !s.config.Kube.Project.Isolation.Enabled {
+		return nil
+	}
+
+	namespace := s.GetProjectNamespace(ref)
+	ns := &corev1.Namespace{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "v1",
+			Kind:       "Namespace",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   namespace,
+			Labels: projectLabels(ref, IsolationComponent),
+		},
+	}
+	data, err := json.Marshal(ns)
+	if err != nil {
+		return err
+	}
+	_, err = s.clientset.CoreV1().Namespaces().Patch(ctx, namespace, types.ApplyPatchType, data, applyPatchOptions(FieldManager))
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to apply project namespace", "error", err, "namespace", namespace)
+		return errors.New("failed to create project namespace")
+	}
+
+	for _, policy := range s.buildNetworkPolicies(ref) {
+		data, err := json.Marshal(policy)
+		if err != nil {
+			return err
+		}
+		_, err = s.clientset.NetworkingV1().NetworkPolicies(namespace).Patch(ctx, policy.Name, types.ApplyPatchType, data, applyPatchOptions(FieldManager))
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed to apply network policy", "error", err, "policy", policy.Name)
+			return errors.New("failed to create project network policy")
+		}
+	}
+
+	if err := s.applyResourceQuota(ctx, ref); err != nil {
+		return err
+	}
+	if err := s.applyLimitRange(ctx, ref); err != nil {
+		return err
+	}
+
+	return s.SyncProjectTLSSecret(ctx, ref)
+}
+
+// DeleteProjectNamespace 刪除專案的 namespace。未啟用 isolation 時不做任何事。
+func (s *service) DeleteProjectNamespace(ctx context.Context, ref string) error {
+	if !s.config.Kube.Project.Isolation.Enabled {
+		return nil
+	}
+
+	namespace := s.GetProjectNamespace(ref)
+	err := s.clientset.CoreV1().Namespaces().Delete(ctx, namespace, metav1.DeleteOptions{})
+	if ignoreNotFound(err) != nil {
+		slog.ErrorContext(ctx, "Failed to delete project namespace", "error", err, "namespace", namespace)
+		return errors.New("failed to delete project namespace")
+	}
+	return nil
+}
+
+// SyncProjectTLSSecret 將共用 namespace 中的 TLS secret 複製到專案 namespace。
+// Traefik 只能讀取與 IngressRoute 相同 namespace 的 secret，憑證更新後由 StartTLSSecretSync 重新同步。
+func (s *service) SyncProjectTLSSecret(ctx context.Context, ref string) error {
+	if s.GetProjectNamespace(ref) == s.namespace {
+		return nil
+	}
+
+	secretName := s.config.Kube.Project.TLSSecretName
+	source, err := s.clientset.CoreV1().Secrets(s.namespace).Get(ctx, secretName, metav1.GetOptions{})
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to read shared TLS secret", "error", err, "secretName", secretName)
+		return errors.New("failed to read shared TLS secret")
+	}
+	return s.applyProjectTLSSecret(ctx, ref, source)
+}
+
+// applyProjectTLSSecret 以 source 的內容建立或更新專案 namespace 中的 TLS secret
+func (s *service) applyProjectTLSSecret(ctx context.Context, ref string, source *corev1.Secret) error {
+	namespace := s.GetProjectNamespace(ref)
+	secret := &corev1.Secret{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "v1",
+			Kind:       "Secret",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      source.Name,
+			Namespace: namespace,
+			Labels:    projectLabels(ref, TLSComponent),
+		},
+		Type: source.Type,
+		Data: source.Data,
+	}
+	data, err := json.Marshal(secret)
+	if err != nil {
+		return err
+	}
+	_, err = s.clientset.CoreV1().Secrets(namespace).Patch(ctx, source.Name, types.ApplyPatchType, data, applyPatchOptions(FieldManager))
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to copy TLS secret", "error", err, "namespace", namespace)
+		return errors.New("failed to copy TLS secret")
+	}
+	return nil
+}
+
+// StartTLSSecretSync 監看共用 namespace 的 TLS secret，內容變更時 (例如 cert-manager 續期) 重新複製到所有專案 namespace。
+// 未啟用 isolation 時專案直接使用共用的 secret，不做任何事。
+func (s *service) StartTLSSecretSync() {
+	if !s.config.Kube.Project.Isolation.Enabled {
+		return
+	}
+
+	secretName := s.config.Kube.Project.TLSSecretName
+	factory := informers.NewSharedInformerFactoryWithOptions(s.clientset, watchResync,
+		informers.WithNamespace(s.namespace),
+		informers.WithTweakListOptions(func(opts *metav1.ListOptions) {
+			opts.FieldSelector = fields.OneTermEqualSelector("metadata.name", secretName).String()
+		}),
+	)
+	sync := func(obj any) {
+		source, ok := obj.(*corev1.Secret)
+		if !ok {
+			return
+		}
+		s.syncAllProjectTLSSecrets(context.Background(), source)
+	}
+	_, err := factory.Core().V1().Secrets().Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		// 啟動時同步一次，補上 API 停止期間的憑證更新
+		AddFunc: sync,
+		UpdateFunc: func(oldObj, newObj any) {
+			// resync 送出的通知內容沒有變更
+			if oldSecret, ok := oldObj.(*corev1.Secret); ok && oldSecret.ResourceVersion == newObj.(*corev1.Secret).ResourceVersion {
+				return
+			}
+			sync(newObj)
+		},
+	})
+	if err != nil {
+		slog.Error("Failed to add TLS secret event handler", "error", err, "cluster", s.cluster.Name)
+		return
+	}
+
+	// informer 與服務同生命週期，不會停止
+	factory.Start(make(chan struct{}))
+}
+
+// syncAllProjectTLSSecrets 將 source 複製到所有專案 namespace，每個專案的失敗不影響其他專案
+func (s *service) syncAllProjectTLSSecrets(ctx context.Context, source *corev1.Secret) {
+	namespaces, err := s.clientset.CoreV1().Namespaces().List(ctx, metav1.ListOptions{
+		LabelSelector: labels.SelectorFromSet(labels.Set{LabelManagedBy: ManagedByValue, LabelComponent: IsolationComponent}).String(),
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to list project namespaces", "error", err, "cluster", s.cluster.Name)
+		return
+	}
+
+	synced := 0
+	for _, namespace := range namespaces.Items {
+		ref := namespace.Labels[LabelProjectRef]
+		if ref == "" || namespace.DeletionTimestamp != nil {
+			continue
+		}
+		if err := s.applyProjectTLSSecret(ctx, ref, source); err == nil {
+			synced++
+		}
+	}
+	slog.InfoContext(ctx, "Synced TLS secret to project namespaces", "cluster", s.cluster.Name, "namespaces", synced, "resourceVersion", source.ResourceVersion)
+}
+
+// buildNetworkPolicies 回傳專案 namespace 的 NetworkPolicy：
+//   - 預設拒絕所有 ingress
+//   - ingress controller 可連到 auth 與 REST API
+//   - 專案內的 Pod、ingress controller (IngressRouteTCP)、平台 API 可連到資料庫，
+//     CNPG operator 可連到資料庫 instance manager
+func (s *service) buildNetworkPolicies(ref string) []*networkingv1.NetworkPolicy {
+	namespace := s.GetProjectNamespace(ref)
+	isolation := s.config.Kube.Project.Isolation
+	tcp := corev1.ProtocolTCP
+	fromNamespace := func(name string) networkingv1.NetworkPolicyPeer {
+		return networkingv1.NetworkPolicyPeer{
+			NamespaceSelector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{namespaceNameLabel: name},
+			},
+		}
+	}
+	port := func(p int) networkingv1.NetworkPolicyPort {
+		return networkingv1.NetworkPolicyPort{Protocol: &tcp, Port: &intstr.IntOrString{Type: intstr.Int, IntVal: int32(p)}}
+	}
+	newPolicy := func(name string, spec networkingv1.NetworkPolicySpec) *networkingv1.NetworkPolicy {
+		return &networkingv1.NetworkPolicy{
+			TypeMeta: metav1.TypeMeta{
+				APIVersion: "networking.k8s.io/v1",
+				Kind:       "NetworkPolicy",
+			},
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      s.GetNetworkPolicyName(ref, name),
+				Namespace: namespace,
+				Labels:    projectLabels(ref, IsolationComponent),
+			},
+			Spec: spec,
+		}
+	}
+
+	return []*networkingv1.NetworkPolicy{
+		newPolicy("default-deny", networkingv1.NetworkPolicySpec{
+			PodSelector: metav1.LabelSelector{},
+			PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress},
+		}),
+		newPolicy("allow-ingress-api", networkingv1.NetworkPolicySpec{
+			PodSelector: metav1.LabelSelector{
+				MatchExpressions: []metav1.LabelSelectorRequirement{{
+					Key:      LabelComponent,
+					Operator: metav1.LabelSelectorOpIn,
+					Values:   []string{AuthAPIComponent, RestAPIComponent, RealtimeComponent},
+				}},
+			},
+			PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress},
+			Ingress: []networkingv1.NetworkPolicyIngressRule{{
+				From:  []networkingv1.NetworkPolicyPeer{fromNamespace(isolation.IngressNamespace)},
+				Ports: []networkingv1.NetworkPolicyPort{port(3000), port(4000), port(8080)},
+			}},
+		}),
+		newPolicy("allow-db", networkingv1.NetworkPolicySpec{
+			PodSelector: metav1.LabelSelector{
+				// CNPG 以 inheritedMetadata 將專案 labels 帶到 instance Pod，
+				// 同時涵蓋主要 cluster 與 PITR 檢視用的 cluster；PgBouncer pooler 同樣開放 5432
+				MatchLabels: map[string]string{
+					LabelProjectRef: ref,
+				},
+				MatchExpressions: []metav1.LabelSelectorRequirement{{
+					Key:      LabelComponent,
+					Operator: metav1.LabelSelectorOpIn,
+					Values:   []string{DBComponent, PoolerComponent},
+				}},
+			},
+			PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress},
+			Ingress: []networkingv1.NetworkPolicyIngressRule{
+				{
+					From: []networkingv1.NetworkPolicyPeer{
+						{PodSelector: &metav1.LabelSelector{}},
+						fromNamespace(isolation.IngressNamespace),
+						fromNamespace(isolation.PlatformNamespace),
+					},
+					Ports: []networkingv1.NetworkPolicyPort{port(5432)},
+				},
+				{
+					From: []networkingv1.NetworkPolicyPeer{fromNamespace(isolation.OperatorNamespace)},
+				},
+			},
+		}),
+	}
+}
+
+func (s *service) applyResourceQuota(ctx context.Context, ref string) error {
+	namespace := s.GetProjectNamespace(ref)
+	cfg := s.config.Kube.Project.Isolation.ResourceQuota
+	hard, err := parseResourceList(map[corev1.ResourceName]string{
+		corev1.ResourceRequestsCPU:     cfg.RequestsCPU,
+		corev1.ResourceRequestsMemory:  cfg.RequestsMemory,
+		corev1.ResourceLimitsCPU:       cfg.LimitsCPU,
+		corev1.ResourceLimitsMemory:    cfg.LimitsMemory,
+		corev1.ResourceRequestsStorage: cfg.RequestsStorage,
+		corev1.ResourcePods:            cfg.Pods,
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "Invalid resource quota configuration", "error", err)
+		return errors.New("invalid resource quota configuration")
+	}
+	if len(hard) == 0 {
+		return nil
+	}
+
+	quota := &corev1.ResourceQuota{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "v1",
+			Kind:       "ResourceQuota",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      s.GetResourceQuotaName(ref),
+			Namespace: namespace,
+			Labels:    projectLabels(ref, IsolationComponent),
+		},
+		Spec: corev1.ResourceQuotaSpec{Hard: hard},
+	}
+	data, err := json.Marshal(quota)
+	if err != nil {
+		return err
+	}
+	_, err = s.clientset.CoreV1().ResourceQuotas(namespace).Patch(ctx, quota.Name, types.ApplyPatchType, data, applyPatchOptions(FieldManager))
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to apply resource quota", "error", err, "namespace", namespace)
+		return errors.New("failed to create project resource quota")
+	}
+	return nil
+}
+
+func (s *service) applyLimitRange(ctx context.Context, ref string) error {
+	namespace := s.GetProjectNamespace(ref)
+	cfg := s.config.Kube.Project.Isolation.LimitRange
+	defaults, err := parseResourceList(map[corev1.ResourceName]string{
+		corev1.ResourceCPU:    cfg.DefaultCPU,
+		corev1.ResourceMemory: cfg.DefaultMemory,
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "Invalid limit range configuration", "error", err)
+		return errors.New("invalid limit range configuration")
+	}
+	defaultRequests, err := parseResourceList(map[corev1.ResourceName]string{
+		corev1.ResourceCPU:    cfg.DefaultRequestCPU,
+		corev1.ResourceMemory: cfg.DefaultRequestMemory,
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "Invalid limit range configuration", "error", err)
+		return errors.New("invalid limit range configuration")
+	}
+	if len(defaults) == 0 && len(defaultRequests) == 0 {
+		return nil
+	}
+
+	limitRange := &corev1.LimitRange{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "v1",
+			Kind:       "LimitRange",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      s.GetLimitRangeName(ref),
+			Namespace: namespace,
+			Labels:    projectLabels(ref, IsolationComponent),
+		},
+		Spec: corev1.LimitRangeSpec{
+			Limits: []corev1.LimitRangeItem{{
+				Type:           corev1.LimitTypeContainer,
+				Default:        defaults,
+				DefaultRequest: defaultRequests,
+			}},
+		},
+	}
+	data, err := json.Marshal(limitRange)
+	if err != nil {
+		return err
+	}
+	_, err = s.clientset.CoreV1().LimitRanges(namespace).Patch(ctx, limitRange.Name, types.ApplyPatchType, data, applyPatchOptions(FieldManager))
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to apply limit range", "error", err, "namespace", namespace)
+		return errors.New("failed to create project limit range")
+	}
+	return nil
+}
+
+// parseResourceList 將設定值轉為 ResourceList，空字串會被略過
+func parseResourceList(values map[corev1.ResourceName]string) (corev1.ResourceList, error) {
+	list := corev1.ResourceList{}
+	for name, value := range values {
+		if value == "" {
+			continue
+		}
+		quantity, err := resource.ParseQuantity(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		list[name] = quantity
+	}
+	return list, nil
+}
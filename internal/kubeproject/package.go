@@ -5,5 +5,8 @@ import (
 )
 
 var Package = do.Package(
+	do.Lazy(NewRESTConfig),
+	do.Lazy(NewClientset),
+	do.Lazy(NewDynamicClient),
 	do.Lazy(NewService),
 )
@@ -0,0 +1,190 @@
+package kubeproject
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/samber/lo"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// WALArchiveDelay 是 WAL 封存可能落後的時間 (CNPG 預設 archive_timeout 為 5 分鐘)，
+// 可還原的最晚時間點為現在減去這段時間
+const WALArchiveDelay = 5 * time.Minute
+
+// RecoveryWindow 是專案資料庫可以還原的時間範圍
+type RecoveryWindow struct {
+	ServerName       string
+	From             time.Time
+	To               time.Time
+	ArchivingHealthy bool
+}
+
+// Contains 回傳 t 是否落在可還原的範圍內
+func (w *RecoveryWindow) Contains(t time.Time) bool {
+	return !t.Before(w.From) && !t.After(w.To)
+}
+
+// PITRClusterInfo 是 PITR 檢視用 cluster 的狀態與連線資訊
+type PITRClusterInfo struct {
+	Name       string
+	Phase      string
+	TargetTime *time.Time
+	Host       string
+	Username   string
+	Password   string
+}
+
+// FindBackupServerName 回傳專案 cluster 目前 WAL 封存使用的 serverName
+func (s *service) FindBackupServerName(ctx context.Context, ref string) (string, error) {
+	cluster, err := s.getDatabaseCluster(ctx, ref)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to get postgres cluster", "error", err, "ref", ref)
+		return "", errors.New("failed to get postgres cluster")
+	}
+
+	serverName, _, _ := unstructured.NestedString(cluster.Object, "spec", "backup", "barmanObjectStore", "serverName")
+	return lo.CoalesceOrEmpty(serverName, ref), nil
+}
+
+// FindRecoveryWindow 依 cluster 狀態與已完成的備份計算可還原的時間範圍
+func (s *service) FindRecoveryWindow(ctx context.Context, ref string) (*RecoveryWindow, error) {
+	if !s.config.Kube.Project.Backup.Enabled {
+		return nil, ErrBackupNotEnabled
+	}
+
+	cluster, err := s.getDatabaseCluster(ctx, ref)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to get postgres cluster", "error", err, "ref", ref)
+		return nil, errors.New("failed to get postgres cluster")
+	}
+
+	serverName, _, _ := unstructured.NestedString(cluster.Object, "spec", "backup", "barmanObjectStore", "serverName")
+	window := &RecoveryWindow{
+		ServerName: lo.CoalesceOrEmpty(serverName, ref),
+		To:         time.Now().Add(-WALArchiveDelay).UTC(),
+	}
+
+	conditions, _, _ := unstructured.NestedSlice(cluster.Object, "status", "conditions")
+	for _, c := range conditions {
+		condition, ok := c.(map[string]any)
+		if ok && condition["type"] == "ContinuousArchiving" {
+			window.ArchivingHealthy = condition["status"] == string(metav1.ConditionTrue)
+		}
+	}
+
+	// 最早的可還原時間點是第一個 base backup 完成的時間
+	first, _, _ := unstructured.NestedString(cluster.Object, "status", "firstRecoverabilityPoint")
+	if t, err := time.Parse(time.RFC3339, first); err == nil {
+		window.From = t.UTC()
+		return window, nil
+	}
+
+	backups, err := s.ListBackups(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	for _, backup := range backups {
+		if backup.Phase != BackupPhaseCompleted || backup.StoppedAt == nil || backup.ServerName != window.ServerName {
+			continue
+		}
+		if window.From.IsZero() || backup.StoppedAt.Before(window.From) {
+			window.From = backup.StoppedAt.UTC()
+		}
+	}
+	if window.From.IsZero() {
+		return nil, ErrNoRecoverabilityPoint
+	}
+
+	return window, nil
+}
+
+// CreatePITRCluster 在專案旁建立一個還原到指定時間點的 cluster 供檢視，
+// 並以 <ref>-pitr.<domain> 的 SNI host 對外提供連線
+func (s *service) CreatePITRCluster(ctx context.Context, ref string, opt *ClusterRecoveryOption) error {
+	cluster, err := s.getDatabaseCluster(ctx, ref)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to get postgres cluster", "error", err, "ref", ref)
+		return errors.New("failed to get postgres cluster")
+	}
+	storageSize, _, _ := unstructured.NestedString(cluster.Object, "spec", "storage", "size")
+
+	name := s.GetPITRClusterName(ref)
+	err = s.CreateCluster(ctx, ref, &ClusterOption{
+		Name:          name,
+		Component:     PITRComponent,
+		StorageSize:   lo.CoalesceOrEmpty(storageSize, "1Gi"),
+		Recovery:      opt,
+		DisableBackup: true,
+	})
+	if err != nil {
+		return err
+	}
+
+	ownerRef, err := s.namedClusterOwnerReference(ctx, ref, name)
+	if err != nil {
+		return err
+	}
+
+	return s.ingress.ApplyDBRoute(ctx, ref, dbRouteOption{
+		Name:        name,
+		Component:   PITRComponent,
+		Host:        s.GetPITRHost(ref),
+		ServiceName: s.GetDatabaseRWServiceName(name),
+		OwnerRef:    ownerRef,
+	})
+}
+
+// FindPITRCluster 取得 PITR 檢視用 cluster 的狀態，cluster 就緒後才會包含密碼
+func (s *service) FindPITRCluster(ctx context.Context, ref string) (*PITRClusterInfo, error) {
+	name := s.GetPITRClusterName(ref)
+	cluster, err := s.dynamicClient.Resource(clusterGVR).
+		Namespace(s.GetProjectNamespace(ref)).
+		Get(ctx, name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, ErrPITRClusterNotFound
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to get point-in-time recovery cluster", "error", err, "ref", ref)
+		return nil, errors.New("failed to get point-in-time recovery cluster")
+	}
+
+	info := &PITRClusterInfo{
+		Name:     name,
+		Host:     s.GetPITRHost(ref),
+		Username: RoleApp,
+	}
+	info.Phase, _, _ = unstructured.NestedString(cluster.Object, "status", "phase")
+	targetTime, _, _ := unstructured.NestedString(cluster.Object, "spec", "bootstrap", "recovery", "recoveryTarget", "targetTime")
+	if t, err := time.Parse(time.RFC3339, targetTime); err == nil {
+		info.TargetTime = &t
+	}
+
+	// CNPG 為 bootstrap owner 建立 <cluster>-app Secret
+	secret, err := s.clientset.CoreV1().Secrets(s.GetProjectNamespace(ref)).Get(ctx, s.GetDatabaseRoleSecretName(name, RoleApp), metav1.GetOptions{})
+	switch {
+	case err == nil:
+		info.Password = string(secret.Data["password"])
+	case !apierrors.IsNotFound(err):
+		slog.ErrorContext(ctx, "Failed to get point-in-time recovery cluster secret", "error", err, "ref", ref)
+		return nil, errors.New("failed to get point-in-time recovery cluster secret")
+	}
+
+	return info, nil
+}
+
+// DeletePITRCluster 移除 PITR 檢視用 cluster，對外的路由由 GC 一併清除
+func (s *service) DeletePITRCluster(ctx context.Context, ref string) error {
+	err := s.dynamicClient.Resource(clusterGVR).
+		Namespace(s.GetProjectNamespace(ref)).
+		Delete(ctx, s.GetPITRClusterName(ref), metav1.DeleteOptions{})
+	if ignoreNotFound(err) != nil {
+		slog.ErrorContext(ctx, "Failed to delete point-in-time recovery cluster", "error", err, "ref", ref)
+		return errors.New("failed to delete point-in-time recovery cluster")
+	}
+	return nil
+}
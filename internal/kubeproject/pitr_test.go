@@ -0,0 +1,153 @@
+package kubeproject
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"baas-api/internal/config"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestRecoveryWindowContains(t *testing.T) {
+	from := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	window := &RecoveryWindow{From: from, To: from.Add(24 * time.Hour)}
+
+	tests := []struct {
+		name   string
+		target time.Time
+		want   bool
+	}{
+		{name: "inside", target: from.Add(time.Hour), want: true},
+		{name: "at start", target: from, want: true},
+		{name: "at end", target: from.Add(24 * time.Hour), want: true},
+		{name: "before start", target: from.Add(-time.Nanosecond), want: false},
+		{name: "after end", target: from.Add(24*time.Hour + time.Nanosecond), want: false},
+		{name: "other time zone inside", target: time.Date(2026, 1, 1, 9, 0, 0, 0, time.FixedZone("UTC+8", 8*60*60)), want: true},
+		{name: "other time zone before start", target: time.Date(2026, 1, 1, 7, 0, 0, 0, time.FixedZone("UTC+8", 8*60*60)), want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := window.Contains(tt.target); got != tt.want {
+				t.Errorf("Contains(%v) = %v, want %v", tt.target, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindRecoveryWindow(t *testing.T) {
+	ref := testRefs("w", 1)[0]
+	first := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// cluster 回傳專案的 CNPG Cluster，serverName 與 firstRecoverabilityPoint 為空時不設定
+	cluster := func(serverName string, firstRecoverabilityPoint string, archiving string) *unstructured.Unstructured {
+		obj := testDatabaseCluster(ref, ref, projectLabels(ref, DBComponent))
+		if serverName != "" {
+			_ = unstructured.SetNestedField(obj.Object, serverName, "spec", "backup", "barmanObjectStore", "serverName")
+		}
+		status := map[string]any{}
+		if firstRecoverabilityPoint != "" {
+			status["firstRecoverabilityPoint"] = firstRecoverabilityPoint
+		}
+		if archiving != "" {
+			status["conditions"] = []any{map[string]any{"type": "ContinuousArchiving", "status": archiving}}
+		}
+		obj.Object["status"] = status
+		return obj
+	}
+	backup := func(name string, clusterName string, phase string, serverName string, stoppedAt time.Time) *unstructured.Unstructured {
+		obj := &unstructured.Unstructured{Object: map[string]any{
+			"spec":   map[string]any{"cluster": map[string]any{"name": clusterName}},
+			"status": map[string]any{"phase": phase, "serverName": serverName, "stoppedAt": stoppedAt.Format(time.RFC3339)},
+		}}
+		obj.SetAPIVersion(backupGVR.GroupVersion().String())
+		obj.SetKind("Backup")
+		obj.SetNamespace(testProjectNamespace)
+		obj.SetName(name)
+		return obj
+	}
+
+	tests := []struct {
+		name          string
+		backupEnabled bool
+		objects       []runtime.Object
+		wantServer    string
+		wantFrom      time.Time
+		wantArchiving bool
+		wantErr       error
+	}{
+		{
+			name:          "first recoverability point",
+			backupEnabled: true,
+			objects:       []runtime.Object{cluster("", first.Format(time.RFC3339), "True")},
+			wantServer:    ref,
+			wantFrom:      first,
+			wantArchiving: true,
+		},
+		{
+			name:          "first recoverability point in another time zone",
+			backupEnabled: true,
+			objects:       []runtime.Object{cluster("", "2026-01-01T08:00:00+08:00", "False")},
+			wantServer:    ref,
+			wantFrom:      first,
+		},
+		{
+			name:          "earliest completed backup of the current server",
+			backupEnabled: true,
+			objects: []runtime.Object{
+				cluster(ref+"-restored", "", "True"),
+				backup("b1", ref, "completed", ref+"-restored", first.Add(2*time.Hour)),
+				backup("b2", ref, "completed", ref+"-restored", first.Add(time.Hour)),
+				// 還原前的 serverName、未完成的備份與其他專案的備份不能作為起點
+				backup("b3", ref, "completed", ref, first),
+				backup("b4", ref, "running", ref+"-restored", first),
+				backup("b5", testRefs("o", 1)[0], "completed", ref+"-restored", first),
+			},
+			wantServer:    ref + "-restored",
+			wantFrom:      first.Add(time.Hour),
+			wantArchiving: true,
+		},
+		{
+			name:          "no completed backup",
+			backupEnabled: true,
+			objects:       []runtime.Object{cluster("", "", ""), backup("b1", ref, "failed", ref, first)},
+			wantErr:       ErrNoRecoverabilityPoint,
+		},
+		{
+			name:    "backup not enabled",
+			objects: []runtime.Object{cluster("", first.Format(time.RFC3339), "True")},
+			wantErr: ErrBackupNotEnabled,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := newTestClusterWithObjects(t, config.KubeClusterConfig{}, tt.objects...)
+			svc.config.Kube.Project.Backup.Enabled = tt.backupEnabled
+
+			before := time.Now()
+			window, err := svc.FindRecoveryWindow(context.Background(), ref)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("FindRecoveryWindow error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if window.ServerName != tt.wantServer {
+				t.Errorf("ServerName = %q, want %q", window.ServerName, tt.wantServer)
+			}
+			if !window.From.Equal(tt.wantFrom) || window.From.Location() != time.UTC {
+				t.Errorf("From = %v, want %v", window.From, tt.wantFrom)
+			}
+			// 最晚的時間點保留 WAL 封存可能落後的時間
+			if latest := before.Add(-WALArchiveDelay); window.To.Before(latest) || window.To.After(time.Now().Add(-WALArchiveDelay)) {
+				t.Errorf("To = %v, want about %v", window.To, latest)
+			}
+			if window.ArchivingHealthy != tt.wantArchiving {
+				t.Errorf("ArchivingHealthy = %v, want %v", window.ArchivingHealthy, tt.wantArchiving)
+			}
+		})
+	}
+}
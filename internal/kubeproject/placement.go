@@ -0,0 +1,75 @@
+package kubeproject
+
+import (
+	"context"
+	"log/slog"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// matchesRegion 回傳叢集是否符合要求的 region，region 為空時不限制
+func (s *service) matchesRegion(region string) bool {
+	return region == "" || s.cluster.Region == region
+}
+
+// hasCapacity 回傳叢集在已有 count 個專案時是否還能放置新專案
+func (s *service) hasCapacity(count int) bool {
+	return s.cluster.MaxProjects <= 0 || count < s.cluster.MaxProjects
+}
+
+// countProjects 以 CNPG Cluster 計算叢集中的專案數。
+//
+// 還原中的 Cluster (db-restore)、還原後的 Cluster (db) 與 PITR 檢視用的 Cluster 與專案的 Cluster 有相同的 project-ref，
+// 依 ref 去重；labels 導入前建立的 Cluster 沒有專案 labels，名稱即為 ref。
+func (s *service) countProjects(ctx context.Context) (int, error) {
+	namespace := s.namespace
+	if s.config.Kube.Project.Isolation.Enabled {
+		namespace = metav1.NamespaceAll
+	}
+	list, err := s.dynamicClient.Resource(clusterGVR).Namespace(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return 0, err
+	}
+
+	refs := map[string]struct{}{}
+	for _, item := range list.Items {
+		ref := item.GetLabels()[LabelProjectRef]
+		if ref == "" && item.GetName() == refFromResourceName(item.GetName()) {
+			ref = item.GetName()
+		}
+		if ref != "" {
+			refs[ref] = struct{}{}
+		}
+	}
+	return len(refs), nil
+}
+
+// PlaceProject 在只有一個叢集時檢查 region 與容量，不需要記錄叢集
+func (s *service) PlaceProject(ctx context.Context, region string) (string, error) {
+	if !s.matchesRegion(region) {
+		return "", ErrNoClusterInRegion
+	}
+	if s.cluster.MaxProjects > 0 {
+		count, err := s.countProjects(ctx)
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed to count projects", "error", err, "cluster", s.cluster.Name)
+			return "", ErrNoClusterAvailable
+		}
+		if !s.hasCapacity(count) {
+			return "", ErrNoClusterAvailable
+		}
+	}
+	return s.cluster.Name, nil
+}
+
+// ForgetProjectCluster 在只有一個叢集時不需要快取
+func (s *service) ForgetProjectCluster(ref string) {}
+
+// hasProject 回傳叢集中是否有專案的 CNPG Cluster
+func (s *service) hasProject(ctx context.Context, ref string) (bool, error) {
+	_, err := s.getDatabaseCluster(ctx, ref)
+	if err == nil {
+		return true, nil
+	}
+	return false, ignoreNotFound(err)
+}
@@ -0,0 +1,90 @@
+package kubeproject
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"baas-api/internal/config"
+
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestPlaceProject(t *testing.T) {
+	tests := []struct {
+		name     string
+		cluster  config.KubeClusterConfig
+		projects int
+		region   string
+		want     string
+		wantErr  error
+	}{
+		{name: "no clusters configured", cluster: config.KubeClusterConfig{}, projects: 3, want: ""},
+		{name: "any region", cluster: config.KubeClusterConfig{Name: "a", Region: "eu"}, want: "a"},
+		{name: "matching region", cluster: config.KubeClusterConfig{Name: "a", Region: "eu"}, region: "eu", want: "a"},
+		{name: "below capacity", cluster: config.KubeClusterConfig{Name: "a", MaxProjects: 3}, projects: 2, want: "a"},
+		{name: "other region", cluster: config.KubeClusterConfig{Name: "a", Region: "eu"}, region: "us", wantErr: ErrNoClusterInRegion},
+		{name: "full", cluster: config.KubeClusterConfig{Name: "a", MaxProjects: 3}, projects: 3, wantErr: ErrNoClusterAvailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := newTestCluster(t, tt.cluster, testRefs("a", tt.projects)...)
+			got, err := svc.PlaceProject(context.Background(), tt.region)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("PlaceProject error = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("PlaceProject = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCountProjects(t *testing.T) {
+	ref, legacy := testRefs("c", 1)[0], testRefs("l", 1)[0]
+
+	tests := []struct {
+		name     string
+		clusters []runtime.Object
+		want     int
+	}{
+		{name: "empty", want: 0},
+		{
+			name:     "labeled",
+			clusters: []runtime.Object{testDatabaseCluster(ref, ref, projectLabels(ref, DBComponent))},
+			want:     1,
+		},
+		{
+			// 還原與 PITR 檢視用的 Cluster 與專案的 Cluster 有相同的 project-ref
+			name: "restore and PITR clusters counted once",
+			clusters: []runtime.Object{
+				testDatabaseCluster(ref, ref, projectLabels(ref, DBComponent)),
+				testDatabaseCluster(ref+"-db-restore", ref, projectLabels(ref, DBComponent)),
+				testDatabaseCluster(ref+"-pitr", ref, projectLabels(ref, "pitr")),
+			},
+			want: 1,
+		},
+		{
+			name:     "legacy cluster named ref",
+			clusters: []runtime.Object{testDatabaseCluster(legacy, legacy, nil), testDatabaseCluster(ref, ref, projectLabels(ref, DBComponent))},
+			want:     2,
+		},
+		{
+			name:     "unrelated cluster",
+			clusters: []runtime.Object{testDatabaseCluster("platform-db", "", nil)},
+			want:     0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := newTestClusterWithObjects(t, config.KubeClusterConfig{Name: "a"}, tt.clusters...)
+			got, err := svc.countProjects(context.Background())
+			if err != nil {
+				t.Fatalf("countProjects: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("countProjects = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,262 @@
+package kubeproject
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+var ErrPoolerNotFound = errors.New("pooler not found")
+
+// PgBouncer pool mode
+const (
+	PoolModeSession     = "session"
+	PoolModeTransaction = "transaction"
+)
+
+// PoolerOption 是專案 PgBouncer pooler 的設定
+type PoolerOption struct {
+	Instances       int32
+	PoolMode        string
+	DefaultPoolSize int32
+	MaxClientConn   int32
+}
+
+// PoolerInfo 是 Pooler 資源目前的設定與狀態
+type PoolerInfo struct {
+	PoolerOption
+	Host           string
+	ReadyInstances int32
+}
+
+// ValidatePooler 檢查 pooler 設定是否合法
+func ValidatePooler(opt PoolerOption) error {
+	if opt.Instances < 1 || opt.Instances > 3 {
+		return errors.New("pooler instances must be between 1 and 3")
+	}
+	if opt.PoolMode != PoolModeSession && opt.PoolMode != PoolModeTransaction {
+		return errors.New("pool mode must be session or transaction")
+	}
+	if opt.DefaultPoolSize < 1 || opt.DefaultPoolSize > 500 {
+		return errors.New("default pool size must be between 1 and 500")
+	}
+	if opt.MaxClientConn < opt.DefaultPoolSize || opt.MaxClientConn > 10000 {
+		return errors.New("max client connections must be between the default pool size and 10000")
+	}
+	return nil
+}
+
+// ApplyPooler 建立或更新專案的 CNPG Pooler (PgBouncer)，並以 <ref>-pooler.<domain> 對外提供連線
+func (s *service) ApplyPooler(ctx context.Context, ref string, opt PoolerOption) error {
+	if err := ValidatePooler(opt); err != nil {
+		slog.ErrorContext(ctx, "Invalid pooler configuration", "error", err, "ref", ref)
+		return err
+	}
+
+	ownerRef, err := s.clusterOwnerReference(ctx, ref)
+	if err != nil {
+		return err
+	}
+
+	name := s.GetPoolerName(ref)
+	pooler := &unstructured.Unstructured{}
+	pooler.SetAPIVersion(poolerGVR.GroupVersion().String())
+	pooler.SetKind("Pooler")
+	pooler.SetName(name)
+	pooler.SetNamespace(s.GetProjectNamespace(ref))
+	pooler.SetLabels(projectLabels(ref, PoolerComponent))
+	pooler.SetOwnerReferences([]metav1.OwnerReference{*ownerRef})
+	pooler.Object["spec"] = map[string]any{
+		"cluster": map[string]any{
+			"name": ownerRef.Name,
+		},
+		"instances": opt.Instances,
+		"type":      "rw",
+		"pgbouncer": map[string]any{
+			"poolMode": opt.PoolMode,
+			"parameters": map[string]any{
+				"default_pool_size": strconv.Itoa(int(opt.DefaultPoolSize)),
+				"max_client_conn":   strconv.Itoa(int(opt.MaxClientConn)),
+			},
+		},
+		// Pod 需要專案 labels 才會被 NetworkPolicy 開放
+		"template": map[string]any{
+			"metadata": map[string]any{
+				"labels": projectLabels(ref, PoolerComponent),
+			},
+			"spec": map[string]any{
+				"containers": []any{},
+			},
+		},
+	}
+
+	_, err = s.dynamicClient.Resource(poolerGVR).
+		Namespace(s.GetProjectNamespace(ref)).
+		Apply(ctx, name, pooler, applyOptions(FieldManager))
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to apply pooler", "error", err, "ref", ref)
+		return errors.New("failed to apply pooler")
+	}
+
+	// CNPG 為 Pooler 建立同名的 Service
+	return s.ingress.ApplyDBRoute(ctx, ref, dbRouteOption{
+		Name:        name,
+		Component:   PoolerComponent,
+		Host:        s.GetPoolerHost(ref),
+		ServiceName: name,
+		OwnerRef:    ownerRef,
+	})
+}
+
+// FindPooler 取得專案 Pooler 的設定與狀態，未啟用時回傳 ErrPoolerNotFound
+func (s *service) FindPooler(ctx context.Context, ref string) (*PoolerInfo, error) {
+	pooler, err := s.dynamicClient.Resource(poolerGVR).
+		Namespace(s.GetProjectNamespace(ref)).
+		Get(ctx, s.GetPoolerName(ref), metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, ErrPoolerNotFound
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to get pooler", "error", err, "ref", ref)
+		return nil, errors.New("failed to get pooler")
+	}
+
+	nestedInt32 := func(fields ...string) int32 {
+		value, _, _ := unstructured.NestedInt64(pooler.Object, fields...)
+		return int32(value)
+	}
+	parameterInt32 := func(name string) int32 {
+		value, _, _ := unstructured.NestedString(pooler.Object, "spec", "pgbouncer", "parameters", name)
+		n, _ := strconv.Atoi(value)
+		return int32(n)
+	}
+	poolMode, _, _ := unstructured.NestedString(pooler.Object, "spec", "pgbouncer", "poolMode")
+
+	return &PoolerInfo{
+		PoolerOption: PoolerOption{
+			Instances:       nestedInt32("spec", "instances"),
+			PoolMode:        poolMode,
+			DefaultPoolSize: parameterInt32("default_pool_size"),
+			MaxClientConn:   parameterInt32("max_client_conn"),
+		},
+		Host:           s.GetPoolerHost(ref),
+		ReadyInstances: nestedInt32("status", "instances"),
+	}, nil
+}
+
+// DeletePooler 移除專案的 Pooler 與對外的路由
+func (s *service) DeletePooler(ctx context.Context, ref string) error {
+	name := s.GetPoolerName(ref)
+	if err := s.ingress.DeleteDBRoute(ctx, ref, name); err != nil {
+		return err
+	}
+
+	err := s.dynamicClient.Resource(poolerGVR).
+		Namespace(s.GetProjectNamespace(ref)).
+		Delete(ctx, name, metav1.DeleteOptions{})
+	if ignoreNotFound(err) != nil {
+		slog.ErrorContext(ctx, "Failed to delete pooler", "error", err, "ref", ref)
+		return errors.New("failed to delete pooler")
+	}
+	return nil
+}
+
+// ApplyRESTAPIPooler 切換 PostgREST 直接連線資料庫或經由 pooler 連線。
+//
+// transaction 模式下 PgBouncer 不支援 prepared statements 與 LISTEN，
+// 因此關閉 PostgREST 的 prepared statements 與 schema reload 通知 channel。
+func (s *service) ApplyRESTAPIPooler(ctx context.Context, ref string, enabled bool, poolMode string) error {
+	deploymentName := s.GetRESTAPIDeploymentName(ref)
+	namespace := s.GetProjectNamespace(ref)
+
+	uriKey := "uri"
+	if enabled {
+		uriKey = "pooler-uri"
+	}
+	envVars := []corev1.EnvVar{
+		{Name: "PGRST_DB_URI", ValueFrom: &corev1.EnvVarSource{
+			SecretKeyRef: &corev1.SecretKeySelector{
+				Key: uriKey,
+				LocalObjectReference: corev1.LocalObjectReference{
+					Name: s.GetDatabaseRoleSecretName(ref, RoleAuthenticator),
+				},
+			},
+		}},
+	}
+	if enabled && poolMode == PoolModeTransaction {
+		envVars = append(envVars,
+			corev1.EnvVar{Name: "PGRST_DB_PREPARED_STATEMENTS", Value: "false"},
+			corev1.EnvVar{Name: "PGRST_DB_CHANNEL_ENABLED", Value: "false"},
+		)
+	}
+
+	// 未送出的 env 會被移除，因此關閉 pooler 時仍需宣告直接連線的 PGRST_DB_URI
+	payload := map[string]any{
+		"apiVersion": "apps/v1",
+		"kind":       "Deployment",
+		"metadata": map[string]any{
+			"name":      deploymentName,
+			"namespace": namespace,
+		},
+		"spec": map[string]any{
+			"template": map[string]any{
+				"spec": map[string]any{
+					"containers": []map[string]any{
+						{
+							"name": s.GetRESTAPIContainerName(ref, PGRSTComponent),
+							"env":  envVars,
+						},
+					},
+				},
+			},
+		},
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to marshal REST API pooler patch", "error", err)
+		return errors.New("failed to marshal REST API pooler patch")
+	}
+
+	_, err = s.clientset.AppsV1().Deployments(namespace).Patch(
+		ctx,
+		deploymentName,
+		types.ApplyPatchType,
+		data,
+		applyPatchOptions(PoolerFieldManager),
+	)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to apply REST API pooler settings", "error", err, "deployment", deploymentName)
+		return errors.New("failed to apply REST API pooler settings")
+	}
+	return nil
+}
+
+// FindRESTAPIPooler 回傳 PostgREST 目前是否經由 pooler 連線
+func (s *service) FindRESTAPIPooler(ctx context.Context, ref string) (bool, error) {
+	deployment, err := s.clientset.AppsV1().Deployments(s.GetProjectNamespace(ref)).Get(ctx, s.GetRESTAPIDeploymentName(ref), metav1.GetOptions{})
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to get REST API deployment", "error", err, "ref", ref)
+		return false, errors.New("failed to get REST API deployment")
+	}
+
+	containerName := s.GetRESTAPIContainerName(ref, PGRSTComponent)
+	for _, container := range deployment.Spec.Template.Spec.Containers {
+		if container.Name != containerName {
+			continue
+		}
+		for _, env := range container.Env {
+			if env.Name == "PGRST_DB_URI" && env.ValueFrom != nil && env.ValueFrom.SecretKeyRef != nil {
+				return env.ValueFrom.SecretKeyRef.Key == "pooler-uri", nil
+			}
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,217 @@
+package kubeproject
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+var ErrPostgresParameterNotAllowed = errors.New("postgres parameter is not allowed")
+
+// PostgresParameterType 決定參數值的格式與範圍的單位
+type PostgresParameterType string
+
+const (
+	PostgresParameterInteger PostgresParameterType = "integer"
+	PostgresParameterReal    PostgresParameterType = "real"
+	// PostgresParameterMemory 的範圍以 kB 計算，值必須帶單位 (kB, MB, GB, TB)
+	PostgresParameterMemory PostgresParameterType = "memory"
+	// PostgresParameterDuration 的範圍以 ms 計算，沒有單位時視為 ms
+	PostgresParameterDuration PostgresParameterType = "duration"
+	PostgresParameterBool     PostgresParameterType = "bool"
+	PostgresParameterEnum     PostgresParameterType = "enum"
+)
+
+// PostgresParameterSpec 描述允許專案調整的 Postgres 參數
+type PostgresParameterSpec struct {
+	Type   PostgresParameterType
+	Min    float64
+	Max    float64
+	Values []string
+	// RestartRequired 表示變更後 CNPG 需要 rolling restart instance 才會生效
+	RestartRequired bool
+}
+
+// PostgresParameterAllowlist 是專案可以透過 API 調整的參數，其他參數由平台或 CNPG 管理
+var PostgresParameterAllowlist = map[string]PostgresParameterSpec{
+	"max_connections":                     {Type: PostgresParameterInteger, Min: 10, Max: 1000, RestartRequired: true},
+	"shared_buffers":                      {Type: PostgresParameterMemory, Min: 16 * 1024, Max: 8 * 1024 * 1024, RestartRequired: true},
+	"max_worker_processes":                {Type: PostgresParameterInteger, Min: 1, Max: 64, RestartRequired: true},
+	"effective_cache_size":                {Type: PostgresParameterMemory, Min: 8 * 1024, Max: 64 * 1024 * 1024},
+	"work_mem":                            {Type: PostgresParameterMemory, Min: 64, Max: 1024 * 1024},
+	"maintenance_work_mem":                {Type: PostgresParameterMemory, Min: 1024, Max: 2 * 1024 * 1024},
+	"max_wal_size":                        {Type: PostgresParameterMemory, Min: 64 * 1024, Max: 64 * 1024 * 1024},
+	"min_wal_size":                        {Type: PostgresParameterMemory, Min: 32 * 1024, Max: 16 * 1024 * 1024},
+	"checkpoint_completion_target":        {Type: PostgresParameterReal, Min: 0, Max: 1},
+	"random_page_cost":                    {Type: PostgresParameterReal, Min: 0, Max: 100},
+	"effective_io_concurrency":            {Type: PostgresParameterInteger, Min: 0, Max: 1000},
+	"default_statistics_target":           {Type: PostgresParameterInteger, Min: 1, Max: 10000},
+	"max_parallel_workers":                {Type: PostgresParameterInteger, Min: 0, Max: 64},
+	"max_parallel_workers_per_gather":     {Type: PostgresParameterInteger, Min: 0, Max: 64},
+	"statement_timeout":                   {Type: PostgresParameterDuration, Min: 0, Max: 24 * 60 * 60 * 1000},
+	"lock_timeout":                        {Type: PostgresParameterDuration, Min: 0, Max: 24 * 60 * 60 * 1000},
+	"idle_in_transaction_session_timeout": {Type: PostgresParameterDuration, Min: 0, Max: 24 * 60 * 60 * 1000},
+	"log_min_duration_statement":          {Type: PostgresParameterDuration, Min: -1, Max: 24 * 60 * 60 * 1000},
+	"log_statement":                       {Type: PostgresParameterEnum, Values: []string{"none", "ddl", "mod", "all"}},
+	"jit":                                 {Type: PostgresParameterBool},
+}
+
+var (
+	memoryValueRegexp   = regexp.MustCompile(`^(\d+)\s*(kB|MB|GB|TB)$`)
+	durationValueRegexp = regexp.MustCompile(`^(-?\d+)\s*(us|ms|s|min|h|d)?$`)
+
+	memoryUnits   = map[string]float64{"kB": 1, "MB": 1024, "GB": 1024 * 1024, "TB": 1024 * 1024 * 1024}
+	durationUnits = map[string]float64{"us": 0.001, "ms": 1, "": 1, "s": 1000, "min": 60 * 1000, "h": 60 * 60 * 1000, "d": 24 * 60 * 60 * 1000}
+	boolValues    = map[string]bool{"on": true, "true": true, "yes": true, "1": true, "off": false, "false": false, "no": false, "0": false}
+)
+
+// parsePostgresParameter 將值轉換為可比較的形式：數值類型為基本單位的數字，其他類型為正規化的字串
+func parsePostgresParameter(spec PostgresParameterSpec, value string) (float64, string, error) {
+	value = strings.TrimSpace(value)
+	switch spec.Type {
+	case PostgresParameterInteger:
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return 0, "", errors.New("must be an integer")
+		}
+		return float64(n), "", nil
+	case PostgresParameterReal:
+		n, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return 0, "", errors.New("must be a number")
+		}
+		return n, "", nil
+	case PostgresParameterMemory:
+		m := memoryValueRegexp.FindStringSubmatch(value)
+		if m == nil {
+			return 0, "", errors.New("must be an amount of memory with a unit (kB, MB, GB or TB)")
+		}
+		n, _ := strconv.ParseFloat(m[1], 64)
+		return n * memoryUnits[m[2]], "", nil
+	case PostgresParameterDuration:
+		m := durationValueRegexp.FindStringSubmatch(value)
+		if m == nil {
+			return 0, "", errors.New("must be a duration in milliseconds or with a unit (us, ms, s, min, h or d)")
+		}
+		n, _ := strconv.ParseFloat(m[1], 64)
+		return n * durationUnits[m[2]], "", nil
+	case PostgresParameterBool:
+		b, ok := boolValues[strings.ToLower(value)]
+		if !ok {
+			return 0, "", errors.New("must be on or off")
+		}
+		return 0, strconv.FormatBool(b), nil
+	case PostgresParameterEnum:
+		v := strings.ToLower(value)
+		if !slices.Contains(spec.Values, v) {
+			return 0, "", fmt.Errorf("must be one of %s", strings.Join(spec.Values, ", "))
+		}
+		return 0, v, nil
+	default:
+		return 0, "", errors.New("unsupported parameter type")
+	}
+}
+
+// ValidatePostgresParameter 檢查參數是否在允許清單中，以及值的格式與範圍
+func ValidatePostgresParameter(name string, value string) error {
+	spec, ok := PostgresParameterAllowlist[name]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrPostgresParameterNotAllowed, name)
+	}
+
+	n, _, err := parsePostgresParameter(spec, value)
+	if err != nil {
+		return fmt.Errorf("%s %w", name, err)
+	}
+	if spec.Type == PostgresParameterInteger || spec.Type == PostgresParameterReal ||
+		spec.Type == PostgresParameterMemory || spec.Type == PostgresParameterDuration {
+		if n < spec.Min || n > spec.Max {
+			return fmt.Errorf("%s is out of range", name)
+		}
+	}
+	return nil
+}
+
+// PostgresParameterEqual 以參數的單位比較兩個值，例如 4MB 與 4096kB 視為相同
+func PostgresParameterEqual(name string, a string, b string) bool {
+	spec, ok := PostgresParameterAllowlist[name]
+	if !ok {
+		return a == b
+	}
+	an, as, aErr := parsePostgresParameter(spec, a)
+	bn, bs, bErr := parsePostgresParameter(spec, b)
+	if aErr != nil || bErr != nil {
+		return a == b
+	}
+	return an == bn && as == bs
+}
+
+// FindPostgresParameters 回傳 Cluster spec.postgresql.parameters 中允許清單內的參數
+func (s *service) FindPostgresParameters(ctx context.Context, ref string) (map[string]string, error) {
+	cluster, err := s.getDatabaseCluster(ctx, ref)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to get postgres cluster", "error", err, "ref", ref)
+		return nil, errors.New("failed to get postgres cluster")
+	}
+
+	// CNPG 的 webhook 也會在 parameters 中寫入預設值，只回傳專案可以管理的參數
+	parameters, _, _ := unstructured.NestedStringMap(cluster.Object, "spec", "postgresql", "parameters")
+	result := map[string]string{}
+	for name, value := range parameters {
+		if _, ok := PostgresParameterAllowlist[name]; ok {
+			result[name] = value
+		}
+	}
+	return result, nil
+}
+
+// ApplyPostgresParameters 以 ParametersFieldManager 套用專案的 Postgres 參數。
+//
+// 先前由此 field manager 設定、但不在 parameters 中的參數會被移除並回到預設值；
+// CNPG 會 reload 設定，需要重新啟動的參數會觸發 rolling restart。
+func (s *service) ApplyPostgresParameters(ctx context.Context, ref string, parameters map[string]string) error {
+	name, err := s.databaseClusterName(ctx, ref)
+	if err != nil {
+		return err
+	}
+	return s.applyPostgresParameters(ctx, ref, name, parameters)
+}
+
+// applyPostgresParameters 套用指定名稱 Cluster 的 Postgres 參數
+func (s *service) applyPostgresParameters(ctx context.Context, ref string, name string, parameters map[string]string) error {
+	values := map[string]any{}
+	for name, value := range parameters {
+		if err := ValidatePostgresParameter(name, value); err != nil {
+			slog.ErrorContext(ctx, "Invalid postgres parameter", "error", err, "ref", ref)
+			return err
+		}
+		values[name] = value
+	}
+
+	obj := &unstructured.Unstructured{}
+	obj.SetAPIVersion(clusterGVR.GroupVersion().String())
+	obj.SetKind("Cluster")
+	obj.SetName(name)
+	obj.SetNamespace(s.GetProjectNamespace(ref))
+	obj.Object["spec"] = map[string]any{
+		"postgresql": map[string]any{
+			"parameters": values,
+		},
+	}
+
+	_, err := s.dynamicClient.Resource(clusterGVR).
+		Namespace(s.GetProjectNamespace(ref)).
+		Apply(ctx, name, obj, applyOptions(ParametersFieldManager))
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to apply postgres parameters", "error", err, "ref", ref)
+		return errors.New("failed to apply postgres parameters")
+	}
+	return nil
+}
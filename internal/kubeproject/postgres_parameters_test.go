@@ -0,0 +1,89 @@
+package kubeproject
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestValidatePostgresParameter(t *testing.T) {
+	tests := []struct {
+		name      string
+		parameter string
+		value     string
+		wantErr   string
+	}{
+		{name: "integer", parameter: "max_connections", value: "200"},
+		{name: "integer at min", parameter: "max_connections", value: "10"},
+		{name: "integer at max", parameter: "max_connections", value: "1000"},
+		{name: "real", parameter: "checkpoint_completion_target", value: "0.9"},
+		{name: "memory in MB", parameter: "shared_buffers", value: "256MB"},
+		{name: "memory with space", parameter: "work_mem", value: "4 MB"},
+		{name: "memory at max", parameter: "shared_buffers", value: "8GB"},
+		{name: "duration without unit", parameter: "statement_timeout", value: "30000"},
+		{name: "duration with unit", parameter: "statement_timeout", value: "30s"},
+		{name: "duration disabled", parameter: "log_min_duration_statement", value: "-1"},
+		{name: "bool", parameter: "jit", value: "off"},
+		{name: "bool upper case", parameter: "jit", value: "TRUE"},
+		{name: "enum", parameter: "log_statement", value: "ddl"},
+		{name: "enum upper case", parameter: "log_statement", value: "MOD"},
+
+		{name: "not allowed", parameter: "shared_preload_libraries", value: "pg_stat_statements", wantErr: "not allowed"},
+		{name: "platform managed", parameter: "ssl", value: "off", wantErr: "not allowed"},
+		{name: "integer with fraction", parameter: "max_connections", value: "10.5", wantErr: "must be an integer"},
+		{name: "integer below min", parameter: "max_connections", value: "9", wantErr: "out of range"},
+		{name: "integer above max", parameter: "max_connections", value: "1001", wantErr: "out of range"},
+		{name: "real above max", parameter: "checkpoint_completion_target", value: "1.5", wantErr: "out of range"},
+		{name: "real not a number", parameter: "random_page_cost", value: "fast", wantErr: "must be a number"},
+		{name: "memory without unit", parameter: "work_mem", value: "4096", wantErr: "with a unit"},
+		{name: "memory lower case unit", parameter: "work_mem", value: "4mb", wantErr: "with a unit"},
+		{name: "memory below min", parameter: "shared_buffers", value: "8MB", wantErr: "out of range"},
+		{name: "memory above max", parameter: "shared_buffers", value: "9GB", wantErr: "out of range"},
+		{name: "duration unknown unit", parameter: "lock_timeout", value: "5 weeks", wantErr: "must be a duration"},
+		{name: "duration above max", parameter: "statement_timeout", value: "2d", wantErr: "out of range"},
+		{name: "duration negative", parameter: "statement_timeout", value: "-1", wantErr: "out of range"},
+		{name: "bool invalid", parameter: "jit", value: "maybe", wantErr: "must be on or off"},
+		{name: "enum invalid", parameter: "log_statement", value: "verbose", wantErr: "must be one of none, ddl, mod, all"},
+		{name: "injection", parameter: "work_mem", value: "4MB'\nssl = off", wantErr: "with a unit"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidatePostgresParameter(tt.parameter, tt.value)
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("ValidatePostgresParameter: %v", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Errorf("ValidatePostgresParameter error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	if err := ValidatePostgresParameter("fsync", "off"); !errors.Is(err, ErrPostgresParameterNotAllowed) {
+		t.Errorf("ValidatePostgresParameter error = %v, want %v", err, ErrPostgresParameterNotAllowed)
+	}
+}
+
+func TestPostgresParameterEqual(t *testing.T) {
+	tests := []struct {
+		name      string
+		parameter string
+		a, b      string
+		want      bool
+	}{
+		{name: "memory units", parameter: "work_mem", a: "4MB", b: "4096kB", want: true},
+		{name: "memory differs", parameter: "work_mem", a: "4MB", b: "8MB", want: false},
+		{name: "duration units", parameter: "statement_timeout", a: "1min", b: "60000", want: true},
+		{name: "bool aliases", parameter: "jit", a: "on", b: "true", want: true},
+		{name: "bool differs", parameter: "jit", a: "on", b: "0", want: false},
+		{name: "enum case", parameter: "log_statement", a: "DDL", b: "ddl", want: true},
+		{name: "invalid value compared as is", parameter: "work_mem", a: "lots", b: "lots", want: true},
+		{name: "unknown parameter compared as is", parameter: "fsync", a: "on", b: "true", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := PostgresParameterEqual(tt.parameter, tt.a, tt.b); got != tt.want {
+				t.Errorf("PostgresParameterEqual(%q, %q, %q) = %v, want %v", tt.parameter, tt.a, tt.b, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,335 @@
+package kubeproject
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"maps"
+	"slices"
+
+	"baas-api/internal/utils"
+
+	"github.com/samber/lo"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// RealtimeStatus 是專案 Realtime 元件的狀態
+type RealtimeStatus struct {
+	Enabled       bool
+	ReadyReplicas int32
+}
+
+// realtimeSecretKeys 是 Realtime 使用的 API Secret key，PGRST_JWT_SECRET 與 REST API 共用以驗證專案的 JWT
+var realtimeSecretKeys = []string{
+	SecretKeyRealtimeSecretKeyBase,
+	SecretKeyRealtimeDBEncKey,
+	SecretKeyRealtimeAPIJWTSecret,
+	SecretKeyPGRSTJWTSecret,
+}
+
+// FindRealtime 回傳專案 Realtime 的狀態，Deployment 存在即視為已啟用
+func (s *service) FindRealtime(ctx context.Context, ref string) (*RealtimeStatus, error) {
+	deploymentName := s.GetRealtimeDeploymentName(ref)
+	deployment, err := s.clientset.AppsV1().Deployments(s.GetProjectNamespace(ref)).Get(ctx, deploymentName, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return &RealtimeStatus{}, nil
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to get realtime deployment", "error", err, "deployment", deploymentName)
+		return nil, errors.New("failed to get realtime deployment")
+	}
+	return &RealtimeStatus{Enabled: true, ReadyReplicas: deployment.Status.ReadyReplicas}, nil
+}
+
+// ApplyRealtime 建立或更新專案的 Realtime Deployment、Service 與路由 (/api/realtime)。
+//
+// Realtime 以 app 角色經由 logical replication 讀取 supabase_realtime publication 的變更，
+// 並以專案的 JWKS 驗證連線的 JWT，postgres_changes 依 JWT 的角色套用 RLS。
+func (s *service) ApplyRealtime(ctx context.Context, ref string) error {
+	ownerRef, err := s.clusterOwnerReference(ctx, ref)
+	if err != nil {
+		return err
+	}
+	if err := s.applyRealtimeDeployment(ctx, ref, ownerRef); err != nil {
+		return err
+	}
+	if err := s.applyRealtimeService(ctx, ref, ownerRef); err != nil {
+		return err
+	}
+	return s.ingress.ApplyRealtimeRoute(ctx, ref, ownerRef)
+}
+
+// DeleteRealtime 移除專案的 Realtime，publication 保留在資料庫中
+func (s *service) DeleteRealtime(ctx context.Context, ref string) error {
+	if err := s.ingress.DeleteRealtimeRoute(ctx, ref); err != nil {
+		return err
+	}
+
+	namespace := s.GetProjectNamespace(ref)
+	err := s.clientset.CoreV1().Services(namespace).Delete(ctx, s.GetRealtimeServiceName(ref), metav1.DeleteOptions{})
+	if ignoreNotFound(err) != nil {
+		slog.ErrorContext(ctx, "Failed to delete realtime service", "error", err, "ref", ref)
+		return errors.New("failed to delete realtime service")
+	}
+
+	err = s.clientset.AppsV1().Deployments(namespace).Delete(ctx, s.GetRealtimeDeploymentName(ref), metav1.DeleteOptions{})
+	if ignoreNotFound(err) != nil {
+		slog.ErrorContext(ctx, "Failed to delete realtime deployment", "error", err, "ref", ref)
+		return errors.New("failed to delete realtime deployment")
+	}
+	return nil
+}
+
+// realtimeSecretValues 只產生 API Secret 中尚未存在的 Realtime 金鑰，重新套用時不會使既有連線失效
+func (s *service) realtimeSecretValues(ctx context.Context, ref string) (map[string]string, error) {
+	secret, err := s.clientset.CoreV1().Secrets(s.GetProjectNamespace(ref)).Get(ctx, s.GetAPISecretName(ref), metav1.GetOptions{})
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to get API secret", "error", err, "ref", ref)
+		return nil, errors.New("failed to get API secret")
+	}
+
+	// DB_ENC_KEY 用於 AES-128，必須是 16 個字元
+	sizes := map[string]int{
+		SecretKeyRealtimeSecretKeyBase: 64,
+		SecretKeyRealtimeDBEncKey:      16,
+		SecretKeyRealtimeAPIJWTSecret:  40,
+	}
+	values := map[string]string{}
+	for _, key := range slices.Sorted(maps.Keys(sizes)) {
+		if len(secret.Data[key]) == 0 {
+			values[key] = utils.GenerateNewPassword(sizes[key])
+		}
+	}
+	return values, nil
+}
+
+func (s *service) applyRealtimeDeployment(ctx context.Context, ref string, ownerRef *metav1.OwnerReference) error {
+	deploymentName := s.GetRealtimeDeploymentName(ref)
+	// CNPG 以 Cluster 名稱建立 app role 的 Secret
+	appSecretName := s.GetDatabaseRoleSecretName(ownerRef.Name, RoleApp)
+
+	values, err := s.realtimeSecretValues(ctx, ref)
+	if err != nil {
+		return err
+	}
+	secretData, err := s.applyAPISecret(ctx, ref, values, nil)
+	if err != nil {
+		return err
+	}
+
+	appSecretEnvVar := func(name string, key string) corev1.EnvVar {
+		return corev1.EnvVar{Name: name, ValueFrom: &corev1.EnvVarSource{
+			SecretKeyRef: &corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: appSecretName},
+				Key:                  key,
+			},
+		}}
+	}
+	jwksEnvVar := s.secretEnvVar(ref, SecretKeyPGRSTJWTSecret)
+	jwksEnvVar.Name = "API_JWT_JWKS"
+
+	deployment := &appsv1.Deployment{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "apps/v1",
+			Kind:       "Deployment",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            deploymentName,
+			Namespace:       s.GetProjectNamespace(ref),
+			Labels:          projectLabels(ref, RealtimeComponent),
+			OwnerReferences: []metav1.OwnerReference{*ownerRef},
+		},
+		// Realtime 以 replication slot 讀取變更，同一時間只能有一個 Pod
+		Spec: appsv1.DeploymentSpec{
+			Replicas: lo.ToPtr(int32(1)),
+			Strategy: appsv1.DeploymentStrategy{Type: appsv1.RecreateDeploymentStrategyType},
+			Selector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{
+					"app": deploymentName,
+				},
+			},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: lo.Assign(projectLabels(ref, RealtimeComponent), map[string]string{
+						"app": deploymentName,
+					}),
+					Annotations: map[string]string{
+						AnnotationSecretChecksum: secretChecksum(secretData, realtimeSecretKeys),
+					},
+				},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{
+							Name:  deploymentName,
+							Image: "supabase/realtime:v2.34.47",
+							Ports: []corev1.ContainerPort{{
+								ContainerPort: 4000,
+							}},
+							Env: []corev1.EnvVar{
+								{Name: "PORT", Value: "4000"},
+								{Name: "APP_NAME", Value: "realtime"},
+								{Name: "DB_HOST", Value: s.GetDatabaseRWServiceName(ownerRef.Name) + "." + s.GetProjectNamespace(ref)},
+								{Name: "DB_PORT", Value: "5432"},
+								{Name: "DB_NAME", Value: "app"},
+								appSecretEnvVar("DB_USER", "username"),
+								appSecretEnvVar("DB_PASSWORD", "password"),
+								{Name: "DB_AFTER_CONNECT_QUERY", Value: "SET search_path TO _realtime"},
+								s.secretEnvVar(ref, SecretKeyRealtimeDBEncKey),
+								s.secretEnvVar(ref, SecretKeyRealtimeSecretKeyBase),
+								s.secretEnvVar(ref, SecretKeyRealtimeAPIJWTSecret),
+								jwksEnvVar,
+								// tenant 由連線 host 的第一段 (<ref>.<domain>) 決定
+								{Name: "SEED_SELF_HOST", Value: "true"},
+								{Name: "SELF_HOST_TENANT_NAME", Value: ref},
+								{Name: "RUN_JANITOR", Value: "true"},
+								{Name: "ERL_AFLAGS", Value: "-proto_dist inet_tcp"},
+								{Name: "DNS_NODES", Value: "''"},
+								{Name: "RLIMIT_NOFILE", Value: "10000"},
+							},
+							ReadinessProbe: &corev1.Probe{
+								ProbeHandler: corev1.ProbeHandler{
+									TCPSocket: &corev1.TCPSocketAction{
+										Port: intstr.FromInt32(4000),
+									},
+								},
+								PeriodSeconds: 5,
+							},
+							LivenessProbe: &corev1.Probe{
+								ProbeHandler: corev1.ProbeHandler{
+									TCPSocket: &corev1.TCPSocketAction{
+										Port: intstr.FromInt32(4000),
+									},
+								},
+								InitialDelaySeconds: 10,
+								PeriodSeconds:       10,
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	data, err := json.Marshal(deployment)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to marshal realtime deployment", "error", err)
+		return errors.New("failed to marshal realtime deployment")
+	}
+
+	_, err = s.clientset.AppsV1().Deployments(s.GetProjectNamespace(ref)).Patch(
+		ctx,
+		deploymentName,
+		types.ApplyPatchType,
+		data,
+		applyPatchOptions(FieldManager),
+	)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to apply realtime deployment", "error", err, "ref", ref)
+		return errors.New("failed to apply realtime deployment")
+	}
+	return nil
+}
+
+func (s *service) applyRealtimeService(ctx context.Context, ref string, ownerRef *metav1.OwnerReference) error {
+	serviceName := s.GetRealtimeServiceName(ref)
+	service := &corev1.Service{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "v1",
+			Kind:       "Service",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            serviceName,
+			Namespace:       s.GetProjectNamespace(ref),
+			Labels:          projectLabels(ref, RealtimeComponent),
+			OwnerReferences: []metav1.OwnerReference{*ownerRef},
+		},
+		Spec: corev1.ServiceSpec{
+			Type: corev1.ServiceTypeClusterIP,
+			Selector: map[string]string{
+				"app": s.GetRealtimeDeploymentName(ref),
+			},
+			Ports: []corev1.ServicePort{
+				{
+					Name:       "http",
+					Port:       4000,
+					TargetPort: intstr.FromInt(4000),
+					Protocol:   corev1.ProtocolTCP,
+				},
+			},
+		},
+	}
+
+	data, err := json.Marshal(service)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to marshal realtime service", "error", err)
+		return errors.New("failed to marshal realtime service")
+	}
+
+	_, err = s.clientset.CoreV1().Services(s.GetProjectNamespace(ref)).Patch(
+		ctx,
+		serviceName,
+		types.ApplyPatchType,
+		data,
+		applyPatchOptions(FieldManager),
+	)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to apply realtime service", "error", err, "ref", ref)
+		return errors.New("failed to apply realtime service")
+	}
+	return nil
+}
+
+// rollRealtimeJWKS 在 JWKS 更新後更新 Realtime Pod 的 secret checksum，未啟用 Realtime 時不做任何事
+func (s *service) rollRealtimeJWKS(ctx context.Context, ref string, secretData map[string]string) error {
+	deploymentName := s.GetRealtimeDeploymentName(ref)
+	_, err := s.clientset.AppsV1().Deployments(s.GetProjectNamespace(ref)).Get(ctx, deploymentName, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to get realtime deployment", "error", err, "deployment", deploymentName)
+		return errors.New("failed to get realtime deployment")
+	}
+
+	payload := map[string]any{
+		"apiVersion": "apps/v1",
+		"kind":       "Deployment",
+		"metadata": map[string]any{
+			"name":      deploymentName,
+			"namespace": s.GetProjectNamespace(ref),
+		},
+		"spec": map[string]any{
+			"template": map[string]any{
+				"metadata": map[string]any{
+					"annotations": map[string]string{
+						AnnotationSecretChecksum: secretChecksum(secretData, realtimeSecretKeys),
+					},
+				},
+			},
+		},
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to marshal realtime JWKS patch", "error", err)
+		return errors.New("failed to marshal realtime JWKS patch")
+	}
+
+	_, err = s.clientset.AppsV1().Deployments(s.GetProjectNamespace(ref)).Patch(
+		ctx,
+		deploymentName,
+		types.ApplyPatchType,
+		data,
+		applyPatchOptions(JWKSFieldManager),
+	)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to roll realtime deployment", "error", err, "deployment", deploymentName)
+		return errors.New("failed to roll realtime deployment")
+	}
+	return nil
+}
@@ -2,13 +2,17 @@ package kubeproject
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"log/slog"
 
 	"github.com/samber/lo"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
 func (s *service) CreateRESTAPIDeployment(ctx context.Context, ref string, jwks string) error {
@@ -19,13 +23,30 @@ func (s *service) CreateRESTAPIDeployment(ctx context.Context, ref string, jwks
 	restURL := s.GetRESTAPIURL(ref)
 	scalarConfig := s.GenerateScalarAPIConfig(restURL)
 
+	ownerRef, err := s.clusterOwnerReference(ctx, ref)
+	if err != nil {
+		return err
+	}
+
+	// JWKS 放在專案的 API Secret 中，PGRST_JWT_SECRET 以 secretKeyRef 引用
+	secretData, err := s.applyAPISecret(ctx, ref, map[string]string{SecretKeyPGRSTJWTSecret: jwks}, nil)
+	if err != nil {
+		return err
+	}
+
 	deployment := &appsv1.Deployment{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "apps/v1",
+			Kind:       "Deployment",
+		},
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      deploymentName,
-			Namespace: s.namespace,
+			Name:            deploymentName,
+			Namespace:       s.GetProjectNamespace(ref),
+			Labels:          projectLabels(ref, RestAPIComponent),
+			OwnerReferences: []metav1.OwnerReference{*ownerRef},
 		},
+		// Replicas 與 pgrst container 的 resources 由 ApplyAPIWorkload 管理
 		Spec: appsv1.DeploymentSpec{
-			Replicas: lo.ToPtr(int32(1)),
 			Selector: &metav1.LabelSelector{
 				MatchLabels: map[string]string{
 					"app": deploymentName,
@@ -33,8 +54,11 @@ func (s *service) CreateRESTAPIDeployment(ctx context.Context, ref string, jwks
 			},
 			Template: corev1.PodTemplateSpec{
 				ObjectMeta: metav1.ObjectMeta{
-					Labels: map[string]string{
+					Labels: lo.Assign(projectLabels(ref, RestAPIComponent), map[string]string{
 						"app": deploymentName,
+					}),
+					Annotations: map[string]string{
+						AnnotationSecretChecksum: secretChecksum(secretData, []string{SecretKeyPGRSTJWTSecret}),
 					},
 				},
 				Spec: corev1.PodSpec{
@@ -42,16 +66,18 @@ func (s *service) CreateRESTAPIDeployment(ctx context.Context, ref string, jwks
 						{
 							Name:  pgrstContainerName,
 							Image: "postgrest/postgrest:v14.2",
-							Ports: []corev1.ContainerPort{{
-								ContainerPort: 3000,
-							}},
+							Ports: []corev1.ContainerPort{
+								{ContainerPort: 3000},
+								{Name: "admin", ContainerPort: 3001},
+							},
 							Env: []corev1.EnvVar{
 								{Name: "PGRST_SERVER_PORT", Value: "3000"},
+								{Name: "PGRST_ADMIN_SERVER_PORT", Value: "3001"},
 								{Name: "PGRST_DB_SCHEMA", Value: "api"},
 								{Name: "PGRST_DB_ANON_ROLE", Value: "anon"},
 								{Name: "PGRST_OPENAPI_SECURITY_ACTIVE", Value: "true"},
 								{Name: "PGRST_OPENAPI_SERVER_PROXY_URI", Value: restURL},
-								{Name: "PGRST_JWT_SECRET", Value: jwks},
+								s.secretEnvVar(ref, SecretKeyPGRSTJWTSecret),
 								{Name: "PGRST_DB_URI", ValueFrom: &corev1.EnvVarSource{
 									SecretKeyRef: &corev1.SecretKeySelector{
 										Key: "uri",
@@ -61,6 +87,28 @@ func (s *service) CreateRESTAPIDeployment(ctx context.Context, ref string, jwks
 									},
 								}},
 							},
+							// PostgREST admin server 提供 /ready (含 schema cache 載入) 與 /live
+							ReadinessProbe: &corev1.Probe{
+								ProbeHandler: corev1.ProbeHandler{
+									HTTPGet: &corev1.HTTPGetAction{
+										Path: "/ready",
+										Port: intstr.FromString("admin"),
+									},
+								},
+								PeriodSeconds:    5,
+								FailureThreshold: 3,
+							},
+							LivenessProbe: &corev1.Probe{
+								ProbeHandler: corev1.ProbeHandler{
+									HTTPGet: &corev1.HTTPGetAction{
+										Path: "/live",
+										Port: intstr.FromString("admin"),
+									},
+								},
+								InitialDelaySeconds: 5,
+								PeriodSeconds:       10,
+								FailureThreshold:    3,
+							},
 						},
 						{
 							Name:  openapiContainerName,
@@ -74,6 +122,25 @@ func (s *service) CreateRESTAPIDeployment(ctx context.Context, ref string, jwks
 									Value: scalarConfig,
 								},
 							},
+							// API reference 是靜態頁面，使用固定的小額資源
+							Resources: corev1.ResourceRequirements{
+								Requests: corev1.ResourceList{
+									corev1.ResourceCPU:    resource.MustParse("10m"),
+									corev1.ResourceMemory: resource.MustParse("32Mi"),
+								},
+								Limits: corev1.ResourceList{
+									corev1.ResourceCPU:    resource.MustParse("100m"),
+									corev1.ResourceMemory: resource.MustParse("128Mi"),
+								},
+							},
+							ReadinessProbe: &corev1.Probe{
+								ProbeHandler: corev1.ProbeHandler{
+									TCPSocket: &corev1.TCPSocketAction{
+										Port: intstr.FromInt32(8080),
+									},
+								},
+								PeriodSeconds: 10,
+							},
 						},
 					},
 				},
@@ -81,7 +148,19 @@ func (s *service) CreateRESTAPIDeployment(ctx context.Context, ref string, jwks
 		},
 	}
 
-	_, err := s.clientset.AppsV1().Deployments(s.namespace).Create(ctx, deployment, metav1.CreateOptions{})
+	data, err := json.Marshal(deployment)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to marshal REST API deployment", "error", err)
+		return errors.New("failed to marshal REST API deployment")
+	}
+
+	_, err = s.clientset.AppsV1().Deployments(s.GetProjectNamespace(ref)).Patch(
+		ctx,
+		deploymentName,
+		types.ApplyPatchType,
+		data,
+		applyPatchOptions(FieldManager),
+	)
 	if err != nil {
 		slog.ErrorContext(ctx, "Failed to create REST API deployment", "error", err)
 		return errors.New("failed to create REST API deployment")
@@ -94,8 +173,8 @@ func (s *service) DeleteRESTAPIDeployment(ctx context.Context, ref string) error
 	deploymentName := s.GetRESTAPIDeploymentName(ref)
 
 	// Delete the deployment
-	err := s.clientset.AppsV1().Deployments(s.namespace).Delete(ctx, deploymentName, metav1.DeleteOptions{})
-	if err != nil {
+	err := s.clientset.AppsV1().Deployments(s.GetProjectNamespace(ref)).Delete(ctx, deploymentName, metav1.DeleteOptions{})
+	if ignoreNotFound(err) != nil {
 		slog.ErrorContext(ctx, "Failed to delete API deployment", "error", err)
 		return errors.New("failed to delete API deployment")
 	}
@@ -0,0 +1,114 @@
+package kubeproject
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+
+	appsv1 "k8s.io/api/apps/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// FindRESTAPIJWKS 回傳 PostgREST 驗證 JWT 使用的公鑰 (單一 JWK 或 JWKS)
+func (s *service) FindRESTAPIJWKS(ctx context.Context, ref string) (string, error) {
+	secret, err := s.clientset.CoreV1().Secrets(s.GetProjectNamespace(ref)).Get(ctx, s.GetAPISecretName(ref), metav1.GetOptions{})
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to get API secret", "error", err, "ref", ref)
+		return "", errors.New("failed to get API secret")
+	}
+	return string(secret.Data[SecretKeyPGRSTJWTSecret]), nil
+}
+
+// ApplyRESTAPIJWKS 更新 PGRST_JWT_SECRET，並更新 REST API 與 Realtime Pod 的 secret checksum 觸發 rolling update
+func (s *service) ApplyRESTAPIJWKS(ctx context.Context, ref string, jwks string) error {
+	deploymentName := s.GetRESTAPIDeploymentName(ref)
+	secretData, err := s.applyAPISecret(ctx, ref, map[string]string{SecretKeyPGRSTJWTSecret: jwks}, nil)
+	if err != nil {
+		return err
+	}
+
+	payload := map[string]any{
+		"apiVersion": "apps/v1",
+		"kind":       "Deployment",
+		"metadata": map[string]any{
+			"name":      deploymentName,
+			"namespace": s.GetProjectNamespace(ref),
+		},
+		"spec": map[string]any{
+			"template": map[string]any{
+				"metadata": map[string]any{
+					"annotations": map[string]string{
+						AnnotationSecretChecksum: secretChecksum(secretData, []string{SecretKeyPGRSTJWTSecret}),
+					},
+				},
+			},
+		},
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to marshal REST API JWKS patch", "error", err)
+		return errors.New("failed to marshal REST API JWKS patch")
+	}
+
+	_, err = s.clientset.AppsV1().Deployments(s.GetProjectNamespace(ref)).Patch(
+		ctx,
+		deploymentName,
+		types.ApplyPatchType,
+		data,
+		applyPatchOptions(JWKSFieldManager),
+	)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to roll REST API deployment", "error", err, "deployment", deploymentName)
+		return errors.New("failed to roll REST API deployment")
+	}
+	return s.rollRealtimeJWKS(ctx, ref, secretData)
+}
+
+// WaitRESTAPIRollout 等待 REST API Deployment 的所有 Pod 都已更新並可用，變更由共用的 informer 通知
+func (s *service) WaitRESTAPIRollout(ctx context.Context, ref string) error {
+	deploymentName := s.GetRESTAPIDeploymentName(ref)
+	changes, err := s.WatchProject(ctx, ref)
+	if err != nil {
+		return err
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case _, ok := <-changes:
+			if !ok {
+				return ctx.Err()
+			}
+		}
+
+		deployment, err := s.clientset.AppsV1().Deployments(s.GetProjectNamespace(ref)).Get(ctx, deploymentName, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			return ErrRESTAPIDeploymentNotFound
+		}
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed to get REST API deployment", "error", err, "deployment", deploymentName)
+			return errors.New("failed to get REST API deployment")
+		}
+		if isRolledOut(deployment) {
+			return nil
+		}
+	}
+}
+
+// isRolledOut 與 kubectl rollout status 的判斷相同：新的 ReplicaSet 已取代所有舊的 Pod 且都可用
+func isRolledOut(deployment *appsv1.Deployment) bool {
+	if deployment.Generation > deployment.Status.ObservedGeneration {
+		return false
+	}
+	replicas := int32(1)
+	if deployment.Spec.Replicas != nil {
+		replicas = *deployment.Spec.Replicas
+	}
+	status := deployment.Status
+	return status.UpdatedReplicas >= replicas &&
+		status.Replicas <= status.UpdatedReplicas &&
+		status.AvailableReplicas >= status.UpdatedReplicas
+}
@@ -2,21 +2,34 @@ package kubeproject
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"log/slog"
 
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
 func (s *service) CreateRESTAPIService(ctx context.Context, ref string) error {
 	serviceName := s.GetRESTAPIServiceName(ref)
 	deploymentName := s.GetRESTAPIDeploymentName(ref)
+	ownerRef, err := s.clusterOwnerReference(ctx, ref)
+	if err != nil {
+		return err
+	}
+
 	service := &corev1.Service{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "v1",
+			Kind:       "Service",
+		},
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      serviceName,
-			Namespace: s.namespace,
+			Name:            serviceName,
+			Namespace:       s.GetProjectNamespace(ref),
+			Labels:          projectLabels(ref, RestAPIComponent),
+			OwnerReferences: []metav1.OwnerReference{*ownerRef},
 		},
 		Spec: corev1.ServiceSpec{
 			Type: corev1.ServiceTypeClusterIP,
@@ -40,7 +53,19 @@ func (s *service) CreateRESTAPIService(ctx context.Context, ref string) error {
 		},
 	}
 
-	_, err := s.clientset.CoreV1().Services(s.namespace).Create(ctx, service, metav1.CreateOptions{})
+	data, err := json.Marshal(service)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to marshal REST(pgrst) API service", "error", err)
+		return errors.New("failed to marshal REST(pgrst) API service")
+	}
+
+	_, err = s.clientset.CoreV1().Services(s.GetProjectNamespace(ref)).Patch(
+		ctx,
+		serviceName,
+		types.ApplyPatchType,
+		data,
+		applyPatchOptions(FieldManager),
+	)
 	if err != nil {
 		slog.ErrorContext(ctx, "Failed to create REST(pgrst) API service", "error", err)
 		return errors.New("failed to create REST(pgrst) API service")
@@ -52,8 +77,8 @@ func (s *service) CreateRESTAPIService(ctx context.Context, ref string) error {
 func (s *service) DeleteRESTAPIService(ctx context.Context, ref string) error {
 	serviceName := s.GetRESTAPIServiceName(ref)
 
-	err := s.clientset.CoreV1().Services(s.namespace).Delete(ctx, serviceName, metav1.DeleteOptions{})
-	if err != nil {
+	err := s.clientset.CoreV1().Services(s.GetProjectNamespace(ref)).Delete(ctx, serviceName, metav1.DeleteOptions{})
+	if ignoreNotFound(err) != nil {
 		slog.ErrorContext(ctx, "Failed to delete REST(pgrst) API service", "error", err)
 		return errors.New("failed to delete REST(pgrst) API service")
 	}
@@ -0,0 +1,273 @@
+package kubeproject
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// PostgREST 的 OpenAPI 模式 (openapi-mode)
+const (
+	OpenAPIModeFollowPrivileges = "follow-privileges"
+	OpenAPIModeIgnorePrivileges = "ignore-privileges"
+	OpenAPIModeDisabled         = "disabled"
+)
+
+// AnnotationDBSchemas 記錄 REST API 公開的 schema；PostgREST 無法接收 NOTIFY 時 (transaction pooler)
+// 以此觸發 rolling update 重新載入
+const AnnotationDBSchemas = "baas.wke/db-schemas"
+
+// restSettingsKey 是 REST API 設定在 ConfigMap 中的 key
+const restSettingsKey = "settings.json"
+
+// RESTAPISettings 是專案可調整的 PostgREST 設定。
+//
+// Schemas 以 in-database configuration (authenticator 角色的 pgrst.db_schemas) 設定，變更時只需 NOTIFY；
+// 其他欄位是 pgrst container 的環境變數，變更時 Deployment 會 rolling update。
+type RESTAPISettings struct {
+	// Schemas 是公開的 schema (db-schemas)，第一個是預設的 schema
+	Schemas []string `json:"schemas"`
+	// MaxRows 是每次回應的最大筆數 (db-max-rows)，nil 表示不限制
+	MaxRows *int32 `json:"maxRows,omitempty"`
+	// PreRequest 是每個請求前呼叫的函式 (db-pre-request)
+	PreRequest string `json:"preRequest,omitempty"`
+	// PoolSize 是連線池大小 (db-pool)
+	PoolSize int32 `json:"poolSize"`
+	// PoolAcquisitionTimeout 是等待連線池的秒數 (db-pool-acquisition-timeout)
+	PoolAcquisitionTimeout int32 `json:"poolAcquisitionTimeout"`
+	// JWTAudience 是 JWT 必須包含的 aud (jwt-aud)
+	JWTAudience string `json:"jwtAudience,omitempty"`
+	// OpenAPIMode 是 OpenAPI 輸出的模式 (openapi-mode)
+	OpenAPIMode string `json:"openApiMode"`
+}
+
+// DefaultRESTAPISettings 是尚未調整過的專案使用的設定，與 CreateRESTAPIDeployment 一致
+func DefaultRESTAPISettings() RESTAPISettings {
+	return RESTAPISettings{
+		Schemas:                []string{"api"},
+		PoolSize:               10,
+		PoolAcquisitionTimeout: 10,
+		OpenAPIMode:            OpenAPIModeFollowPrivileges,
+	}
+}
+
+var (
+	identifierRegex    = regexp.MustCompile(`^[a-z_][a-z0-9_]{0,62}$`)
+	functionNameRegex  = regexp.MustCompile(`^([a-z_][a-z0-9_]{0,62}\.)?[a-z_][a-z0-9_]{0,62}$`)
+	reservedSchemas    = []string{"information_schema", "auth", "baas_migrations"}
+	maxExposedSchemas  = 10
+	maxJWTAudienceSize = 255
+)
+
+// ValidateRESTAPISettings 檢查 REST API 設定是否合法
+func ValidateRESTAPISettings(settings RESTAPISettings) error {
+	if len(settings.Schemas) == 0 || len(settings.Schemas) > maxExposedSchemas {
+		return fmt.Errorf("between 1 and %d schemas must be exposed", maxExposedSchemas)
+	}
+	for i, schema := range settings.Schemas {
+		if !identifierRegex.MatchString(schema) {
+			return fmt.Errorf("invalid schema name %q", schema)
+		}
+		// auth 保存簽章金鑰等資料，不可公開
+		if strings.HasPrefix(schema, "pg_") || slices.Contains(reservedSchemas, schema) {
+			return fmt.Errorf("schema %q cannot be exposed", schema)
+		}
+		if slices.Contains(settings.Schemas[:i], schema) {
+			return fmt.Errorf("schema %q is listed more than once", schema)
+		}
+	}
+	if settings.MaxRows != nil && (*settings.MaxRows < 1 || *settings.MaxRows > 1000000) {
+		return errors.New("max rows must be between 1 and 1000000")
+	}
+	if settings.PreRequest != "" && !functionNameRegex.MatchString(settings.PreRequest) {
+		return errors.New("pre-request must be a function name, optionally schema-qualified")
+	}
+	if settings.PoolSize < 1 || settings.PoolSize > 100 {
+		return errors.New("pool size must be between 1 and 100")
+	}
+	if settings.PoolAcquisitionTimeout < 1 || settings.PoolAcquisitionTimeout > 60 {
+		return errors.New("pool acquisition timeout must be between 1 and 60 seconds")
+	}
+	if len(settings.JWTAudience) > maxJWTAudienceSize || strings.ContainsFunc(settings.JWTAudience, func(r rune) bool { return r < 0x20 || r == 0x7f }) {
+		return fmt.Errorf("JWT audience must be at most %d printable characters", maxJWTAudienceSize)
+	}
+	switch settings.OpenAPIMode {
+	case OpenAPIModeFollowPrivileges, OpenAPIModeIgnorePrivileges, OpenAPIModeDisabled:
+	default:
+		return errors.New("OpenAPI mode must be follow-privileges, ignore-privileges or disabled")
+	}
+	return nil
+}
+
+// FindRESTAPISettings 回傳專案的 REST API 設定，尚未調整過時回傳預設值
+func (s *service) FindRESTAPISettings(ctx context.Context, ref string) (*RESTAPISettings, error) {
+	configMap, err := s.clientset.CoreV1().ConfigMaps(s.GetProjectNamespace(ref)).Get(ctx, s.GetRESTAPISettingsConfigMapName(ref), metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		settings := DefaultRESTAPISettings()
+		return &settings, nil
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to get REST API settings", "error", err, "ref", ref)
+		return nil, errors.New("failed to get REST API settings")
+	}
+
+	settings := DefaultRESTAPISettings()
+	if err := json.Unmarshal([]byte(configMap.Data[restSettingsKey]), &settings); err != nil {
+		slog.ErrorContext(ctx, "Failed to parse REST API settings", "error", err, "ref", ref)
+		return nil, errors.New("failed to parse REST API settings")
+	}
+	return &settings, nil
+}
+
+// ApplyRESTAPISettings 保存 REST API 設定並套用 pgrst container 的環境變數。
+//
+// 環境變數沒有改變時 Pod template 不變，不會 rolling update；Schemas 須由呼叫端先寫入資料庫並 NOTIFY。
+func (s *service) ApplyRESTAPISettings(ctx context.Context, ref string, settings RESTAPISettings) error {
+	if err := ValidateRESTAPISettings(settings); err != nil {
+		slog.ErrorContext(ctx, "Invalid REST API settings", "error", err, "ref", ref)
+		return err
+	}
+	if err := s.saveRESTAPISettings(ctx, ref, settings); err != nil {
+		return err
+	}
+
+	deploymentName := s.GetRESTAPIDeploymentName(ref)
+	namespace := s.GetProjectNamespace(ref)
+	deployment, err := s.clientset.AppsV1().Deployments(namespace).Get(ctx, deploymentName, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return ErrRESTAPIDeploymentNotFound
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to get REST API deployment", "error", err, "deployment", deploymentName)
+		return errors.New("failed to get REST API deployment")
+	}
+
+	envVars := []corev1.EnvVar{
+		{Name: "PGRST_DB_POOL", Value: strconv.Itoa(int(settings.PoolSize))},
+		{Name: "PGRST_DB_POOL_ACQUISITION_TIMEOUT", Value: strconv.Itoa(int(settings.PoolAcquisitionTimeout))},
+		{Name: "PGRST_OPENAPI_MODE", Value: settings.OpenAPIMode},
+	}
+	if settings.MaxRows != nil {
+		envVars = append(envVars, corev1.EnvVar{Name: "PGRST_DB_MAX_ROWS", Value: strconv.Itoa(int(*settings.MaxRows))})
+	}
+	if settings.PreRequest != "" {
+		envVars = append(envVars, corev1.EnvVar{Name: "PGRST_DB_PRE_REQUEST", Value: settings.PreRequest})
+	}
+	if settings.JWTAudience != "" {
+		envVars = append(envVars, corev1.EnvVar{Name: "PGRST_JWT_AUD", Value: settings.JWTAudience})
+	}
+
+	template := map[string]any{
+		"spec": map[string]any{
+			"containers": []map[string]any{
+				{
+					"name": s.GetRESTAPIContainerName(ref, PGRSTComponent),
+					"env":  envVars,
+				},
+			},
+		},
+	}
+	if !restAPIChannelEnabled(deployment.Spec.Template.Spec.Containers, s.GetRESTAPIContainerName(ref, PGRSTComponent)) {
+		template["metadata"] = map[string]any{
+			"annotations": map[string]string{
+				AnnotationDBSchemas: strings.Join(settings.Schemas, ","),
+			},
+		}
+	}
+
+	// 未送出的 env 會被移除，因此每次都宣告所有由此 field manager 管理的環境變數
+	payload := map[string]any{
+		"apiVersion": "apps/v1",
+		"kind":       "Deployment",
+		"metadata": map[string]any{
+			"name":      deploymentName,
+			"namespace": namespace,
+		},
+		"spec": map[string]any{
+			"template": template,
+		},
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to marshal REST API settings patch", "error", err)
+		return errors.New("failed to marshal REST API settings patch")
+	}
+
+	_, err = s.clientset.AppsV1().Deployments(namespace).Patch(
+		ctx,
+		deploymentName,
+		types.ApplyPatchType,
+		data,
+		applyPatchOptions(RESTSettingsFieldManager),
+	)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to apply REST API settings", "error", err, "deployment", deploymentName)
+		return errors.New("failed to apply REST API settings")
+	}
+	return nil
+}
+
+// restAPIChannelEnabled 回傳 PostgREST 是否監聽 NOTIFY (經由 transaction pooler 連線時關閉)
+func restAPIChannelEnabled(containers []corev1.Container, containerName string) bool {
+	for _, container := range containers {
+		if container.Name != containerName {
+			continue
+		}
+		for _, env := range container.Env {
+			if env.Name == "PGRST_DB_CHANNEL_ENABLED" {
+				return env.Value != "false"
+			}
+		}
+	}
+	return true
+}
+
+// saveRESTAPISettings 將設定保存在 ConfigMap，不設定 ownerReference，以備份還原切換 Cluster 時不會被移除
+func (s *service) saveRESTAPISettings(ctx context.Context, ref string, settings RESTAPISettings) error {
+	value, err := json.Marshal(settings)
+	if err != nil {
+		return err
+	}
+
+	name := s.GetRESTAPISettingsConfigMapName(ref)
+	configMap := &corev1.ConfigMap{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "v1",
+			Kind:       "ConfigMap",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: s.GetProjectNamespace(ref),
+			Labels:    projectLabels(ref, RESTSettingsComponent),
+		},
+		Data: map[string]string{restSettingsKey: string(value)},
+	}
+	payload, err := json.Marshal(configMap)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to marshal REST API settings", "error", err, "ref", ref)
+		return errors.New("failed to marshal REST API settings")
+	}
+	_, err = s.clientset.CoreV1().ConfigMaps(s.GetProjectNamespace(ref)).Patch(
+		ctx,
+		name,
+		types.ApplyPatchType,
+		payload,
+		applyPatchOptions(FieldManager),
+	)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to save REST API settings", "error", err, "ref", ref)
+		return errors.New("failed to save REST API settings")
+	}
+	return nil
+}
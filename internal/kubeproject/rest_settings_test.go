@@ -0,0 +1,76 @@
+package kubeproject
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/samber/lo"
+)
+
+func TestValidateRESTAPISettings(t *testing.T) {
+	with := func(modify func(*RESTAPISettings)) RESTAPISettings {
+		settings := DefaultRESTAPISettings()
+		modify(&settings)
+		return settings
+	}
+	manySchemas := make([]string, maxExposedSchemas+1)
+	for i := range manySchemas {
+		manySchemas[i] = fmt.Sprintf("s%d", i)
+	}
+
+	tests := []struct {
+		name     string
+		settings RESTAPISettings
+		wantErr  string
+	}{
+		{name: "default", settings: DefaultRESTAPISettings()},
+		{
+			name: "all fields",
+			settings: RESTAPISettings{
+				Schemas:                []string{"api", "public", "_private"},
+				MaxRows:                lo.ToPtr[int32](1000),
+				PreRequest:             "api.check_request",
+				PoolSize:               100,
+				PoolAcquisitionTimeout: 60,
+				JWTAudience:            "https://example.com",
+				OpenAPIMode:            OpenAPIModeDisabled,
+			},
+		},
+		{name: "unqualified pre-request", settings: with(func(s *RESTAPISettings) { s.PreRequest = "check_request" })},
+		{name: "max schemas", settings: with(func(s *RESTAPISettings) { s.Schemas = manySchemas[:maxExposedSchemas] })},
+
+		{name: "no schemas", settings: with(func(s *RESTAPISettings) { s.Schemas = nil }), wantErr: "schemas must be exposed"},
+		{name: "too many schemas", settings: with(func(s *RESTAPISettings) { s.Schemas = manySchemas }), wantErr: "schemas must be exposed"},
+		{name: "upper case schema", settings: with(func(s *RESTAPISettings) { s.Schemas = []string{"API"} }), wantErr: "invalid schema name"},
+		{name: "quoted schema", settings: with(func(s *RESTAPISettings) { s.Schemas = []string{`api", "auth`} }), wantErr: "invalid schema name"},
+		{name: "auth schema", settings: with(func(s *RESTAPISettings) { s.Schemas = []string{"api", "auth"} }), wantErr: `schema "auth" cannot be exposed`},
+		{name: "migrations schema", settings: with(func(s *RESTAPISettings) { s.Schemas = []string{"baas_migrations"} }), wantErr: "cannot be exposed"},
+		{name: "information schema", settings: with(func(s *RESTAPISettings) { s.Schemas = []string{"information_schema"} }), wantErr: "cannot be exposed"},
+		{name: "system schema", settings: with(func(s *RESTAPISettings) { s.Schemas = []string{"pg_catalog"} }), wantErr: "cannot be exposed"},
+		{name: "duplicate schema", settings: with(func(s *RESTAPISettings) { s.Schemas = []string{"api", "public", "api"} }), wantErr: "listed more than once"},
+		{name: "zero max rows", settings: with(func(s *RESTAPISettings) { s.MaxRows = lo.ToPtr[int32](0) }), wantErr: "max rows"},
+		{name: "too many max rows", settings: with(func(s *RESTAPISettings) { s.MaxRows = lo.ToPtr[int32](1000001) }), wantErr: "max rows"},
+		{name: "pre-request with call", settings: with(func(s *RESTAPISettings) { s.PreRequest = "api.check()" }), wantErr: "pre-request"},
+		{name: "pre-request with three parts", settings: with(func(s *RESTAPISettings) { s.PreRequest = "db.api.check" }), wantErr: "pre-request"},
+		{name: "zero pool size", settings: with(func(s *RESTAPISettings) { s.PoolSize = 0 }), wantErr: "pool size"},
+		{name: "large pool size", settings: with(func(s *RESTAPISettings) { s.PoolSize = 101 }), wantErr: "pool size"},
+		{name: "zero acquisition timeout", settings: with(func(s *RESTAPISettings) { s.PoolAcquisitionTimeout = 0 }), wantErr: "pool acquisition timeout"},
+		{name: "long acquisition timeout", settings: with(func(s *RESTAPISettings) { s.PoolAcquisitionTimeout = 61 }), wantErr: "pool acquisition timeout"},
+		{name: "long audience", settings: with(func(s *RESTAPISettings) { s.JWTAudience = strings.Repeat("a", maxJWTAudienceSize+1) }), wantErr: "JWT audience"},
+		{name: "audience with newline", settings: with(func(s *RESTAPISettings) { s.JWTAudience = "aud\nPGRST_DB_URI=x" }), wantErr: "JWT audience"},
+		{name: "empty OpenAPI mode", settings: with(func(s *RESTAPISettings) { s.OpenAPIMode = "" }), wantErr: "OpenAPI mode"},
+		{name: "unknown OpenAPI mode", settings: with(func(s *RESTAPISettings) { s.OpenAPIMode = "public" }), wantErr: "OpenAPI mode"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateRESTAPISettings(tt.settings)
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("ValidateRESTAPISettings: %v", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Errorf("ValidateRESTAPISettings error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,413 @@
+package kubeproject
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"baas-api/internal/config"
+
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	utilrand "k8s.io/apimachinery/pkg/util/rand"
+)
+
+// ClusterRecoveryOption 指定從哪一份備份還原 cluster
+type ClusterRecoveryOption struct {
+	// SourceRef 是備份所屬的專案
+	SourceRef string
+	// ServerName 是備份在 object store 中的 serverName
+	ServerName string
+	// BackupID 指定要還原的 base backup，空白時使用最新的備份並重播所有 WAL
+	BackupID string
+	// TargetTime 指定 point-in-time recovery 的目標時間，CNPG 會自動選擇之前最近的 base backup
+	TargetTime *time.Time
+}
+
+// recoveryExternalClusterName 是 bootstrap.recovery 參照的 externalClusters 名稱
+const recoveryExternalClusterName = "origin"
+
+func (s *service) setClusterRecovery(cluster *unstructured.Unstructured, ref string, opt *ClusterRecoveryOption) error {
+	recovery := map[string]any{
+		"source":   recoveryExternalClusterName,
+		"database": "app",
+		"owner":    "app",
+	}
+	switch {
+	case opt.TargetTime != nil:
+		recovery["recoveryTarget"] = map[string]any{
+			"targetTime": opt.TargetTime.UTC().Format(time.RFC3339),
+		}
+	case opt.BackupID != "":
+		recovery["recoveryTarget"] = map[string]any{
+			"backupID": opt.BackupID,
+		}
+	}
+	if err := unstructured.SetNestedMap(cluster.Object, map[string]any{"recovery": recovery}, "spec", "bootstrap"); err != nil {
+		slog.Error("Failed to set recovery bootstrap in Postgres cluster spec", "error", err)
+		return ErrFailedToSetSpecRecovery
+	}
+
+	externalClusters := []any{
+		map[string]any{
+			"name":              recoveryExternalClusterName,
+			"barmanObjectStore": s.buildBarmanObjectStore(ref, opt.SourceRef, opt.ServerName),
+		},
+	}
+	if err := unstructured.SetNestedSlice(cluster.Object, externalClusters, "spec", "externalClusters"); err != nil {
+		slog.Error("Failed to set external clusters in Postgres cluster spec", "error", err)
+		return ErrFailedToSetSpecRecovery
+	}
+	return nil
+}
+
+// RestoreCluster 在新的 Cluster 以備份還原專案資料庫，新的 Cluster 健康後才切換專案使用的 Cluster。
+//
+// 還原期間原本的 Cluster 持續提供服務，新的 Cluster 無法進入 healthy 狀態時會被移除；
+// 切換時將資料庫路由、authenticator Secret 與參照 Cluster 的資源改為新的 Cluster，最後才刪除舊的 Cluster。
+// 完成後需呼叫 RestartAPIDeployments 讓 API 重新讀取 Secret。
+func (s *service) RestoreCluster(ctx context.Context, ref string, opt *ClusterRecoveryOption) error {
+	if !s.config.Kube.Project.Backup.Enabled {
+		return ErrBackupNotEnabled
+	}
+
+	current, err := s.getDatabaseCluster(ctx, ref)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to get postgres cluster", "error", err, "ref", ref)
+		return errors.New("failed to get postgres cluster")
+	}
+	storageSize, _, _ := unstructured.NestedString(current.Object, "spec", "storage", "size")
+	instances, err := s.FindDatabaseCluster(ctx, ref)
+	if err != nil {
+		return err
+	}
+	parameters, err := s.FindPostgresParameters(ctx, ref)
+	if err != nil {
+		return err
+	}
+
+	name := newRestoredClusterName(ref)
+	err = s.CreateCluster(ctx, ref, &ClusterOption{
+		Name:             name,
+		Component:        DBRestoreComponent,
+		StorageSize:      lo.CoalesceOrEmpty(storageSize, "1Gi"),
+		BackupServerName: newBackupServerName(ref),
+		Recovery:         opt,
+	})
+	if err != nil {
+		return err
+	}
+
+	err = s.prepareRestoredCluster(ctx, ref, name, parameters, instances.DatabaseClusterConfig)
+	if err != nil {
+		// 還原逾時也要移除新的 Cluster，原本的 Cluster 不受影響
+		if err := s.deleteNamedCluster(context.WithoutCancel(ctx), ref, name); err != nil {
+			slog.ErrorContext(ctx, "Failed to remove restored postgres cluster", "error", err, "ref", ref, "cluster", name)
+		}
+		return err
+	}
+
+	return s.switchCluster(ctx, ref, current.GetName(), name, instances.Instances > 1)
+}
+
+// prepareRestoredCluster 套用原本 Cluster 的參數與 instance 設定並等待新的 Cluster 進入 healthy 狀態
+func (s *service) prepareRestoredCluster(ctx context.Context, ref string, name string, parameters map[string]string, cluster config.DatabaseClusterConfig) error {
+	if err := s.applyPostgresParameters(ctx, ref, name, parameters); err != nil {
+		return err
+	}
+	// standby 會在 primary 還原完成後由 CNPG 從 primary 複製
+	if err := s.applyClusterInstances(ctx, ref, name, cluster); err != nil {
+		return err
+	}
+	return s.waitNamedClusterHealthy(ctx, ref, name)
+}
+
+// switchCluster 將專案使用的 Cluster 從 from 切換為 to，完成後刪除 from
+func (s *service) switchCluster(ctx context.Context, ref string, from string, to string, readOnly bool) error {
+	namespace := s.GetProjectNamespace(ref)
+
+	// 服務中的 Cluster 以 DBComponent label 識別，之後的操作都會使用新的 Cluster
+	data, err := json.Marshal(map[string]any{
+		"metadata": map[string]any{"labels": map[string]string{LabelComponent: DBComponent}},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = s.dynamicClient.Resource(clusterGVR).Namespace(namespace).
+		Patch(ctx, to, types.MergePatchType, data, metav1.PatchOptions{FieldManager: FieldManager})
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to label restored postgres cluster", "error", err, "ref", ref, "cluster", to)
+		return errors.New("failed to label restored postgres cluster")
+	}
+
+	ownerRef, err := s.namedClusterOwnerReference(ctx, ref, to)
+	if err != nil {
+		return err
+	}
+	if err := s.applyPrimaryDBRoute(ctx, ref, ownerRef); err != nil {
+		return err
+	}
+	if err := s.applyReadOnlyDBRoute(ctx, ref, to, readOnly); err != nil {
+		return err
+	}
+
+	// authenticator 的密碼不變，連線字串改為新的 -rw service
+	password, err := s.FindDatabaseRolePassword(ctx, ref, RoleAuthenticator)
+	if err != nil {
+		return err
+	}
+	if err := s.applyDatabaseRoleSecret(ctx, s.buildDatabaseRoleSecret(ref, to, RoleAuthenticator, *password)); err != nil {
+		slog.ErrorContext(ctx, "Failed to update authenticator secret", "error", err, "ref", ref)
+		return errors.New("failed to update authenticator secret")
+	}
+
+	if err := s.repointClusterReferences(ctx, ref, from, to); err != nil {
+		return err
+	}
+	if err := s.repointDatabaseEnv(ctx, ref, from, to); err != nil {
+		return err
+	}
+	if err := s.replaceClusterOwnerReference(ctx, ref, from, ownerRef); err != nil {
+		return err
+	}
+
+	return s.deleteNamedCluster(ctx, ref, from)
+}
+
+// deleteNamedCluster 刪除專案 namespace 中指定名稱的 Cluster，CNPG 建立的 Pod、PVC 與 Secret 由 GC 清除
+func (s *service) deleteNamedCluster(ctx context.Context, ref string, name string) error {
+	err := s.dynamicClient.Resource(clusterGVR).
+		Namespace(s.GetProjectNamespace(ref)).
+		Delete(ctx, name, metav1.DeleteOptions{})
+	if ignoreNotFound(err) != nil {
+		slog.ErrorContext(ctx, "Failed to delete postgres cluster", "error", err, "ref", ref, "cluster", name)
+		return ErrFailedToDeletePostgresCluster
+	}
+	return nil
+}
+
+// clusterReferencingResources 是以 spec.cluster.name 參照 Cluster 的資源
+var clusterReferencingResources = []schema.GroupVersionResource{databaseGVR, poolerGVR, scheduledBackupGVR}
+
+// repointClusterReferences 將 Database、Pooler 與 ScheduledBackup 的 spec.cluster.name 從 from 改為 to。
+//
+// 已完成的 Backup 保留原本的 Cluster 名稱，作為還原來源的紀錄。
+func (s *service) repointClusterReferences(ctx context.Context, ref string, from string, to string) error {
+	namespace := s.GetProjectNamespace(ref)
+	data, err := json.Marshal(map[string]any{
+		"spec": map[string]any{"cluster": map[string]any{"name": to}},
+	})
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, gvr := range clusterReferencingResources {
+		client := s.dynamicClient.Resource(gvr).Namespace(namespace)
+		list, err := client.List(ctx, metav1.ListOptions{LabelSelector: projectSelector(ref)})
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed to list project resources", "resource", gvr.Resource, "error", err)
+			errs = append(errs, err)
+			continue
+		}
+		for _, item := range list.Items {
+			cluster, _, _ := unstructured.NestedString(item.Object, "spec", "cluster", "name")
+			if cluster != from {
+				continue
+			}
+			_, err := client.Patch(ctx, item.GetName(), types.MergePatchType, data, metav1.PatchOptions{FieldManager: FieldManager})
+			if ignoreNotFound(err) != nil {
+				slog.ErrorContext(ctx, "Failed to update cluster reference", "resource", gvr.Resource, "name", item.GetName(), "error", err)
+				errs = append(errs, err)
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		return errors.New("failed to update project cluster references")
+	}
+	return nil
+}
+
+// repointDatabaseEnv 將 Deployment 與 CronJob 中參照 from 的 app、superuser Secret 與 -rw service 的環境變數改為 to
+func (s *service) repointDatabaseEnv(ctx context.Context, ref string, from string, to string) error {
+	namespace := s.GetProjectNamespace(ref)
+	listOpts := metav1.ListOptions{LabelSelector: projectSelector(ref)}
+	secretNames := map[string]string{}
+	for _, role := range []string{RoleApp, "superuser"} {
+		secretNames[s.GetDatabaseRoleSecretName(from, role)] = s.GetDatabaseRoleSecretName(to, role)
+	}
+	fromHost := s.GetDatabaseRWServiceName(from) + "." + namespace
+	toHost := s.GetDatabaseRWServiceName(to) + "." + namespace
+
+	repoint := func(spec *corev1.PodSpec) bool {
+		changed := false
+		for _, containers := range [][]corev1.Container{spec.InitContainers, spec.Containers} {
+			for i := range containers {
+				for j := range containers[i].Env {
+					env := &containers[i].Env[j]
+					if env.ValueFrom != nil && env.ValueFrom.SecretKeyRef != nil {
+						if name, ok := secretNames[env.ValueFrom.SecretKeyRef.Name]; ok {
+							env.ValueFrom.SecretKeyRef.Name = name
+							changed = true
+						}
+					}
+					if strings.Contains(env.Value, fromHost) {
+						env.Value = strings.ReplaceAll(env.Value, fromHost, toHost)
+						changed = true
+					}
+				}
+			}
+		}
+		return changed
+	}
+
+	var errs []error
+	deployments := s.clientset.AppsV1().Deployments(namespace)
+	if list, err := deployments.List(ctx, listOpts); err != nil {
+		errs = append(errs, err)
+	} else {
+		for _, item := range list.Items {
+			if !repoint(&item.Spec.Template.Spec) {
+				continue
+			}
+			if _, err := deployments.Update(ctx, &item, metav1.UpdateOptions{FieldManager: FieldManager}); err != nil {
+				slog.ErrorContext(ctx, "Failed to update deployment database env", "deployment", item.Name, "error", err)
+				errs = append(errs, err)
+			}
+		}
+	}
+
+	cronJobs := s.clientset.BatchV1().CronJobs(namespace)
+	if list, err := cronJobs.List(ctx, listOpts); err != nil {
+		errs = append(errs, err)
+	} else {
+		for _, item := range list.Items {
+			if !repoint(&item.Spec.JobTemplate.Spec.Template.Spec) {
+				continue
+			}
+			if _, err := cronJobs.Update(ctx, &item, metav1.UpdateOptions{FieldManager: FieldManager}); err != nil {
+				slog.ErrorContext(ctx, "Failed to update cron job database env", "cronJob", item.Name, "error", err)
+				errs = append(errs, err)
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		slog.ErrorContext(ctx, "Failed to update project database env", "ref", ref, "error", errors.Join(errs...))
+		return errors.New("failed to update project database env")
+	}
+	return nil
+}
+
+// clusterOwnedResources 是以 Cluster 為 owner 的專案資源
+func (s *service) clusterOwnedResources() []schema.GroupVersionResource {
+	return append([]schema.GroupVersionResource{
+		{Group: "apps", Version: "v1", Resource: "deployments"},
+		{Group: "batch", Version: "v1", Resource: "jobs"},
+		{Group: "", Version: "v1", Resource: "services"},
+		{Group: "autoscaling", Version: "v2", Resource: "horizontalpodautoscalers"},
+		{Group: "policy", Version: "v1", Resource: "poddisruptionbudgets"},
+		databaseGVR,
+		poolerGVR,
+		scheduledBackupGVR,
+		backupGVR,
+	}, s.ingress.GVRs()...)
+}
+
+// replaceClusterOwnerReference 將專案資源上指向 from Cluster 的 OwnerReference 改為 owner，
+// 刪除 from 時這些資源不會被 GC 一併刪除。
+//
+// CNPG 自己建立的資源 (controller reference) 不會被修改。
+func (s *service) replaceClusterOwnerReference(ctx context.Context, ref string, from string, owner *metav1.OwnerReference) error {
+	namespace := s.GetProjectNamespace(ref)
+	listOpts := metav1.ListOptions{LabelSelector: projectSelector(ref)}
+	isClusterRef := func(o metav1.OwnerReference) bool {
+		return o.Kind == "Cluster" && o.APIVersion == clusterGVR.GroupVersion().String() && o.Name == from
+	}
+
+	var errs []error
+	for _, gvr := range s.clusterOwnedResources() {
+		client := s.dynamicClient.Resource(gvr).Namespace(namespace)
+		list, err := client.List(ctx, listOpts)
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed to list project resources", "resource", gvr.Resource, "error", err)
+			errs = append(errs, err)
+			continue
+		}
+
+		for _, item := range list.Items {
+			current := item.GetOwnerReferences()
+			if !slices.ContainsFunc(current, isClusterRef) || slices.ContainsFunc(current, func(o metav1.OwnerReference) bool {
+				return isClusterRef(o) && lo.FromPtr(o.Controller)
+			}) {
+				continue
+			}
+
+			next := append(lo.Reject(current, func(o metav1.OwnerReference, _ int) bool { return isClusterRef(o) }), *owner)
+			data, err := json.Marshal(map[string]any{
+				"metadata": map[string]any{"ownerReferences": next},
+			})
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			_, err = client.Patch(ctx, item.GetName(), types.MergePatchType, data, metav1.PatchOptions{FieldManager: FieldManager})
+			if ignoreNotFound(err) != nil {
+				slog.ErrorContext(ctx, "Failed to update owner references", "resource", gvr.Resource, "name", item.GetName(), "error", err)
+				errs = append(errs, err)
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		return errors.New("failed to update project owner references")
+	}
+	return nil
+}
+
+// newRestoredClusterName 產生還原用 Cluster 的名稱，CNPG 以此為前綴建立 -rw service 與 -app Secret
+func newRestoredClusterName(ref string) string {
+	return generateResourceName(ref, DBComponent, utilrand.String(5))
+}
+
+// isProjectClusterName 回傳 name 是否為專案的資料庫 Cluster (建立時的 ref 或還原時建立的 Cluster)
+func isProjectClusterName(ref string, name string) bool {
+	return name == ref || strings.HasPrefix(name, generateResourceName(ref, DBComponent)+"-")
+}
+
+// RestartAPIDeployments 觸發 API Deployment 的 rolling restart，讓 Pod 重新讀取 Secret
+func (s *service) RestartAPIDeployments(ctx context.Context, ref string) error {
+	data, err := json.Marshal(map[string]any{
+		"spec": map[string]any{
+			"template": map[string]any{
+				"metadata": map[string]any{
+					"annotations": map[string]string{
+						"kubectl.kubernetes.io/restartedAt": time.Now().Format(time.RFC3339),
+					},
+				},
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	deployments := s.clientset.AppsV1().Deployments(s.GetProjectNamespace(ref))
+	for _, name := range []string{s.GetAuthAPIDeploymentName(ref), s.GetRESTAPIDeploymentName(ref), s.GetRealtimeDeploymentName(ref)} {
+		_, err := deployments.Patch(ctx, name, types.StrategicMergePatchType, data, metav1.PatchOptions{FieldManager: FieldManager})
+		if ignoreNotFound(err) != nil {
+			slog.ErrorContext(ctx, "Failed to restart API deployment", "error", err, "deployment", name)
+			return errors.New("failed to restart API deployment")
+		}
+	}
+	return nil
+}
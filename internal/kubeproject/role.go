@@ -2,15 +2,38 @@ package kubeproject
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"log/slog"
 
+	"github.com/samber/lo"
+
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 )
 
-func (s *service) buildDatabaseRoleSecret(ref string, role string, password string) *corev1.Secret {
+// databaseRoleSecretName 回傳角色連線資訊所在的 Secret 名稱。
+//
+// authenticator 的 Secret 由平台建立，名稱固定為 <ref>-authenticator；
+// app 與 superuser 的 Secret 由 CNPG 以 Cluster 名稱建立 (<cluster>-app)，從備份還原後會改變。
+func (s *service) databaseRoleSecretName(ctx context.Context, ref string, role string) (string, error) {
+	if role == RoleAuthenticator {
+		return s.GetDatabaseRoleSecretName(ref, role), nil
+	}
+	name, err := s.databaseClusterName(ctx, ref)
+	if err != nil {
+		return "", err
+	}
+	return s.GetDatabaseRoleSecretName(name, role), nil
+}
+
+// buildDatabaseRoleSecret 建立角色的 Secret，連線字串指向 clusterName 的 -rw service
+func (s *service) buildDatabaseRoleSecret(ref string, clusterName string, role string, password string) *corev1.Secret {
 	secretName := s.GetDatabaseRoleSecretName(ref, role)
+	if role != RoleAuthenticator {
+		secretName = s.GetDatabaseRoleSecretName(clusterName, role)
+	}
 
 	return &corev1.Secret{
 		TypeMeta: metav1.TypeMeta{
@@ -19,30 +42,52 @@ func (s *service) buildDatabaseRoleSecret(ref string, role string, password stri
 		},
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      secretName,
-			Namespace: s.namespace,
-			Labels: map[string]string{
-				"cnpg.io/cluster":  ref,
+			Namespace: s.GetProjectNamespace(ref),
+			Labels: lo.Assign(projectLabels(ref, DBComponent), map[string]string{
+				"cnpg.io/cluster":  clusterName,
 				"cnpg.io/reload":   "true",
 				"cnpg.io/userType": "app",
-			},
+			}),
 		},
 		Type: "kubernetes.io/basic-auth",
 		StringData: map[string]string{
 			"username": role,
 			"password": password,
-			"uri":      fmt.Sprintf("postgresql://%s:%s@%s-rw:5432/app", role, password, ref),
+			"uri":      fmt.Sprintf("postgresql://%s:%s@%s.%s:5432/app", role, password, s.GetDatabaseRWServiceName(clusterName), s.GetProjectNamespace(ref)),
+			// 經由 PgBouncer pooler 的連線，pooler 未啟用時無法連線
+			"pooler-uri": fmt.Sprintf("postgresql://%s:%s@%s.%s:5432/app", role, password, s.GetPoolerName(ref), s.GetProjectNamespace(ref)),
 		},
 	}
 }
 
+func (s *service) applyDatabaseRoleSecret(ctx context.Context, secret *corev1.Secret) error {
+	data, err := json.Marshal(secret)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.clientset.CoreV1().Secrets(secret.Namespace).Patch(
+		ctx,
+		secret.Name,
+		types.ApplyPatchType,
+		data,
+		applyPatchOptions(FieldManager),
+	)
+	return err
+}
+
 func (s *service) CreateDatabaseRoleSecret(ctx context.Context, ref string, role string, password string) error {
-	secret := s.buildDatabaseRoleSecret(ref, role, password)
+	clusterName, err := s.databaseClusterName(ctx, ref)
+	if err != nil {
+		return err
+	}
+	secret := s.buildDatabaseRoleSecret(ref, clusterName, role, password)
 
-	_, err := s.clientset.CoreV1().Secrets(s.namespace).Create(ctx, secret, metav1.CreateOptions{})
+	err = s.applyDatabaseRoleSecret(ctx, secret)
 	if err != nil {
 		slog.ErrorContext(ctx, "failed to create database role secret",
 			"secret_name", secret.Name,
-			"namespace", s.namespace,
+			"namespace", s.GetProjectNamespace(ref),
 			"error", err,
 		)
 		return fmt.Errorf("failed to create database role secret")
@@ -52,13 +97,17 @@ func (s *service) CreateDatabaseRoleSecret(ctx context.Context, ref string, role
 }
 
 func (s *service) UpdateDatabaseRoleSecret(ctx context.Context, ref string, role string, password string) error {
-	secret := s.buildDatabaseRoleSecret(ref, role, password)
+	clusterName, err := s.databaseClusterName(ctx, ref)
+	if err != nil {
+		return err
+	}
+	secret := s.buildDatabaseRoleSecret(ref, clusterName, role, password)
 
-	_, err := s.clientset.CoreV1().Secrets(s.namespace).Update(ctx, secret, metav1.UpdateOptions{})
+	err = s.applyDatabaseRoleSecret(ctx, secret)
 	if err != nil {
 		slog.ErrorContext(ctx, "failed to update database role secret",
 			"secret_name", secret.Name,
-			"namespace", s.namespace,
+			"namespace", s.GetProjectNamespace(ref),
 			"error", err,
 		)
 		return fmt.Errorf("failed to update database role secret")
@@ -68,8 +117,11 @@ func (s *service) UpdateDatabaseRoleSecret(ctx context.Context, ref string, role
 }
 
 func (s *service) FindDatabaseRolePassword(ctx context.Context, ref string, role string) (*string, error) {
-	secretName := s.GetDatabaseRoleSecretName(ref, role)
-	secret, err := s.clientset.CoreV1().Secrets(s.namespace).Get(ctx, secretName, metav1.GetOptions{})
+	secretName, err := s.databaseRoleSecretName(ctx, ref, role)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := s.clientset.CoreV1().Secrets(s.GetProjectNamespace(ref)).Get(ctx, secretName, metav1.GetOptions{})
 	if err != nil {
 		slog.Error("Failed to read database secret", "error", err)
 		return nil, ErrFailedToReadDatabaseSecret
@@ -86,8 +138,11 @@ func (s *service) FindDatabaseRolePassword(ctx context.Context, ref string, role
 }
 
 func (s *service) FindDatabaseRoleSecret(ctx context.Context, ref string, role string) (*corev1.Secret, error) {
-	secretName := s.GetDatabaseRoleSecretName(ref, role)
-	secret, err := s.clientset.CoreV1().Secrets(s.namespace).Get(ctx, secretName, metav1.GetOptions{})
+	secretName, err := s.databaseRoleSecretName(ctx, ref, role)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := s.clientset.CoreV1().Secrets(s.GetProjectNamespace(ref)).Get(ctx, secretName, metav1.GetOptions{})
 	if err != nil {
 		slog.Error("Failed to read database secret", "error", err)
 		return nil, ErrFailedToReadDatabaseSecret
@@ -96,9 +151,12 @@ func (s *service) FindDatabaseRoleSecret(ctx context.Context, ref string, role s
 }
 
 func (s *service) DeleteDatabaseRoleSecret(ctx context.Context, ref string, role string) error {
-	secretName := s.GetDatabaseRoleSecretName(ref, role)
-	err := s.clientset.CoreV1().Secrets(s.namespace).Delete(ctx, secretName, metav1.DeleteOptions{})
+	secretName, err := s.databaseRoleSecretName(ctx, ref, role)
 	if err != nil {
+		return err
+	}
+	err = s.clientset.CoreV1().Secrets(s.GetProjectNamespace(ref)).Delete(ctx, secretName, metav1.DeleteOptions{})
+	if ignoreNotFound(err) != nil {
 		slog.Error("Failed to delete database role secret", "error", err, "secretName", secretName)
 		return fmt.Errorf("failed to delete database role secret")
 	}
@@ -0,0 +1,232 @@
+package kubeproject
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+
+	"baas-api/internal/config"
+
+	"github.com/samber/do/v2"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+)
+
+// ProjectClusterRepository 讀取專案記錄的叢集，由 project.Repository 實作
+type ProjectClusterRepository interface {
+	// FindClusterByRef 回傳專案記錄的叢集 (未記錄時為空字串)，專案不存在時 found 為 false
+	FindClusterByRef(ctx context.Context, ref string) (cluster string, found bool, err error)
+}
+
+//go:generate go run ./routergen
+
+// router 在設定多個叢集時實作 Service，將每個呼叫轉發給專案所在叢集的 service。
+//
+// 專案的叢集在建立時由 PlaceProject 選擇並記錄在平台資料庫，router 第一次用到時讀取並快取。
+type router struct {
+	repo ProjectClusterRepository
+	// clusters 依設定順序排列，defaultName 是未記錄叢集的專案所在的叢集
+	clusters    []*service
+	byName      map[string]*service
+	defaultName string
+
+	mu   sync.RWMutex
+	refs map[string]*service
+}
+
+var _ Service = (*router)(nil)
+
+func newRouter(i do.Injector, cfg *config.Config) (*router, error) {
+	repo, err := do.InvokeAs[ProjectClusterRepository](i)
+	if err != nil {
+		return nil, fmt.Errorf("project cluster repository: %w", err)
+	}
+	r := &router{
+		repo:   repo,
+		byName: map[string]*service{},
+		refs:   map[string]*service{},
+	}
+	for _, cluster := range cfg.Kube.Clusters {
+		if cluster.Name == "" {
+			return nil, errors.New("kube.clusters: cluster name is required")
+		}
+		if _, ok := r.byName[cluster.Name]; ok {
+			return nil, fmt.Errorf("kube.clusters: duplicate cluster %q", cluster.Name)
+		}
+
+		restConfig, err := clusterRESTConfig(cluster)
+		if err != nil {
+			return nil, err
+		}
+		clientset, err := kubernetes.NewForConfig(restConfig)
+		if err != nil {
+			return nil, err
+		}
+		dynamicClient, err := dynamic.NewForConfig(restConfig)
+		if err != nil {
+			return nil, err
+		}
+		svc, err := newService(cfg, cluster, clientset, dynamicClient)
+		if err != nil {
+			return nil, err
+		}
+		r.clusters = append(r.clusters, svc)
+		r.byName[cluster.Name] = svc
+	}
+
+	r.defaultName = cfg.Kube.DefaultCluster
+	if r.defaultName == "" {
+		r.defaultName = r.clusters[0].cluster.Name
+	}
+	if _, ok := r.byName[r.defaultName]; !ok {
+		return nil, fmt.Errorf("kube.defaultCluster: unknown cluster %q", r.defaultName)
+	}
+	return r, nil
+}
+
+// defaultCluster 回傳預設叢集，也用於與叢集無關的方法 (名稱、host 等只由設定決定)
+func (r *router) defaultCluster() *service {
+	return r.byName[r.defaultName]
+}
+
+// forRef 回傳專案所在叢集的 service。
+//
+// 只快取從平台資料庫或在叢集中找到的結果；找不到專案而使用預設叢集時不快取，
+// 之後 PlaceProject 放到其他叢集的專案不會一直轉發到預設叢集。
+func (r *router) forRef(ctx context.Context, ref string) (*service, error) {
+	if len(r.clusters) == 1 {
+		return r.clusters[0], nil
+	}
+
+	r.mu.RLock()
+	svc, ok := r.refs[ref]
+	r.mu.RUnlock()
+	if ok {
+		return svc, nil
+	}
+
+	svc, resolved, err := r.lookup(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	if resolved {
+		r.mu.Lock()
+		r.refs[ref] = svc
+		r.mu.Unlock()
+	}
+	return svc, nil
+}
+
+// lookup 從平台資料庫讀取專案的叢集，resolved 表示結果可以快取。
+//
+// 專案記錄已刪除時 (例如刪除流程中) 改為在各叢集中尋找專案的 CNPG Cluster。
+func (r *router) lookup(ctx context.Context, ref string) (svc *service, resolved bool, err error) {
+	name, found, err := r.repo.FindClusterByRef(ctx, ref)
+	if err != nil {
+		return nil, false, ErrFailedToFindCluster
+	}
+	if !found {
+		return r.probe(ctx, ref)
+	}
+	if name == "" {
+		return r.defaultCluster(), true, nil
+	}
+	svc, ok := r.byName[name]
+	if !ok {
+		slog.ErrorContext(ctx, "Project is placed in a cluster that is not configured", "ref", ref, "cluster", name)
+		return nil, false, fmt.Errorf("%w: %s", ErrUnknownCluster, name)
+	}
+	return svc, true, nil
+}
+
+// probe 在各叢集中尋找專案的 CNPG Cluster，都找不到時使用預設叢集 (found 為 false)
+func (r *router) probe(ctx context.Context, ref string) (svc *service, found bool, err error) {
+	for _, svc := range r.clusters {
+		exists, err := svc.hasProject(ctx, ref)
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed to find project in cluster", "error", err, "ref", ref, "cluster", svc.cluster.Name)
+			return nil, false, ErrFailedToFindCluster
+		}
+		if exists {
+			return svc, true, nil
+		}
+	}
+	return r.defaultCluster(), false, nil
+}
+
+// ForgetProjectCluster 移除快取的專案叢集，下次使用時重新讀取
+func (r *router) ForgetProjectCluster(ref string) {
+	r.mu.Lock()
+	delete(r.refs, ref)
+	r.mu.Unlock()
+}
+
+// PlaceProject 在符合 region 且未達 MaxProjects 的叢集中選擇專案數最少的叢集，數量相同時依設定順序
+func (r *router) PlaceProject(ctx context.Context, region string) (string, error) {
+	var chosen *service
+	chosenCount := 0
+	matched := false
+	for _, svc := range r.clusters {
+		if !svc.matchesRegion(region) {
+			continue
+		}
+		matched = true
+
+		count, err := svc.countProjects(ctx)
+		if err != nil {
+			// 無法連線的叢集不放置新專案
+			slog.WarnContext(ctx, "Failed to count projects, skipping cluster", "error", err, "cluster", svc.cluster.Name)
+			continue
+		}
+		if !svc.hasCapacity(count) {
+			continue
+		}
+		if chosen == nil || count < chosenCount {
+			chosen, chosenCount = svc, count
+		}
+	}
+
+	switch {
+	case !matched:
+		return "", ErrNoClusterInRegion
+	case chosen == nil:
+		return "", ErrNoClusterAvailable
+	}
+	slog.InfoContext(ctx, "Placed project", "cluster", chosen.cluster.Name, "region", chosen.cluster.Region, "projects", chosenCount)
+	return chosen.cluster.Name, nil
+}
+
+func (r *router) DeleteAllForProject(ctx context.Context, ref string) error {
+	c, err := r.forRef(ctx, ref)
+	if err != nil {
+		return err
+	}
+	if err := c.DeleteAllForProject(ctx, ref); err != nil {
+		return err
+	}
+	r.ForgetProjectCluster(ref)
+	return nil
+}
+
+// ===== 不依 ref 轉發的方法 =====
+//
+// 其他 Service 方法由 routergen 產生在 router_gen.go：以 ctx、ref 開頭的方法轉發給專案所在的叢集，
+// 與叢集無關的方法轉發給預設叢集。
+
+// StartTLSSecretSync 在每個叢集監看共用的 TLS secret
+func (r *router) StartTLSSecretSync() {
+	for _, svc := range r.clusters {
+		svc.StartTLSSecretSync()
+	}
+}
+
+// CreateJWKSConfigMap 的 ref 在 opt 中
+func (r *router) CreateJWKSConfigMap(ctx context.Context, opt CreateJWKSConfigMapOption) error {
+	c, err := r.forRef(ctx, opt.Ref)
+	if err != nil {
+		return err
+	}
+	return c.CreateJWKSConfigMap(ctx, opt)
+}
@@ -0,0 +1,704 @@
+// Code generated by routergen. DO NOT EDIT.
+
+package kubeproject
+
+import (
+	"context"
+	"time"
+
+	"baas-api/internal/config"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func (r *router) GetProjectNamespace(ref string) string {
+	return r.defaultCluster().GetProjectNamespace(ref)
+}
+
+func (r *router) EnsureProjectNamespace(ctx context.Context, ref string) error {
+	c, err := r.forRef(ctx, ref)
+	if err != nil {
+		return err
+	}
+	return c.EnsureProjectNamespace(ctx, ref)
+}
+
+func (r *router) DeleteProjectNamespace(ctx context.Context, ref string) error {
+	c, err := r.forRef(ctx, ref)
+	if err != nil {
+		return err
+	}
+	return c.DeleteProjectNamespace(ctx, ref)
+}
+
+func (r *router) SyncProjectTLSSecret(ctx context.Context, ref string) error {
+	c, err := r.forRef(ctx, ref)
+	if err != nil {
+		return err
+	}
+	return c.SyncProjectTLSSecret(ctx, ref)
+}
+
+func (r *router) DeleteJWKSConfigMap(ctx context.Context, ref string) error {
+	c, err := r.forRef(ctx, ref)
+	if err != nil {
+		return err
+	}
+	return c.DeleteJWKSConfigMap(ctx, ref)
+}
+
+func (r *router) CreateCluster(ctx context.Context, ref string, opt *ClusterOption) error {
+	c, err := r.forRef(ctx, ref)
+	if err != nil {
+		return err
+	}
+	return c.CreateCluster(ctx, ref, opt)
+}
+
+func (r *router) DeleteCluster(ctx context.Context, ref string) error {
+	c, err := r.forRef(ctx, ref)
+	if err != nil {
+		return err
+	}
+	return c.DeleteCluster(ctx, ref)
+}
+
+func (r *router) FindClusterStatus(ctx context.Context, ref string) (*string, error) {
+	c, err := r.forRef(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	return c.FindClusterStatus(ctx, ref)
+}
+
+func (r *router) WaitClusterHealthy(ctx context.Context, ref string) error {
+	c, err := r.forRef(ctx, ref)
+	if err != nil {
+		return err
+	}
+	return c.WaitClusterHealthy(ctx, ref)
+}
+
+func (r *router) WatchProject(ctx context.Context, ref string) (<-chan struct{}, error) {
+	c, err := r.forRef(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	return c.WatchProject(ctx, ref)
+}
+
+func (r *router) ListProjectEvents(ctx context.Context, ref string, since time.Time) ([]ProjectEvent, error) {
+	c, err := r.forRef(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	return c.ListProjectEvents(ctx, ref, since)
+}
+
+func (r *router) ApplyDatabaseCluster(ctx context.Context, ref string, cluster config.DatabaseClusterConfig) error {
+	c, err := r.forRef(ctx, ref)
+	if err != nil {
+		return err
+	}
+	return c.ApplyDatabaseCluster(ctx, ref, cluster)
+}
+
+func (r *router) GetProjectHost(ref string) string {
+	return r.defaultCluster().GetProjectHost(ref)
+}
+
+func (r *router) GetProjectReadOnlyHost(ref string) string {
+	return r.defaultCluster().GetProjectReadOnlyHost(ref)
+}
+
+func (r *router) GetRealtimeURL(ref string) string {
+	return r.defaultCluster().GetRealtimeURL(ref)
+}
+
+func (r *router) FindDatabaseCACertificate(ctx context.Context, ref string) (string, error) {
+	c, err := r.forRef(ctx, ref)
+	if err != nil {
+		return "", err
+	}
+	return c.FindDatabaseCACertificate(ctx, ref)
+}
+
+func (r *router) FindDatabaseCluster(ctx context.Context, ref string) (*DatabaseClusterInfo, error) {
+	c, err := r.forRef(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	return c.FindDatabaseCluster(ctx, ref)
+}
+
+func (r *router) FindPostgresParameters(ctx context.Context, ref string) (map[string]string, error) {
+	c, err := r.forRef(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	return c.FindPostgresParameters(ctx, ref)
+}
+
+func (r *router) ApplyPooler(ctx context.Context, ref string, opt PoolerOption) error {
+	c, err := r.forRef(ctx, ref)
+	if err != nil {
+		return err
+	}
+	return c.ApplyPooler(ctx, ref, opt)
+}
+
+func (r *router) FindPooler(ctx context.Context, ref string) (*PoolerInfo, error) {
+	c, err := r.forRef(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	return c.FindPooler(ctx, ref)
+}
+
+func (r *router) DeletePooler(ctx context.Context, ref string) error {
+	c, err := r.forRef(ctx, ref)
+	if err != nil {
+		return err
+	}
+	return c.DeletePooler(ctx, ref)
+}
+
+func (r *router) ApplyRESTAPIPooler(ctx context.Context, ref string, enabled bool, poolMode string) error {
+	c, err := r.forRef(ctx, ref)
+	if err != nil {
+		return err
+	}
+	return c.ApplyRESTAPIPooler(ctx, ref, enabled, poolMode)
+}
+
+func (r *router) FindRESTAPIPooler(ctx context.Context, ref string) (bool, error) {
+	c, err := r.forRef(ctx, ref)
+	if err != nil {
+		return false, err
+	}
+	return c.FindRESTAPIPooler(ctx, ref)
+}
+
+func (r *router) ApplyPostgresParameters(ctx context.Context, ref string, parameters map[string]string) error {
+	c, err := r.forRef(ctx, ref)
+	if err != nil {
+		return err
+	}
+	return c.ApplyPostgresParameters(ctx, ref, parameters)
+}
+
+func (r *router) CreateDatabase(ctx context.Context, ref string) error {
+	c, err := r.forRef(ctx, ref)
+	if err != nil {
+		return err
+	}
+	return c.CreateDatabase(ctx, ref)
+}
+
+func (r *router) DeleteDatabase(ctx context.Context, ref string) error {
+	c, err := r.forRef(ctx, ref)
+	if err != nil {
+		return err
+	}
+	return c.DeleteDatabase(ctx, ref)
+}
+
+func (r *router) CreateMigrationJob(ctx context.Context, ref string) error {
+	c, err := r.forRef(ctx, ref)
+	if err != nil {
+		return err
+	}
+	return c.CreateMigrationJob(ctx, ref)
+}
+
+func (r *router) WaitMigrationJob(ctx context.Context, ref string) (*MigrationResult, error) {
+	c, err := r.forRef(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	return c.WaitMigrationJob(ctx, ref)
+}
+
+func (r *router) FindMigrationResult(ctx context.Context, ref string) (*MigrationResult, error) {
+	c, err := r.forRef(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	return c.FindMigrationResult(ctx, ref)
+}
+
+func (r *router) FindMigrationLogs(ctx context.Context, ref string) (*MigrationResult, error) {
+	c, err := r.forRef(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	return c.FindMigrationLogs(ctx, ref)
+}
+
+func (r *router) ListUserMigrationFiles(ctx context.Context, ref string) (map[string]string, error) {
+	c, err := r.forRef(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	return c.ListUserMigrationFiles(ctx, ref)
+}
+
+func (r *router) PutUserMigrationFile(ctx context.Context, ref string, filename string, content string) error {
+	c, err := r.forRef(ctx, ref)
+	if err != nil {
+		return err
+	}
+	return c.PutUserMigrationFile(ctx, ref, filename, content)
+}
+
+func (r *router) DeleteUserMigrationFile(ctx context.Context, ref string, filename string) error {
+	c, err := r.forRef(ctx, ref)
+	if err != nil {
+		return err
+	}
+	return c.DeleteUserMigrationFile(ctx, ref, filename)
+}
+
+func (r *router) RunUserMigrations(ctx context.Context, ref string, action string) (*MigrationResult, error) {
+	c, err := r.forRef(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	return c.RunUserMigrations(ctx, ref, action)
+}
+
+func (r *router) WaitUserMigrationJob(ctx context.Context, ref string, run *MigrationResult) (*MigrationResult, error) {
+	c, err := r.forRef(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	return c.WaitUserMigrationJob(ctx, ref, run)
+}
+
+func (r *router) FindUserMigrationResult(ctx context.Context, ref string) (*MigrationResult, error) {
+	c, err := r.forRef(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	return c.FindUserMigrationResult(ctx, ref)
+}
+
+func (r *router) FindUserMigrationLogs(ctx context.Context, ref string) (*MigrationResult, error) {
+	c, err := r.forRef(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	return c.FindUserMigrationLogs(ctx, ref)
+}
+
+func (r *router) IsExtensionAllowed(name string) bool {
+	return r.defaultCluster().IsExtensionAllowed(name)
+}
+
+func (r *router) FindDatabaseExtensions(ctx context.Context, ref string) ([]DatabaseExtension, error) {
+	c, err := r.forRef(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	return c.FindDatabaseExtensions(ctx, ref)
+}
+
+func (r *router) ApplyDatabaseExtensions(ctx context.Context, ref string, extensions []DatabaseExtensionSpec) error {
+	c, err := r.forRef(ctx, ref)
+	if err != nil {
+		return err
+	}
+	return c.ApplyDatabaseExtensions(ctx, ref, extensions)
+}
+
+func (r *router) GetBackupDestinationPath(ref string) string {
+	return r.defaultCluster().GetBackupDestinationPath(ref)
+}
+
+func (r *router) CreateBackupCredentialsSecret(ctx context.Context, ref string, accessKeyID string, secretAccessKey string) error {
+	c, err := r.forRef(ctx, ref)
+	if err != nil {
+		return err
+	}
+	return c.CreateBackupCredentialsSecret(ctx, ref, accessKeyID, secretAccessKey)
+}
+
+func (r *router) CreateScheduledBackup(ctx context.Context, ref string) error {
+	c, err := r.forRef(ctx, ref)
+	if err != nil {
+		return err
+	}
+	return c.CreateScheduledBackup(ctx, ref)
+}
+
+func (r *router) CreateBackup(ctx context.Context, ref string) (*BackupInfo, error) {
+	c, err := r.forRef(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	return c.CreateBackup(ctx, ref)
+}
+
+func (r *router) ListBackups(ctx context.Context, ref string) ([]BackupInfo, error) {
+	c, err := r.forRef(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	return c.ListBackups(ctx, ref)
+}
+
+func (r *router) FindBackup(ctx context.Context, ref string, name string) (*BackupInfo, error) {
+	c, err := r.forRef(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	return c.FindBackup(ctx, ref, name)
+}
+
+func (r *router) RestoreCluster(ctx context.Context, ref string, opt *ClusterRecoveryOption) error {
+	c, err := r.forRef(ctx, ref)
+	if err != nil {
+		return err
+	}
+	return c.RestoreCluster(ctx, ref, opt)
+}
+
+func (r *router) RestartAPIDeployments(ctx context.Context, ref string) error {
+	c, err := r.forRef(ctx, ref)
+	if err != nil {
+		return err
+	}
+	return c.RestartAPIDeployments(ctx, ref)
+}
+
+func (r *router) GetPITRHost(ref string) string {
+	return r.defaultCluster().GetPITRHost(ref)
+}
+
+func (r *router) FindBackupServerName(ctx context.Context, ref string) (string, error) {
+	c, err := r.forRef(ctx, ref)
+	if err != nil {
+		return "", err
+	}
+	return c.FindBackupServerName(ctx, ref)
+}
+
+func (r *router) FindRecoveryWindow(ctx context.Context, ref string) (*RecoveryWindow, error) {
+	c, err := r.forRef(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	return c.FindRecoveryWindow(ctx, ref)
+}
+
+func (r *router) CreatePITRCluster(ctx context.Context, ref string, opt *ClusterRecoveryOption) error {
+	c, err := r.forRef(ctx, ref)
+	if err != nil {
+		return err
+	}
+	return c.CreatePITRCluster(ctx, ref, opt)
+}
+
+func (r *router) FindPITRCluster(ctx context.Context, ref string) (*PITRClusterInfo, error) {
+	c, err := r.forRef(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	return c.FindPITRCluster(ctx, ref)
+}
+
+func (r *router) DeletePITRCluster(ctx context.Context, ref string) error {
+	c, err := r.forRef(ctx, ref)
+	if err != nil {
+		return err
+	}
+	return c.DeletePITRCluster(ctx, ref)
+}
+
+func (r *router) FindDatabaseRoleSecret(ctx context.Context, ref string, role string) (*corev1.Secret, error) {
+	c, err := r.forRef(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	return c.FindDatabaseRoleSecret(ctx, ref, role)
+}
+
+func (r *router) FindDatabaseRolePassword(ctx context.Context, ref string, role string) (*string, error) {
+	c, err := r.forRef(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	return c.FindDatabaseRolePassword(ctx, ref, role)
+}
+
+func (r *router) CreateDatabaseRoleSecret(ctx context.Context, ref string, role string, password string) error {
+	c, err := r.forRef(ctx, ref)
+	if err != nil {
+		return err
+	}
+	return c.CreateDatabaseRoleSecret(ctx, ref, role, password)
+}
+
+func (r *router) UpdateDatabaseRoleSecret(ctx context.Context, ref string, role string, password string) error {
+	c, err := r.forRef(ctx, ref)
+	if err != nil {
+		return err
+	}
+	return c.UpdateDatabaseRoleSecret(ctx, ref, role, password)
+}
+
+func (r *router) DeleteDatabaseRoleSecret(ctx context.Context, ref string, role string) error {
+	c, err := r.forRef(ctx, ref)
+	if err != nil {
+		return err
+	}
+	return c.DeleteDatabaseRoleSecret(ctx, ref, role)
+}
+
+func (r *router) CreateAuthAPIDeployment(ctx context.Context, ref string, opt *APIDeploymentOption) error {
+	c, err := r.forRef(ctx, ref)
+	if err != nil {
+		return err
+	}
+	return c.CreateAuthAPIDeployment(ctx, ref, opt)
+}
+
+func (r *router) DeleteAuthAPIDeployment(ctx context.Context, ref string) error {
+	c, err := r.forRef(ctx, ref)
+	if err != nil {
+		return err
+	}
+	return c.DeleteAuthAPIDeployment(ctx, ref)
+}
+
+func (r *router) PatchAuthAPIDeployment(ctx context.Context, ref string, opt *APIDeploymentOption) error {
+	c, err := r.forRef(ctx, ref)
+	if err != nil {
+		return err
+	}
+	return c.PatchAuthAPIDeployment(ctx, ref, opt)
+}
+
+func (r *router) CreateAuthAPIService(ctx context.Context, ref string) error {
+	c, err := r.forRef(ctx, ref)
+	if err != nil {
+		return err
+	}
+	return c.CreateAuthAPIService(ctx, ref)
+}
+
+func (r *router) DeleteAuthAPIService(ctx context.Context, ref string) error {
+	c, err := r.forRef(ctx, ref)
+	if err != nil {
+		return err
+	}
+	return c.DeleteAuthAPIService(ctx, ref)
+}
+
+func (r *router) CreateRESTAPIDeployment(ctx context.Context, ref string, jwks string) error {
+	c, err := r.forRef(ctx, ref)
+	if err != nil {
+		return err
+	}
+	return c.CreateRESTAPIDeployment(ctx, ref, jwks)
+}
+
+func (r *router) DeleteRESTAPIDeployment(ctx context.Context, ref string) error {
+	c, err := r.forRef(ctx, ref)
+	if err != nil {
+		return err
+	}
+	return c.DeleteRESTAPIDeployment(ctx, ref)
+}
+
+func (r *router) CreateRESTAPIService(ctx context.Context, ref string) error {
+	c, err := r.forRef(ctx, ref)
+	if err != nil {
+		return err
+	}
+	return c.CreateRESTAPIService(ctx, ref)
+}
+
+func (r *router) DeleteRESTAPIService(ctx context.Context, ref string) error {
+	c, err := r.forRef(ctx, ref)
+	if err != nil {
+		return err
+	}
+	return c.DeleteRESTAPIService(ctx, ref)
+}
+
+func (r *router) FindRESTAPIJWKS(ctx context.Context, ref string) (string, error) {
+	c, err := r.forRef(ctx, ref)
+	if err != nil {
+		return "", err
+	}
+	return c.FindRESTAPIJWKS(ctx, ref)
+}
+
+func (r *router) ApplyRESTAPIJWKS(ctx context.Context, ref string, jwks string) error {
+	c, err := r.forRef(ctx, ref)
+	if err != nil {
+		return err
+	}
+	return c.ApplyRESTAPIJWKS(ctx, ref, jwks)
+}
+
+func (r *router) WaitRESTAPIRollout(ctx context.Context, ref string) error {
+	c, err := r.forRef(ctx, ref)
+	if err != nil {
+		return err
+	}
+	return c.WaitRESTAPIRollout(ctx, ref)
+}
+
+func (r *router) ListCronJobs(ctx context.Context, ref string) ([]CronJobInfo, error) {
+	c, err := r.forRef(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	return c.ListCronJobs(ctx, ref)
+}
+
+func (r *router) FindCronJob(ctx context.Context, ref string, name string) (*CronJobInfo, error) {
+	c, err := r.forRef(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	return c.FindCronJob(ctx, ref, name)
+}
+
+func (r *router) ApplyCronJob(ctx context.Context, ref string, opt CronJobOption) error {
+	c, err := r.forRef(ctx, ref)
+	if err != nil {
+		return err
+	}
+	return c.ApplyCronJob(ctx, ref, opt)
+}
+
+func (r *router) DeleteCronJob(ctx context.Context, ref string, name string) error {
+	c, err := r.forRef(ctx, ref)
+	if err != nil {
+		return err
+	}
+	return c.DeleteCronJob(ctx, ref, name)
+}
+
+func (r *router) TriggerCronJob(ctx context.Context, ref string, name string) (*CronJobRun, error) {
+	c, err := r.forRef(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	return c.TriggerCronJob(ctx, ref, name)
+}
+
+func (r *router) ListCronJobRuns(ctx context.Context, ref string, name string) ([]CronJobRun, error) {
+	c, err := r.forRef(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	return c.ListCronJobRuns(ctx, ref, name)
+}
+
+func (r *router) FindCronJobRunLogs(ctx context.Context, ref string, name string, jobName string) (*CronJobRun, error) {
+	c, err := r.forRef(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	return c.FindCronJobRunLogs(ctx, ref, name, jobName)
+}
+
+func (r *router) FindRESTAPISettings(ctx context.Context, ref string) (*RESTAPISettings, error) {
+	c, err := r.forRef(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	return c.FindRESTAPISettings(ctx, ref)
+}
+
+func (r *router) ApplyRESTAPISettings(ctx context.Context, ref string, settings RESTAPISettings) error {
+	c, err := r.forRef(ctx, ref)
+	if err != nil {
+		return err
+	}
+	return c.ApplyRESTAPISettings(ctx, ref, settings)
+}
+
+func (r *router) FindRealtime(ctx context.Context, ref string) (*RealtimeStatus, error) {
+	c, err := r.forRef(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	return c.FindRealtime(ctx, ref)
+}
+
+func (r *router) ApplyRealtime(ctx context.Context, ref string) error {
+	c, err := r.forRef(ctx, ref)
+	if err != nil {
+		return err
+	}
+	return c.ApplyRealtime(ctx, ref)
+}
+
+func (r *router) DeleteRealtime(ctx context.Context, ref string) error {
+	c, err := r.forRef(ctx, ref)
+	if err != nil {
+		return err
+	}
+	return c.DeleteRealtime(ctx, ref)
+}
+
+func (r *router) StreamProjectLogs(ctx context.Context, ref string, opt LogStreamOption, lines chan<- LogLine) error {
+	c, err := r.forRef(ctx, ref)
+	if err != nil {
+		return err
+	}
+	return c.StreamProjectLogs(ctx, ref, opt, lines)
+}
+
+func (r *router) ApplyAPIWorkload(ctx context.Context, ref string, component string, workload config.WorkloadConfig) error {
+	c, err := r.forRef(ctx, ref)
+	if err != nil {
+		return err
+	}
+	return c.ApplyAPIWorkload(ctx, ref, component, workload)
+}
+
+func (r *router) FindAPIWorkload(ctx context.Context, ref string, component string) (*config.WorkloadConfig, error) {
+	c, err := r.forRef(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	return c.FindAPIWorkload(ctx, ref, component)
+}
+
+func (r *router) CreateIngressRoute(ctx context.Context, ref string) error {
+	c, err := r.forRef(ctx, ref)
+	if err != nil {
+		return err
+	}
+	return c.CreateIngressRoute(ctx, ref)
+}
+
+func (r *router) DeleteIngressRoute(ctx context.Context, ref string) error {
+	c, err := r.forRef(ctx, ref)
+	if err != nil {
+		return err
+	}
+	return c.DeleteIngressRoute(ctx, ref)
+}
+
+func (r *router) CreateIngressRouteTCP(ctx context.Context, ref string) error {
+	c, err := r.forRef(ctx, ref)
+	if err != nil {
+		return err
+	}
+	return c.CreateIngressRouteTCP(ctx, ref)
+}
+
+func (r *router) DeleteIngressRouteTCP(ctx context.Context, ref string) error {
+	c, err := r.forRef(ctx, ref)
+	if err != nil {
+		return err
+	}
+	return c.DeleteIngressRouteTCP(ctx, ref)
+}
@@ -0,0 +1,268 @@
+package kubeproject
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"baas-api/internal/config"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+)
+
+const testProjectNamespace = "baas-project"
+
+// testRefs 回傳 n 個不重複的專案 ref
+func testRefs(prefix string, n int) []string {
+	refs := make([]string, n)
+	for i := range refs {
+		refs[i] = prefix + strings.Repeat("a", 20-len(prefix)-2) + string(rune('a'+i/26)) + string(rune('a'+i%26))
+	}
+	return refs
+}
+
+// newTestCluster 建立操作 fake 叢集的 service，refs 是叢集中已有 CNPG Cluster 的專案
+func newTestCluster(t *testing.T, cluster config.KubeClusterConfig, refs ...string) *service {
+	t.Helper()
+	var objects []runtime.Object
+	for _, ref := range refs {
+		objects = append(objects, testDatabaseCluster(ref, ref, projectLabels(ref, DBComponent)))
+	}
+	return newTestClusterWithObjects(t, cluster, objects...)
+}
+
+func newTestClusterWithObjects(t *testing.T, cluster config.KubeClusterConfig, objects ...runtime.Object) *service {
+	t.Helper()
+	cfg := &config.Config{}
+	cfg.Kube.Project.Namespace = testProjectNamespace
+	dynamicClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(
+		runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{clusterGVR: "ClusterList", backupGVR: "BackupList"},
+		objects...,
+	)
+	svc, err := newService(cfg, cluster, k8sfake.NewSimpleClientset(), dynamicClient)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return svc
+}
+
+// testDatabaseCluster 回傳 CNPG Cluster，labels 為 nil 時是 labels 導入前建立的 Cluster
+func testDatabaseCluster(name string, ref string, labels map[string]string) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	obj.SetAPIVersion(clusterGVR.GroupVersion().String())
+	obj.SetKind("Cluster")
+	obj.SetNamespace(testProjectNamespace)
+	obj.SetName(name)
+	obj.SetLabels(labels)
+	return obj
+}
+
+// fakeClusterRepository 回傳記錄的專案叢集，err 不為 nil 時讀取失敗
+type fakeClusterRepository struct {
+	mu       sync.Mutex
+	clusters map[string]string
+	err      error
+	calls    int
+}
+
+func (r *fakeClusterRepository) FindClusterByRef(ctx context.Context, ref string) (string, bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls++
+	if r.err != nil {
+		return "", false, r.err
+	}
+	cluster, ok := r.clusters[ref]
+	return cluster, ok, nil
+}
+
+func (r *fakeClusterRepository) set(ref string, cluster string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.clusters[ref] = cluster
+}
+
+func newTestRouter(repo ProjectClusterRepository, defaultName string, clusters ...*service) *router {
+	r := &router{
+		repo:        repo,
+		clusters:    clusters,
+		byName:      map[string]*service{},
+		defaultName: defaultName,
+		refs:        map[string]*service{},
+	}
+	for _, svc := range clusters {
+		r.byName[svc.cluster.Name] = svc
+	}
+	return r
+}
+
+func TestRouterPlaceProject(t *testing.T) {
+	type cluster struct {
+		name        string
+		region      string
+		maxProjects int
+		projects    int
+	}
+	tests := []struct {
+		name     string
+		clusters []cluster
+		region   string
+		want     string
+		wantErr  error
+	}{
+		{
+			name:     "fewest projects",
+			clusters: []cluster{{name: "a", projects: 3}, {name: "b", projects: 1}, {name: "c", projects: 2}},
+			want:     "b",
+		},
+		{
+			name:     "tie uses config order",
+			clusters: []cluster{{name: "a", projects: 2}, {name: "b", projects: 1}, {name: "c", projects: 1}},
+			want:     "b",
+		},
+		{
+			name:     "region",
+			clusters: []cluster{{name: "a", region: "eu"}, {name: "b", region: "us", projects: 5}},
+			region:   "us",
+			want:     "b",
+		},
+		{
+			name:     "full cluster skipped",
+			clusters: []cluster{{name: "a", maxProjects: 2, projects: 2}, {name: "b", maxProjects: 10, projects: 5}},
+			want:     "b",
+		},
+		{
+			name:     "unlimited cluster",
+			clusters: []cluster{{name: "a", maxProjects: 1, projects: 1}, {name: "b", projects: 30}},
+			want:     "b",
+		},
+		{
+			name:     "no region",
+			clusters: []cluster{{name: "a", region: "eu"}},
+			region:   "us",
+			wantErr:  ErrNoClusterInRegion,
+		},
+		{
+			name:     "all clusters in region full",
+			clusters: []cluster{{name: "a", region: "us", maxProjects: 1, projects: 1}, {name: "b", region: "eu"}},
+			region:   "us",
+			wantErr:  ErrNoClusterAvailable,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var services []*service
+			for _, c := range tt.clusters {
+				services = append(services, newTestCluster(t,
+					config.KubeClusterConfig{Name: c.name, Region: c.region, MaxProjects: c.maxProjects},
+					testRefs(c.name, c.projects)...,
+				))
+			}
+			r := newTestRouter(&fakeClusterRepository{}, tt.clusters[0].name, services...)
+
+			got, err := r.PlaceProject(context.Background(), tt.region)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("PlaceProject error = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("PlaceProject = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRouterForRef(t *testing.T) {
+	recorded, legacy, deleted, missing := testRefs("r", 1)[0], testRefs("l", 1)[0], testRefs("d", 1)[0], testRefs("m", 1)[0]
+
+	tests := []struct {
+		name       string
+		ref        string
+		repoErr    error
+		want       string
+		wantErr    error
+		wantCached bool
+	}{
+		{name: "recorded cluster", ref: recorded, want: "b", wantCached: true},
+		{name: "not recorded uses default", ref: legacy, want: "a", wantCached: true},
+		{name: "deleted record found in cluster", ref: deleted, want: "b", wantCached: true},
+		{name: "not found uses default without caching", ref: missing, want: "a", wantCached: false},
+		{name: "unknown cluster", ref: testRefs("u", 1)[0], wantErr: ErrUnknownCluster},
+		{name: "repository error", ref: recorded, repoErr: errors.New("connection refused"), wantErr: ErrFailedToFindCluster},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakeClusterRepository{
+				clusters: map[string]string{recorded: "b", legacy: "", testRefs("u", 1)[0]: "removed"},
+				err:      tt.repoErr,
+			}
+			r := newTestRouter(repo, "a",
+				newTestCluster(t, config.KubeClusterConfig{Name: "a"}),
+				newTestCluster(t, config.KubeClusterConfig{Name: "b"}, deleted),
+			)
+
+			svc, err := r.forRef(context.Background(), tt.ref)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("forRef error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if svc.cluster.Name != tt.want {
+				t.Errorf("forRef = %q, want %q", svc.cluster.Name, tt.want)
+			}
+			if _, cached := r.refs[tt.ref]; cached != tt.wantCached {
+				t.Errorf("cached = %v, want %v", cached, tt.wantCached)
+			}
+		})
+	}
+}
+
+func TestRouterForRefAfterPlacement(t *testing.T) {
+	ctx := context.Background()
+	ref := testRefs("p", 1)[0]
+	repo := &fakeClusterRepository{clusters: map[string]string{}}
+	r := newTestRouter(repo, "a",
+		newTestCluster(t, config.KubeClusterConfig{Name: "a"}),
+		newTestCluster(t, config.KubeClusterConfig{Name: "b"}),
+	)
+
+	// 專案記錄建立前的查詢不影響之後放置的叢集
+	if svc, err := r.forRef(ctx, ref); err != nil || svc.cluster.Name != "a" {
+		t.Fatalf("forRef before the project exists = %v, %v", svc, err)
+	}
+	repo.set(ref, "b")
+	if svc, err := r.forRef(ctx, ref); err != nil || svc.cluster.Name != "b" {
+		t.Fatalf("forRef after placement = %v, %v, want b", svc, err)
+	}
+
+	// 快取的叢集在記錄變更後移除
+	repo.set(ref, "a")
+	if svc, _ := r.forRef(ctx, ref); svc.cluster.Name != "b" {
+		t.Fatalf("forRef = %q, want the cached cluster b", svc.cluster.Name)
+	}
+	r.ForgetProjectCluster(ref)
+	if svc, err := r.forRef(ctx, ref); err != nil || svc.cluster.Name != "a" {
+		t.Errorf("forRef after ForgetProjectCluster = %v, %v, want a", svc, err)
+	}
+	calls := repo.calls
+	if _, err := r.forRef(ctx, ref); err != nil || repo.calls != calls {
+		t.Errorf("forRef read the repository again (%d calls), want the cached cluster", repo.calls-calls)
+	}
+}
+
+func TestRouterSingleCluster(t *testing.T) {
+	repo := &fakeClusterRepository{err: fmt.Errorf("not used")}
+	r := newTestRouter(repo, "a", newTestCluster(t, config.KubeClusterConfig{Name: "a"}))
+	svc, err := r.forRef(context.Background(), testRefs("s", 1)[0])
+	if err != nil || svc.cluster.Name != "a" || repo.calls != 0 {
+		t.Errorf("forRef = %v, %v with %d repository calls", svc, err, repo.calls)
+	}
+}
@@ -0,0 +1,282 @@
+// Command routergen 產生 kubeproject router 轉發 Service 方法的程式碼 (router_gen.go)。
+//
+// 以 (ctx context.Context, ref string, ...) 開頭的方法轉發給專案所在叢集的 service，
+// 沒有 context 的方法只由設定決定，轉發給預設叢集；已在 router 手寫的方法不會產生。
+//
+// 在 internal/kubeproject 目錄執行：go generate ./...
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const (
+	outputFile    = "router_gen.go"
+	interfaceName = "Service"
+	receiverType  = "router"
+)
+
+func main() {
+	if err := run("."); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func run(dir string) error {
+	fset := token.NewFileSet()
+	files, err := parsePackage(fset, dir)
+	if err != nil {
+		return err
+	}
+
+	iface, imports := findInterface(files)
+	if iface == nil {
+		return fmt.Errorf("interface %s not found", interfaceName)
+	}
+	handwritten := routerMethods(files)
+
+	var body bytes.Buffer
+	used := map[string]bool{}
+	for _, field := range iface.Methods.List {
+		fn, ok := field.Type.(*ast.FuncType)
+		if !ok || len(field.Names) == 0 {
+			return fmt.Errorf("embedded interfaces are not supported")
+		}
+		name := field.Names[0].Name
+		if handwritten[name] {
+			continue
+		}
+		method, err := generateMethod(fset, name, fn, used)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		body.WriteString(method)
+	}
+
+	var out bytes.Buffer
+	out.WriteString("// Code generated by routergen. DO NOT EDIT.\n\npackage kubeproject\n\nimport (\n")
+	// 保留 service.go 的 import 分組 (以空行分隔)
+	group, prevLine, prevGroup := 0, 0, -1
+	for _, spec := range imports {
+		line := fset.Position(spec.Pos()).Line
+		if prevLine > 0 && line > prevLine+1 {
+			group++
+		}
+		prevLine = line
+		if !used[importName(spec)] {
+			continue
+		}
+		if prevGroup >= 0 && group != prevGroup {
+			out.WriteString("\n")
+		}
+		prevGroup = group
+		out.WriteString("\t" + nodeString(fset, spec) + "\n")
+	}
+	out.WriteString(")\n")
+	out.Write(body.Bytes())
+
+	src, err := format.Source(out.Bytes())
+	if err != nil {
+		return fmt.Errorf("format generated code: %w", err)
+	}
+	return os.WriteFile(filepath.Join(dir, outputFile), src, 0o644)
+}
+
+// parsePackage 解析目錄中的非測試、非產生的 Go 檔案
+func parsePackage(fset *token.FileSet, dir string) ([]*ast.File, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return nil, err
+	}
+	var files []*ast.File
+	for _, path := range paths {
+		if strings.HasSuffix(path, "_test.go") || filepath.Base(path) == outputFile {
+			continue
+		}
+		file, err := parser.ParseFile(fset, path, nil, parser.SkipObjectResolution)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+	return files, nil
+}
+
+// findInterface 回傳 Service interface 與其所在檔案的 import
+func findInterface(files []*ast.File) (*ast.InterfaceType, []*ast.ImportSpec) {
+	for _, file := range files {
+		for _, decl := range file.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok || gen.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range gen.Specs {
+				typeSpec := spec.(*ast.TypeSpec)
+				if iface, ok := typeSpec.Type.(*ast.InterfaceType); ok && typeSpec.Name.Name == interfaceName {
+					return iface, file.Imports
+				}
+			}
+		}
+	}
+	return nil, nil
+}
+
+// routerMethods 回傳已在 router 手寫的方法
+func routerMethods(files []*ast.File) map[string]bool {
+	methods := map[string]bool{}
+	for _, file := range files {
+		for _, decl := range file.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok || fn.Recv == nil || len(fn.Recv.List) != 1 {
+				continue
+			}
+			if star, ok := fn.Recv.List[0].Type.(*ast.StarExpr); ok {
+				if ident, ok := star.X.(*ast.Ident); ok && ident.Name == receiverType {
+					methods[fn.Name.Name] = true
+				}
+			}
+		}
+	}
+	return methods
+}
+
+func generateMethod(fset *token.FileSet, name string, fn *ast.FuncType, used map[string]bool) (string, error) {
+	var params, args []string
+	var paramTypes []string
+	for i, field := range fn.Params.List {
+		typ := nodeString(fset, field.Type)
+		markUsed(field.Type, used)
+		names := field.Names
+		if len(names) == 0 {
+			names = []*ast.Ident{ast.NewIdent("p" + strconv.Itoa(i))}
+		}
+		for _, n := range names {
+			params = append(params, n.Name+" "+typ)
+			paramTypes = append(paramTypes, typ)
+			if strings.HasPrefix(typ, "...") {
+				args = append(args, n.Name+"...")
+			} else {
+				args = append(args, n.Name)
+			}
+		}
+	}
+
+	var results []ast.Expr
+	if fn.Results != nil {
+		for _, field := range fn.Results.List {
+			markUsed(field.Type, used)
+			for range max(len(field.Names), 1) {
+				results = append(results, field.Type)
+			}
+		}
+	}
+	resultTypes := make([]string, len(results))
+	for i, result := range results {
+		resultTypes[i] = nodeString(fset, result)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "\nfunc (r *%s) %s(%s)", receiverType, name, strings.Join(params, ", "))
+	switch len(resultTypes) {
+	case 0:
+	case 1:
+		b.WriteString(" " + resultTypes[0])
+	default:
+		b.WriteString(" (" + strings.Join(resultTypes, ", ") + ")")
+	}
+	b.WriteString(" {\n")
+
+	call := "c." + name + "(" + strings.Join(args, ", ") + ")"
+	hasContext := len(paramTypes) > 0 && paramTypes[0] == "context.Context"
+	switch {
+	case !hasContext:
+		// 與叢集無關的方法只由設定決定
+		call = "r.defaultCluster()." + strings.TrimPrefix(call, "c.")
+	case len(paramTypes) < 2 || paramTypes[1] != "string" || args[1] != "ref":
+		return "", fmt.Errorf("methods with a context must take ref as the second parameter or be written by hand in router.go")
+	case len(resultTypes) == 0 || resultTypes[len(resultTypes)-1] != "error":
+		return "", fmt.Errorf("methods with a context must return an error or be written by hand in router.go")
+	default:
+		zeros := make([]string, 0, len(results))
+		for _, result := range results[:len(results)-1] {
+			zero, err := zeroValue(result)
+			if err != nil {
+				return "", err
+			}
+			zeros = append(zeros, zero)
+		}
+		zeros = append(zeros, "err")
+		fmt.Fprintf(&b, "\tc, err := r.forRef(%s, %s)\n\tif err != nil {\n\t\treturn %s\n\t}\n", args[0], args[1], strings.Join(zeros, ", "))
+	}
+	if len(resultTypes) == 0 {
+		b.WriteString("\t" + call + "\n}\n")
+	} else {
+		b.WriteString("\treturn " + call + "\n}\n")
+	}
+	return b.String(), nil
+}
+
+// zeroValue 回傳型別的零值運算式，只支援 router 會用到的型別
+func zeroValue(expr ast.Expr) (string, error) {
+	switch t := expr.(type) {
+	case *ast.StarExpr, *ast.ArrayType, *ast.MapType, *ast.ChanType, *ast.FuncType, *ast.InterfaceType:
+		return "nil", nil
+	case *ast.Ident:
+		switch {
+		case t.Name == "string":
+			return `""`, nil
+		case t.Name == "bool":
+			return "false", nil
+		case t.Name == "error" || t.Name == "any":
+			return "nil", nil
+		case slices.Contains([]string{"int", "int8", "int16", "int32", "int64", "uint", "uint8", "uint16", "uint32", "uint64", "float32", "float64", "byte", "rune"}, t.Name):
+			return "0", nil
+		}
+	}
+	return "", fmt.Errorf("unsupported result type %T, return a pointer or write the method by hand in router.go", expr)
+}
+
+// markUsed 記錄型別中用到的 package
+func markUsed(expr ast.Expr, used map[string]bool) {
+	ast.Inspect(expr, func(n ast.Node) bool {
+		if sel, ok := n.(*ast.SelectorExpr); ok {
+			if ident, ok := sel.X.(*ast.Ident); ok {
+				used[ident.Name] = true
+			}
+		}
+		return true
+	})
+}
+
+func importName(spec *ast.ImportSpec) string {
+	if spec.Name != nil {
+		return spec.Name.Name
+	}
+	path, _ := strconv.Unquote(spec.Path.Value)
+	parts := strings.Split(path, "/")
+	name := parts[len(parts)-1]
+	// 例如 github.com/samber/do/v2
+	if len(parts) > 1 && len(name) > 1 && name[0] == 'v' && strings.Trim(name[1:], "0123456789") == "" {
+		name = parts[len(parts)-2]
+	}
+	return name
+}
+
+func nodeString(fset *token.FileSet, node ast.Node) string {
+	var b bytes.Buffer
+	if err := format.Node(&b, fset, node); err != nil {
+		log.Fatal(err)
+	}
+	return b.String()
+}
@@ -7,6 +7,7 @@ import (
 	"context"
 	"fmt"
 	"path/filepath"
+	"time"
 
 	"baas-api/internal/config"
 
@@ -21,20 +22,78 @@ import (
 
 type Service interface {
 	// === 基礎設施層 ===
+	// Project Namespace (namespace-per-project isolation)
+	GetProjectNamespace(ref string) string
+	EnsureProjectNamespace(ctx context.Context, ref string) error
+	DeleteProjectNamespace(ctx context.Context, ref string) error
+	SyncProjectTLSSecret(ctx context.Context, ref string) error
+	// StartTLSSecretSync 在背景將共用 TLS secret 的更新同步到所有專案 namespace
+	StartTLSSecretSync()
+
 	// Prepare Cluster Required Resources
 	CreateJWKSConfigMap(ctx context.Context, opt CreateJWKSConfigMapOption) error
 	DeleteJWKSConfigMap(ctx context.Context, ref string) error
 
 	// CNPG Cluster 管理
-	CreateCluster(ctx context.Context, ref string, storageSize string) error
+	CreateCluster(ctx context.Context, ref string, opt *ClusterOption) error
 	DeleteCluster(ctx context.Context, ref string) error
 	FindClusterStatus(ctx context.Context, ref string) (*string, error)
 	WaitClusterHealthy(ctx context.Context, ref string) error
+	// WatchProject 訂閱專案資源的變更通知 (共用 informer)
+	WatchProject(ctx context.Context, ref string) (<-chan struct{}, error)
+	ListProjectEvents(ctx context.Context, ref string, since time.Time) ([]ProjectEvent, error)
+	ApplyDatabaseCluster(ctx context.Context, ref string, cluster config.DatabaseClusterConfig) error
+	GetProjectHost(ref string) string
+	GetProjectReadOnlyHost(ref string) string
+	GetRealtimeURL(ref string) string
+	// FindDatabaseCACertificate 回傳用戶端驗證資料庫 TLS 憑證的 CA (PEM)
+	FindDatabaseCACertificate(ctx context.Context, ref string) (string, error)
+	FindDatabaseCluster(ctx context.Context, ref string) (*DatabaseClusterInfo, error)
+	FindPostgresParameters(ctx context.Context, ref string) (map[string]string, error)
+	// Connection Pooling (CNPG Pooler)
+	ApplyPooler(ctx context.Context, ref string, opt PoolerOption) error
+	FindPooler(ctx context.Context, ref string) (*PoolerInfo, error)
+	DeletePooler(ctx context.Context, ref string) error
+	ApplyRESTAPIPooler(ctx context.Context, ref string, enabled bool, poolMode string) error
+	FindRESTAPIPooler(ctx context.Context, ref string) (bool, error)
+	ApplyPostgresParameters(ctx context.Context, ref string, parameters map[string]string) error
 
 	// Database Management
 	CreateDatabase(ctx context.Context, ref string) error
 	DeleteDatabase(ctx context.Context, ref string) error
 	CreateMigrationJob(ctx context.Context, ref string) error
+	WaitMigrationJob(ctx context.Context, ref string) (*MigrationResult, error)
+	FindMigrationResult(ctx context.Context, ref string) (*MigrationResult, error)
+	FindMigrationLogs(ctx context.Context, ref string) (*MigrationResult, error)
+	// User Migrations (使用者上傳的 dbmate migrations)
+	ListUserMigrationFiles(ctx context.Context, ref string) (map[string]string, error)
+	PutUserMigrationFile(ctx context.Context, ref string, filename string, content string) error
+	DeleteUserMigrationFile(ctx context.Context, ref string, filename string) error
+	RunUserMigrations(ctx context.Context, ref string, action string) (*MigrationResult, error)
+	WaitUserMigrationJob(ctx context.Context, ref string, run *MigrationResult) (*MigrationResult, error)
+	FindUserMigrationResult(ctx context.Context, ref string) (*MigrationResult, error)
+	FindUserMigrationLogs(ctx context.Context, ref string) (*MigrationResult, error)
+	IsExtensionAllowed(name string) bool
+	FindDatabaseExtensions(ctx context.Context, ref string) ([]DatabaseExtension, error)
+	ApplyDatabaseExtensions(ctx context.Context, ref string, extensions []DatabaseExtensionSpec) error
+
+	// Backup & Restore (CNPG barman object store)
+	GetBackupDestinationPath(ref string) string
+	CreateBackupCredentialsSecret(ctx context.Context, ref string, accessKeyID string, secretAccessKey string) error
+	CreateScheduledBackup(ctx context.Context, ref string) error
+	CreateBackup(ctx context.Context, ref string) (*BackupInfo, error)
+	ListBackups(ctx context.Context, ref string) ([]BackupInfo, error)
+	FindBackup(ctx context.Context, ref string, name string) (*BackupInfo, error)
+	RestoreCluster(ctx context.Context, ref string, opt *ClusterRecoveryOption) error
+	RestartAPIDeployments(ctx context.Context, ref string) error
+
+	// Point-in-time Recovery
+	GetPITRHost(ref string) string
+	FindBackupServerName(ctx context.Context, ref string) (string, error)
+	FindRecoveryWindow(ctx context.Context, ref string) (*RecoveryWindow, error)
+	CreatePITRCluster(ctx context.Context, ref string, opt *ClusterRecoveryOption) error
+	FindPITRCluster(ctx context.Context, ref string) (*PITRClusterInfo, error)
+	DeletePITRCluster(ctx context.Context, ref string) error
 
 	// Database Role Management
 	FindDatabaseRoleSecret(ctx context.Context, ref string, role string) (*corev1.Secret, error)
@@ -56,34 +115,68 @@ type Service interface {
 	DeleteRESTAPIDeployment(ctx context.Context, ref string) error
 	CreateRESTAPIService(ctx context.Context, ref string) error
 	DeleteRESTAPIService(ctx context.Context, ref string) error
+	// JWKS rotation (PGRST_JWT_SECRET)
+	FindRESTAPIJWKS(ctx context.Context, ref string) (string, error)
+	ApplyRESTAPIJWKS(ctx context.Context, ref string, jwks string) error
+	WaitRESTAPIRollout(ctx context.Context, ref string) error
+	// Cron jobs (排程工作)
+	ListCronJobs(ctx context.Context, ref string) ([]CronJobInfo, error)
+	FindCronJob(ctx context.Context, ref string, name string) (*CronJobInfo, error)
+	ApplyCronJob(ctx context.Context, ref string, opt CronJobOption) error
+	DeleteCronJob(ctx context.Context, ref string, name string) error
+	TriggerCronJob(ctx context.Context, ref string, name string) (*CronJobRun, error)
+	ListCronJobRuns(ctx context.Context, ref string, name string) ([]CronJobRun, error)
+	FindCronJobRunLogs(ctx context.Context, ref string, name string, jobName string) (*CronJobRun, error)
+	// REST API settings (PostgREST 設定)
+	FindRESTAPISettings(ctx context.Context, ref string) (*RESTAPISettings, error)
+	ApplyRESTAPISettings(ctx context.Context, ref string, settings RESTAPISettings) error
+	// Realtime (變更推送，選用元件)
+	FindRealtime(ctx context.Context, ref string) (*RealtimeStatus, error)
+	ApplyRealtime(ctx context.Context, ref string) error
+	DeleteRealtime(ctx context.Context, ref string) error
+
+	// Container Logs
+	StreamProjectLogs(ctx context.Context, ref string, opt LogStreamOption, lines chan<- LogLine) error
+
+	// API Workload (replicas, resources, HPA, PDB)
+	ApplyAPIWorkload(ctx context.Context, ref string, component string, workload config.WorkloadConfig) error
+	FindAPIWorkload(ctx context.Context, ref string, component string) (*config.WorkloadConfig, error)
 
 	// === 網路層 ===
-	// Ingress for REST API (PostgREST) and Auth API
+	// Ingress for REST API (PostgREST) and Auth API (Traefik or Gateway API, see ingressProvider)
 	CreateIngressRoute(ctx context.Context, ref string) error
 	DeleteIngressRoute(ctx context.Context, ref string) error
 	CreateIngressRouteTCP(ctx context.Context, ref string) error
 	DeleteIngressRouteTCP(ctx context.Context, ref string) error
+
+	// === 專案層 ===
+	// PlaceProject 依 region 與叢集容量為新專案選擇叢集，回傳應記錄在專案上的叢集名稱 (單一叢集時為空字串)
+	PlaceProject(ctx context.Context, region string) (string, error)
+	// ForgetProjectCluster 移除快取的專案叢集，專案記錄的叢集變更或建立失敗時呼叫
+	ForgetProjectCluster(ref string)
+	// DeleteAllForProject 依 label selector 刪除專案的所有資源
+	DeleteAllForProject(ctx context.Context, ref string) error
 }
 
 var _ Service = (*service)(nil)
 
 type service struct {
 	config        *config.Config `do:""`
-	kubeConfig    *rest.Config
-	clientset     *kubernetes.Clientset
-	dynamicClient *dynamic.DynamicClient
+	clientset     kubernetes.Interface
+	dynamicClient dynamic.Interface
 	namespace     string
+	watcher       *statusWatcher
+	ingress       ingressProvider
+	// cluster 是 service 操作的叢集，未設定 config.Kube.Clusters 時名稱為空
+	cluster config.KubeClusterConfig
 }
 
-func NewService(i do.Injector) (*service, error) {
+// NewRESTConfig 建立連線到 Kubernetes API server 的設定
+func NewRESTConfig(i do.Injector) (*rest.Config, error) {
 	cfg := do.MustInvoke[*config.Config](i)
-	svc := &service{}
-
-	var kc *rest.Config
-	var err error
 
 	// 1. 優先嘗試讀取 In-Cluster Config (適用於 Pod 內部 / 生產環境)
-	kc, err = rest.InClusterConfig()
+	kc, err := rest.InClusterConfig()
 	// 2. 如果 In-Cluster 失敗 (代表可能在 Local 開發環境)，則嘗試讀取 kubeconfig
 	if err != nil {
 		kubeConfigPath := cfg.Kube.ConfigPath
@@ -102,24 +195,76 @@ func NewService(i do.Injector) (*service, error) {
 			return nil, fmt.Errorf("無法初始化 K8s Config (InCluster 失敗且無法讀取 %s): %w", kubeConfigPath, err)
 		}
 	}
+	kc.WarningHandler = rest.NoWarnings{} // 忽略 API 警告
 
-	// 3. 初始化 Clients
-	clientset, err := kubernetes.NewForConfig(kc)
+	return kc, nil
+}
+
+// clusterRESTConfig 建立 config.Kube.Clusters 中一個叢集的連線設定，ConfigPath 為空時使用 in-cluster config
+func clusterRESTConfig(cluster config.KubeClusterConfig) (*rest.Config, error) {
+	var kc *rest.Config
+	var err error
+	if cluster.ConfigPath == "" {
+		kc, err = rest.InClusterConfig()
+	} else {
+		kc, err = clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+			&clientcmd.ClientConfigLoadingRules{ExplicitPath: cluster.ConfigPath},
+			&clientcmd.ConfigOverrides{CurrentContext: cluster.Context},
+		).ClientConfig()
+	}
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("failed to load config of cluster %q: %w", cluster.Name, err)
 	}
-	dynamicClient, err := dynamic.NewForConfig(kc)
+	kc.WarningHandler = rest.NoWarnings{} // 忽略 API 警告
+	return kc, nil
+}
+
+// NewClientset 建立 typed client，測試時以 client-go 的 fake clientset 取代
+func NewClientset(i do.Injector) (kubernetes.Interface, error) {
+	return kubernetes.NewForConfig(do.MustInvoke[*rest.Config](i))
+}
+
+// NewDynamicClient 建立 CRD (CNPG、Traefik、Gateway API) 使用的 dynamic client，測試時以 fake dynamic client 取代
+func NewDynamicClient(i do.Injector) (dynamic.Interface, error) {
+	return dynamic.NewForConfig(do.MustInvoke[*rest.Config](i))
+}
+
+// NewService 建立 kubeproject.Service。
+//
+// 未設定 config.Kube.Clusters 時回傳操作注入的 kubernetes.Interface 與 dynamic.Interface 的 service，
+// 否則回傳依專案所在叢集轉發呼叫的 router。
+func NewService(i do.Injector) (Service, error) {
+	cfg := do.MustInvoke[*config.Config](i)
+	if len(cfg.Kube.Clusters) > 0 {
+		return newRouter(i, cfg)
+	}
+
+	svc, err := newService(cfg, config.KubeClusterConfig{},
+		do.MustInvoke[kubernetes.Interface](i),
+		do.MustInvoke[dynamic.Interface](i),
+	)
 	if err != nil {
 		return nil, err
 	}
+	return svc, nil
+}
+
+// newService 建立操作單一叢集的 service
+func newService(cfg *config.Config, cluster config.KubeClusterConfig, clientset kubernetes.Interface, dynamicClient dynamic.Interface) (*service, error) {
+	svc := &service{
+		config:        cfg,
+		clientset:     clientset,
+		dynamicClient: dynamicClient,
+		namespace:     cfg.Kube.Project.Namespace,
+		watcher:       newStatusWatcher(),
+		cluster:       cluster,
+	}
 
-	// 4. 設定 Service 屬性
-	svc.kubeConfig = kc
-	svc.kubeConfig.WarningHandler = rest.NoWarnings{} // 忽略 API 警告
-	svc.clientset = clientset
-	svc.dynamicClient = dynamicClient
-	svc.config = cfg
-	svc.namespace = cfg.Kube.Project.Namespace
+	var err error
+	svc.ingress, err = newIngressProvider(svc)
+	if err != nil {
+		return nil, err
+	}
 
 	return svc, nil
 }
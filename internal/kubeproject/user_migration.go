@@ -0,0 +1,247 @@
+package kubeproject
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"maps"
+	"strconv"
+	"time"
+
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// 使用者 migration 執行的 dbmate 命令
+const (
+	MigrationActionUp       = "up"
+	MigrationActionRollback = "rollback"
+)
+
+// UserMigrationsTable 是使用者 migration 的版本紀錄表。
+//
+// 與平台 migration 的 public.schema_migrations 分開，rollback 時不會回滾平台的 migration。
+const UserMigrationsTable = "baas_migrations.schema_migrations"
+
+// UserMigrationsLimitBytes 是所有使用者 migration 檔案的大小上限，ConfigMap 最大為 1MiB
+const UserMigrationsLimitBytes = 900 * 1024
+
+// userMigration 是最近一次使用者 migration 的 Job，Job 名稱由保存的結果取得
+func (s *service) userMigration(ref string, jobName string) migrationTarget {
+	return migrationTarget{
+		component:  UserMigrationComponent,
+		jobName:    jobName,
+		resultName: s.GetUserMigrationResultConfigMapName(ref),
+	}
+}
+
+// ListUserMigrationFiles 回傳使用者上傳的 migration 檔案 (檔名 → 內容)
+func (s *service) ListUserMigrationFiles(ctx context.Context, ref string) (map[string]string, error) {
+	configMap, err := s.clientset.CoreV1().ConfigMaps(s.GetProjectNamespace(ref)).Get(ctx, s.GetUserMigrationFilesConfigMapName(ref), metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return map[string]string{}, nil
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to get user migrations", "error", err, "ref", ref)
+		return nil, errors.New("failed to get user migrations")
+	}
+	if configMap.Data == nil {
+		return map[string]string{}, nil
+	}
+	return configMap.Data, nil
+}
+
+// PutUserMigrationFile 新增或取代一個 migration 檔案
+func (s *service) PutUserMigrationFile(ctx context.Context, ref string, filename string, content string) error {
+	files, err := s.ListUserMigrationFiles(ctx, ref)
+	if err != nil {
+		return err
+	}
+	files = maps.Clone(files)
+	files[filename] = content
+	return s.applyUserMigrationFiles(ctx, ref, files)
+}
+
+// DeleteUserMigrationFile 移除一個 migration 檔案，檔案不存在時不做任何事
+func (s *service) DeleteUserMigrationFile(ctx context.Context, ref string, filename string) error {
+	files, err := s.ListUserMigrationFiles(ctx, ref)
+	if err != nil {
+		return err
+	}
+	if _, ok := files[filename]; !ok {
+		return nil
+	}
+	files = maps.Clone(files)
+	delete(files, filename)
+	return s.applyUserMigrationFiles(ctx, ref, files)
+}
+
+// applyUserMigrationFiles 以 server-side apply 寫入所有 migration 檔案。
+//
+// ConfigMap 不設定 ownerReference，以備份還原切換 Cluster 時不會被移除，刪除專案時依 label 移除。
+func (s *service) applyUserMigrationFiles(ctx context.Context, ref string, files map[string]string) error {
+	size := 0
+	for filename, content := range files {
+		size += len(filename) + len(content)
+	}
+	if size > UserMigrationsLimitBytes {
+		return ErrUserMigrationsTooLarge
+	}
+
+	name := s.GetUserMigrationFilesConfigMapName(ref)
+	configMap := &corev1.ConfigMap{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "v1",
+			Kind:       "ConfigMap",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: s.GetProjectNamespace(ref),
+			Labels:    projectLabels(ref, UserMigrationComponent),
+		},
+		Data: files,
+	}
+	payload, err := json.Marshal(configMap)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to marshal user migrations", "error", err, "ref", ref)
+		return errors.New("failed to marshal user migrations")
+	}
+	_, err = s.clientset.CoreV1().ConfigMaps(s.GetProjectNamespace(ref)).Patch(
+		ctx,
+		name,
+		types.ApplyPatchType,
+		payload,
+		applyPatchOptions(FieldManager),
+	)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to apply user migrations", "error", err, "ref", ref)
+		return errors.New("failed to apply user migrations")
+	}
+	return nil
+}
+
+// RunUserMigrations 建立執行 dbmate up 或 rollback 的 Job，回傳 pending 狀態的結果。
+//
+// 每次執行使用新的 Job 名稱；上一次執行尚未結束時回傳 ErrMigrationRunning。
+func (s *service) RunUserMigrations(ctx context.Context, ref string, action string) (*MigrationResult, error) {
+	files, err := s.ListUserMigrationFiles(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, ErrNoUserMigrations
+	}
+
+	running, err := s.isUserMigrationRunning(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	if running {
+		return nil, ErrMigrationRunning
+	}
+
+	ownerRef, err := s.clusterOwnerReference(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	jobName := generateResourceName(ref, UserMigrationComponent, strconv.FormatInt(time.Now().Unix(), 36))
+	job := s.dbmateJob(ref, jobName, UserMigrationComponent,
+		[]string{"--wait", "--no-dump-schema", action},
+		[]corev1.EnvVar{{Name: "DBMATE_MIGRATIONS_TABLE", Value: UserMigrationsTable}},
+		[]corev1.VolumeProjection{
+			{ConfigMap: &corev1.ConfigMapProjection{LocalObjectReference: corev1.LocalObjectReference{Name: s.GetUserMigrationFilesConfigMapName(ref)}}},
+		},
+		ownerRef,
+	)
+	// rollback 失敗時重試可能回滾更多版本
+	job.Spec.BackoffLimit = lo.ToPtr(int32(0))
+
+	data, err := json.Marshal(job)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to marshal user migration job", "error", err, "jobName", jobName)
+		return nil, errors.New("failed to marshal user migration job")
+	}
+	_, err = s.clientset.BatchV1().Jobs(s.GetProjectNamespace(ref)).Patch(
+		ctx,
+		jobName,
+		types.ApplyPatchType,
+		data,
+		applyPatchOptions(FieldManager),
+	)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to create user migration job", "error", err, "jobName", jobName)
+		return nil, errors.New("failed to create user migration job")
+	}
+
+	// 先記錄 pending 狀態，查詢時才能找到這次執行的 Job
+	result := &MigrationResult{
+		JobName: jobName,
+		Action:  action,
+		Status:  MigrationPending,
+	}
+	if err := s.recordMigrationResult(ctx, ref, s.userMigration(ref, jobName), result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// isUserMigrationRunning 回傳上一次使用者 migration 是否仍在執行。
+//
+// 保存的結果未結束但 Job 已不存在 (例如 API 在等待時重新啟動) 時視為已結束。
+func (s *service) isUserMigrationRunning(ctx context.Context, ref string) (bool, error) {
+	recorded, err := s.findRecordedMigrationResult(ctx, ref, s.userMigration(ref, ""))
+	if err != nil {
+		return false, err
+	}
+	if recorded == nil || recorded.IsFinished() {
+		return false, nil
+	}
+	job, err := s.findMigrationJob(ctx, ref, recorded.JobName)
+	if apierrors.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to get user migration job", "error", err, "ref", ref)
+		return false, errors.New("failed to get user migration job")
+	}
+	return !migrationJobResult(job).IsFinished(), nil
+}
+
+// WaitUserMigrationJob 等待 RunUserMigrations 建立的 Job 結束並保存結果
+func (s *service) WaitUserMigrationJob(ctx context.Context, ref string, run *MigrationResult) (*MigrationResult, error) {
+	return s.waitMigrationJob(ctx, ref, s.userMigration(ref, run.JobName), run.Action)
+}
+
+// FindUserMigrationResult 回傳最近一次使用者 migration 的狀態，沒有執行過時回傳 ErrMigrationNotFound
+func (s *service) FindUserMigrationResult(ctx context.Context, ref string) (*MigrationResult, error) {
+	target, err := s.lastUserMigration(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	return s.findMigrationResult(ctx, ref, target)
+}
+
+// FindUserMigrationLogs 回傳最近一次使用者 migration 的 dbmate 輸出
+func (s *service) FindUserMigrationLogs(ctx context.Context, ref string) (*MigrationResult, error) {
+	target, err := s.lastUserMigration(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	return s.findMigrationLogs(ctx, ref, target)
+}
+
+func (s *service) lastUserMigration(ctx context.Context, ref string) (migrationTarget, error) {
+	recorded, err := s.findRecordedMigrationResult(ctx, ref, s.userMigration(ref, ""))
+	if err != nil {
+		return migrationTarget{}, err
+	}
+	if recorded == nil {
+		return migrationTarget{}, ErrMigrationNotFound
+	}
+	return s.userMigration(ref, recorded.JobName), nil
+}
@@ -8,12 +8,26 @@ import (
 
 // Constants for better maintainability
 const (
-	AuthAPIComponent    = "auth-api"
-	RestAPIComponent    = "rest-api"
-	APIIngressComponent = "api"
-	DBComponent         = "db"
-	PGRSTComponent      = "pgrst"
-	OpenAPIComponent    = "openapi"
+	AuthAPIComponent       = "auth-api"
+	RestAPIComponent       = "rest-api"
+	APIIngressComponent    = "api"
+	DBComponent            = "db"
+	DBRestoreComponent     = "db-restore"
+	DBReadOnlyComponent    = "db-ro"
+	PoolerComponent        = "pooler"
+	PGRSTComponent         = "pgrst"
+	OpenAPIComponent       = "openapi"
+	JWKSComponent          = "jwks"
+	MigrationComponent     = "migration"
+	UserMigrationComponent = "user-migration"
+	RESTSettingsComponent  = "rest-settings"
+	CronComponent          = "cron"
+	RealtimeComponent      = "realtime"
+	IsolationComponent     = "isolation"
+	TLSComponent           = "tls"
+	BackupComponent        = "backup"
+	PITRComponent          = "pitr"
+	APISecretComponent     = "api-secret"
 
 	RoleApp           = "app"
 	RoleAuthenticator = "authenticator"
@@ -45,11 +59,41 @@ func (s *service) GetRESTAPIURL(ref string) string {
 }
 
 func (s *service) GetJWKSConfigMapName(ref string) string {
-	return generateResourceName(ref, "jwks")
+	return generateResourceName(ref, JWKSComponent)
 }
 
 func (s *service) GetMigrationJobName(ref string) string {
-	return generateResourceName(ref, "migration")
+	return generateResourceName(ref, MigrationComponent)
+}
+
+// GetMigrationResultConfigMapName 是保存 migration 結果與輸出的 ConfigMap
+func (*service) GetMigrationResultConfigMapName(ref string) string {
+	return generateResourceName(ref, MigrationComponent, "result")
+}
+
+// GetUserMigrationFilesConfigMapName 是保存使用者上傳的 migration 檔案的 ConfigMap
+func (*service) GetUserMigrationFilesConfigMapName(ref string) string {
+	return generateResourceName(ref, UserMigrationComponent, "files")
+}
+
+// GetRESTAPISettingsConfigMapName 是保存專案 PostgREST 設定的 ConfigMap
+func (*service) GetRESTAPISettingsConfigMapName(ref string) string {
+	return generateResourceName(ref, RESTSettingsComponent)
+}
+
+// GetCronJobName 是專案排程工作的 CronJob，排程建立的 Job 名稱會再加上 11 個字元
+func (*service) GetCronJobName(ref string, name string) string {
+	return generateResourceName(ref, CronComponent, name)
+}
+
+// GetUserMigrationResultConfigMapName 是保存最近一次使用者 migration 結果的 ConfigMap
+func (*service) GetUserMigrationResultConfigMapName(ref string) string {
+	return generateResourceName(ref, UserMigrationComponent, "result")
+}
+
+// GetAPISecretName 是保存 auth 與 REST API secret 環境變數的 Secret
+func (*service) GetAPISecretName(ref string) string {
+	return generateResourceName(ref, APISecretComponent)
 }
 
 func (*service) GetAuthAPIDeploymentName(ref string) string {
@@ -107,6 +151,68 @@ func (*service) GetDatabaseRWServiceName(ref string) string {
 	return generateResourceName(ref, "rw")
 }
 
+func (*service) GetDatabaseROServiceName(ref string) string {
+	return generateResourceName(ref, "ro")
+}
+
+func (*service) GetDBReadOnlyIngressRouteTCPName(ref string) string {
+	return generateResourceName(ref, DBReadOnlyComponent)
+}
+
+// GetProjectReadOnlyHost 是唯讀 replica 的 SNI host
+func (s *service) GetProjectReadOnlyHost(ref string) string {
+	return generateResourceName(ref, "ro") + "." + s.config.App.ExternalDomain
+}
+
+func (*service) GetPoolerName(ref string) string {
+	return generateResourceName(ref, PoolerComponent)
+}
+
+// GetPoolerHost 是 PgBouncer pooler 的 SNI host
+func (s *service) GetPoolerHost(ref string) string {
+	return s.GetPoolerName(ref) + "." + s.config.App.ExternalDomain
+}
+
+func (*service) GetBackupCredentialsSecretName(ref string) string {
+	return generateResourceName(ref, BackupComponent, "s3")
+}
+
+func (*service) GetScheduledBackupName(ref string) string {
+	return generateResourceName(ref, BackupComponent)
+}
+
+func (*service) GetPITRClusterName(ref string) string {
+	return generateResourceName(ref, PITRComponent)
+}
+
+func (s *service) GetPITRHost(ref string) string {
+	return s.GetPITRClusterName(ref) + "." + s.config.App.ExternalDomain
+}
+
 func generateResourceName(parts ...string) string {
 	return strings.Join(parts, "-")
 }
+
+// ===== Realtime =====
+
+// GetRealtimeURL 是 Realtime 的 WebSocket 端點，路由將 /api/realtime 改寫為 Realtime 的 /socket
+func (s *service) GetRealtimeURL(ref string) string {
+	u := url.URL{
+		Scheme: "wss",
+		Host:   s.GetProjectHost(ref),
+		Path:   "/api/realtime/websocket",
+	}
+	return u.String()
+}
+
+func (*service) GetRealtimeDeploymentName(ref string) string {
+	return generateResourceName(ref, RealtimeComponent)
+}
+
+func (*service) GetRealtimeServiceName(ref string) string {
+	return generateResourceName(ref, RealtimeComponent)
+}
+
+func (*service) GetRealtimeIngressRouteName(ref string) string {
+	return generateResourceName(ref, RealtimeComponent)
+}
@@ -0,0 +1,317 @@
+package kubeproject
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/tools/cache"
+)
+
+// watchResync 是 informer 重新同步 cache 的間隔，變更本身由 watch 即時送達
+const watchResync = 10 * time.Minute
+
+// watchSyncTimeout 是等待 informer 完成第一次 List 的時間，超過時 WatchProject 改以輪詢通知
+const watchSyncTimeout = time.Minute
+
+// watchPollInterval 是 informer 尚未同步時輪詢通知 subscriber 的間隔
+const watchPollInterval = 5 * time.Second
+
+// watchedGVRs 是專案狀態會用到、由共用 informer 監看的資源
+var watchedGVRs = []schema.GroupVersionResource{clusterGVR, deploymentGVR, jobGVR}
+
+// statusWatcher 以一組共用的 dynamic informer 監看所有專案的 Cluster、Deployment、Job 與 Event，
+// 並將變更通知轉發給訂閱該專案的 subscriber，避免每個等待中的呼叫各自輪詢 API server。
+type statusWatcher struct {
+	once    sync.Once
+	factory dynamicinformer.DynamicSharedInformerFactory
+	// synced 在所有 informer 完成第一次 List 後關閉
+	synced chan struct{}
+	// syncTimedOut 在第一次同步超過 watchSyncTimeout 時關閉，之後 informer 仍會繼續同步
+	syncTimedOut chan struct{}
+
+	mu          sync.Mutex
+	subscribers map[string]map[chan struct{}]struct{}
+	// events 是各 namespace 的 Event informer。Event 沒有專案 labels，無法使用 factory 的 label selector，
+	// 因此只在 namespace 有 subscriber 時監看該 namespace，避免 namespace 隔離時監看整個叢集的 Event
+	events map[string]*namespaceEvents
+}
+
+// namespaceEvents 是單一 namespace 的 Event informer 與其 subscriber 數
+type namespaceEvents struct {
+	factory     dynamicinformer.DynamicSharedInformerFactory
+	stopCh      chan struct{}
+	synced      chan struct{}
+	subscribers int
+}
+
+func newStatusWatcher() *statusWatcher {
+	return &statusWatcher{
+		synced:       make(chan struct{}),
+		syncTimedOut: make(chan struct{}),
+		subscribers:  map[string]map[chan struct{}]struct{}{},
+		events:       map[string]*namespaceEvents{},
+	}
+}
+
+// startWatcher 在第一次需要時啟動 informer，之後的呼叫不做任何事
+func (s *service) startWatcher() {
+	w := s.watcher
+	w.once.Do(func() {
+		// 未啟用 namespace 隔離時所有專案都在同一個 namespace
+		namespace := s.namespace
+		if s.config.Kube.Project.Isolation.Enabled {
+			namespace = metav1.NamespaceAll
+		}
+		selector := labels.SelectorFromSet(labels.Set{LabelManagedBy: ManagedByValue}).String()
+		w.factory = dynamicinformer.NewFilteredDynamicSharedInformerFactory(s.dynamicClient, watchResync, namespace, func(opts *metav1.ListOptions) {
+			opts.LabelSelector = selector
+		})
+
+		for _, gvr := range watchedGVRs {
+			w.addEventHandler(w.factory, gvr, labelProjectRef)
+		}
+
+		// informer 與服務同生命週期，不會停止
+		stopCh := make(chan struct{})
+		w.factory.Start(stopCh)
+		go func() {
+			for {
+				err := waitForCacheSync(w.factory, watchSyncTimeout)
+				if err == nil {
+					break
+				}
+				// API server 無法連線或缺少權限時 informer 會持續重試，期間 WatchProject 以輪詢通知
+				slog.Error("Project status informers not synced, falling back to polling", "error", err)
+				select {
+				case <-w.syncTimedOut:
+				default:
+					close(w.syncTimedOut)
+				}
+			}
+			slog.Info("Project status informers synced")
+			close(w.synced)
+		}()
+	})
+}
+
+// waitForCacheSync 等待 factory 中的 informer 完成第一次 List，超過 timeout 時回傳錯誤
+func waitForCacheSync(factory dynamicinformer.DynamicSharedInformerFactory, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	for gvr, ok := range factory.WaitForCacheSync(ctx.Done()) {
+		if !ok {
+			return fmt.Errorf("informer cache of %s not synced within %s", gvr.Resource, timeout)
+		}
+	}
+	return nil
+}
+
+// watchEvents 啟動 (或共用) namespace 的 Event informer，呼叫者結束時須呼叫 releaseEvents
+func (s *service) watchEvents(namespace string) {
+	w := s.watcher
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if events, ok := w.events[namespace]; ok {
+		events.subscribers++
+		return
+	}
+
+	events := &namespaceEvents{
+		factory:     dynamicinformer.NewFilteredDynamicSharedInformerFactory(s.dynamicClient, watchResync, namespace, nil),
+		stopCh:      make(chan struct{}),
+		synced:      make(chan struct{}),
+		subscribers: 1,
+	}
+	w.addEventHandler(events.factory, eventGVR, involvedObjectProjectRef)
+	events.factory.Start(events.stopCh)
+	go func() {
+		if err := waitForCacheSync(events.factory, watchSyncTimeout); err != nil {
+			// 未同步時 ListProjectEvents 直接查詢 API server
+			slog.Error("Event informer not synced", "error", err, "namespace", namespace)
+			return
+		}
+		close(events.synced)
+	}()
+	w.events[namespace] = events
+}
+
+// releaseEvents 在 namespace 沒有 subscriber 時停止其 Event informer
+func (s *service) releaseEvents(namespace string) {
+	w := s.watcher
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	events, ok := w.events[namespace]
+	if !ok {
+		return
+	}
+	events.subscribers--
+	if events.subscribers > 0 {
+		return
+	}
+	close(events.stopCh)
+	delete(w.events, namespace)
+}
+
+// cachedEvents 回傳 namespace 已同步的 Event informer，沒有時回傳 nil
+func (w *statusWatcher) cachedEvents(namespace string) dynamicinformer.DynamicSharedInformerFactory {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	events, ok := w.events[namespace]
+	if !ok {
+		return nil
+	}
+	select {
+	case <-events.synced:
+		return events.factory
+	default:
+		return nil
+	}
+}
+
+// addEventHandler 在資源變更時，以 projectRef 取得所屬專案並通知 subscriber
+func (w *statusWatcher) addEventHandler(factory dynamicinformer.DynamicSharedInformerFactory, gvr schema.GroupVersionResource, projectRef func(*unstructured.Unstructured) string) {
+	notify := func(obj any) {
+		if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+			obj = tombstone.Obj
+		}
+		u, ok := obj.(*unstructured.Unstructured)
+		if !ok {
+			return
+		}
+		if ref := projectRef(u); ref != "" {
+			w.notify(ref)
+		}
+	}
+	_, err := factory.ForResource(gvr).Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    notify,
+		UpdateFunc: func(_, obj any) { notify(obj) },
+		DeleteFunc: notify,
+	})
+	if err != nil {
+		slog.Error("Failed to add informer event handler", "error", err, "resource", gvr.Resource)
+	}
+}
+
+// labelProjectRef 從 project-ref label 取得資源所屬的專案
+func labelProjectRef(obj *unstructured.Unstructured) string {
+	return obj.GetLabels()[LabelProjectRef]
+}
+
+// involvedObjectProjectRef 從 Event 的 involvedObject 名稱取得所屬的專案
+func involvedObjectProjectRef(obj *unstructured.Unstructured) string {
+	name, _, _ := unstructured.NestedString(obj.Object, "involvedObject", "name")
+	return refFromResourceName(name)
+}
+
+// notify 通知訂閱該專案的 subscriber
+func (w *statusWatcher) notify(ref string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for ch := range w.subscribers[ref] {
+		// channel 只保留一個待處理的通知，subscriber 收到後自行讀取最新狀態
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// isSynced 回傳 informer cache 是否可以取代直接查詢 API server
+func (w *statusWatcher) isSynced() bool {
+	select {
+	case <-w.synced:
+		return true
+	default:
+		return false
+	}
+}
+
+// WatchProject 訂閱專案 Cluster、Deployment、Job 與 Event 的變更。
+//
+// 回傳的 channel 在訂閱時以及每次變更時收到通知 (多個變更可能合併為一個)，
+// 收到後應透過 FindClusterStatus 等方法讀取 cache 中的最新狀態；ctx 結束時 channel 會被關閉。
+// informer 在 watchSyncTimeout 內未同步時，改為每 watchPollInterval 通知一次，讀取會直接查詢 API server。
+func (s *service) WatchProject(ctx context.Context, ref string) (<-chan struct{}, error) {
+	s.startWatcher()
+	w := s.watcher
+
+	select {
+	case <-w.synced:
+	case <-w.syncTimedOut:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	namespace := s.GetProjectNamespace(ref)
+	s.watchEvents(namespace)
+
+	ch := make(chan struct{}, 1)
+	ch <- struct{}{}
+
+	w.mu.Lock()
+	if w.subscribers[ref] == nil {
+		w.subscribers[ref] = map[chan struct{}]struct{}{}
+	}
+	w.subscribers[ref][ch] = struct{}{}
+	w.mu.Unlock()
+
+	go func() {
+		var poll <-chan time.Time
+		synced := w.synced
+		if !w.isSynced() {
+			ticker := time.NewTicker(watchPollInterval)
+			defer ticker.Stop()
+			poll = ticker.C
+		}
+	loop:
+		for {
+			select {
+			case <-ctx.Done():
+				break loop
+			case <-synced:
+				poll, synced = nil, nil
+			case <-poll:
+				select {
+				case ch <- struct{}{}:
+				default:
+				}
+			}
+		}
+
+		s.releaseEvents(namespace)
+		w.mu.Lock()
+		delete(w.subscribers[ref], ch)
+		if len(w.subscribers[ref]) == 0 {
+			delete(w.subscribers, ref)
+		}
+		w.mu.Unlock()
+		close(ch)
+	}()
+
+	return ch, nil
+}
+
+// getCachedCluster 從 informer cache 取得專案 namespace 中指定名稱的 Cluster，cache 尚未同步時回傳 false
+func (s *service) getCachedCluster(ref string, name string) (*unstructured.Unstructured, bool, error) {
+	if !s.watcher.isSynced() {
+		return nil, false, nil
+	}
+	obj, err := s.watcher.factory.ForResource(clusterGVR).Lister().ByNamespace(s.GetProjectNamespace(ref)).Get(name)
+	if err != nil {
+		return nil, true, err
+	}
+	cluster, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return nil, true, apierrors.NewNotFound(clusterGVR.GroupResource(), name)
+	}
+	return cluster, true, nil
+}
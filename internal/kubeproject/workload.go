@@ -0,0 +1,435 @@
+package kubeproject
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"baas-api/internal/config"
+
+	"github.com/samber/lo"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+var ErrUnknownWorkloadComponent = errors.New("unknown workload component")
+
+// workloadTarget 是 workload 設定套用的 Deployment 與 container
+type workloadTarget struct {
+	component      string
+	deploymentName string
+	containerName  string
+}
+
+func (s *service) getWorkloadTarget(ref string, component string) (*workloadTarget, error) {
+	switch component {
+	case AuthAPIComponent:
+		return &workloadTarget{
+			component:      AuthAPIComponent,
+			deploymentName: s.GetAuthAPIDeploymentName(ref),
+			containerName:  s.GetAuthAPIContainerName(ref),
+		}, nil
+	case RestAPIComponent:
+		return &workloadTarget{
+			component:      RestAPIComponent,
+			deploymentName: s.GetRESTAPIDeploymentName(ref),
+			containerName:  s.GetRESTAPIContainerName(ref, PGRSTComponent),
+		}, nil
+	default:
+		return nil, ErrUnknownWorkloadComponent
+	}
+}
+
+// ValidateWorkload 檢查 workload 設定是否合法
+func ValidateWorkload(workload config.WorkloadConfig) error {
+	if workload.Replicas < 1 {
+		return errors.New("replicas must be at least 1")
+	}
+	if _, err := buildResourceRequirements(workload.Resources); err != nil {
+		return err
+	}
+
+	autoscaling := workload.Autoscaling
+	if autoscaling.Enabled {
+		if autoscaling.MinReplicas < 1 {
+			return errors.New("autoscaling minReplicas must be at least 1")
+		}
+		if autoscaling.MaxReplicas < autoscaling.MinReplicas {
+			return errors.New("autoscaling maxReplicas must be greater than or equal to minReplicas")
+		}
+		if autoscaling.TargetCPUUtilizationPercentage < 1 || autoscaling.TargetCPUUtilizationPercentage > 100 {
+			return errors.New("autoscaling targetCPUUtilizationPercentage must be between 1 and 100")
+		}
+		// HPA 以 requests 計算使用率，沒有 CPU requests 時無法運作
+		if workload.Resources.RequestsCPU == "" {
+			return errors.New("autoscaling requires resources.requestsCPU")
+		}
+	}
+
+	pdb := workload.PodDisruptionBudget
+	if pdb.MinAvailable != "" && pdb.MaxUnavailable != "" {
+		return errors.New("podDisruptionBudget accepts only one of minAvailable or maxUnavailable")
+	}
+	for name, value := range map[string]string{"minAvailable": pdb.MinAvailable, "maxUnavailable": pdb.MaxUnavailable} {
+		if value == "" {
+			continue
+		}
+		v := intstr.Parse(value)
+		if _, err := intstr.GetScaledValueFromIntOrPercent(&v, 100, true); err != nil || v.IntValue() < 0 {
+			return fmt.Errorf("podDisruptionBudget %s must be a non-negative integer or percentage", name)
+		}
+	}
+
+	// PDB 不允許任何 eviction 時節點無法排空，HPA 可能將副本數降到 minReplicas
+	replicas, replicasField := workload.Replicas, "replicas"
+	if autoscaling.Enabled {
+		replicas, replicasField = autoscaling.MinReplicas, "autoscaling minReplicas"
+	}
+	if pdb.MinAvailable != "" {
+		v := intstr.Parse(pdb.MinAvailable)
+		minAvailable, _ := intstr.GetScaledValueFromIntOrPercent(&v, int(replicas), true)
+		if minAvailable >= int(replicas) {
+			return fmt.Errorf("podDisruptionBudget minAvailable must be less than %s (%d)", replicasField, replicas)
+		}
+	}
+	if pdb.MaxUnavailable != "" {
+		v := intstr.Parse(pdb.MaxUnavailable)
+		maxUnavailable, _ := intstr.GetScaledValueFromIntOrPercent(&v, int(replicas), true)
+		if maxUnavailable < 1 {
+			return errors.New("podDisruptionBudget maxUnavailable must allow at least one pod")
+		}
+	}
+
+	return nil
+}
+
+// ValidateWorkloadLimits 檢查 workload 設定是否在方案的上限內
+func ValidateWorkloadLimits(workload config.WorkloadConfig, limits config.PlanLimits) error {
+	if limits.MaxReplicas > 0 {
+		if workload.Replicas > limits.MaxReplicas {
+			return fmt.Errorf("replicas must not exceed %d", limits.MaxReplicas)
+		}
+		if workload.Autoscaling.Enabled && workload.Autoscaling.MaxReplicas > limits.MaxReplicas {
+			return fmt.Errorf("autoscaling maxReplicas must not exceed %d", limits.MaxReplicas)
+		}
+	}
+
+	for _, ceiling := range []struct{ field, value, max string }{
+		{"limitsCPU", workload.Resources.LimitsCPU, limits.MaxCPU},
+		{"limitsMemory", workload.Resources.LimitsMemory, limits.MaxMemory},
+	} {
+		if ceiling.max == "" {
+			continue
+		}
+		if ceiling.value == "" {
+			return fmt.Errorf("resources.%s is required by the plan", ceiling.field)
+		}
+		value, err := resource.ParseQuantity(ceiling.value)
+		if err != nil {
+			return fmt.Errorf("invalid resources.%s: %w", ceiling.field, err)
+		}
+		limit, err := resource.ParseQuantity(ceiling.max)
+		if err != nil {
+			return fmt.Errorf("invalid plan limit for resources.%s: %w", ceiling.field, err)
+		}
+		if value.Cmp(limit) > 0 {
+			return fmt.Errorf("resources.%s must not exceed %s", ceiling.field, ceiling.max)
+		}
+	}
+	return nil
+}
+
+func buildResourceRequirements(resources config.WorkloadResources) (corev1.ResourceRequirements, error) {
+	requests, err := parseResourceList(map[corev1.ResourceName]string{
+		corev1.ResourceCPU:    resources.RequestsCPU,
+		corev1.ResourceMemory: resources.RequestsMemory,
+	})
+	if err != nil {
+		return corev1.ResourceRequirements{}, fmt.Errorf("invalid resource requests: %w", err)
+	}
+	limits, err := parseResourceList(map[corev1.ResourceName]string{
+		corev1.ResourceCPU:    resources.LimitsCPU,
+		corev1.ResourceMemory: resources.LimitsMemory,
+	})
+	if err != nil {
+		return corev1.ResourceRequirements{}, fmt.Errorf("invalid resource limits: %w", err)
+	}
+	for name, request := range requests {
+		if limit, ok := limits[name]; ok && request.Cmp(limit) > 0 {
+			return corev1.ResourceRequirements{}, fmt.Errorf("%s request must not exceed its limit", name)
+		}
+	}
+
+	return corev1.ResourceRequirements{
+		Requests: lo.Ternary(len(requests) > 0, requests, nil),
+		Limits:   lo.Ternary(len(limits) > 0, limits, nil),
+	}, nil
+}
+
+// ApplyAPIWorkload 套用 API Deployment 的副本數與資源設定，並建立或移除對應的 HPA 與 PDB。
+//
+// 這些欄位使用獨立的 WorkloadFieldManager，重新執行 Create*Deployment 不會覆蓋專案的調整。
+func (s *service) ApplyAPIWorkload(ctx context.Context, ref string, component string, workload config.WorkloadConfig) error {
+	target, err := s.getWorkloadTarget(ref, component)
+	if err != nil {
+		return err
+	}
+	if err := ValidateWorkload(workload); err != nil {
+		slog.ErrorContext(ctx, "Invalid workload configuration", "error", err, "ref", ref, "component", component)
+		return err
+	}
+	resources, _ := buildResourceRequirements(workload.Resources)
+
+	if err := s.applyWorkloadDeployment(ctx, ref, target, workload, resources); err != nil {
+		return err
+	}
+	if err := s.applyHorizontalPodAutoscaler(ctx, ref, target, workload.Autoscaling); err != nil {
+		return err
+	}
+	return s.applyPodDisruptionBudget(ctx, ref, target, workload.PodDisruptionBudget)
+}
+
+func (s *service) applyWorkloadDeployment(ctx context.Context, ref string, target *workloadTarget, workload config.WorkloadConfig, resources corev1.ResourceRequirements) error {
+	namespace := s.GetProjectNamespace(ref)
+
+	spec := map[string]any{
+		"template": map[string]any{
+			"spec": map[string]any{
+				"containers": []map[string]any{
+					{
+						"name":      target.containerName,
+						"resources": resources,
+					},
+				},
+			},
+		},
+	}
+	// 啟用 HPA 時副本數交由 HPA 控制，不再宣告 replicas
+	if !workload.Autoscaling.Enabled {
+		spec["replicas"] = workload.Replicas
+	}
+
+	payload := map[string]any{
+		"apiVersion": "apps/v1",
+		"kind":       "Deployment",
+		"metadata": map[string]any{
+			"name":      target.deploymentName,
+			"namespace": namespace,
+		},
+		"spec": spec,
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to marshal workload patch", "error", err)
+		return errors.New("failed to marshal workload patch")
+	}
+
+	_, err = s.clientset.AppsV1().Deployments(namespace).Patch(
+		ctx,
+		target.deploymentName,
+		types.ApplyPatchType,
+		data,
+		applyPatchOptions(WorkloadFieldManager),
+	)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to apply deployment workload", "error", err, "deployment", target.deploymentName)
+		return errors.New("failed to apply deployment workload")
+	}
+	return nil
+}
+
+func (s *service) applyHorizontalPodAutoscaler(ctx context.Context, ref string, target *workloadTarget, autoscaling config.WorkloadAutoscaling) error {
+	namespace := s.GetProjectNamespace(ref)
+	hpas := s.clientset.AutoscalingV2().HorizontalPodAutoscalers(namespace)
+
+	if !autoscaling.Enabled {
+		if err := ignoreNotFound(hpas.Delete(ctx, target.deploymentName, metav1.DeleteOptions{})); err != nil {
+			slog.ErrorContext(ctx, "Failed to delete horizontal pod autoscaler", "error", err, "name", target.deploymentName)
+			return errors.New("failed to delete horizontal pod autoscaler")
+		}
+		return nil
+	}
+
+	ownerRef, err := s.clusterOwnerReference(ctx, ref)
+	if err != nil {
+		return err
+	}
+
+	hpa := &autoscalingv2.HorizontalPodAutoscaler{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "autoscaling/v2",
+			Kind:       "HorizontalPodAutoscaler",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            target.deploymentName,
+			Namespace:       namespace,
+			Labels:          projectLabels(ref, target.component),
+			OwnerReferences: []metav1.OwnerReference{*ownerRef},
+		},
+		Spec: autoscalingv2.HorizontalPodAutoscalerSpec{
+			ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{
+				APIVersion: "apps/v1",
+				Kind:       "Deployment",
+				Name:       target.deploymentName,
+			},
+			MinReplicas: lo.ToPtr(autoscaling.MinReplicas),
+			MaxReplicas: autoscaling.MaxReplicas,
+			Metrics: []autoscalingv2.MetricSpec{{
+				Type: autoscalingv2.ResourceMetricSourceType,
+				Resource: &autoscalingv2.ResourceMetricSource{
+					Name: corev1.ResourceCPU,
+					Target: autoscalingv2.MetricTarget{
+						Type:               autoscalingv2.UtilizationMetricType,
+						AverageUtilization: lo.ToPtr(autoscaling.TargetCPUUtilizationPercentage),
+					},
+				},
+			}},
+		},
+	}
+	data, err := json.Marshal(hpa)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to marshal horizontal pod autoscaler", "error", err)
+		return errors.New("failed to marshal horizontal pod autoscaler")
+	}
+
+	if _, err := hpas.Patch(ctx, hpa.Name, types.ApplyPatchType, data, applyPatchOptions(WorkloadFieldManager)); err != nil {
+		slog.ErrorContext(ctx, "Failed to apply horizontal pod autoscaler", "error", err, "name", hpa.Name)
+		return errors.New("failed to apply horizontal pod autoscaler")
+	}
+	return nil
+}
+
+func (s *service) applyPodDisruptionBudget(ctx context.Context, ref string, target *workloadTarget, budget config.WorkloadPodDisruptionBudget) error {
+	namespace := s.GetProjectNamespace(ref)
+	pdbs := s.clientset.PolicyV1().PodDisruptionBudgets(namespace)
+
+	if budget.MinAvailable == "" && budget.MaxUnavailable == "" {
+		if err := ignoreNotFound(pdbs.Delete(ctx, target.deploymentName, metav1.DeleteOptions{})); err != nil {
+			slog.ErrorContext(ctx, "Failed to delete pod disruption budget", "error", err, "name", target.deploymentName)
+			return errors.New("failed to delete pod disruption budget")
+		}
+		return nil
+	}
+
+	ownerRef, err := s.clusterOwnerReference(ctx, ref)
+	if err != nil {
+		return err
+	}
+
+	spec := policyv1.PodDisruptionBudgetSpec{
+		Selector: &metav1.LabelSelector{
+			MatchLabels: map[string]string{"app": target.deploymentName},
+		},
+	}
+	if budget.MinAvailable != "" {
+		spec.MinAvailable = lo.ToPtr(intstr.Parse(budget.MinAvailable))
+	} else {
+		spec.MaxUnavailable = lo.ToPtr(intstr.Parse(budget.MaxUnavailable))
+	}
+
+	pdb := &policyv1.PodDisruptionBudget{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "policy/v1",
+			Kind:       "PodDisruptionBudget",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            target.deploymentName,
+			Namespace:       namespace,
+			Labels:          projectLabels(ref, target.component),
+			OwnerReferences: []metav1.OwnerReference{*ownerRef},
+		},
+		Spec: spec,
+	}
+	data, err := json.Marshal(pdb)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to marshal pod disruption budget", "error", err)
+		return errors.New("failed to marshal pod disruption budget")
+	}
+
+	if _, err := pdbs.Patch(ctx, pdb.Name, types.ApplyPatchType, data, applyPatchOptions(WorkloadFieldManager)); err != nil {
+		slog.ErrorContext(ctx, "Failed to apply pod disruption budget", "error", err, "name", pdb.Name)
+		return errors.New("failed to apply pod disruption budget")
+	}
+	return nil
+}
+
+// FindAPIWorkload 從 Deployment、HPA 與 PDB 讀回目前生效的 workload 設定
+func (s *service) FindAPIWorkload(ctx context.Context, ref string, component string) (*config.WorkloadConfig, error) {
+	target, err := s.getWorkloadTarget(ref, component)
+	if err != nil {
+		return nil, err
+	}
+	namespace := s.GetProjectNamespace(ref)
+
+	deployment, err := s.clientset.AppsV1().Deployments(namespace).Get(ctx, target.deploymentName, metav1.GetOptions{})
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to get API deployment", "error", err, "deployment", target.deploymentName)
+		return nil, errors.New("failed to get API deployment")
+	}
+
+	workload := &config.WorkloadConfig{
+		Replicas: lo.FromPtrOr(deployment.Spec.Replicas, 1),
+	}
+	if container, ok := lo.Find(deployment.Spec.Template.Spec.Containers, func(c corev1.Container) bool {
+		return c.Name == target.containerName
+	}); ok {
+		workload.Resources = workloadResourcesFromRequirements(container.Resources)
+	}
+
+	hpa, err := s.clientset.AutoscalingV2().HorizontalPodAutoscalers(namespace).Get(ctx, target.deploymentName, metav1.GetOptions{})
+	switch {
+	case err == nil:
+		workload.Autoscaling = config.WorkloadAutoscaling{
+			Enabled:     true,
+			MinReplicas: lo.FromPtrOr(hpa.Spec.MinReplicas, 1),
+			MaxReplicas: hpa.Spec.MaxReplicas,
+		}
+		for _, metric := range hpa.Spec.Metrics {
+			if metric.Resource != nil && metric.Resource.Name == corev1.ResourceCPU {
+				workload.Autoscaling.TargetCPUUtilizationPercentage = lo.FromPtr(metric.Resource.Target.AverageUtilization)
+			}
+		}
+	case !apierrors.IsNotFound(err):
+		slog.ErrorContext(ctx, "Failed to get horizontal pod autoscaler", "error", err, "name", target.deploymentName)
+		return nil, errors.New("failed to get horizontal pod autoscaler")
+	}
+
+	pdb, err := s.clientset.PolicyV1().PodDisruptionBudgets(namespace).Get(ctx, target.deploymentName, metav1.GetOptions{})
+	switch {
+	case err == nil:
+		if pdb.Spec.MinAvailable != nil {
+			workload.PodDisruptionBudget.MinAvailable = pdb.Spec.MinAvailable.String()
+		}
+		if pdb.Spec.MaxUnavailable != nil {
+			workload.PodDisruptionBudget.MaxUnavailable = pdb.Spec.MaxUnavailable.String()
+		}
+	case !apierrors.IsNotFound(err):
+		slog.ErrorContext(ctx, "Failed to get pod disruption budget", "error", err, "name", target.deploymentName)
+		return nil, errors.New("failed to get pod disruption budget")
+	}
+
+	return workload, nil
+}
+
+func workloadResourcesFromRequirements(requirements corev1.ResourceRequirements) config.WorkloadResources {
+	quantity := func(list corev1.ResourceList, name corev1.ResourceName) string {
+		if q, ok := list[name]; ok {
+			return q.String()
+		}
+		return ""
+	}
+	return config.WorkloadResources{
+		RequestsCPU:    quantity(requirements.Requests, corev1.ResourceCPU),
+		RequestsMemory: quantity(requirements.Requests, corev1.ResourceMemory),
+		LimitsCPU:      quantity(requirements.Limits, corev1.ResourceCPU),
+		LimitsMemory:   quantity(requirements.Limits, corev1.ResourceMemory),
+	}
+}
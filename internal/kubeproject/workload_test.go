@@ -0,0 +1,132 @@
+package kubeproject
+
+import (
+	"strings"
+	"testing"
+
+	"baas-api/internal/config"
+)
+
+func TestValidateWorkload(t *testing.T) {
+	resources := config.WorkloadResources{RequestsCPU: "100m", RequestsMemory: "128Mi", LimitsCPU: "500m", LimitsMemory: "256Mi"}
+	autoscaling := config.WorkloadAutoscaling{Enabled: true, MinReplicas: 2, MaxReplicas: 5, TargetCPUUtilizationPercentage: 80}
+	withPDB := func(replicas int32, minAvailable, maxUnavailable string) config.WorkloadConfig {
+		return config.WorkloadConfig{
+			Replicas:            replicas,
+			Resources:           resources,
+			PodDisruptionBudget: config.WorkloadPodDisruptionBudget{MinAvailable: minAvailable, MaxUnavailable: maxUnavailable},
+		}
+	}
+
+	tests := []struct {
+		name     string
+		workload config.WorkloadConfig
+		wantErr  string
+	}{
+		{name: "single replica", workload: config.WorkloadConfig{Replicas: 1}},
+		{name: "resources", workload: config.WorkloadConfig{Replicas: 2, Resources: resources}},
+		{name: "autoscaling", workload: config.WorkloadConfig{Replicas: 2, Resources: resources, Autoscaling: autoscaling}},
+		{name: "pdb minAvailable", workload: withPDB(3, "2", "")},
+		{name: "pdb minAvailable percentage", workload: withPDB(3, "50%", "")},
+		{name: "pdb maxUnavailable", workload: withPDB(1, "", "1")},
+		{name: "pdb maxUnavailable percentage rounds up", workload: withPDB(2, "", "10%")},
+
+		{name: "no replicas", workload: config.WorkloadConfig{Replicas: 0}, wantErr: "replicas must be at least 1"},
+		{name: "invalid quantity", workload: config.WorkloadConfig{Replicas: 1, Resources: config.WorkloadResources{LimitsCPU: "lots"}}, wantErr: "invalid resource limits"},
+		{name: "requests above limits", workload: config.WorkloadConfig{Replicas: 1, Resources: config.WorkloadResources{RequestsCPU: "1", LimitsCPU: "500m"}}, wantErr: "cpu"},
+		{
+			name:     "autoscaling minReplicas",
+			workload: config.WorkloadConfig{Replicas: 1, Resources: resources, Autoscaling: config.WorkloadAutoscaling{Enabled: true, MaxReplicas: 3, TargetCPUUtilizationPercentage: 80}},
+			wantErr:  "minReplicas must be at least 1",
+		},
+		{
+			name:     "autoscaling maxReplicas below minReplicas",
+			workload: config.WorkloadConfig{Replicas: 1, Resources: resources, Autoscaling: config.WorkloadAutoscaling{Enabled: true, MinReplicas: 3, MaxReplicas: 2, TargetCPUUtilizationPercentage: 80}},
+			wantErr:  "maxReplicas must be greater than or equal to minReplicas",
+		},
+		{
+			name:     "autoscaling target out of range",
+			workload: config.WorkloadConfig{Replicas: 1, Resources: resources, Autoscaling: config.WorkloadAutoscaling{Enabled: true, MinReplicas: 1, MaxReplicas: 2, TargetCPUUtilizationPercentage: 101}},
+			wantErr:  "targetCPUUtilizationPercentage",
+		},
+		{
+			name:     "autoscaling without cpu requests",
+			workload: config.WorkloadConfig{Replicas: 1, Autoscaling: autoscaling},
+			wantErr:  "requires resources.requestsCPU",
+		},
+		{name: "pdb both fields", workload: withPDB(3, "1", "1"), wantErr: "only one of"},
+		{name: "pdb negative", workload: withPDB(3, "-1", ""), wantErr: "non-negative"},
+		{name: "pdb invalid percentage", workload: withPDB(3, "", "abc%"), wantErr: "non-negative"},
+		// 不允許任何 eviction 的 PDB 會讓節點無法排空
+		{name: "pdb minAvailable equals replicas", workload: withPDB(2, "2", ""), wantErr: "minAvailable must be less than replicas (2)"},
+		{name: "pdb minAvailable single replica", workload: withPDB(1, "1", ""), wantErr: "minAvailable must be less than replicas (1)"},
+		{name: "pdb minAvailable percentage rounds up", workload: withPDB(3, "90%", ""), wantErr: "minAvailable must be less than replicas (3)"},
+		{name: "pdb minAvailable 100%", workload: withPDB(3, "100%", ""), wantErr: "minAvailable must be less than replicas"},
+		{name: "pdb maxUnavailable zero", workload: withPDB(3, "", "0"), wantErr: "maxUnavailable must allow at least one pod"},
+		{name: "pdb maxUnavailable 0%", workload: withPDB(3, "", "0%"), wantErr: "maxUnavailable must allow at least one pod"},
+		{
+			name: "pdb checked against autoscaling minReplicas",
+			workload: config.WorkloadConfig{
+				Replicas:            5,
+				Resources:           resources,
+				Autoscaling:         autoscaling,
+				PodDisruptionBudget: config.WorkloadPodDisruptionBudget{MinAvailable: "2"},
+			},
+			wantErr: "minAvailable must be less than autoscaling minReplicas (2)",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateWorkload(tt.workload)
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("ValidateWorkload: %v", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Errorf("ValidateWorkload error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateWorkloadLimits(t *testing.T) {
+	limits := config.PlanLimits{MaxReplicas: 2, MaxCPU: "1", MaxMemory: "512Mi"}
+	resources := config.WorkloadResources{LimitsCPU: "500m", LimitsMemory: "256Mi"}
+
+	tests := []struct {
+		name     string
+		workload config.WorkloadConfig
+		limits   config.PlanLimits
+		wantErr  string
+	}{
+		{name: "no limits", workload: config.WorkloadConfig{Replicas: 10}},
+		{name: "within limits", workload: config.WorkloadConfig{Replicas: 2, Resources: resources}, limits: limits},
+		{name: "equal to limits", workload: config.WorkloadConfig{Replicas: 2, Resources: config.WorkloadResources{LimitsCPU: "1000m", LimitsMemory: "512Mi"}}, limits: limits},
+		{name: "too many replicas", workload: config.WorkloadConfig{Replicas: 3, Resources: resources}, limits: limits, wantErr: "replicas must not exceed 2"},
+		{
+			name:     "autoscaling above limit",
+			workload: config.WorkloadConfig{Replicas: 1, Resources: resources, Autoscaling: config.WorkloadAutoscaling{Enabled: true, MinReplicas: 1, MaxReplicas: 4}},
+			limits:   limits,
+			wantErr:  "autoscaling maxReplicas must not exceed 2",
+		},
+		{
+			name:     "disabled autoscaling is ignored",
+			workload: config.WorkloadConfig{Replicas: 1, Resources: resources, Autoscaling: config.WorkloadAutoscaling{MaxReplicas: 4}},
+			limits:   limits,
+		},
+		{name: "cpu above limit", workload: config.WorkloadConfig{Replicas: 1, Resources: config.WorkloadResources{LimitsCPU: "1500m", LimitsMemory: "256Mi"}}, limits: limits, wantErr: "resources.limitsCPU must not exceed 1"},
+		{name: "memory above limit", workload: config.WorkloadConfig{Replicas: 1, Resources: config.WorkloadResources{LimitsCPU: "1", LimitsMemory: "1Gi"}}, limits: limits, wantErr: "resources.limitsMemory must not exceed 512Mi"},
+		{name: "limits required", workload: config.WorkloadConfig{Replicas: 1, Resources: config.WorkloadResources{LimitsMemory: "256Mi"}}, limits: limits, wantErr: "resources.limitsCPU is required"},
+		{name: "invalid value", workload: config.WorkloadConfig{Replicas: 1, Resources: config.WorkloadResources{LimitsCPU: "lots", LimitsMemory: "256Mi"}}, limits: limits, wantErr: "invalid resources.limitsCPU"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateWorkloadLimits(tt.workload, tt.limits)
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("ValidateWorkloadLimits: %v", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Errorf("ValidateWorkloadLimits error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
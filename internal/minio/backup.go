@@ -0,0 +1,153 @@
+package minio
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"baas-api/internal/utils"
+
+	"github.com/minio/madmin-go/v4"
+	"github.com/minio/minio-go/v7"
+)
+
+// EnsureBackupBucket 建立平台共用的 backup bucket (已存在時略過)
+func (s *service) EnsureBackupBucket(ctx context.Context) error {
+	bucket := s.config.Kube.Project.Backup.Bucket
+	exists, err := s.client.BucketExists(ctx, bucket)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to check backup bucket", "error", err, "bucket", bucket)
+		return errors.New("failed to check backup bucket")
+	}
+	if exists {
+		return nil
+	}
+
+	err = s.client.MakeBucket(ctx, bucket, minio.MakeBucketOptions{Region: s.config.S3.Region})
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to create backup bucket", "error", err, "bucket", bucket)
+		return errors.New("failed to create backup bucket")
+	}
+	return nil
+}
+
+// CreateBackupUser 建立專案的備份使用者，只能存取 backup bucket 中自己的路徑前綴。
+//
+// 每次呼叫都會產生新的 secret key，呼叫端需要同步更新 Kubernetes 上的憑證。
+func (s *service) CreateBackupUser(ctx context.Context, ref string) (string, string, error) {
+	accessKeyID := GetBackupUserNameByRef(ref)
+	secretAccessKey := utils.GenerateNewPassword(40)
+
+	if err := s.adminClient.AddUser(ctx, accessKeyID, secretAccessKey); err != nil {
+		slog.ErrorContext(ctx, "Failed to create backup user", "error", err, "ref", ref)
+		return "", "", errors.New("failed to create backup user")
+	}
+
+	if err := s.SetBackupPolicy(ctx, ref); err != nil {
+		return "", "", err
+	}
+
+	return accessKeyID, secretAccessKey, nil
+}
+
+// SetBackupPolicy 設定備份使用者的 policy。
+//
+// readOnlyRefs 額外授與其他專案備份的唯讀權限，用於從其他專案的備份還原。
+func (s *service) SetBackupPolicy(ctx context.Context, ref string, readOnlyRefs ...string) error {
+	bucket := s.config.Kube.Project.Backup.Bucket
+	userName := GetBackupUserNameByRef(ref)
+	policyName := GetBackupPolicyNameByRef(ref)
+
+	objectARN := func(ref string) string {
+		return "arn:aws:s3:::" + bucket + "/" + GetBackupPrefixByRef(ref) + "*"
+	}
+	listPrefixes := []string{GetBackupPrefixByRef(ref) + "*"}
+	readObjects := []string{}
+	for _, readOnlyRef := range readOnlyRefs {
+		if readOnlyRef == ref {
+			continue
+		}
+		listPrefixes = append(listPrefixes, GetBackupPrefixByRef(readOnlyRef)+"*")
+		readObjects = append(readObjects, objectARN(readOnlyRef))
+	}
+
+	statements := []map[string]any{
+		{
+			"Effect":   "Allow",
+			"Action":   []string{"s3:GetBucketLocation"},
+			"Resource": []string{"arn:aws:s3:::" + bucket},
+		},
+		{
+			"Effect":    "Allow",
+			"Action":    []string{"s3:ListBucket"},
+			"Resource":  []string{"arn:aws:s3:::" + bucket},
+			"Condition": map[string]any{"StringLike": map[string]any{"s3:prefix": listPrefixes}},
+		},
+		{
+			"Effect":   "Allow",
+			"Action":   []string{"s3:PutObject", "s3:GetObject", "s3:DeleteObject"},
+			"Resource": []string{objectARN(ref)},
+		},
+	}
+	if len(readObjects) > 0 {
+		statements = append(statements, map[string]any{
+			"Effect":   "Allow",
+			"Action":   []string{"s3:GetObject"},
+			"Resource": readObjects,
+		})
+	}
+
+	policy, err := json.Marshal(map[string]any{
+		"Version":   "2012-10-17",
+		"Statement": statements,
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to marshal backup policy", "error", err)
+		return errors.New("failed to marshal backup policy")
+	}
+
+	if err := s.adminClient.AddCannedPolicy(ctx, policyName, policy); err != nil {
+		slog.ErrorContext(ctx, "Failed to set backup policy", "error", err, "ref", ref)
+		return errors.New("failed to set backup policy")
+	}
+
+	// AttachPolicy 對已套用的 policy 會回傳錯誤，先確認是否已經綁定
+	info, err := s.adminClient.GetUserInfo(ctx, userName)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to get backup user", "error", err, "ref", ref)
+		return errors.New("failed to get backup user")
+	}
+	if slices.Contains(strings.Split(info.PolicyName, ","), policyName) {
+		return nil
+	}
+
+	_, err = s.adminClient.AttachPolicy(ctx, madmin.PolicyAssociationReq{
+		User:     userName,
+		Policies: []string{policyName},
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to attach backup policy", "error", err, "ref", ref)
+		return errors.New("failed to attach backup policy")
+	}
+	return nil
+}
+
+// DeleteBackupUser 移除專案的備份使用者與 policy，已封存的備份資料會保留
+func (s *service) DeleteBackupUser(ctx context.Context, ref string) error {
+	var errs []error
+	if err := s.adminClient.RemoveUser(ctx, GetBackupUserNameByRef(ref)); err != nil {
+		slog.ErrorContext(ctx, "Failed to delete backup user", "error", err, "ref", ref)
+		errs = append(errs, err)
+	}
+	if err := s.adminClient.RemoveCannedPolicy(ctx, GetBackupPolicyNameByRef(ref)); err != nil {
+		slog.ErrorContext(ctx, "Failed to delete backup policy", "error", err, "ref", ref)
+		errs = append(errs, err)
+	}
+	if len(errs) > 0 {
+		return errors.New("failed to delete backup user")
+	}
+	return nil
+}
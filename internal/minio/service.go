@@ -18,8 +18,14 @@ type Service interface {
 	DeleteBucket(ctx context.Context, bucketName string) error
 	CreateBucketUser(ctx context.Context, accessKeyID string, secretAccessKey string) error
 	DeleteBucketUser(ctx context.Context, accessKeyID string) error
-	CreateBucketPolicy(ctx context.Context, ref string, bucketName string) error
+	CreateBucketPolicy(ctx context.Context, accessKeyID string, bucketName string) error
 	DeleteBucketPolicy(ctx context.Context, bucketname string) error
+
+	// Project database backups
+	EnsureBackupBucket(ctx context.Context) error
+	CreateBackupUser(ctx context.Context, ref string) (string, string, error)
+	SetBackupPolicy(ctx context.Context, ref string, readOnlyRefs ...string) error
+	DeleteBackupUser(ctx context.Context, ref string) error
 }
 
 type service struct {
@@ -84,7 +90,7 @@ func (s *service) DeleteBucketUser(ctx context.Context, ref string) error {
 	return nil
 }
 
-func (s *service) CreateBucketPolicy(ctx context.Context, ref string, bucketName string) error {
+func (s *service) CreateBucketPolicy(ctx context.Context, accessKeyID string, bucketName string) error {
 	policy := `{
 		"Version": "2012-10-17",
 		"Statement": [
@@ -118,7 +124,7 @@ func (s *service) CreateBucketPolicy(ctx context.Context, ref string, bucketName
 		return errors.New("failed to set user policy")
 	}
 	_, err = s.adminClient.AttachPolicy(ctx, madmin.PolicyAssociationReq{
-		User:     ref,
+		User:     accessKeyID,
 		Policies: []string{bucketName},
 	})
 	if err != nil {
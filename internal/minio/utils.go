@@ -7,3 +7,17 @@ func GetBucketNameByRef(ref string) string {
 func GetBucketPolicyNameByRef(ref string) string {
 	return GetBucketNameByRef(ref) + "-policy"
 }
+
+// GetBackupUserNameByRef 回傳專案備份使用的 MinIO 使用者名稱
+func GetBackupUserNameByRef(ref string) string {
+	return "baas-backup-" + ref
+}
+
+func GetBackupPolicyNameByRef(ref string) string {
+	return GetBackupUserNameByRef(ref) + "-policy"
+}
+
+// GetBackupPrefixByRef 回傳專案備份在 backup bucket 中的路徑前綴
+func GetBackupPrefixByRef(ref string) string {
+	return ref + "/"
+}
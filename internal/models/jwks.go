@@ -0,0 +1,10 @@
+package models
+
+import "time"
+
+// JWK 是專案資料庫 auth.jwks 中的一把簽章金鑰，auth API 以最新的一把簽發 JWT
+type JWK struct {
+	ID        string    `gorm:"column:id"`
+	PublicKey string    `gorm:"column:public_key"`
+	CreatedAt time.Time `gorm:"column:created_at"`
+}
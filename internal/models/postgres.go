@@ -0,0 +1,16 @@
+package models
+
+// PostgresSetting 是 pg_settings 中的一筆設定，Setting 為含單位的顯示值 (current_setting)
+type PostgresSetting struct {
+	Name           string `gorm:"column:name"`
+	Setting        string `gorm:"column:setting"`
+	PendingRestart bool   `gorm:"column:pending_restart"`
+}
+
+// PostgresAvailableExtension 是 pg_available_extensions 中的一筆 extension
+type PostgresAvailableExtension struct {
+	Name             string  `gorm:"column:name"`
+	DefaultVersion   string  `gorm:"column:default_version"`
+	InstalledVersion *string `gorm:"column:installed_version"`
+	Comment          string  `gorm:"column:comment"`
+}
@@ -20,11 +20,33 @@ type Project struct {
 	Reference         string     `gorm:"type:varchar(20);not null;unique"`   // VARCHAR(20) NOT NULL UNIQUE, 也是外鍵
 	PasswordExpiredAt *time.Time `gorm:"type:timestamptz;default:now()" json:"password_expired_at"`
 	InitializedAt     *time.Time `gorm:"type:timestamptz" json:"initialized_at"`
+	// Cluster 是專案所在的 Kubernetes 叢集 (config.Kube.Clusters)，NULL 代表預設叢集
+	Cluster *string `gorm:"type:varchar(63)" json:"cluster"`
+	// Plan 是專案的方案 (config.Kube.Plans)，NULL 代表預設方案
+	Plan *string `gorm:"type:varchar(63)" json:"plan"`
+	// JWKSRotatedAt 是最近一次切換簽章金鑰的時間，NULL 代表從未輪替 (以專案建立時間計算)
+	JWKSRotatedAt *time.Time `gorm:"column:jwks_rotated_at;type:timestamptz" json:"jwks_rotated_at"`
+	// JWKSRetireAt 是移除舊金鑰的時間，NULL 代表沒有進行中的輪替
+	JWKSRetireAt *time.Time `gorm:"column:jwks_retire_at;type:timestamptz" json:"jwks_retire_at"`
+	// RestoreStatus 是最近一次資料庫還原的狀態，NULL 代表從未還原
+	RestoreStatus *string `gorm:"column:restore_status;type:varchar(16)" json:"restore_status"`
+	// RestoreStartedAt 與 RestoreFinishedAt 是最近一次資料庫還原開始與結束的時間
+	RestoreStartedAt  *time.Time `gorm:"column:restore_started_at;type:timestamptz" json:"restore_started_at"`
+	RestoreFinishedAt *time.Time `gorm:"column:restore_finished_at;type:timestamptz" json:"restore_finished_at"`
+	// RestoreError 是最近一次資料庫還原失敗的原因
+	RestoreError *string `gorm:"column:restore_error;type:text" json:"restore_error"`
 
 	// gorm one-to-one
 	Object Object `gorm:"foreignKey:ID;references:ID"`
 }
 
+// 專案資料庫還原的狀態 (Project.RestoreStatus)
+const (
+	RestoreStatusRunning   = "running"
+	RestoreStatusSucceeded = "succeeded"
+	RestoreStatusFailed    = "failed"
+)
+
 func (Project) TableName() string {
 	return "dbo.projects"
 }
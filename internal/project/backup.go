@@ -0,0 +1,165 @@
+package project
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"baas-api/internal/dto"
+	"baas-api/internal/kubeproject"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/samber/lo"
+)
+
+func (s *service) ListProjectBackups(ctx context.Context, in *dto.ListProjectBackupsInput, userID string) (*dto.ListProjectBackupsOutput, error) {
+	if _, err := s.findOwnedProject(ctx, in.Ref, userID); err != nil {
+		return nil, err
+	}
+
+	backups, err := s.kube.ListBackups(ctx, in.Ref)
+	if err != nil {
+		return nil, err
+	}
+
+	out := &dto.ListProjectBackupsOutput{}
+	out.Body.Backups = lo.Map(backups, func(backup kubeproject.BackupInfo, _ int) dto.ProjectBackup {
+		return backupToDTO(&backup)
+	})
+	return out, nil
+}
+
+func (s *service) CreateProjectBackup(ctx context.Context, in *dto.CreateProjectBackupInput, userID string) (*dto.CreateProjectBackupOutput, error) {
+	if !s.config.Kube.Project.Backup.Enabled {
+		return nil, huma.Error400BadRequest("Database backups are not enabled")
+	}
+	if _, err := s.findOwnedProject(ctx, in.Body.Ref, userID); err != nil {
+		return nil, err
+	}
+
+	backup, err := s.kube.CreateBackup(ctx, in.Body.Ref)
+	if err != nil {
+		return nil, err
+	}
+
+	out := &dto.CreateProjectBackupOutput{}
+	out.Body.Backup = backupToDTO(backup)
+	return out, nil
+}
+
+func (s *service) PrepareProjectRestore(ctx context.Context, in *dto.RestoreProjectBackupInput, userID string) (*kubeproject.ClusterRecoveryOption, error) {
+	if !s.config.Kube.Project.Backup.Enabled {
+		return nil, huma.Error400BadRequest("Database backups are not enabled")
+	}
+
+	ref := in.Body.Ref
+	sourceRef := lo.CoalesceOrEmpty(in.Body.SourceRef, ref)
+	if _, err := s.findOwnedProject(ctx, ref, userID); err != nil {
+		return nil, err
+	}
+	if sourceRef != ref {
+		if _, err := s.findOwnedProject(ctx, sourceRef, userID); err != nil {
+			return nil, err
+		}
+	}
+
+	backup, err := s.kube.FindBackup(ctx, sourceRef, in.Body.BackupName)
+	if errors.Is(err, kubeproject.ErrBackupNotFound) {
+		return nil, huma.Error404NotFound("Backup not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if backup.Phase != kubeproject.BackupPhaseCompleted || backup.BackupID == "" {
+		return nil, huma.Error409Conflict("Backup is not completed")
+	}
+
+	return &kubeproject.ClusterRecoveryOption{
+		SourceRef:  sourceRef,
+		ServerName: lo.CoalesceOrEmpty(backup.ServerName, sourceRef),
+		BackupID:   backup.BackupID,
+	}, nil
+}
+
+func (s *service) StartProjectRestore(ctx context.Context, ref string) error {
+	now := time.Now()
+	claimed, err := s.project.ClaimRestore(ctx, ref, now, now.Add(-RestoreTimeout))
+	if err != nil {
+		return err
+	}
+	if !claimed {
+		return huma.Error409Conflict("A database restore is already running for this project")
+	}
+	return nil
+}
+
+// RestoreProjectDatabase 以備份在新的 Cluster 還原專案的資料庫，健康後切換並重啟 API
+func (s *service) RestoreProjectDatabase(ctx context.Context, ref string, opt *kubeproject.ClusterRecoveryOption) (err error) {
+	// ctx 逾時後仍需記錄結果與撤銷權限
+	cleanupCtx := context.WithoutCancel(ctx)
+	defer func() {
+		if finishErr := s.project.FinishRestore(cleanupCtx, ref, time.Now(), err); finishErr != nil {
+			slog.ErrorContext(cleanupCtx, "Failed to record project restore result", "ref", ref, "error", finishErr)
+		}
+	}()
+
+	// 從其他專案的備份還原時，還原期間讓目標專案的備份使用者可以讀取來源的路徑前綴
+	if opt.SourceRef != ref {
+		if err := s.minio.SetBackupPolicy(ctx, ref, opt.SourceRef); err != nil {
+			return err
+		}
+		defer func() {
+			if err := s.minio.SetBackupPolicy(cleanupCtx, ref); err != nil {
+				slog.ErrorContext(cleanupCtx, "Failed to revoke source backup access", "ref", ref, "source", opt.SourceRef, "error", err)
+			}
+		}()
+	}
+
+	err = s.kube.RestoreCluster(ctx, ref, opt)
+	if err != nil {
+		return err
+	}
+
+	// 新的 Cluster 由 CNPG 產生 app 與 superuser 的密碼
+	s.usersdb.InvalidateDB(ref)
+	err = s.kube.RestartAPIDeployments(ctx, ref)
+	if err != nil {
+		return err
+	}
+
+	// 新的 WAL 封存目錄需要一份 base backup 才能再做時間點還原
+	_, err = s.kube.CreateBackup(ctx, ref)
+	return err
+}
+
+func (s *service) GetProjectRestoreStatus(ctx context.Context, in *dto.GetProjectRestoreStatusInput, userID string) (*dto.GetProjectRestoreStatusOutput, error) {
+	if _, err := s.findOwnedProject(ctx, in.Ref, userID); err != nil {
+		return nil, err
+	}
+
+	project, err := s.project.FindRestoreByRef(ctx, in.Ref)
+	if err != nil {
+		return nil, err
+	}
+
+	out := &dto.GetProjectRestoreStatusOutput{}
+	out.Body.Status = lo.FromPtrOr(project.RestoreStatus, "none")
+	out.Body.StartedAt = project.RestoreStartedAt
+	out.Body.FinishedAt = project.RestoreFinishedAt
+	out.Body.Error = lo.FromPtr(project.RestoreError)
+	return out, nil
+}
+
+func backupToDTO(backup *kubeproject.BackupInfo) dto.ProjectBackup {
+	return dto.ProjectBackup{
+		Name:       backup.Name,
+		Phase:      backup.Phase,
+		OnDemand:   backup.OnDemand,
+		ServerName: backup.ServerName,
+		BackupID:   backup.BackupID,
+		StartedAt:  backup.StartedAt,
+		StoppedAt:  backup.StoppedAt,
+		Error:      backup.Error,
+	}
+}
@@ -0,0 +1,200 @@
+package project
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"baas-api/internal/dto"
+	"baas-api/internal/kubeproject"
+
+	"github.com/danielgtaylor/huma/v2"
+)
+
+func (s *service) ListProjectCronJobs(ctx context.Context, in *dto.ListProjectCronJobsInput, userID string) (*dto.ListProjectCronJobsOutput, error) {
+	if _, err := s.findOwnedProject(ctx, in.Ref, userID); err != nil {
+		return nil, err
+	}
+
+	cronJobs, err := s.kube.ListCronJobs(ctx, in.Ref)
+	if err != nil {
+		return nil, err
+	}
+
+	out := &dto.ListProjectCronJobsOutput{}
+	out.Body.CronJobs = make([]dto.ProjectCronJob, 0, len(cronJobs))
+	for _, cronJob := range cronJobs {
+		out.Body.CronJobs = append(out.Body.CronJobs, cronJobToDTO(&cronJob))
+	}
+	return out, nil
+}
+
+// PutProjectCronJob 建立或取代排程工作，RPC 工作的函式須已存在於 api schema
+func (s *service) PutProjectCronJob(ctx context.Context, jwt string, in *dto.PutProjectCronJobInput, userID string) (*dto.PutProjectCronJobOutput, error) {
+	ref := in.Body.Ref
+	if _, err := s.findOwnedProject(ctx, ref, userID); err != nil {
+		return nil, err
+	}
+
+	opt := kubeproject.CronJobOption{
+		Name:     in.Body.Name,
+		Schedule: in.Body.Schedule,
+		Enabled:  in.Body.Enabled,
+		Task: kubeproject.CronJobTask{
+			Type:     in.Body.Type,
+			SQL:      in.Body.SQL,
+			Function: in.Body.Function,
+			Args:     in.Body.Args,
+		},
+	}
+	// 只保留該類型使用的欄位
+	switch opt.Task.Type {
+	case kubeproject.CronTaskSQL:
+		opt.Task.Function, opt.Task.Args = "", nil
+	case kubeproject.CronTaskRPC:
+		opt.Task.SQL = ""
+	}
+	if err := kubeproject.ValidateCronJob(opt); err != nil {
+		return nil, huma.Error422UnprocessableEntity(err.Error())
+	}
+
+	cronJobs, err := s.kube.ListCronJobs(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	exists := slices.ContainsFunc(cronJobs, func(cronJob kubeproject.CronJobInfo) bool { return cronJob.Name == opt.Name })
+	if !exists && len(cronJobs) >= kubeproject.CronJobLimit {
+		return nil, huma.Error409Conflict(fmt.Sprintf("A project can have at most %d cron jobs", kubeproject.CronJobLimit))
+	}
+
+	if opt.Task.Type == kubeproject.CronTaskRPC {
+		found, err := s.usersdb.HasFunction(ctx, jwt, ref, "api", opt.Task.Function)
+		if err != nil {
+			return nil, err
+		}
+		if !found {
+			return nil, huma.Error422UnprocessableEntity("Function api." + opt.Task.Function + " does not exist")
+		}
+	}
+
+	if err := s.kube.ApplyCronJob(ctx, ref, opt); err != nil {
+		return nil, err
+	}
+
+	cronJob, err := s.kube.FindCronJob(ctx, ref, opt.Name)
+	if err != nil {
+		return nil, err
+	}
+	out := &dto.PutProjectCronJobOutput{}
+	out.Body.CronJob = cronJobToDTO(cronJob)
+	return out, nil
+}
+
+func (s *service) DeleteProjectCronJob(ctx context.Context, in *dto.DeleteProjectCronJobInput, userID string) (*dto.DeleteProjectCronJobOutput, error) {
+	if _, err := s.findOwnedProject(ctx, in.Ref, userID); err != nil {
+		return nil, err
+	}
+
+	err := s.kube.DeleteCronJob(ctx, in.Ref, in.Name)
+	if errors.Is(err, kubeproject.ErrCronJobNotFound) {
+		return nil, huma.Error404NotFound("Cron job not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	out := &dto.DeleteProjectCronJobOutput{}
+	out.Body.Success = true
+	return out, nil
+}
+
+func (s *service) ListProjectCronJobRuns(ctx context.Context, in *dto.ListProjectCronJobRunsInput, userID string) (*dto.ListProjectCronJobRunsOutput, error) {
+	if _, err := s.findOwnedProject(ctx, in.Ref, userID); err != nil {
+		return nil, err
+	}
+
+	runs, err := s.kube.ListCronJobRuns(ctx, in.Ref, in.Name)
+	if errors.Is(err, kubeproject.ErrCronJobNotFound) {
+		return nil, huma.Error404NotFound("Cron job not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	out := &dto.ListProjectCronJobRunsOutput{}
+	out.Body.Runs = make([]dto.ProjectCronJobRun, 0, len(runs))
+	for _, run := range runs {
+		out.Body.Runs = append(out.Body.Runs, cronJobRunToDTO(&run))
+	}
+	return out, nil
+}
+
+func (s *service) TriggerProjectCronJob(ctx context.Context, in *dto.TriggerProjectCronJobInput, userID string) (*dto.TriggerProjectCronJobOutput, error) {
+	if _, err := s.findOwnedProject(ctx, in.Body.Ref, userID); err != nil {
+		return nil, err
+	}
+
+	run, err := s.kube.TriggerCronJob(ctx, in.Body.Ref, in.Body.Name)
+	switch {
+	case errors.Is(err, kubeproject.ErrCronJobNotFound):
+		return nil, huma.Error404NotFound("Cron job not found")
+	case errors.Is(err, kubeproject.ErrCronJobRunning):
+		return nil, huma.Error409Conflict("The cron job is already running")
+	case err != nil:
+		return nil, err
+	}
+
+	out := &dto.TriggerProjectCronJobOutput{}
+	out.Body.Run = cronJobRunToDTO(run)
+	return out, nil
+}
+
+func (s *service) GetProjectCronJobRun(ctx context.Context, in *dto.GetProjectCronJobRunInput, userID string) (*dto.GetProjectCronJobRunOutput, error) {
+	if _, err := s.findOwnedProject(ctx, in.Ref, userID); err != nil {
+		return nil, err
+	}
+
+	run, err := s.kube.FindCronJobRunLogs(ctx, in.Ref, in.Name, in.JobName)
+	if errors.Is(err, kubeproject.ErrCronJobRunNotFound) {
+		return nil, huma.Error404NotFound("Cron job run not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	out := &dto.GetProjectCronJobRunOutput{}
+	out.Body.Run = cronJobRunToDTO(run)
+	out.Body.Logs = run.Logs
+	return out, nil
+}
+
+func cronJobToDTO(cronJob *kubeproject.CronJobInfo) dto.ProjectCronJob {
+	out := dto.ProjectCronJob{
+		Name:               cronJob.Name,
+		Schedule:           cronJob.Schedule,
+		Enabled:            cronJob.Enabled,
+		Type:               cronJob.Task.Type,
+		SQL:                cronJob.Task.SQL,
+		Function:           cronJob.Task.Function,
+		Args:               cronJob.Task.Args,
+		LastScheduleTime:   cronJob.LastScheduleTime,
+		LastSuccessfulTime: cronJob.LastSuccessfulTime,
+	}
+	if cronJob.LastRun != nil {
+		lastRun := cronJobRunToDTO(cronJob.LastRun)
+		out.LastRun = &lastRun
+	}
+	return out
+}
+
+func cronJobRunToDTO(run *kubeproject.CronJobRun) dto.ProjectCronJobRun {
+	return dto.ProjectCronJobRun{
+		JobName:     run.JobName,
+		Trigger:     run.Trigger,
+		Status:      run.Status,
+		Message:     run.Message,
+		StartedAt:   run.StartedAt,
+		CompletedAt: run.CompletedAt,
+	}
+}
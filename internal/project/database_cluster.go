@@ -0,0 +1,127 @@
+package project
+
+import (
+	"context"
+	"errors"
+
+	"baas-api/internal/config"
+	"baas-api/internal/dto"
+	"baas-api/internal/kubeproject"
+
+	"github.com/danielgtaylor/huma/v2"
+)
+
+func (s *service) GetProjectDatabaseCluster(ctx context.Context, in *dto.GetProjectDatabaseClusterInput, userID string) (*dto.GetProjectDatabaseClusterOutput, error) {
+	if _, err := s.findOwnedProject(ctx, in.Ref, userID); err != nil {
+		return nil, err
+	}
+
+	cluster, err := s.kube.FindDatabaseCluster(ctx, in.Ref)
+	if err != nil {
+		return nil, err
+	}
+
+	out := &dto.GetProjectDatabaseClusterOutput{}
+	out.Body.Cluster, err = s.databaseClusterToDTO(ctx, in.Ref, cluster)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// UpdateProjectDatabaseCluster 以目前生效的設定 (或指定方案的預設值) 為基礎，覆寫有提供的欄位後套用
+func (s *service) UpdateProjectDatabaseCluster(ctx context.Context, in *dto.UpdateProjectDatabaseClusterInput, userID string) (*dto.GetProjectDatabaseClusterOutput, error) {
+	ref := in.Body.Ref
+	if _, err := s.findOwnedProject(ctx, ref, userID); err != nil {
+		return nil, err
+	}
+
+	projectPlan, projectPlanName, err := s.projectPlan(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	current, err := s.kube.FindDatabaseCluster(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	cluster := current.DatabaseClusterConfig
+	if in.Body.Plan != nil {
+		plan, err := s.selectablePlan(*in.Body.Plan, projectPlanName)
+		if err != nil {
+			return nil, err
+		}
+		cluster = plan.Database
+	}
+
+	if in.Body.Instances != nil {
+		cluster.Instances = *in.Body.Instances
+	}
+	if in.Body.Synchronous != nil {
+		cluster.Synchronous = config.DatabaseSynchronousConfig(*in.Body.Synchronous)
+	}
+
+	if err := kubeproject.ValidateDatabaseCluster(cluster); err != nil {
+		return nil, huma.Error422UnprocessableEntity(err.Error())
+	}
+	if err := kubeproject.ValidateDatabaseClusterLimits(cluster, projectPlan.Limits); err != nil {
+		return nil, huma.Error403Forbidden("Exceeds the " + projectPlanName + " plan: " + err.Error())
+	}
+	if err := s.kube.ApplyDatabaseCluster(ctx, ref, cluster); err != nil {
+		return nil, err
+	}
+
+	// 狀態會在 CNPG 完成調整後更新，這裡回傳套用的設定
+	current.DatabaseClusterConfig = cluster
+	current.ReadOnlyHost = ""
+	if cluster.Instances > 1 {
+		current.ReadOnlyHost = s.kube.GetProjectReadOnlyHost(ref)
+	}
+
+	out := &dto.GetProjectDatabaseClusterOutput{}
+	out.Body.Cluster, err = s.databaseClusterToDTO(ctx, ref, current)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (s *service) databaseClusterToDTO(ctx context.Context, ref string, cluster *kubeproject.DatabaseClusterInfo) (dto.ProjectDatabaseCluster, error) {
+	connection, err := s.databaseConnection(ctx, ref)
+	if err != nil {
+		return dto.ProjectDatabaseCluster{}, err
+	}
+	if cluster.ReadOnlyHost != "" {
+		connection.ReadOnly = databaseConnectionString(cluster.ReadOnlyHost)
+	}
+
+	return dto.ProjectDatabaseCluster{
+		Instances:      cluster.Instances,
+		Synchronous:    dto.ProjectDatabaseSynchronous(cluster.Synchronous),
+		ReadyInstances: cluster.ReadyInstances,
+		CurrentPrimary: cluster.CurrentPrimary,
+		Connection:     *connection,
+	}, nil
+}
+
+// databaseConnection 回傳直接連線與 (啟用時) 經由 pooler 的連線字串
+func (s *service) databaseConnection(ctx context.Context, ref string) (*dto.ProjectDatabaseConnection, error) {
+	connection := &dto.ProjectDatabaseConnection{
+		ReadWrite: databaseConnectionString(s.kube.GetProjectHost(ref)),
+	}
+
+	pooler, err := s.kube.FindPooler(ctx, ref)
+	switch {
+	case err == nil:
+		connection.Pooled = databaseConnectionString(pooler.Host)
+	case !errors.Is(err, kubeproject.ErrPoolerNotFound):
+		return nil, err
+	}
+	return connection, nil
+}
+
+// databaseConnectionString 產生不含密碼的連線字串，SNI 路由需要 TLS
+func databaseConnectionString(host string) string {
+	return "postgresql://" + kubeproject.RoleApp + "@" + host + ":5432/app?sslmode=require"
+}
@@ -0,0 +1,138 @@
+package project
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/url"
+
+	"baas-api/internal/config"
+	"baas-api/internal/dto"
+	"baas-api/internal/kubeproject"
+	"baas-api/internal/middlewares"
+)
+
+// databaseUsers 是可以登入專案資料庫的角色
+var databaseUsers = []dto.ProjectDatabaseUser{
+	{Username: kubeproject.RoleApp, Description: "Owner of the app database with full privileges"},
+	{Username: kubeproject.RoleAuthenticator, Description: "Login role of the REST API; can only switch to anon, authenticated and app_admin"},
+}
+
+// GetProjectDatabaseConnection 回傳連線到專案資料庫所需的資訊，不含密碼
+func (s *service) GetProjectDatabaseConnection(ctx context.Context, in *dto.GetProjectDatabaseConnectionInput, userID string) (*dto.GetProjectDatabaseConnectionOutput, error) {
+	if _, err := s.findOwnedProject(ctx, in.Ref, userID); err != nil {
+		return nil, err
+	}
+	return s.projectDatabaseConnection(ctx, in.Ref, false)
+}
+
+// RevealProjectDatabaseConnection 回傳含各角色密碼的連線資訊，權限要求與 RevealProjectAuthSecret 相同
+func (s *service) RevealProjectDatabaseConnection(ctx context.Context, jwt string, session *middlewares.Session, in *dto.RevealProjectDatabaseConnectionInput) (*dto.GetProjectDatabaseConnectionOutput, error) {
+	ref := in.Body.Ref
+	if _, err := s.findRevealableProject(ctx, jwt, session, ref); err != nil {
+		return nil, err
+	}
+
+	out, err := s.projectDatabaseConnection(ctx, ref, true)
+	if err != nil {
+		return nil, err
+	}
+
+	slog.InfoContext(ctx, "Revealed database passwords", "ref", ref, "user", session.UserID)
+	return out, nil
+}
+
+func (s *service) projectDatabaseConnection(ctx context.Context, ref string, withPasswords bool) (*dto.GetProjectDatabaseConnectionOutput, error) {
+	cluster, err := s.kube.FindDatabaseCluster(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	caCertificate, err := s.kube.FindDatabaseCACertificate(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	connection := dto.ProjectDatabaseConnectionDetails{
+		Host:          s.kube.GetProjectHost(ref),
+		ReadOnlyHost:  cluster.ReadOnlyHost,
+		Port:          5432,
+		Database:      "app",
+		SSLMode:       "verify-full",
+		CACertificate: caCertificate,
+		DirectTLS:     s.config.Kube.Ingress.Provider == config.IngressProviderGateway,
+		Users:         make([]dto.ProjectDatabaseUser, 0, len(databaseUsers)),
+	}
+	pooler, err := s.kube.FindPooler(ctx, ref)
+	switch {
+	case err == nil:
+		connection.PooledHost = pooler.Host
+	case !errors.Is(err, kubeproject.ErrPoolerNotFound):
+		return nil, err
+	}
+
+	var appPassword string
+	for _, user := range databaseUsers {
+		if withPasswords {
+			password, err := s.kube.FindDatabaseRolePassword(ctx, ref, user.Username)
+			if err != nil {
+				return nil, err
+			}
+			if password != nil {
+				user.Password = *password
+			}
+		}
+		if user.Username == kubeproject.RoleApp {
+			appPassword = user.Password
+		}
+		connection.Users = append(connection.Users, user)
+	}
+	connection.ConnectionStrings = databaseConnectionStrings(connection.Host, kubeproject.RoleApp, appPassword, connection.DirectTLS)
+
+	out := &dto.GetProjectDatabaseConnectionOutput{}
+	out.Body.Connection = connection
+	return out, nil
+}
+
+// databaseConnectionStrings 產生各用戶端的連線字串，password 為空時不含密碼。
+//
+// 資料庫依 SNI 路由，libpq (psql、node-postgres 相容) 與 JDBC 都會送出 SNI；CA 憑證的路徑固定為 ca.crt。
+// directTLS 時 (Gateway API 的 TLSRoute 看不到 SSLRequest 之後的 SNI) 加上 sslnegotiation=direct，
+// 不支援 direct TLS 的 Prisma 與 node-postgres 不提供連線字串。
+func databaseConnectionStrings(host, username, password string, directTLS bool) dto.ProjectDatabaseConnectionStrings {
+	userinfo := url.User(username)
+	if password != "" {
+		userinfo = url.UserPassword(username, password)
+	}
+	uri := func(query url.Values) string {
+		u := url.URL{
+			Scheme:   "postgresql",
+			User:     userinfo,
+			Host:     host + ":5432",
+			Path:     "/app",
+			RawQuery: query.Encode(),
+		}
+		return u.String()
+	}
+	verifyFull := url.Values{"sslmode": {"verify-full"}, "sslrootcert": {"ca.crt"}}
+
+	jdbcQuery := url.Values{"user": {username}, "sslmode": {"verify-full"}, "sslrootcert": {"ca.crt"}}
+	if password != "" {
+		jdbcQuery.Set("password", password)
+	}
+
+	if directTLS {
+		verifyFull.Set("sslnegotiation", "direct")
+		jdbcQuery.Set("sslNegotiation", "direct")
+		return dto.ProjectDatabaseConnectionStrings{
+			PSQL: uri(verifyFull),
+			JDBC: "jdbc:postgresql://" + host + ":5432/app?" + jdbcQuery.Encode(),
+		}
+	}
+
+	return dto.ProjectDatabaseConnectionStrings{
+		PSQL:         uri(verifyFull),
+		JDBC:         "jdbc:postgresql://" + host + ":5432/app?" + jdbcQuery.Encode(),
+		Prisma:       uri(url.Values{"sslmode": {"require"}, "sslaccept": {"strict"}, "sslcert": {"ca.crt"}}),
+		NodePostgres: uri(verifyFull),
+	}
+}
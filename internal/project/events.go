@@ -0,0 +1,65 @@
+package project
+
+import (
+	"context"
+	"time"
+
+	"baas-api/internal/dto"
+	"baas-api/internal/kubeproject"
+
+	"github.com/samber/lo"
+)
+
+// eventSeverityLevel 用於依最低嚴重程度篩選事件
+var eventSeverityLevel = map[string]int{
+	kubeproject.EventSeverityInfo:    0,
+	kubeproject.EventSeverityWarning: 1,
+	kubeproject.EventSeverityError:   2,
+}
+
+func (s *service) ListProjectEvents(ctx context.Context, in *dto.ListProjectEventsInput, userID string) (*dto.ListProjectEventsOutput, error) {
+	if _, err := s.findOwnedProject(ctx, in.Ref, userID); err != nil {
+		return nil, err
+	}
+
+	events, err := s.kube.ListProjectEvents(ctx, in.Ref, in.Since)
+	if err != nil {
+		return nil, err
+	}
+
+	minLevel := eventSeverityLevel[in.Severity]
+	out := &dto.ListProjectEventsOutput{}
+	out.Body.Events = lo.FilterMap(events, func(event kubeproject.ProjectEvent, _ int) (dto.ProjectKubernetesEvent, bool) {
+		return projectEventToDTO(event), eventSeverityLevel[event.Severity] >= minLevel
+	})
+	return out, nil
+}
+
+// sendProjectEvents 將 since 之後的專案事件依時間先後送到 c，回傳最後一個事件的時間
+func (s *service) sendProjectEvents(ctx context.Context, c chan any, ref string, since time.Time) (time.Time, error) {
+	events, err := s.kube.ListProjectEvents(ctx, ref, since)
+	if err != nil {
+		return since, err
+	}
+	for i := len(events) - 1; i >= 0; i-- {
+		c <- projectEventToDTO(events[i])
+	}
+	if len(events) > 0 {
+		since = events[0].LastSeen
+	}
+	return since, nil
+}
+
+func projectEventToDTO(event kubeproject.ProjectEvent) dto.ProjectKubernetesEvent {
+	return dto.ProjectKubernetesEvent{
+		Severity:  event.Severity,
+		Reason:    event.Reason,
+		Message:   event.Message,
+		Detail:    event.Detail,
+		Kind:      event.Kind,
+		Name:      event.Name,
+		Count:     event.Count,
+		FirstSeen: event.FirstSeen,
+		LastSeen:  event.LastSeen,
+	}
+}
@@ -0,0 +1,126 @@
+package project
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+
+	"baas-api/internal/dto"
+	"baas-api/internal/kubeproject"
+	"baas-api/internal/models"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/samber/lo"
+)
+
+func (s *service) ListProjectDatabaseExtensions(ctx context.Context, jwt string, in *dto.ListProjectDatabaseExtensionsInput, userID string) (*dto.ListProjectDatabaseExtensionsOutput, error) {
+	if _, err := s.findOwnedProject(ctx, in.Ref, userID); err != nil {
+		return nil, err
+	}
+
+	current, err := s.kube.FindDatabaseExtensions(ctx, in.Ref)
+	if err != nil {
+		return nil, err
+	}
+
+	// 平台固定建立的 extension 在前，其後依允許清單的順序
+	names := lo.Uniq(append(
+		lo.FilterMap(current, func(e kubeproject.DatabaseExtension, _ int) (string, bool) { return e.Name, e.Builtin }),
+		lo.Filter(s.config.Kube.Project.Extensions.Allowlist, func(name string, _ int) bool { return s.kube.IsExtensionAllowed(name) })...,
+	))
+	available, err := s.usersdb.GetAvailableExtensions(ctx, jwt, in.Ref, names)
+	if err != nil {
+		return nil, err
+	}
+
+	out := &dto.ListProjectDatabaseExtensionsOutput{}
+	out.Body.Extensions = lo.Map(names, func(name string, _ int) dto.ProjectDatabaseExtension {
+		return extensionToDTO(name, current, available)
+	})
+	return out, nil
+}
+
+// UpdateProjectDatabaseExtension 啟用或停用允許清單中的 extension
+func (s *service) UpdateProjectDatabaseExtension(ctx context.Context, jwt string, in *dto.UpdateProjectDatabaseExtensionInput, userID string) (*dto.UpdateProjectDatabaseExtensionOutput, error) {
+	ref := in.Body.Ref
+	name := in.Body.Name
+	if _, err := s.findOwnedProject(ctx, ref, userID); err != nil {
+		return nil, err
+	}
+
+	if kubeproject.IsBuiltinExtension(name) {
+		return nil, huma.Error409Conflict("Extension is managed by the platform: " + name)
+	}
+	if !s.kube.IsExtensionAllowed(name) {
+		return nil, huma.Error422UnprocessableEntity("Extension is not allowed: " + name)
+	}
+
+	available, err := s.usersdb.GetAvailableExtensions(ctx, jwt, ref, []string{name})
+	if err != nil {
+		return nil, err
+	}
+	if in.Body.Enabled && len(available) == 0 {
+		return nil, huma.Error422UnprocessableEntity("Extension is not available in the Postgres image: " + name)
+	}
+	// 未指定版本時安裝 image 的預設版本 (default_version)，一定可以安裝
+	if in.Body.Enabled && in.Body.Version != "" {
+		versions, err := s.usersdb.GetAvailableExtensionVersions(ctx, jwt, ref, name)
+		if err != nil {
+			return nil, err
+		}
+		if !slices.Contains(versions, in.Body.Version) {
+			return nil, huma.Error422UnprocessableEntity("Extension version is not available in the Postgres image: " + name + " " + in.Body.Version + " (available: " + strings.Join(versions, ", ") + ")")
+		}
+	}
+
+	current, err := s.kube.FindDatabaseExtensions(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	requested := kubeproject.DatabaseExtensionSpec{
+		Name:    name,
+		Enabled: in.Body.Enabled,
+		Version: in.Body.Version,
+	}
+	managed := lo.FilterMap(current, func(e kubeproject.DatabaseExtension, _ int) (kubeproject.DatabaseExtensionSpec, bool) {
+		return e.DatabaseExtensionSpec, !e.Builtin && e.Name != name
+	})
+	managed = append(managed, requested)
+
+	err = s.kube.ApplyDatabaseExtensions(ctx, ref, managed)
+	if errors.Is(err, kubeproject.ErrExtensionNotAllowed) {
+		return nil, huma.Error422UnprocessableEntity(err.Error())
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// 新的設定尚未被 CNPG 套用
+	next := []kubeproject.DatabaseExtension{{DatabaseExtensionSpec: requested}}
+
+	out := &dto.UpdateProjectDatabaseExtensionOutput{}
+	out.Body.Extension = extensionToDTO(name, next, available)
+	return out, nil
+}
+
+func extensionToDTO(name string, current []kubeproject.DatabaseExtension, available []models.PostgresAvailableExtension) dto.ProjectDatabaseExtension {
+	extension := dto.ProjectDatabaseExtension{
+		Name:    name,
+		Builtin: kubeproject.IsBuiltinExtension(name),
+	}
+	if i := slices.IndexFunc(current, func(e kubeproject.DatabaseExtension) bool { return e.Name == name }); i >= 0 {
+		extension.Enabled = current[i].Enabled
+		extension.Version = current[i].Version
+		extension.Applied = current[i].Applied
+		extension.Message = current[i].Message
+	}
+	if i := slices.IndexFunc(available, func(e models.PostgresAvailableExtension) bool { return e.Name == name }); i >= 0 {
+		extension.Available = true
+		extension.Comment = available[i].Comment
+		extension.DefaultVersion = available[i].DefaultVersion
+		extension.InstalledVersion = lo.FromPtr(available[i].InstalledVersion)
+	}
+	return extension
+}
@@ -0,0 +1,62 @@
+package project
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"baas-api/internal/dto"
+	"baas-api/internal/models"
+
+	"github.com/danielgtaylor/huma/v2"
+)
+
+func TestUpdateProjectDatabaseExtension(t *testing.T) {
+	tests := []struct {
+		name        string
+		extension   string
+		enabled     bool
+		version     string
+		wantStatus  int
+		wantApplied bool
+	}{
+		{name: "default version", extension: "vector", enabled: true, wantApplied: true},
+		{name: "available version", extension: "vector", enabled: true, version: "0.7.4", wantApplied: true},
+		{name: "unavailable version", extension: "vector", enabled: true, version: "9.9.9", wantStatus: 422},
+		{name: "disable ignores version", extension: "vector", version: "9.9.9", wantApplied: true},
+		{name: "not in the image", extension: "postgis", enabled: true, wantStatus: 422},
+		{name: "not allowed", extension: "plpython3u", enabled: true, wantStatus: 422},
+		{name: "builtin", extension: "pgcrypto", enabled: true, wantStatus: 409},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kube := &fakeExtensionKube{allowed: []string{"vector", "postgis"}}
+			s := &service{
+				kube: kube,
+				usersdb: &fakeExtensionUsersDB{
+					versions: map[string][]string{"vector": {"0.7.4", "0.8.0"}},
+					defaults: map[string]string{"vector": "0.8.0"},
+				},
+				project: &fakeProjectRepository{projects: map[string]*models.ProjectView{testRef: {Reference: testRef, OwnerID: "user"}}},
+			}
+			in := &dto.UpdateProjectDatabaseExtensionInput{}
+			in.Body.Ref = testRef
+			in.Body.Name = tt.extension
+			in.Body.Enabled = tt.enabled
+			in.Body.Version = tt.version
+
+			_, err := s.UpdateProjectDatabaseExtension(context.Background(), "jwt", in, "user")
+			if tt.wantStatus != 0 {
+				var statusErr huma.StatusError
+				if !errors.As(err, &statusErr) || statusErr.GetStatus() != tt.wantStatus {
+					t.Fatalf("UpdateProjectDatabaseExtension error = %v, want status %d", err, tt.wantStatus)
+				}
+			} else if err != nil {
+				t.Fatalf("UpdateProjectDatabaseExtension: %v", err)
+			}
+			if applied := len(kube.applied) > 0; applied != tt.wantApplied {
+				t.Errorf("applied = %v, want %v", kube.applied, tt.wantApplied)
+			}
+		})
+	}
+}
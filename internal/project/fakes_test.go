@@ -0,0 +1,940 @@
+package project
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"baas-api/internal/authsetting"
+	"baas-api/internal/dto"
+	"baas-api/internal/kubeproject"
+	"baas-api/internal/models"
+	"baas-api/internal/pgrest"
+	"baas-api/internal/usersdb"
+
+	"github.com/lestrrat-go/jwx/v3/jwk"
+	"github.com/lib/pq"
+	"github.com/minio/madmin-go/v4"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	"k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/apimachinery/pkg/watch"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+// ===== Kubernetes =====
+
+// fakeCustomResources 是 kubeproject 以 dynamic client 操作的 CRD 與對應的 List kind
+var fakeCustomResources = map[schema.GroupVersionResource]string{
+	{Group: "postgresql.cnpg.io", Version: "v1", Resource: "clusters"}:               "ClusterList",
+	{Group: "postgresql.cnpg.io", Version: "v1", Resource: "databases"}:              "DatabaseList",
+	{Group: "postgresql.cnpg.io", Version: "v1", Resource: "scheduledbackups"}:       "ScheduledBackupList",
+	{Group: "postgresql.cnpg.io", Version: "v1", Resource: "backups"}:                "BackupList",
+	{Group: "postgresql.cnpg.io", Version: "v1", Resource: "poolers"}:                "PoolerList",
+	{Group: "traefik.io", Version: "v1alpha1", Resource: "ingressroutes"}:            "IngressRouteList",
+	{Group: "traefik.io", Version: "v1alpha1", Resource: "middlewares"}:              "MiddlewareList",
+	{Group: "traefik.io", Version: "v1alpha1", Resource: "ingressroutetcps"}:         "IngressRouteTCPList",
+	{Group: "gateway.networking.k8s.io", Version: "v1", Resource: "httproutes"}:      "HTTPRouteList",
+	{Group: "gateway.networking.k8s.io", Version: "v1alpha2", Resource: "tlsroutes"}: "TLSRouteList",
+}
+
+// fakeBridgedResources 是 typed clientset 建立、但共用 informer 以 dynamic client 監看的資源
+var fakeBridgedResources = map[schema.GroupVersionResource]string{
+	{Group: "apps", Version: "v1", Resource: "deployments"}: "Deployment",
+	{Group: "batch", Version: "v1", Resource: "jobs"}:       "Job",
+	{Group: "", Version: "v1", Resource: "events"}:          "Event",
+	// 刪除專案時以 dynamic client 刪除的資源
+	{Group: "autoscaling", Version: "v2", Resource: "horizontalpodautoscalers"}: "HorizontalPodAutoscaler",
+	{Group: "policy", Version: "v1", Resource: "poddisruptionbudgets"}:          "PodDisruptionBudget",
+	{Group: "batch", Version: "v1", Resource: "cronjobs"}:                       "CronJob",
+	{Group: "", Version: "v1", Resource: "services"}:                            "Service",
+	{Group: "", Version: "v1", Resource: "configmaps"}:                          "ConfigMap",
+	{Group: "", Version: "v1", Resource: "secrets"}:                             "Secret",
+}
+
+var clusterGVR = schema.GroupVersionResource{Group: "postgresql.cnpg.io", Version: "v1", Resource: "clusters"}
+
+// newFakeKube 建立 typed 與 dynamic 的 fake client。
+//
+// dynamic client 加上 server-side apply 的 reactor，並以 typed clientset 的內容回應 Deployment、Job 與 Event；
+// typed clientset 模擬 Job controller，migration Job 建立後立即標記為完成。
+func newFakeKube(t *testing.T) (*k8sfake.Clientset, *dynamicfake.FakeDynamicClient) {
+	t.Helper()
+	clientset := k8sfake.NewClientset()
+
+	listKinds := map[schema.GroupVersionResource]string{}
+	for gvr, listKind := range fakeCustomResources {
+		listKinds[gvr] = listKind
+	}
+	for gvr, kind := range fakeBridgedResources {
+		listKinds[gvr] = kind + "List"
+	}
+	dynamicClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), listKinds)
+
+	dynamicClient.PrependReactor("patch", "*", dynamicApplyReactor(dynamicClient.Tracker()))
+	for gvr, kind := range fakeBridgedResources {
+		bridgeTypedResource(dynamicClient, clientset.Tracker(), gvr, kind)
+	}
+	clientset.PrependReactor("patch", "jobs", completeJobReactor(clientset.Tracker()))
+	clientset.PrependReactor("patch", "secrets", secretStringDataReactor(clientset.Tracker()))
+
+	return clientset, dynamicClient
+}
+
+// dynamicApplyReactor 處理 ApplyPatchType：物件不存在時建立，存在時合併後更新。
+//
+// fake dynamic client 的 tracker 只支援對已存在的物件 apply。
+func dynamicApplyReactor(tracker clienttesting.ObjectTracker) clienttesting.ReactionFunc {
+	return func(action clienttesting.Action) (bool, runtime.Object, error) {
+		patchAction, ok := action.(clienttesting.PatchActionImpl)
+		if !ok || patchAction.GetPatchType() != types.ApplyPatchType {
+			return false, nil, nil
+		}
+		gvr, ns, name := patchAction.GetResource(), patchAction.GetNamespace(), patchAction.GetName()
+
+		applied := &unstructured.Unstructured{Object: map[string]any{}}
+		if err := yaml.Unmarshal(patchAction.GetPatch(), &applied.Object); err != nil {
+			return true, nil, err
+		}
+		applied.SetName(name)
+		applied.SetNamespace(ns)
+
+		current, err := tracker.Get(gvr, ns, name)
+		if apierrors.IsNotFound(err) {
+			applied.SetUID(uuid.NewUUID())
+			applied.SetCreationTimestamp(metav1.Now())
+			if err := tracker.Create(gvr, applied, ns); err != nil {
+				return true, nil, err
+			}
+			return true, applied, nil
+		}
+		if err != nil {
+			return true, nil, err
+		}
+
+		merged := current.(*unstructured.Unstructured).DeepCopy()
+		mergeApplied(merged.Object, applied.Object)
+		if err := tracker.Update(gvr, merged, ns); err != nil {
+			return true, nil, err
+		}
+		return true, merged, nil
+	}
+}
+
+// mergeApplied 以 applied 覆寫 dst：map 逐一合併，其他值 (包含 list) 直接取代
+func mergeApplied(dst, applied map[string]any) {
+	for key, value := range applied {
+		nested, ok := value.(map[string]any)
+		current, currentOK := dst[key].(map[string]any)
+		if ok && currentOK {
+			mergeApplied(current, nested)
+			continue
+		}
+		dst[key] = value
+	}
+}
+
+// bridgeTypedResource 讓 dynamic client 以 unstructured 讀取、監看與刪除 typed clientset 中的資源
+func bridgeTypedResource(dynamicClient *dynamicfake.FakeDynamicClient, tracker clienttesting.ObjectTracker, gvr schema.GroupVersionResource, kind string) {
+	dynamicClient.PrependReactor("get", gvr.Resource, func(action clienttesting.Action) (bool, runtime.Object, error) {
+		getAction := action.(clienttesting.GetAction)
+		obj, err := tracker.Get(gvr, getAction.GetNamespace(), getAction.GetName())
+		if err != nil {
+			return true, nil, err
+		}
+		u, err := toUnstructured(obj)
+		return true, u, err
+	})
+	dynamicClient.PrependReactor("list", gvr.Resource, func(action clienttesting.Action) (bool, runtime.Object, error) {
+		obj, err := tracker.List(gvr, gvr.GroupVersion().WithKind(kind), action.GetNamespace())
+		if err != nil {
+			return true, nil, err
+		}
+		content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+		if err != nil {
+			return true, nil, err
+		}
+		list := &unstructured.UnstructuredList{}
+		list.SetUnstructuredContent(content)
+		selector := action.(clienttesting.ListAction).GetListRestrictions().Labels
+		if selector != nil && !selector.Empty() {
+			items := list.Items[:0]
+			for _, item := range list.Items {
+				if selector.Matches(labels.Set(item.GetLabels())) {
+					items = append(items, item)
+				}
+			}
+			list.Items = items
+		}
+		return true, list, nil
+	})
+	dynamicClient.PrependReactor("delete", gvr.Resource, func(action clienttesting.Action) (bool, runtime.Object, error) {
+		deleteAction := action.(clienttesting.DeleteAction)
+		return true, nil, tracker.Delete(gvr, deleteAction.GetNamespace(), deleteAction.GetName())
+	})
+	dynamicClient.PrependWatchReactor(gvr.Resource, func(action clienttesting.Action) (bool, watch.Interface, error) {
+		w, err := tracker.Watch(gvr, action.GetNamespace())
+		if err != nil {
+			return true, nil, err
+		}
+		return true, watch.Filter(w, func(event watch.Event) (watch.Event, bool) {
+			u, err := toUnstructured(event.Object)
+			if err != nil {
+				return event, false
+			}
+			event.Object = u
+			return event, true
+		}), nil
+	})
+}
+
+func toUnstructured(obj runtime.Object) (*unstructured.Unstructured, error) {
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return nil, err
+	}
+	return &unstructured.Unstructured{Object: content}, nil
+}
+
+// completeJobReactor 在 Job 套用後將其標記為成功完成，取代 Job controller
+func completeJobReactor(tracker clienttesting.ObjectTracker) clienttesting.ReactionFunc {
+	return func(action clienttesting.Action) (bool, runtime.Object, error) {
+		handled, obj, err := clienttesting.ObjectReaction(tracker)(action)
+		if !handled || err != nil {
+			return handled, obj, err
+		}
+		job := obj.(*batchv1.Job).DeepCopy()
+		now := metav1.Now()
+		job.Status.StartTime = &now
+		job.Status.CompletionTime = &now
+		job.Status.Succeeded = 1
+		job.Status.Conditions = []batchv1.JobCondition{
+			{Type: batchv1.JobComplete, Status: corev1.ConditionTrue, LastTransitionTime: now},
+		}
+		if err := tracker.Update(action.GetResource(), job, action.GetNamespace()); err != nil {
+			return true, nil, err
+		}
+		return true, job, nil
+	}
+}
+
+// secretStringDataReactor 如同 API server 將 Secret 的 stringData 合併到 data
+func secretStringDataReactor(tracker clienttesting.ObjectTracker) clienttesting.ReactionFunc {
+	return func(action clienttesting.Action) (bool, runtime.Object, error) {
+		handled, obj, err := clienttesting.ObjectReaction(tracker)(action)
+		if !handled || err != nil {
+			return handled, obj, err
+		}
+		secret := obj.(*corev1.Secret).DeepCopy()
+		if len(secret.StringData) == 0 {
+			return true, secret, nil
+		}
+		if secret.Data == nil {
+			secret.Data = map[string][]byte{}
+		}
+		for key, value := range secret.StringData {
+			secret.Data[key] = []byte(value)
+		}
+		secret.StringData = nil
+		if err := tracker.Update(action.GetResource(), secret, action.GetNamespace()); err != nil {
+			return true, nil, err
+		}
+		return true, secret, nil
+	}
+}
+
+// ===== MinIO =====
+
+// fakeMinIO 是記憶體中的 MinIO server，回應 minio-go 的 bucket 請求與 madmin 的 admin API，
+// 讓 minio.Service 的實作 (admin 請求的加密、policy 內容與綁定) 實際執行
+type fakeMinIO struct {
+	*httptest.Server
+	accessKeyID string
+	secretKey   string
+
+	mu       sync.Mutex
+	buckets  map[string]bool
+	quotas   map[string]madmin.BucketQuota
+	users    map[string]string
+	policies map[string]fakeMinIOPolicy
+	attached map[string][]string
+}
+
+// fakeMinIOPolicy 是 canned policy 中檢查的欄位
+type fakeMinIOPolicy struct {
+	Version   string
+	Statement []struct {
+		Effect    string
+		Action    []string
+		Resource  []string
+		Condition map[string]map[string][]string
+	}
+}
+
+func newFakeMinIO(t *testing.T) *fakeMinIO {
+	t.Helper()
+	m := &fakeMinIO{
+		accessKeyID: "minio-admin",
+		secretKey:   "minio-admin-secret",
+		buckets:     map[string]bool{},
+		quotas:      map[string]madmin.BucketQuota{},
+		users:       map[string]string{},
+		policies:    map[string]fakeMinIOPolicy{},
+		attached:    map[string][]string{},
+	}
+	m.Server = httptest.NewServer(http.HandlerFunc(m.handle))
+	t.Cleanup(m.Close)
+	return m
+}
+
+// endpoint 是 minio-go 與 madmin 使用的 host:port
+func (m *fakeMinIO) endpoint() string {
+	return strings.TrimPrefix(m.URL, "http://")
+}
+
+func (m *fakeMinIO) handle(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if path, ok := strings.CutPrefix(r.URL.Path, "/minio/admin/"); ok {
+		_, path, _ = strings.Cut(path, "/")
+		m.handleAdmin(w, r, path, body)
+		return
+	}
+
+	bucket := strings.Trim(r.URL.Path, "/")
+	switch {
+	case r.Method == http.MethodGet && r.URL.Query().Has("location"):
+		w.Header().Set("Content-Type", "application/xml")
+		_, _ = io.WriteString(w, `<LocationConstraint xmlns="http://s3.amazonaws.com/doc/2006-03-01/">us-east-1</LocationConstraint>`)
+	case r.Method == http.MethodHead:
+		if !m.buckets[bucket] {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	case r.Method == http.MethodPut:
+		if m.buckets[bucket] {
+			writeS3Error(w, http.StatusConflict, "BucketAlreadyOwnedByYou", bucket)
+			return
+		}
+		m.buckets[bucket] = true
+	case r.Method == http.MethodDelete:
+		if !m.buckets[bucket] {
+			writeS3Error(w, http.StatusNotFound, "NoSuchBucket", bucket)
+			return
+		}
+		delete(m.buckets, bucket)
+		delete(m.quotas, bucket)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeS3Error(w, http.StatusNotImplemented, "NotImplemented", bucket)
+	}
+}
+
+func (m *fakeMinIO) handleAdmin(w http.ResponseWriter, r *http.Request, path string, body []byte) {
+	query := r.URL.Query()
+	switch r.Method + " " + path {
+	case "PUT set-bucket-quota":
+		bucket := query.Get("bucket")
+		var quota madmin.BucketQuota
+		if !m.buckets[bucket] {
+			writeAdminError(w, http.StatusNotFound, "NoSuchBucket")
+			return
+		}
+		if err := json.Unmarshal(body, &quota); err != nil {
+			writeAdminError(w, http.StatusBadRequest, "XMinioAdminInvalidArgument")
+			return
+		}
+		m.quotas[bucket] = quota
+	case "PUT add-user":
+		var req madmin.AddOrUpdateUserReq
+		if !m.decrypt(w, body, &req) {
+			return
+		}
+		m.users[query.Get("accessKey")] = req.SecretKey
+	case "DELETE remove-user":
+		accessKey := query.Get("accessKey")
+		if _, ok := m.users[accessKey]; !ok {
+			writeAdminError(w, http.StatusNotFound, "XMinioAdminNoSuchUser")
+			return
+		}
+		delete(m.users, accessKey)
+		delete(m.attached, accessKey)
+	case "GET user-info":
+		accessKey := query.Get("accessKey")
+		if _, ok := m.users[accessKey]; !ok {
+			writeAdminError(w, http.StatusNotFound, "XMinioAdminNoSuchUser")
+			return
+		}
+		_ = json.NewEncoder(w).Encode(madmin.UserInfo{
+			PolicyName: strings.Join(m.attached[accessKey], ","),
+			Status:     madmin.AccountEnabled,
+		})
+	case "PUT add-canned-policy":
+		var policy fakeMinIOPolicy
+		if err := json.Unmarshal(body, &policy); err != nil || policy.Version != "2012-10-17" || len(policy.Statement) == 0 {
+			writeAdminError(w, http.StatusBadRequest, "XMinioMalformedIAMPolicy")
+			return
+		}
+		m.policies[query.Get("name")] = policy
+	case "DELETE remove-canned-policy":
+		name := query.Get("name")
+		if _, ok := m.policies[name]; !ok {
+			writeAdminError(w, http.StatusNotFound, "XMinioAdminNoSuchPolicy")
+			return
+		}
+		delete(m.policies, name)
+	case "POST idp/builtin/policy/attach":
+		var req madmin.PolicyAssociationReq
+		if !m.decrypt(w, body, &req) {
+			return
+		}
+		if _, ok := m.users[req.User]; !ok {
+			writeAdminError(w, http.StatusNotFound, "XMinioAdminNoSuchUser")
+			return
+		}
+		var attached []string
+		for _, policy := range req.Policies {
+			if _, ok := m.policies[policy]; !ok {
+				writeAdminError(w, http.StatusNotFound, "XMinioAdminNoSuchPolicy")
+				return
+			}
+			if !slices.Contains(m.attached[req.User], policy) {
+				attached = append(attached, policy)
+			}
+		}
+		// 與 MinIO 相同，已綁定的 policy 再次綁定時回傳錯誤
+		if len(attached) == 0 {
+			writeAdminError(w, http.StatusBadRequest, "XMinioAdminPolicyChangeAlreadyApplied")
+			return
+		}
+		m.attached[req.User] = append(m.attached[req.User], attached...)
+		m.writeEncrypted(w, madmin.PolicyAssociationResp{PoliciesAttached: attached})
+	default:
+		writeAdminError(w, http.StatusNotImplemented, "XMinioAdminNotImplemented")
+	}
+}
+
+// decrypt 以 admin 的 secret key 解密 madmin 加密的請求
+func (m *fakeMinIO) decrypt(w http.ResponseWriter, body []byte, v any) bool {
+	data, err := madmin.DecryptData(m.secretKey, bytes.NewReader(body))
+	if err == nil {
+		err = json.Unmarshal(data, v)
+	}
+	if err != nil {
+		writeAdminError(w, http.StatusBadRequest, "XMinioAdminConfigBadJSON")
+		return false
+	}
+	return true
+}
+
+func (m *fakeMinIO) writeEncrypted(w http.ResponseWriter, v any) {
+	data, _ := json.Marshal(v)
+	encrypted, err := madmin.EncryptData(m.secretKey, data)
+	if err != nil {
+		writeAdminError(w, http.StatusInternalServerError, "InternalError")
+		return
+	}
+	_, _ = w.Write(encrypted)
+}
+
+func writeAdminError(w http.ResponseWriter, status int, code string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(madmin.ErrorResponse{Code: code, Message: code})
+}
+
+func writeS3Error(w http.ResponseWriter, status int, code string, bucket string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	_, _ = fmt.Fprintf(w, "<Error><Code>%s</Code><Message>%s</Message><BucketName>%s</BucketName></Error>", code, code, bucket)
+}
+
+func (m *fakeMinIO) hasBucket(name string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.buckets[name]
+}
+
+func (m *fakeMinIO) hasUser(accessKeyID string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, ok := m.users[accessKeyID]
+	return ok
+}
+
+// quota 回傳 bucket 的 quota
+func (m *fakeMinIO) quota(bucket string) (madmin.BucketQuota, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	quota, ok := m.quotas[bucket]
+	return quota, ok
+}
+
+// userPolicies 回傳綁定在使用者的 policy 內容
+func (m *fakeMinIO) userPolicies(accessKeyID string) map[string]fakeMinIOPolicy {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	policies := map[string]fakeMinIOPolicy{}
+	for _, name := range m.attached[accessKeyID] {
+		policies[name] = m.policies[name]
+	}
+	return policies
+}
+
+// ===== PostgREST =====
+
+// fakePostgREST 回應平台資料庫的 RPC，並記錄收到的請求內容。
+//
+// RPC 寫入的專案 auth 設定與 provider 記錄在 db，與 authsetting.Repository 讀到的內容一致。
+type fakePostgREST struct {
+	*httptest.Server
+	project pgrest.CreateProjectOutput
+	db      *fakeAuthSettingRepository
+
+	mu       sync.Mutex
+	requests map[string][]json.RawMessage
+}
+
+func newFakePostgREST(t *testing.T, project pgrest.CreateProjectOutput, db *fakeAuthSettingRepository) *fakePostgREST {
+	t.Helper()
+	f := &fakePostgREST{project: project, db: db, requests: map[string][]json.RawMessage{}}
+	f.Server = httptest.NewServer(http.HandlerFunc(f.handle))
+	t.Cleanup(f.Close)
+	return f
+}
+
+func (f *fakePostgREST) handle(w http.ResponseWriter, r *http.Request) {
+	name, ok := strings.CutPrefix(r.URL.Path, "/rpc/")
+	if !ok || r.Method != http.MethodPost {
+		http.NotFound(w, r)
+		return
+	}
+	body, _ := io.ReadAll(r.Body)
+	f.mu.Lock()
+	f.requests[name] = append(f.requests[name], body)
+	f.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	switch name {
+	case "create_project":
+		f.db.updateSettings(f.project.ID, []string{"*"}, nil)
+		_ = json.NewEncoder(w).Encode([]pgrest.CreateProjectOutput{f.project})
+	case "update_project":
+		var payload pgrest.UpdateProjectPayload
+		_ = json.Unmarshal(body, &payload)
+		f.db.updateSettings(payload.ID, payload.TrustedOrigins, payload.ProxyURL)
+		_ = json.NewEncoder(w).Encode(pgrest.UpdateProjectOutput{Ref: f.project.Ref})
+	case "delete_project":
+		_ = json.NewEncoder(w).Encode([]pgrest.DeleteProjectOutput{{
+			Ref:           f.project.Ref,
+			S3Bucket:      f.project.S3Bucket,
+			S3AccessKeyID: f.project.S3AccessKeyID,
+		}})
+	case "create_or_update_auth_providers":
+		var payload struct {
+			Payload pgrest.CreateOrUpdateAuthProviderPayload `json:"payload"`
+		}
+		_ = json.Unmarshal(body, &payload)
+		f.db.upsertProviders(payload.Payload.ProjectID, payload.Payload.Providers)
+		w.WriteHeader(http.StatusNoContent)
+	case "check_project_permission", "check_project_permission_by_ref":
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		_ = json.NewEncoder(w).Encode(pgrest.PgRestError{Code: "PGRST202", Message: "unknown function " + name})
+	}
+}
+
+// lastRequest 回傳最後一次呼叫 RPC 的 body
+func (f *fakePostgREST) lastRequest(name string) json.RawMessage {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	requests := f.requests[name]
+	if len(requests) == 0 {
+		return nil
+	}
+	return requests[len(requests)-1]
+}
+
+// ===== Repositories =====
+
+// fakeAuthSettingRepository 只實作 provisioning 與專案設定會用到的方法
+type fakeAuthSettingRepository struct {
+	authsetting.Repository
+
+	mu        sync.Mutex
+	secrets   map[string]string
+	settings  map[string]*models.ProjectAuthSettings
+	providers map[string]map[string]*models.ProjectAuthProvider
+}
+
+func newFakeAuthSettingRepository() *fakeAuthSettingRepository {
+	return &fakeAuthSettingRepository{
+		secrets:   map[string]string{},
+		settings:  map[string]*models.ProjectAuthSettings{},
+		providers: map[string]map[string]*models.ProjectAuthProvider{},
+	}
+}
+
+func (r *fakeAuthSettingRepository) FindByProjectID(ctx context.Context, projectID string) (*models.ProjectAuthSettings, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	setting, ok := r.settings[projectID]
+	if !ok {
+		return nil, authsetting.ErrProjectAuthSettingNotFound
+	}
+	copied := *setting
+	return &copied, nil
+}
+
+func (r *fakeAuthSettingRepository) FindAllOAuthProviders(ctx context.Context, projectID string) ([]*models.ProjectAuthProvider, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var providers []*models.ProjectAuthProvider
+	for _, name := range slices.Sorted(maps.Keys(r.providers[projectID])) {
+		copied := *r.providers[projectID][name]
+		providers = append(providers, &copied)
+	}
+	return providers, nil
+}
+
+func (r *fakeAuthSettingRepository) UpdateSecret(ctx context.Context, projectID string, secret string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.secrets[projectID] = secret
+	return nil
+}
+
+func (r *fakeAuthSettingRepository) secret(projectID string) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.secrets[projectID]
+}
+
+// updateSettings 模擬 create_project 與 update_project，nil 的欄位不更新
+func (r *fakeAuthSettingRepository) updateSettings(projectID string, trustedOrigins []string, proxyURL *string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	setting, ok := r.settings[projectID]
+	if !ok {
+		setting = &models.ProjectAuthSettings{ProjectID: projectID, TrustedOrigins: pq.StringArray{}}
+		r.settings[projectID] = setting
+	}
+	if trustedOrigins != nil {
+		setting.TrustedOrigins = trustedOrigins
+	}
+	if proxyURL != nil {
+		setting.ProxyURL = proxyURL
+	}
+}
+
+// upsertProviders 模擬 create_or_update_auth_providers，ClientSecret 為 nil 時保留原本的值
+func (r *fakeAuthSettingRepository) upsertProviders(projectID string, providers map[string]dto.AuthProvider) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.providers[projectID] == nil {
+		r.providers[projectID] = map[string]*models.ProjectAuthProvider{}
+	}
+	for name, provider := range providers {
+		stored, ok := r.providers[projectID][name]
+		if !ok {
+			stored = &models.ProjectAuthProvider{ProjectID: projectID, Name: name}
+			r.providers[projectID][name] = stored
+		}
+		stored.Enabled = provider.Enabled
+		stored.ClientID = provider.ClientID
+		if provider.ClientSecret != nil {
+			stored.ClientSecret = provider.ClientSecret
+		}
+	}
+}
+
+// deleteProvider 從平台資料庫移除 provider
+func (r *fakeAuthSettingRepository) deleteProvider(projectID string, name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.providers[projectID], name)
+}
+
+// fakeProjectRepository 記錄專案的方案與 JWKS 輪替狀態，fakeUsersDB 不會在 provisioning 流程中被呼叫
+type fakeProjectRepository struct {
+	Repository
+
+	mu    sync.Mutex
+	plans map[string]string
+	// projects 是 FindByRef 回傳的專案，JWKS 輪替狀態記錄在 jwksRotatedAt 與 jwksRetireAt
+	projects      map[string]*models.ProjectView
+	jwksRotatedAt map[string]time.Time
+	jwksRetireAt  map[string]time.Time
+}
+
+func (r *fakeProjectRepository) UpdatePlanByRef(ctx context.Context, ref string, plan string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.plans == nil {
+		r.plans = map[string]string{}
+	}
+	r.plans[ref] = plan
+	return nil
+}
+
+func (r *fakeProjectRepository) FindByRef(ctx context.Context, ref string) (*models.ProjectView, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	project, ok := r.projects[ref]
+	if !ok {
+		return nil, ErrProjectNotFound
+	}
+	copied := *project
+	return &copied, nil
+}
+
+func (r *fakeProjectRepository) FindJWKSRotationsDue(ctx context.Context, rotatedBefore time.Time, now time.Time) ([]string, []string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var rotate, retire []string
+	for _, ref := range slices.Sorted(maps.Keys(r.projects)) {
+		if retireAt, ok := r.jwksRetireAt[ref]; ok {
+			if !retireAt.After(now) {
+				retire = append(retire, ref)
+			}
+			continue
+		}
+		last, ok := r.jwksRotatedAt[ref]
+		if !ok {
+			last = r.projects[ref].CreatedAt
+		}
+		if last.Before(rotatedBefore) {
+			rotate = append(rotate, ref)
+		}
+	}
+	return rotate, retire, nil
+}
+
+func (r *fakeProjectRepository) ClaimJWKSRotation(ctx context.Context, ref string, retireAt time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.jwksRetireAt[ref]; ok {
+		return false, nil
+	}
+	r.jwksRetireAt[ref] = retireAt
+	return true, nil
+}
+
+func (r *fakeProjectRepository) ClaimJWKSRetirement(ctx context.Context, ref string, now time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	retireAt, ok := r.jwksRetireAt[ref]
+	if !ok || retireAt.After(now) {
+		return false, nil
+	}
+	delete(r.jwksRetireAt, ref)
+	return true, nil
+}
+
+func (r *fakeProjectRepository) UpdateJWKSRotatedAtByRef(ctx context.Context, ref string, rotatedAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.jwksRotatedAt[ref] = rotatedAt
+	return nil
+}
+
+func (r *fakeProjectRepository) UpdateJWKSRetireAtByRef(ctx context.Context, ref string, retireAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.jwksRetireAt[ref] = retireAt
+	return nil
+}
+
+func (r *fakeProjectRepository) ReleaseJWKSRotation(ctx context.Context, ref string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.jwksRetireAt, ref)
+	return nil
+}
+
+// retireAt 回傳預定移除舊金鑰的時間，沒有進行中的輪替時 ok 為 false
+func (r *fakeProjectRepository) retireAt(ref string) (time.Time, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	retireAt, ok := r.jwksRetireAt[ref]
+	return retireAt, ok
+}
+
+// fakeUsersDB 只實作 auth API 簽章金鑰 (auth.jwks) 的方法
+type fakeUsersDB struct {
+	usersdb.Service
+
+	mu   sync.Mutex
+	keys map[string][]models.JWK
+}
+
+func (u *fakeUsersDB) GetJWKSKeys(ctx context.Context, ref string) ([]models.JWK, error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return slices.Clone(u.keys[ref]), nil
+}
+
+func (u *fakeUsersDB) InsertJWKSKey(ctx context.Context, ref, kid, publicKey, privateKey string) error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.keys[ref] = append(u.keys[ref], models.JWK{ID: kid, PublicKey: publicKey, CreatedAt: time.Now()})
+	return nil
+}
+
+func (u *fakeUsersDB) DeleteJWKSKeysExcept(ctx context.Context, ref, kid string) error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.keys[ref] = slices.DeleteFunc(u.keys[ref], func(key models.JWK) bool { return key.ID != kid })
+	return nil
+}
+
+// keyIDs 回傳 auth API 的簽章金鑰 ID (由舊到新)
+func (u *fakeUsersDB) keyIDs(ref string) []string {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	var ids []string
+	for _, key := range u.keys[ref] {
+		ids = append(ids, key.ID)
+	}
+	return ids
+}
+
+// fakeJWKSKube 只實作 JWKS 輪替用到的 REST API 方法，failApply 為 true 時更新 PGRST_JWT_SECRET 失敗
+type fakeJWKSKube struct {
+	kubeproject.Service
+
+	mu        sync.Mutex
+	jwks      map[string]string
+	failApply bool
+}
+
+func (k *fakeJWKSKube) FindRESTAPIJWKS(ctx context.Context, ref string) (string, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	return k.jwks[ref], nil
+}
+
+func (k *fakeJWKSKube) ApplyRESTAPIJWKS(ctx context.Context, ref string, jwks string) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if k.failApply {
+		return errors.New("failed to apply API secret")
+	}
+	k.jwks[ref] = jwks
+	return nil
+}
+
+func (k *fakeJWKSKube) WaitRESTAPIRollout(ctx context.Context, ref string) error {
+	return nil
+}
+
+// keyIDs 回傳 REST API 接受的金鑰 ID
+func (k *fakeJWKSKube) keyIDs(t *testing.T, ref string) []string {
+	t.Helper()
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	set, err := jwk.Parse([]byte(k.jwks[ref]))
+	if err != nil {
+		t.Fatalf("REST API JWKS: %v", err)
+	}
+	var ids []string
+	for i := range set.Len() {
+		key, _ := set.Key(i)
+		if kid, ok := key.KeyID(); ok {
+			ids = append(ids, kid)
+		}
+	}
+	return ids
+}
+
+// fakePITRKube 只實作可還原範圍的查詢，err 不為 nil 時回傳 err
+type fakePITRKube struct {
+	kubeproject.Service
+
+	window *kubeproject.RecoveryWindow
+	err    error
+}
+
+func (k *fakePITRKube) FindRecoveryWindow(ctx context.Context, ref string) (*kubeproject.RecoveryWindow, error) {
+	if k.err != nil {
+		return nil, k.err
+	}
+	copied := *k.window
+	return &copied, nil
+}
+
+// fakeExtensionKube 以 allowed 為允許清單，記錄最後套用的 extension
+type fakeExtensionKube struct {
+	kubeproject.Service
+
+	allowed []string
+	applied []kubeproject.DatabaseExtensionSpec
+}
+
+func (k *fakeExtensionKube) IsExtensionAllowed(name string) bool {
+	return slices.Contains(k.allowed, name)
+}
+
+func (k *fakeExtensionKube) FindDatabaseExtensions(ctx context.Context, ref string) ([]kubeproject.DatabaseExtension, error) {
+	return nil, nil
+}
+
+func (k *fakeExtensionKube) ApplyDatabaseExtensions(ctx context.Context, ref string, extensions []kubeproject.DatabaseExtensionSpec) error {
+	k.applied = extensions
+	return nil
+}
+
+// fakeExtensionUsersDB 回傳 Postgres image 中可安裝的 extension 與各 extension 的版本
+type fakeExtensionUsersDB struct {
+	usersdb.Service
+
+	versions map[string][]string
+	defaults map[string]string
+}
+
+func (u *fakeExtensionUsersDB) GetAvailableExtensions(ctx context.Context, jwt, ref string, names []string) ([]models.PostgresAvailableExtension, error) {
+	var extensions []models.PostgresAvailableExtension
+	for _, name := range names {
+		if version, ok := u.defaults[name]; ok {
+			extensions = append(extensions, models.PostgresAvailableExtension{Name: name, DefaultVersion: version})
+		}
+	}
+	return extensions, nil
+}
+
+func (u *fakeExtensionUsersDB) GetAvailableExtensionVersions(ctx context.Context, jwt, ref string, name string) ([]string, error) {
+	return u.versions[name], nil
+}
+
+var (
+	_ authsetting.Repository = (*fakeAuthSettingRepository)(nil)
+	_ Repository             = (*fakeProjectRepository)(nil)
+	_ usersdb.Service        = (*fakeUsersDB)(nil)
+	_ kubeproject.Service    = (*fakeJWKSKube)(nil)
+	_ kubeproject.Service    = (*fakePITRKube)(nil)
+	_ kubeproject.Service    = (*fakeExtensionKube)(nil)
+	_ usersdb.Service        = (*fakeExtensionUsersDB)(nil)
+)
@@ -3,6 +3,8 @@ package project
 
 import (
 	"context"
+	"errors"
+	"log/slog"
 	"net/http"
 	"time"
 
@@ -15,8 +17,24 @@ import (
 	"github.com/danielgtaylor/huma/v2"
 	"github.com/danielgtaylor/huma/v2/sse"
 	"github.com/samber/do/v2"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/util/retry"
 )
 
+// RestoreTimeout 是背景還原資料庫的最長時間
+const RestoreTimeout = 30 * time.Minute
+
+// UserMigrationTimeout 是背景等待使用者 migration Job 的最長時間
+const UserMigrationTimeout = 30 * time.Minute
+
+// PostInstallBackoff 控制 CreateProjectPostInstall 失敗時的重試間隔
+var PostInstallBackoff = wait.Backoff{
+	Steps:    5,
+	Duration: 5 * time.Second,
+	Factor:   2.0,
+	Jitter:   0.1,
+}
+
 type Controller interface {
 	RegisterTestAny(api huma.API)
 	RegisterGetProjectByRef(api huma.API)
@@ -27,6 +45,49 @@ type Controller interface {
 	RegisterDeleteProjectByRef(api huma.API)
 	RegisterGetUsersProjects(api huma.API)
 	RegisterResetDatabasePassword(api huma.API)
+	RegisterGetProjectWorkload(api huma.API)
+	RegisterUpdateProjectWorkload(api huma.API)
+	RegisterGetProjectDatabaseCluster(api huma.API)
+	RegisterUpdateProjectDatabaseCluster(api huma.API)
+	RegisterGetProjectPostgresParameters(api huma.API)
+	RegisterUpdateProjectPostgresParameters(api huma.API)
+	RegisterListProjectDatabaseExtensions(api huma.API)
+	RegisterGetProjectPooler(api huma.API)
+	RegisterUpdateProjectPooler(api huma.API)
+	RegisterGetProjectRESTSettings(api huma.API)
+	RegisterUpdateProjectRESTSettings(api huma.API)
+	RegisterUpdateProjectDatabaseExtension(api huma.API)
+	RegisterListProjectBackups(api huma.API)
+	RegisterCreateProjectBackup(api huma.API)
+	RegisterRestoreProjectBackup(api huma.API)
+	RegisterGetProjectRestoreStatus(api huma.API)
+	RegisterGetProjectRecoveryWindow(api huma.API)
+	RegisterRestoreProjectToPointInTime(api huma.API)
+	RegisterGetProjectPITRCluster(api huma.API)
+	RegisterDeleteProjectPITRCluster(api huma.API)
+	RegisterGetProjectMigration(api huma.API)
+	RegisterGetProjectMigrationLogs(api huma.API)
+	RegisterListProjectUserMigrations(api huma.API)
+	RegisterUploadProjectUserMigration(api huma.API)
+	RegisterDeleteProjectUserMigration(api huma.API)
+	RegisterApplyProjectUserMigrations(api huma.API)
+	RegisterRollbackProjectUserMigration(api huma.API)
+	RegisterGetProjectUserMigrationRun(api huma.API)
+	RegisterListProjectCronJobs(api huma.API)
+	RegisterPutProjectCronJob(api huma.API)
+	RegisterDeleteProjectCronJob(api huma.API)
+	RegisterListProjectCronJobRuns(api huma.API)
+	RegisterTriggerProjectCronJob(api huma.API)
+	RegisterGetProjectCronJobRun(api huma.API)
+	RegisterGetProjectRealtime(api huma.API)
+	RegisterUpdateProjectRealtime(api huma.API)
+	RegisterGetProjectJWKSRotation(api huma.API)
+	RegisterRotateProjectJWKS(api huma.API)
+	RegisterStreamProjectLogs(api huma.API)
+	RegisterListProjectEvents(api huma.API)
+	RegisterRevealProjectAuthSecret(api huma.API)
+	RegisterGetProjectDatabaseConnection(api huma.API)
+	RegisterRevealProjectDatabaseConnection(api huma.API)
 }
 
 type controller struct {
@@ -113,7 +174,17 @@ func (c *controller) RegisterCreateProject(api huma.API) {
 			if err != nil {
 				return
 			}
-			_ = c.project.CreateProjectPostInstall(postCtx, out.Body.Reference, internalOut.AuthSecret, internalOut.JWKSPublicKey)
+			// 所有 Kubernetes 資源都以 server-side apply 建立，失敗時可安全地整段重試；
+			// migration 失敗時重新套用不會再次執行 Job，因此不重試
+			retriable := func(err error) bool {
+				return postCtx.Err() == nil && !errors.Is(err, kubeproject.ErrMigrationFailed)
+			}
+			err = retry.OnError(PostInstallBackoff, retriable, func() error {
+				return c.project.CreateProjectPostInstall(postCtx, out.Body.Reference, internalOut)
+			})
+			if err != nil {
+				slog.Error("Failed to run project post-install", "ref", out.Body.Reference, "error", err)
+			}
 		}()
 
 		return out, nil
@@ -180,6 +251,7 @@ func (c *controller) RegisterGetProjectStatus(api huma.API) {
 		Middlewares: huma.Middlewares{c.authMiddleware},
 	}, map[string]any{
 		"project-status": dto.ProjectStatusEvent{},
+		"project-event":  dto.ProjectKubernetesEvent{},
 		"error":          dto.ErrorEvent{},
 	}, func(ctx context.Context, in *dto.GetProjectByRefInput, send sse.Sender) {
 		session, err := utils.GetSessionFromContext(ctx)
@@ -292,3 +364,1179 @@ func (c *controller) RegisterResetDatabasePassword(api huma.API) {
 		return out, nil
 	})
 }
+
+func (c *controller) RegisterGetProjectWorkload(api huma.API) {
+	huma.Register(api, huma.Operation{
+		OperationID: "get-project-workload",
+		Method:      http.MethodGet,
+		Path:        "/project/workload",
+		Summary:     "Get Project API Workload",
+		Description: "Retrieve the replicas, resources, autoscaling and disruption budget of a project API component.",
+		Tags:        []string{"Project"},
+		Middlewares: huma.Middlewares{c.authMiddleware},
+	}, func(ctx context.Context, in *dto.GetProjectWorkloadInput) (*dto.GetProjectWorkloadOutput, error) {
+		session, err := utils.GetSessionFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		out, err := c.project.GetProjectWorkload(ctx, in, session.UserID)
+		if err != nil {
+			return nil, err
+		}
+
+		return out, nil
+	})
+}
+
+func (c *controller) RegisterUpdateProjectWorkload(api huma.API) {
+	huma.Register(api, huma.Operation{
+		OperationID: "update-project-workload",
+		Method:      http.MethodPatch,
+		Path:        "/project/workload",
+		Summary:     "Update Project API Workload",
+		Description: "Override the workload settings of a project API component, optionally starting from the defaults of a plan.",
+		Tags:        []string{"Project"},
+		Middlewares: huma.Middlewares{c.authMiddleware},
+	}, func(ctx context.Context, in *dto.UpdateProjectWorkloadInput) (*dto.GetProjectWorkloadOutput, error) {
+		session, err := utils.GetSessionFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		out, err := c.project.UpdateProjectWorkload(ctx, in, session.UserID)
+		if err != nil {
+			return nil, err
+		}
+
+		return out, nil
+	})
+}
+
+func (c *controller) RegisterGetProjectDatabaseCluster(api huma.API) {
+	huma.Register(api, huma.Operation{
+		OperationID: "get-project-database-cluster",
+		Method:      http.MethodGet,
+		Path:        "/project/database/cluster",
+		Summary:     "Get Project Database Cluster",
+		Description: "Retrieve the Postgres instances, synchronous replication settings and read-write/read-only connection strings of a project.",
+		Tags:        []string{"Project"},
+		Middlewares: huma.Middlewares{c.authMiddleware},
+	}, func(ctx context.Context, in *dto.GetProjectDatabaseClusterInput) (*dto.GetProjectDatabaseClusterOutput, error) {
+		session, err := utils.GetSessionFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		out, err := c.project.GetProjectDatabaseCluster(ctx, in, session.UserID)
+		if err != nil {
+			return nil, err
+		}
+
+		return out, nil
+	})
+}
+
+func (c *controller) RegisterUpdateProjectDatabaseCluster(api huma.API) {
+	huma.Register(api, huma.Operation{
+		OperationID: "update-project-database-cluster",
+		Method:      http.MethodPatch,
+		Path:        "/project/database/cluster",
+		Summary:     "Update Project Database Cluster",
+		Description: "Scale the Postgres instances of a project and change synchronous replication on the live cluster, optionally starting from the defaults of a plan.",
+		Tags:        []string{"Project"},
+		Middlewares: huma.Middlewares{c.authMiddleware},
+	}, func(ctx context.Context, in *dto.UpdateProjectDatabaseClusterInput) (*dto.GetProjectDatabaseClusterOutput, error) {
+		session, err := utils.GetSessionFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		out, err := c.project.UpdateProjectDatabaseCluster(ctx, in, session.UserID)
+		if err != nil {
+			return nil, err
+		}
+
+		return out, nil
+	})
+}
+
+func (c *controller) RegisterGetProjectPostgresParameters(api huma.API) {
+	huma.Register(api, huma.Operation{
+		OperationID: "get-project-postgres-parameters",
+		Method:      http.MethodGet,
+		Path:        "/project/database/parameters",
+		Summary:     "Get Project Postgres Parameters",
+		Description: "List the Postgres parameters a project can tune with the desired and currently applied values.",
+		Tags:        []string{"Project"},
+		Middlewares: huma.Middlewares{c.authMiddleware},
+	}, func(ctx context.Context, in *dto.GetProjectPostgresParametersInput) (*dto.GetProjectPostgresParametersOutput, error) {
+		session, err := utils.GetSessionFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		jwt, err := utils.GetJWTFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		out, err := c.project.GetProjectPostgresParameters(ctx, jwt, in, session.UserID)
+		if err != nil {
+			return nil, err
+		}
+
+		return out, nil
+	})
+}
+
+func (c *controller) RegisterUpdateProjectPostgresParameters(api huma.API) {
+	huma.Register(api, huma.Operation{
+		OperationID: "update-project-postgres-parameters",
+		Method:      http.MethodPatch,
+		Path:        "/project/database/parameters",
+		Summary:     "Update Project Postgres Parameters",
+		Description: "Change allowlisted Postgres parameters of a project. Parameters such as max_connections or shared_buffers restart the database instances.",
+		Tags:        []string{"Project"},
+		Middlewares: huma.Middlewares{c.authMiddleware},
+	}, func(ctx context.Context, in *dto.UpdateProjectPostgresParametersInput) (*dto.UpdateProjectPostgresParametersOutput, error) {
+		session, err := utils.GetSessionFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		jwt, err := utils.GetJWTFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		out, err := c.project.UpdateProjectPostgresParameters(ctx, jwt, in, session.UserID)
+		if err != nil {
+			return nil, err
+		}
+
+		return out, nil
+	})
+}
+
+func (c *controller) RegisterListProjectDatabaseExtensions(api huma.API) {
+	huma.Register(api, huma.Operation{
+		OperationID: "list-project-database-extensions",
+		Method:      http.MethodGet,
+		Path:        "/project/database/extensions",
+		Summary:     "List Project Database Extensions",
+		Description: "List the built-in and allowlisted Postgres extensions with their availability in the image and state on the project database.",
+		Tags:        []string{"Project"},
+		Middlewares: huma.Middlewares{c.authMiddleware},
+	}, func(ctx context.Context, in *dto.ListProjectDatabaseExtensionsInput) (*dto.ListProjectDatabaseExtensionsOutput, error) {
+		session, err := utils.GetSessionFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		jwt, err := utils.GetJWTFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		out, err := c.project.ListProjectDatabaseExtensions(ctx, jwt, in, session.UserID)
+		if err != nil {
+			return nil, err
+		}
+
+		return out, nil
+	})
+}
+
+func (c *controller) RegisterUpdateProjectDatabaseExtension(api huma.API) {
+	huma.Register(api, huma.Operation{
+		OperationID: "update-project-database-extension",
+		Method:      http.MethodPost,
+		Path:        "/project/database/extensions",
+		Summary:     "Enable or Disable Project Database Extension",
+		Description: "Enable or disable an allowlisted Postgres extension on the project database.",
+		Tags:        []string{"Project"},
+		Middlewares: huma.Middlewares{c.authMiddleware},
+	}, func(ctx context.Context, in *dto.UpdateProjectDatabaseExtensionInput) (*dto.UpdateProjectDatabaseExtensionOutput, error) {
+		session, err := utils.GetSessionFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		jwt, err := utils.GetJWTFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		out, err := c.project.UpdateProjectDatabaseExtension(ctx, jwt, in, session.UserID)
+		if err != nil {
+			return nil, err
+		}
+
+		return out, nil
+	})
+}
+
+func (c *controller) RegisterGetProjectPooler(api huma.API) {
+	huma.Register(api, huma.Operation{
+		OperationID: "get-project-pooler",
+		Method:      http.MethodGet,
+		Path:        "/project/database/pooler",
+		Summary:     "Get Project Connection Pooler",
+		Description: "Retrieve the PgBouncer connection pooling settings of a project with direct and pooled connection strings.",
+		Tags:        []string{"Project"},
+		Middlewares: huma.Middlewares{c.authMiddleware},
+	}, func(ctx context.Context, in *dto.GetProjectPoolerInput) (*dto.GetProjectPoolerOutput, error) {
+		session, err := utils.GetSessionFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		out, err := c.project.GetProjectPooler(ctx, in, session.UserID)
+		if err != nil {
+			return nil, err
+		}
+
+		return out, nil
+	})
+}
+
+func (c *controller) RegisterUpdateProjectPooler(api huma.API) {
+	huma.Register(api, huma.Operation{
+		OperationID: "update-project-pooler",
+		Method:      http.MethodPut,
+		Path:        "/project/database/pooler",
+		Summary:     "Update Project Connection Pooler",
+		Description: "Enable, tune or disable PgBouncer connection pooling for a project, and choose whether the REST API connects through it.",
+		Tags:        []string{"Project"},
+		Middlewares: huma.Middlewares{c.authMiddleware},
+	}, func(ctx context.Context, in *dto.UpdateProjectPoolerInput) (*dto.GetProjectPoolerOutput, error) {
+		session, err := utils.GetSessionFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		out, err := c.project.UpdateProjectPooler(ctx, in, session.UserID)
+		if err != nil {
+			return nil, err
+		}
+
+		return out, nil
+	})
+}
+
+func (c *controller) RegisterGetProjectRESTSettings(api huma.API) {
+	huma.Register(api, huma.Operation{
+		OperationID: "get-project-rest-settings",
+		Method:      http.MethodGet,
+		Path:        "/project/rest/settings",
+		Summary:     "Get Project REST API Settings",
+		Description: "Retrieve the PostgREST settings of a project, such as the exposed schemas, row limit and connection pool.",
+		Tags:        []string{"Project"},
+		Middlewares: huma.Middlewares{c.authMiddleware},
+	}, func(ctx context.Context, in *dto.GetProjectRESTSettingsInput) (*dto.GetProjectRESTSettingsOutput, error) {
+		session, err := utils.GetSessionFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		out, err := c.project.GetProjectRESTSettings(ctx, in, session.UserID)
+		if err != nil {
+			return nil, err
+		}
+
+		return out, nil
+	})
+}
+
+func (c *controller) RegisterUpdateProjectRESTSettings(api huma.API) {
+	huma.Register(api, huma.Operation{
+		OperationID: "update-project-rest-settings",
+		Method:      http.MethodPut,
+		Path:        "/project/rest/settings",
+		Summary:     "Update Project REST API Settings",
+		Description: "Change the PostgREST settings of a project. Changing only the exposed schemas reloads the schema cache in place; other settings restart the REST API.",
+		Tags:        []string{"Project"},
+		Middlewares: huma.Middlewares{c.authMiddleware},
+	}, func(ctx context.Context, in *dto.UpdateProjectRESTSettingsInput) (*dto.GetProjectRESTSettingsOutput, error) {
+		session, err := utils.GetSessionFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		jwt, err := utils.GetJWTFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		out, err := c.project.UpdateProjectRESTSettings(ctx, jwt, in, session.UserID)
+		if err != nil {
+			return nil, err
+		}
+
+		return out, nil
+	})
+}
+
+func (c *controller) RegisterGetProjectRealtime(api huma.API) {
+	huma.Register(api, huma.Operation{
+		OperationID: "get-project-realtime",
+		Method:      http.MethodGet,
+		Path:        "/project/realtime",
+		Summary:     "Get Project Realtime",
+		Description: "Retrieve whether the realtime service of a project is deployed, its WebSocket endpoint and the tables whose changes it broadcasts.",
+		Tags:        []string{"Project"},
+		Middlewares: huma.Middlewares{c.authMiddleware},
+	}, func(ctx context.Context, in *dto.GetProjectRealtimeInput) (*dto.GetProjectRealtimeOutput, error) {
+		session, err := utils.GetSessionFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		jwt, err := utils.GetJWTFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		out, err := c.project.GetProjectRealtime(ctx, jwt, in, session.UserID)
+		if err != nil {
+			return nil, err
+		}
+
+		return out, nil
+	})
+}
+
+func (c *controller) RegisterUpdateProjectRealtime(api huma.API) {
+	huma.Register(api, huma.Operation{
+		OperationID: "update-project-realtime",
+		Method:      http.MethodPut,
+		Path:        "/project/realtime",
+		Summary:     "Update Project Realtime",
+		Description: "Deploy or remove the realtime service of a project and choose the tables whose changes are pushed to clients over WebSocket. Clients authenticate with project JWTs and only receive rows allowed by row-level security.",
+		Tags:        []string{"Project"},
+		Middlewares: huma.Middlewares{c.authMiddleware},
+	}, func(ctx context.Context, in *dto.UpdateProjectRealtimeInput) (*dto.GetProjectRealtimeOutput, error) {
+		session, err := utils.GetSessionFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		jwt, err := utils.GetJWTFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		out, err := c.project.UpdateProjectRealtime(ctx, jwt, in, session.UserID)
+		if err != nil {
+			return nil, err
+		}
+
+		return out, nil
+	})
+}
+
+func (c *controller) RegisterListProjectBackups(api huma.API) {
+	huma.Register(api, huma.Operation{
+		OperationID: "list-project-backups",
+		Method:      http.MethodGet,
+		Path:        "/project/backups",
+		Summary:     "List Project Database Backups",
+		Description: "List the scheduled and on-demand backups of a project database.",
+		Tags:        []string{"Project Backup"},
+		Middlewares: huma.Middlewares{c.authMiddleware},
+	}, func(ctx context.Context, in *dto.ListProjectBackupsInput) (*dto.ListProjectBackupsOutput, error) {
+		session, err := utils.GetSessionFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		out, err := c.project.ListProjectBackups(ctx, in, session.UserID)
+		if err != nil {
+			return nil, err
+		}
+
+		return out, nil
+	})
+}
+
+func (c *controller) RegisterCreateProjectBackup(api huma.API) {
+	huma.Register(api, huma.Operation{
+		OperationID: "create-project-backup",
+		Method:      http.MethodPost,
+		Path:        "/project/backups",
+		Summary:     "Create Project Database Backup",
+		Description: "Trigger an on-demand backup of a project database.",
+		Tags:        []string{"Project Backup"},
+		Middlewares: huma.Middlewares{c.authMiddleware},
+	}, func(ctx context.Context, in *dto.CreateProjectBackupInput) (*dto.CreateProjectBackupOutput, error) {
+		session, err := utils.GetSessionFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		out, err := c.project.CreateProjectBackup(ctx, in, session.UserID)
+		if err != nil {
+			return nil, err
+		}
+
+		return out, nil
+	})
+}
+
+func (c *controller) RegisterRestoreProjectBackup(api huma.API) {
+	huma.Register(api, huma.Operation{
+		OperationID:   "restore-project-backup",
+		Method:        http.MethodPost,
+		Path:          "/project/backups/restore",
+		Summary:       "Restore Project Database Backup",
+		Description:   "Replace a project database with a backup of the same or another project. The backup is restored into a new database cluster in the background; the project switches to it once it is healthy and the old cluster is removed. Only one restore can run per project.",
+		Tags:          []string{"Project Backup"},
+		DefaultStatus: http.StatusAccepted,
+		Middlewares:   huma.Middlewares{c.authMiddleware},
+	}, func(ctx context.Context, in *dto.RestoreProjectBackupInput) (*dto.RestoreProjectBackupOutput, error) {
+		session, err := utils.GetSessionFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		opt, err := c.project.PrepareProjectRestore(ctx, in, session.UserID)
+		if err != nil {
+			return nil, err
+		}
+
+		ref := in.Body.Ref
+		if err := c.project.StartProjectRestore(ctx, ref); err != nil {
+			return nil, err
+		}
+		go func() {
+			restoreCtx, cancel := context.WithTimeout(context.Background(), RestoreTimeout)
+			defer cancel()
+			if err := c.project.RestoreProjectDatabase(restoreCtx, ref, opt); err != nil {
+				slog.Error("Failed to restore project database", "ref", ref, "source", opt.SourceRef, "error", err)
+			}
+		}()
+
+		out := &dto.RestoreProjectBackupOutput{}
+		out.Body.Accepted = true
+		return out, nil
+	})
+}
+
+func (c *controller) RegisterGetProjectRestoreStatus(api huma.API) {
+	huma.Register(api, huma.Operation{
+		OperationID: "get-project-restore-status",
+		Method:      http.MethodGet,
+		Path:        "/project/backups/restore",
+		Summary:     "Get Project Database Restore Status",
+		Description: "Get the status of the latest database restore of a project.",
+		Tags:        []string{"Project Backup"},
+		Middlewares: huma.Middlewares{c.authMiddleware},
+	}, func(ctx context.Context, in *dto.GetProjectRestoreStatusInput) (*dto.GetProjectRestoreStatusOutput, error) {
+		session, err := utils.GetSessionFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		out, err := c.project.GetProjectRestoreStatus(ctx, in, session.UserID)
+		if err != nil {
+			return nil, err
+		}
+
+		return out, nil
+	})
+}
+
+func (c *controller) RegisterGetProjectRecoveryWindow(api huma.API) {
+	huma.Register(api, huma.Operation{
+		OperationID: "get-project-recovery-window",
+		Method:      http.MethodGet,
+		Path:        "/project/pitr/window",
+		Summary:     "Get Project Recovery Window",
+		Description: "Get the time range a project database can be restored to from its WAL archive.",
+		Tags:        []string{"Project Backup"},
+		Middlewares: huma.Middlewares{c.authMiddleware},
+	}, func(ctx context.Context, in *dto.GetProjectRecoveryWindowInput) (*dto.GetProjectRecoveryWindowOutput, error) {
+		session, err := utils.GetSessionFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		out, err := c.project.GetProjectRecoveryWindow(ctx, in, session.UserID)
+		if err != nil {
+			return nil, err
+		}
+
+		return out, nil
+	})
+}
+
+func (c *controller) RegisterRestoreProjectToPointInTime(api huma.API) {
+	huma.Register(api, huma.Operation{
+		OperationID:   "restore-project-to-point-in-time",
+		Method:        http.MethodPost,
+		Path:          "/project/pitr",
+		Summary:       "Restore Project Database to Point in Time",
+		Description:   "Recover a project database to a point in time. In replace mode the database is recovered into a new cluster in the background and the project switches to it once it is healthy, like a backup restore; in inspect mode a separate cluster is created side by side.",
+		Tags:          []string{"Project Backup"},
+		DefaultStatus: http.StatusAccepted,
+		Middlewares:   huma.Middlewares{c.authMiddleware},
+	}, func(ctx context.Context, in *dto.RestoreProjectToPointInTimeInput) (*dto.RestoreProjectToPointInTimeOutput, error) {
+		session, err := utils.GetSessionFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		opt, err := c.project.PrepareProjectPITR(ctx, in, session.UserID)
+		if err != nil {
+			return nil, err
+		}
+
+		ref := in.Body.Ref
+		out := &dto.RestoreProjectToPointInTimeOutput{}
+		out.Body.Accepted = true
+		out.Body.Mode = in.Body.Mode
+
+		if in.Body.Mode == PITRModeInspect {
+			host, err := c.project.CreateProjectPITRCluster(ctx, ref, opt)
+			if err != nil {
+				return nil, err
+			}
+			out.Body.Host = host
+			return out, nil
+		}
+
+		if err := c.project.StartProjectRestore(ctx, ref); err != nil {
+			return nil, err
+		}
+		go func() {
+			restoreCtx, cancel := context.WithTimeout(context.Background(), RestoreTimeout)
+			defer cancel()
+			if err := c.project.RestoreProjectDatabase(restoreCtx, ref, opt); err != nil {
+				slog.Error("Failed to restore project database to point in time", "ref", ref, "targetTime", opt.TargetTime, "error", err)
+			}
+		}()
+
+		return out, nil
+	})
+}
+
+func (c *controller) RegisterGetProjectPITRCluster(api huma.API) {
+	huma.Register(api, huma.Operation{
+		OperationID: "get-project-pitr-cluster",
+		Method:      http.MethodGet,
+		Path:        "/project/pitr",
+		Summary:     "Get Project Point-in-Time Inspection Cluster",
+		Description: "Get the status and connection details of the point-in-time inspection cluster of a project.",
+		Tags:        []string{"Project Backup"},
+		Middlewares: huma.Middlewares{c.authMiddleware},
+	}, func(ctx context.Context, in *dto.GetProjectPITRClusterInput) (*dto.GetProjectPITRClusterOutput, error) {
+		session, err := utils.GetSessionFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		out, err := c.project.GetProjectPITRCluster(ctx, in, session.UserID)
+		if err != nil {
+			return nil, err
+		}
+
+		return out, nil
+	})
+}
+
+func (c *controller) RegisterDeleteProjectPITRCluster(api huma.API) {
+	huma.Register(api, huma.Operation{
+		OperationID: "delete-project-pitr-cluster",
+		Method:      http.MethodDelete,
+		Path:        "/project/pitr",
+		Summary:     "Delete Project Point-in-Time Inspection Cluster",
+		Description: "Delete the point-in-time inspection cluster of a project.",
+		Tags:        []string{"Project Backup"},
+		Middlewares: huma.Middlewares{c.authMiddleware},
+	}, func(ctx context.Context, in *dto.DeleteProjectPITRClusterInput) (*dto.DeleteProjectPITRClusterOutput, error) {
+		session, err := utils.GetSessionFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		out, err := c.project.DeleteProjectPITRCluster(ctx, in, session.UserID)
+		if err != nil {
+			return nil, err
+		}
+
+		return out, nil
+	})
+}
+
+func (c *controller) RegisterGetProjectMigration(api huma.API) {
+	huma.Register(api, huma.Operation{
+		OperationID: "get-project-migration",
+		Method:      http.MethodGet,
+		Path:        "/project/migration",
+		Summary:     "Get Project Migration",
+		Description: "Get the status of the database migration run when the project was provisioned.",
+		Tags:        []string{"Project"},
+		Middlewares: huma.Middlewares{c.authMiddleware},
+	}, func(ctx context.Context, in *dto.GetProjectMigrationInput) (*dto.GetProjectMigrationOutput, error) {
+		session, err := utils.GetSessionFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		out, err := c.project.GetProjectMigration(ctx, in, session.UserID)
+		if err != nil {
+			return nil, err
+		}
+
+		return out, nil
+	})
+}
+
+func (c *controller) RegisterGetProjectMigrationLogs(api huma.API) {
+	huma.Register(api, huma.Operation{
+		OperationID: "get-project-migration-logs",
+		Method:      http.MethodGet,
+		Path:        "/project/migration/logs",
+		Summary:     "Get Project Migration Logs",
+		Description: "Get the dbmate output of the project database migration. The output is kept after the migration Job is cleaned up.",
+		Tags:        []string{"Project"},
+		Middlewares: huma.Middlewares{c.authMiddleware},
+	}, func(ctx context.Context, in *dto.GetProjectMigrationLogsInput) (*dto.GetProjectMigrationLogsOutput, error) {
+		session, err := utils.GetSessionFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		out, err := c.project.GetProjectMigrationLogs(ctx, in, session.UserID)
+		if err != nil {
+			return nil, err
+		}
+
+		return out, nil
+	})
+}
+
+func (c *controller) RegisterListProjectUserMigrations(api huma.API) {
+	huma.Register(api, huma.Operation{
+		OperationID: "list-project-user-migrations",
+		Method:      http.MethodGet,
+		Path:        "/project/migrations",
+		Summary:     "List Project Migrations",
+		Description: "List the uploaded dbmate migrations of a project and whether each version is applied.",
+		Tags:        []string{"Project Migration"},
+		Middlewares: huma.Middlewares{c.authMiddleware},
+	}, func(ctx context.Context, in *dto.ListProjectUserMigrationsInput) (*dto.ListProjectUserMigrationsOutput, error) {
+		session, err := utils.GetSessionFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+		jwt, err := utils.GetJWTFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		out, err := c.project.ListProjectUserMigrations(ctx, jwt, in, session.UserID)
+		if err != nil {
+			return nil, err
+		}
+
+		return out, nil
+	})
+}
+
+func (c *controller) RegisterUploadProjectUserMigration(api huma.API) {
+	huma.Register(api, huma.Operation{
+		OperationID: "upload-project-user-migration",
+		Method:      http.MethodPost,
+		Path:        "/project/migrations",
+		Summary:     "Upload Project Migration",
+		Description: "Upload or replace a dbmate migration file. Applied versions cannot be replaced.",
+		Tags:        []string{"Project Migration"},
+		Middlewares: huma.Middlewares{c.authMiddleware},
+	}, func(ctx context.Context, in *dto.UploadProjectUserMigrationInput) (*dto.UploadProjectUserMigrationOutput, error) {
+		session, err := utils.GetSessionFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+		jwt, err := utils.GetJWTFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		out, err := c.project.UploadProjectUserMigration(ctx, jwt, in, session.UserID)
+		if err != nil {
+			return nil, err
+		}
+
+		return out, nil
+	})
+}
+
+func (c *controller) RegisterDeleteProjectUserMigration(api huma.API) {
+	huma.Register(api, huma.Operation{
+		OperationID: "delete-project-user-migration",
+		Method:      http.MethodDelete,
+		Path:        "/project/migrations",
+		Summary:     "Delete Project Migration",
+		Description: "Remove a migration file that is not applied.",
+		Tags:        []string{"Project Migration"},
+		Middlewares: huma.Middlewares{c.authMiddleware},
+	}, func(ctx context.Context, in *dto.DeleteProjectUserMigrationInput) (*dto.DeleteProjectUserMigrationOutput, error) {
+		session, err := utils.GetSessionFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+		jwt, err := utils.GetJWTFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		out, err := c.project.DeleteProjectUserMigration(ctx, jwt, in, session.UserID)
+		if err != nil {
+			return nil, err
+		}
+
+		return out, nil
+	})
+}
+
+func (c *controller) RegisterApplyProjectUserMigrations(api huma.API) {
+	huma.Register(api, huma.Operation{
+		OperationID:   "apply-project-user-migrations",
+		Method:        http.MethodPost,
+		Path:          "/project/migrations/apply",
+		Summary:       "Apply Project Migrations",
+		Description:   "Run dbmate up with the uploaded migrations. The run continues in the background; poll /project/migrations/run for its status and output.",
+		Tags:          []string{"Project Migration"},
+		DefaultStatus: http.StatusAccepted,
+		Middlewares:   huma.Middlewares{c.authMiddleware},
+	}, func(ctx context.Context, in *dto.RunProjectUserMigrationsInput) (*dto.RunProjectUserMigrationsOutput, error) {
+		return c.runProjectUserMigrations(ctx, in.Body.Ref, kubeproject.MigrationActionUp)
+	})
+}
+
+func (c *controller) RegisterRollbackProjectUserMigration(api huma.API) {
+	huma.Register(api, huma.Operation{
+		OperationID:   "rollback-project-user-migration",
+		Method:        http.MethodPost,
+		Path:          "/project/migrations/rollback",
+		Summary:       "Roll Back Project Migration",
+		Description:   "Run dbmate rollback to revert the latest applied migration with its -- migrate:down section. The run continues in the background.",
+		Tags:          []string{"Project Migration"},
+		DefaultStatus: http.StatusAccepted,
+		Middlewares:   huma.Middlewares{c.authMiddleware},
+	}, func(ctx context.Context, in *dto.RunProjectUserMigrationsInput) (*dto.RunProjectUserMigrationsOutput, error) {
+		return c.runProjectUserMigrations(ctx, in.Body.Ref, kubeproject.MigrationActionRollback)
+	})
+}
+
+// runProjectUserMigrations 建立 migration Job 後在背景等待，保存結果與 dbmate 的輸出
+func (c *controller) runProjectUserMigrations(ctx context.Context, ref string, action string) (*dto.RunProjectUserMigrationsOutput, error) {
+	session, err := utils.GetSessionFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	jwt, err := utils.GetJWTFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	run, err := c.project.RunProjectUserMigrations(ctx, jwt, ref, action, session.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	go func() {
+		waitCtx, cancel := context.WithTimeout(context.Background(), UserMigrationTimeout)
+		defer cancel()
+		if _, err := c.kube.WaitUserMigrationJob(waitCtx, ref, run); err != nil {
+			slog.Error("Failed to wait for user migration job", "ref", ref, "jobName", run.JobName, "error", err)
+		}
+	}()
+
+	out := &dto.RunProjectUserMigrationsOutput{}
+	out.Body.Migration = migrationToDTO(run)
+	return out, nil
+}
+
+func (c *controller) RegisterGetProjectUserMigrationRun(api huma.API) {
+	huma.Register(api, huma.Operation{
+		OperationID: "get-project-user-migration-run",
+		Method:      http.MethodGet,
+		Path:        "/project/migrations/run",
+		Summary:     "Get Project Migration Run",
+		Description: "Get the status and dbmate output of the latest apply or rollback.",
+		Tags:        []string{"Project Migration"},
+		Middlewares: huma.Middlewares{c.authMiddleware},
+	}, func(ctx context.Context, in *dto.GetProjectUserMigrationRunInput) (*dto.GetProjectUserMigrationRunOutput, error) {
+		session, err := utils.GetSessionFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		out, err := c.project.GetProjectUserMigrationRun(ctx, in, session.UserID)
+		if err != nil {
+			return nil, err
+		}
+
+		return out, nil
+	})
+}
+
+func (c *controller) RegisterListProjectCronJobs(api huma.API) {
+	huma.Register(api, huma.Operation{
+		OperationID: "list-project-cron-jobs",
+		Method:      http.MethodGet,
+		Path:        "/project/cron",
+		Summary:     "List Project Cron Jobs",
+		Description: "List the scheduled jobs of a project with their last status.",
+		Tags:        []string{"Project Cron"},
+		Middlewares: huma.Middlewares{c.authMiddleware},
+	}, func(ctx context.Context, in *dto.ListProjectCronJobsInput) (*dto.ListProjectCronJobsOutput, error) {
+		session, err := utils.GetSessionFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		out, err := c.project.ListProjectCronJobs(ctx, in, session.UserID)
+		if err != nil {
+			return nil, err
+		}
+
+		return out, nil
+	})
+}
+
+func (c *controller) RegisterPutProjectCronJob(api huma.API) {
+	huma.Register(api, huma.Operation{
+		OperationID: "put-project-cron-job",
+		Method:      http.MethodPut,
+		Path:        "/project/cron",
+		Summary:     "Create or Replace Project Cron Job",
+		Description: "Create or replace a scheduled job that runs SQL or calls a function of the api schema in the project database as the app role. Schedules are in UTC.",
+		Tags:        []string{"Project Cron"},
+		Middlewares: huma.Middlewares{c.authMiddleware},
+	}, func(ctx context.Context, in *dto.PutProjectCronJobInput) (*dto.PutProjectCronJobOutput, error) {
+		session, err := utils.GetSessionFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		jwt, err := utils.GetJWTFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		out, err := c.project.PutProjectCronJob(ctx, jwt, in, session.UserID)
+		if err != nil {
+			return nil, err
+		}
+
+		return out, nil
+	})
+}
+
+func (c *controller) RegisterDeleteProjectCronJob(api huma.API) {
+	huma.Register(api, huma.Operation{
+		OperationID: "delete-project-cron-job",
+		Method:      http.MethodDelete,
+		Path:        "/project/cron",
+		Summary:     "Delete Project Cron Job",
+		Description: "Delete a scheduled job and its run history.",
+		Tags:        []string{"Project Cron"},
+		Middlewares: huma.Middlewares{c.authMiddleware},
+	}, func(ctx context.Context, in *dto.DeleteProjectCronJobInput) (*dto.DeleteProjectCronJobOutput, error) {
+		session, err := utils.GetSessionFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		out, err := c.project.DeleteProjectCronJob(ctx, in, session.UserID)
+		if err != nil {
+			return nil, err
+		}
+
+		return out, nil
+	})
+}
+
+func (c *controller) RegisterListProjectCronJobRuns(api huma.API) {
+	huma.Register(api, huma.Operation{
+		OperationID: "list-project-cron-job-runs",
+		Method:      http.MethodGet,
+		Path:        "/project/cron/runs",
+		Summary:     "List Project Cron Job Runs",
+		Description: "List the kept runs of a scheduled job, newest first.",
+		Tags:        []string{"Project Cron"},
+		Middlewares: huma.Middlewares{c.authMiddleware},
+	}, func(ctx context.Context, in *dto.ListProjectCronJobRunsInput) (*dto.ListProjectCronJobRunsOutput, error) {
+		session, err := utils.GetSessionFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		out, err := c.project.ListProjectCronJobRuns(ctx, in, session.UserID)
+		if err != nil {
+			return nil, err
+		}
+
+		return out, nil
+	})
+}
+
+func (c *controller) RegisterTriggerProjectCronJob(api huma.API) {
+	huma.Register(api, huma.Operation{
+		OperationID:   "trigger-project-cron-job",
+		Method:        http.MethodPost,
+		Path:          "/project/cron/run",
+		Summary:       "Run Project Cron Job",
+		Description:   "Run a scheduled job now. The run continues in the background; follow it with the runs endpoint.",
+		Tags:          []string{"Project Cron"},
+		DefaultStatus: http.StatusAccepted,
+		Middlewares:   huma.Middlewares{c.authMiddleware},
+	}, func(ctx context.Context, in *dto.TriggerProjectCronJobInput) (*dto.TriggerProjectCronJobOutput, error) {
+		session, err := utils.GetSessionFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		out, err := c.project.TriggerProjectCronJob(ctx, in, session.UserID)
+		if err != nil {
+			return nil, err
+		}
+
+		return out, nil
+	})
+}
+
+func (c *controller) RegisterGetProjectCronJobRun(api huma.API) {
+	huma.Register(api, huma.Operation{
+		OperationID: "get-project-cron-job-run",
+		Method:      http.MethodGet,
+		Path:        "/project/cron/run",
+		Summary:     "Get Project Cron Job Run",
+		Description: "Get the status and psql output of a run of a scheduled job.",
+		Tags:        []string{"Project Cron"},
+		Middlewares: huma.Middlewares{c.authMiddleware},
+	}, func(ctx context.Context, in *dto.GetProjectCronJobRunInput) (*dto.GetProjectCronJobRunOutput, error) {
+		session, err := utils.GetSessionFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		out, err := c.project.GetProjectCronJobRun(ctx, in, session.UserID)
+		if err != nil {
+			return nil, err
+		}
+
+		return out, nil
+	})
+}
+
+func (c *controller) RegisterGetProjectJWKSRotation(api huma.API) {
+	huma.Register(api, huma.Operation{
+		OperationID: "get-project-jwks-rotation",
+		Method:      http.MethodGet,
+		Path:        "/project/jwks/rotation",
+		Summary:     "Get Project JWKS Rotation",
+		Description: "Get the signing key of the project auth API, the keys the REST API accepts and the rotation schedule.",
+		Tags:        []string{"Project"},
+		Middlewares: huma.Middlewares{c.authMiddleware},
+	}, func(ctx context.Context, in *dto.GetProjectJWKSRotationInput) (*dto.GetProjectJWKSRotationOutput, error) {
+		session, err := utils.GetSessionFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		out, err := c.project.GetProjectJWKSRotation(ctx, in, session.UserID)
+		if err != nil {
+			return nil, err
+		}
+
+		return out, nil
+	})
+}
+
+func (c *controller) RegisterRotateProjectJWKS(api huma.API) {
+	huma.Register(api, huma.Operation{
+		OperationID:   "rotate-project-jwks",
+		Method:        http.MethodPost,
+		Path:          "/project/jwks/rotate",
+		Summary:       "Rotate Project JWKS",
+		Description:   "Add a new signing key. The REST API accepts both keys before the auth API switches to the new key; the old key is removed after the retirement time. The rotation runs in the background.",
+		Tags:          []string{"Project"},
+		DefaultStatus: http.StatusAccepted,
+		Middlewares:   huma.Middlewares{c.authMiddleware},
+	}, func(ctx context.Context, in *dto.RotateProjectJWKSInput) (*dto.RotateProjectJWKSOutput, error) {
+		session, err := utils.GetSessionFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		out, err := c.project.PrepareProjectJWKSRotation(ctx, in, session.UserID)
+		if err != nil {
+			return nil, err
+		}
+
+		ref := in.Body.Ref
+		go func() {
+			rotateCtx, cancel := context.WithTimeout(context.Background(), JWKSRotationTimeout)
+			defer cancel()
+			if err := c.project.RotateProjectJWKS(rotateCtx, ref); err != nil {
+				slog.Error("Failed to rotate project JWKS", "ref", ref, "error", err)
+			}
+		}()
+
+		return out, nil
+	})
+}
+
+func (c *controller) RegisterStreamProjectLogs(api huma.API) {
+	sse.Register(api, huma.Operation{
+		OperationID: "stream-project-logs",
+		Method:      http.MethodGet,
+		Path:        "/project/logs",
+		Summary:     "Stream Project Logs (SSE)",
+		Description: "Stream the logs of the auth-api, pgrst or openapi container of all pods of a project. Known secret values are redacted.",
+		Tags:        []string{"Project"},
+		Middlewares: huma.Middlewares{c.authMiddleware},
+	}, map[string]any{
+		"log":   dto.ProjectLogEvent{},
+		"error": dto.ErrorEvent{},
+	}, func(ctx context.Context, in *dto.StreamProjectLogsInput, send sse.Sender) {
+		session, err := utils.GetSessionFromContext(ctx)
+		if err != nil {
+			send.Data(dto.ErrorEvent{Message: "Unauthorized access"})
+			return
+		}
+
+		dataChan := make(chan any, 1)
+		go func() {
+			defer close(dataChan)
+			err := c.project.StreamProjectLogs(ctx, dataChan, in, session.UserID)
+			if err != nil {
+				dataChan <- dto.ErrorEvent{Message: err.Error()}
+				return
+			}
+		}()
+
+		for {
+			select {
+			case data, ok := <-dataChan:
+				if !ok {
+					return
+				}
+				if err := send.Data(data); err != nil {
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	})
+}
+
+func (c *controller) RegisterListProjectEvents(api huma.API) {
+	huma.Register(api, huma.Operation{
+		OperationID: "list-project-events",
+		Method:      http.MethodGet,
+		Path:        "/project/events",
+		Summary:     "List Project Events",
+		Description: "List the Kubernetes events of the project resources, such as image pull or storage failures, as user-readable messages with a severity. The same events are sent as `project-event` on the project status stream.",
+		Tags:        []string{"Project"},
+		Middlewares: huma.Middlewares{c.authMiddleware},
+	}, func(ctx context.Context, in *dto.ListProjectEventsInput) (*dto.ListProjectEventsOutput, error) {
+		session, err := utils.GetSessionFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		out, err := c.project.ListProjectEvents(ctx, in, session.UserID)
+		if err != nil {
+			return nil, err
+		}
+
+		return out, nil
+	})
+}
+
+func (c *controller) RegisterRevealProjectAuthSecret(api huma.API) {
+	huma.Register(api, huma.Operation{
+		OperationID: "reveal-project-auth-secret",
+		Method:      http.MethodPost,
+		Path:        "/project/settings/auth/reveal",
+		Summary:     "Reveal OAuth Client Secret",
+		Description: "Return the plaintext client secret of an OAuth provider. Project settings only return masked secrets. Requires project permission and a recently signed-in session.",
+		Tags:        []string{"Project"},
+		Middlewares: huma.Middlewares{c.authMiddleware},
+	}, func(ctx context.Context, in *dto.RevealProjectAuthSecretInput) (*dto.RevealProjectAuthSecretOutput, error) {
+		session, err := utils.GetSessionFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+		jwt, err := utils.GetJWTFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		out, err := c.project.RevealProjectAuthSecret(ctx, jwt, session, in)
+		if err != nil {
+			return nil, err
+		}
+
+		return out, nil
+	})
+}
+
+func (c *controller) RegisterGetProjectDatabaseConnection(api huma.API) {
+	huma.Register(api, huma.Operation{
+		OperationID: "get-project-database-connection",
+		Method:      http.MethodGet,
+		Path:        "/project/database/connection",
+		Summary:     "Get Project Database Connection",
+		Description: "Return how to connect to the Postgres database of a project: hosts, port, database, login roles, TLS requirements, the CA certificate and connection strings for psql, JDBC, Prisma and node-postgres. Passwords are not included.",
+		Tags:        []string{"Project"},
+		Middlewares: huma.Middlewares{c.authMiddleware},
+	}, func(ctx context.Context, in *dto.GetProjectDatabaseConnectionInput) (*dto.GetProjectDatabaseConnectionOutput, error) {
+		session, err := utils.GetSessionFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		out, err := c.project.GetProjectDatabaseConnection(ctx, in, session.UserID)
+		if err != nil {
+			return nil, err
+		}
+
+		return out, nil
+	})
+}
+
+func (c *controller) RegisterRevealProjectDatabaseConnection(api huma.API) {
+	huma.Register(api, huma.Operation{
+		OperationID: "reveal-project-database-connection",
+		Method:      http.MethodPost,
+		Path:        "/project/database/connection/reveal",
+		Summary:     "Reveal Project Database Connection",
+		Description: "Return the database connection details of a project including the role passwords and connection strings with the app password. Requires project permission and a recently signed-in session.",
+		Tags:        []string{"Project"},
+		Middlewares: huma.Middlewares{c.authMiddleware},
+	}, func(ctx context.Context, in *dto.RevealProjectDatabaseConnectionInput) (*dto.GetProjectDatabaseConnectionOutput, error) {
+		session, err := utils.GetSessionFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+		jwt, err := utils.GetJWTFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		out, err := c.project.RevealProjectDatabaseConnection(ctx, jwt, session, in)
+		if err != nil {
+			return nil, err
+		}
+
+		return out, nil
+	})
+}
@@ -0,0 +1,194 @@
+package project
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"time"
+
+	"baas-api/internal/dto"
+	"baas-api/internal/utils"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/google/uuid"
+	"github.com/lestrrat-go/jwx/v3/jwk"
+	"github.com/samber/lo"
+)
+
+// JWKSRotationTimeout 是輪替或移除金鑰 (包含等待 REST API rolling update) 的最長時間
+const JWKSRotationTimeout = 10 * time.Minute
+
+func (s *service) GetProjectJWKSRotation(ctx context.Context, in *dto.GetProjectJWKSRotationInput, userID string) (*dto.GetProjectJWKSRotationOutput, error) {
+	project, err := s.findOwnedProject(ctx, in.Ref, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	rotatedAt, retireAt, err := s.project.FindJWKSRotationByRef(ctx, in.Ref)
+	if err != nil {
+		return nil, err
+	}
+	keys, err := s.usersdb.GetJWKSKeys(ctx, in.Ref)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to get project signing keys", "error", err, "ref", in.Ref)
+		return nil, huma.Error500InternalServerError("Failed to get project signing keys")
+	}
+	published, err := s.publishedJWKS(ctx, in.Ref)
+	if err != nil {
+		return nil, err
+	}
+
+	out := &dto.GetProjectJWKSRotationOutput{}
+	out.Body.RotatedAt = rotatedAt
+	out.Body.RetireAt = retireAt
+	if rotation := s.config.Kube.Project.JWKSRotation; rotation.Enabled && retireAt == nil {
+		last := project.CreatedAt
+		if rotatedAt != nil {
+			last = *rotatedAt
+		}
+		out.Body.NextRotation = lo.ToPtr(last.Add(rotation.Interval))
+	}
+	if len(keys) > 0 {
+		out.Body.SigningKeyID = keys[len(keys)-1].ID
+	}
+	out.Body.PublishedKeys = []string{}
+	for i := range published.Len() {
+		key, _ := published.Key(i)
+		if kid, ok := key.KeyID(); ok {
+			out.Body.PublishedKeys = append(out.Body.PublishedKeys, kid)
+		}
+	}
+	return out, nil
+}
+
+// PrepareProjectJWKSRotation 確認權限並標記輪替開始，實際的輪替由呼叫端在背景執行 (RotateProjectJWKS)
+func (s *service) PrepareProjectJWKSRotation(ctx context.Context, in *dto.RotateProjectJWKSInput, userID string) (*dto.RotateProjectJWKSOutput, error) {
+	if _, err := s.findOwnedProject(ctx, in.Body.Ref, userID); err != nil {
+		return nil, err
+	}
+
+	retireAt := time.Now().Add(s.config.Kube.Project.JWKSRotation.RetireAfter)
+	claimed, err := s.project.ClaimJWKSRotation(ctx, in.Body.Ref, retireAt)
+	if err != nil {
+		return nil, err
+	}
+	if !claimed {
+		return nil, huma.Error409Conflict("The previous key rotation has not finished; the old key is removed after the retirement time")
+	}
+
+	out := &dto.RotateProjectJWKSOutput{}
+	out.Body.Accepted = true
+	out.Body.RetireAt = retireAt
+	return out, nil
+}
+
+// RotateProjectJWKS 產生新的簽章金鑰：先讓 REST API 同時接受新舊金鑰，rolling update 完成後才讓 auth API 以新金鑰簽發。
+//
+// 呼叫前須以 ClaimJWKSRotation 標記輪替；失敗時釋放標記讓專案可以再次輪替，
+// 已發布但沒有用來簽發的金鑰會在下一次移除舊金鑰時一併清除。
+func (s *service) RotateProjectJWKS(ctx context.Context, ref string) error {
+	if err := s.rotateProjectJWKS(ctx, ref); err != nil {
+		// ctx 可能已經逾時，釋放標記不受影響
+		if releaseErr := s.project.ReleaseJWKSRotation(context.WithoutCancel(ctx), ref); releaseErr != nil {
+			slog.ErrorContext(ctx, "Failed to release project JWKS rotation", "error", releaseErr, "ref", ref)
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *service) rotateProjectJWKS(ctx context.Context, ref string) error {
+	published, err := s.publishedJWKS(ctx, ref)
+	if err != nil {
+		return err
+	}
+
+	kid, err := uuid.NewV7()
+	if err != nil {
+		return errors.New("failed to generate JWK ID")
+	}
+	publicKey, privateKey, err := utils.NewEd25519JWKWithKIDStringified(ctx, kid.String())
+	if err != nil {
+		return err
+	}
+	key, err := jwk.ParseKey([]byte(publicKey))
+	if err != nil {
+		return err
+	}
+	if err := published.AddKey(key); err != nil {
+		return err
+	}
+
+	// 1. REST API 接受新舊兩把金鑰
+	if err := s.applyRESTAPIJWKS(ctx, ref, published); err != nil {
+		return err
+	}
+	// 2. auth API 以最新的金鑰簽發 JWT
+	if err := s.usersdb.InsertJWKSKey(ctx, ref, kid.String(), publicKey, privateKey); err != nil {
+		slog.ErrorContext(ctx, "Failed to add project signing key", "error", err, "ref", ref)
+		return errors.New("failed to add project signing key")
+	}
+	return s.project.UpdateJWKSRotatedAtByRef(ctx, ref, time.Now())
+}
+
+// RetireProjectJWKS 移除簽章金鑰以外的舊金鑰，REST API 只接受目前的簽章金鑰。
+//
+// 呼叫前須以 ClaimJWKSRetirement 標記。
+func (s *service) RetireProjectJWKS(ctx context.Context, ref string) error {
+	keys, err := s.usersdb.GetJWKSKeys(ctx, ref)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to get project signing keys", "error", err, "ref", ref)
+		return errors.New("failed to get project signing keys")
+	}
+	if len(keys) == 0 {
+		return errors.New("project has no signing key")
+	}
+	signing := keys[len(keys)-1]
+
+	key, err := jwk.ParseKey([]byte(signing.PublicKey))
+	if err != nil {
+		return err
+	}
+	published := jwk.NewSet()
+	if err := published.AddKey(key); err != nil {
+		return err
+	}
+
+	// 1. auth API 不再公開舊金鑰
+	if err := s.usersdb.DeleteJWKSKeysExcept(ctx, ref, signing.ID); err != nil {
+		slog.ErrorContext(ctx, "Failed to delete old project signing keys", "error", err, "ref", ref)
+		return errors.New("failed to delete old project signing keys")
+	}
+	// 2. REST API 不再接受舊金鑰簽發的 JWT
+	return s.applyRESTAPIJWKS(ctx, ref, published)
+}
+
+// publishedJWKS 讀取 REST API 目前接受的金鑰，舊專案的 PGRST_JWT_SECRET 是單一的 JWK
+func (s *service) publishedJWKS(ctx context.Context, ref string) (jwk.Set, error) {
+	current, err := s.kube.FindRESTAPIJWKS(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	if current == "" {
+		return jwk.NewSet(), nil
+	}
+	set, err := jwk.Parse([]byte(current))
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to parse REST API JWKS", "error", err, "ref", ref)
+		return nil, errors.New("failed to parse REST API JWKS")
+	}
+	return set, nil
+}
+
+// applyRESTAPIJWKS 更新 REST API 的 JWKS 並等待 rolling update 完成
+func (s *service) applyRESTAPIJWKS(ctx context.Context, ref string, set jwk.Set) error {
+	data, err := json.Marshal(set)
+	if err != nil {
+		return err
+	}
+	if err := s.kube.ApplyRESTAPIJWKS(ctx, ref, string(data)); err != nil {
+		return err
+	}
+	return s.kube.WaitRESTAPIRollout(ctx, ref)
+}
@@ -0,0 +1,94 @@
+package project
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"baas-api/internal/config"
+
+	"github.com/samber/do/v2"
+)
+
+// JWKSRotator 移除到期的舊金鑰 (包含手動輪替的)，並依 config.Kube.Project.JWKSRotation 定期輪替專案的簽章金鑰
+type JWKSRotator struct {
+	config  *config.Config `do:""`
+	project Repository     `do:""`
+	service Service        `do:""`
+}
+
+func NewJWKSRotator(i do.Injector) (*JWKSRotator, error) {
+	return &JWKSRotator{
+		config:  do.MustInvoke[*config.Config](i),
+		project: do.MustInvokeAs[Repository](i),
+		service: do.MustInvokeAs[Service](i),
+	}, nil
+}
+
+// Start 在背景執行排程。未啟用時仍會移除到期的舊金鑰，只是不自動輪替
+func (r *JWKSRotator) Start() {
+	rotation := r.config.Kube.Project.JWKSRotation
+	if rotation.CheckInterval <= 0 {
+		slog.Error("JWKS rotation check interval is not set, old signing keys will not be retired")
+		return
+	}
+	if rotation.Enabled && rotation.Interval <= 0 {
+		slog.Error("JWKS rotation is enabled without an interval, only retiring old signing keys")
+	}
+	slog.Info("Starting JWKS rotation", "enabled", rotation.Enabled, "interval", rotation.Interval, "retireAfter", rotation.RetireAfter)
+	go func() {
+		ticker := time.NewTicker(rotation.CheckInterval)
+		defer ticker.Stop()
+		for {
+			r.runOnce(context.Background())
+			<-ticker.C
+		}
+	}()
+}
+
+// autoRotate 回傳是否自動輪替超過 Interval 的金鑰
+func (r *JWKSRotator) autoRotate() bool {
+	rotation := r.config.Kube.Project.JWKSRotation
+	return rotation.Enabled && rotation.Interval > 0
+}
+
+// runOnce 處理所有到期的專案，每個專案的失敗不影響其他專案
+func (r *JWKSRotator) runOnce(ctx context.Context) {
+	rotation := r.config.Kube.Project.JWKSRotation
+	now := time.Now()
+	rotate, retire, err := r.project.FindJWKSRotationsDue(ctx, now.Add(-rotation.Interval), now)
+	if err != nil {
+		return
+	}
+
+	for _, ref := range retire {
+		claimed, err := r.project.ClaimJWKSRetirement(ctx, ref, now)
+		if err != nil || !claimed {
+			continue
+		}
+		retireCtx, cancel := context.WithTimeout(ctx, JWKSRotationTimeout)
+		err = r.service.RetireProjectJWKS(retireCtx, ref)
+		cancel()
+		if err != nil {
+			slog.Error("Failed to retire project JWKS", "ref", ref, "error", err)
+			// 下一次檢查時重試
+			_ = r.project.UpdateJWKSRetireAtByRef(ctx, ref, time.Now())
+		}
+	}
+
+	if !r.autoRotate() {
+		return
+	}
+	for _, ref := range rotate {
+		claimed, err := r.project.ClaimJWKSRotation(ctx, ref, time.Now().Add(rotation.RetireAfter))
+		if err != nil || !claimed {
+			continue
+		}
+		rotateCtx, cancel := context.WithTimeout(ctx, JWKSRotationTimeout)
+		err = r.service.RotateProjectJWKS(rotateCtx, ref)
+		cancel()
+		if err != nil {
+			slog.Error("Failed to rotate project JWKS", "ref", ref, "error", err)
+		}
+	}
+}
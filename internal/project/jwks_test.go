@@ -0,0 +1,117 @@
+package project
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"baas-api/internal/config"
+	"baas-api/internal/dto"
+	"baas-api/internal/models"
+	"baas-api/internal/utils"
+
+	"github.com/danielgtaylor/huma/v2"
+)
+
+func TestManualJWKSRotationWithoutSchedule(t *testing.T) {
+	ctx := context.Background()
+
+	cfg := &config.Config{}
+	cfg.Kube.Project.JWKSRotation = config.ProjectJWKSRotationConfig{
+		Enabled:       false,
+		Interval:      time.Hour,
+		RetireAfter:   0,
+		CheckInterval: time.Hour,
+	}
+
+	publicKey, _, err := utils.NewEd25519JWKWithKIDStringified(ctx, "initial")
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo := &fakeProjectRepository{
+		// 建立超過 Interval，啟用排程時會被自動輪替
+		projects:      map[string]*models.ProjectView{testRef: {Reference: testRef, OwnerID: "user", CreatedAt: time.Now().Add(-24 * time.Hour)}},
+		jwksRotatedAt: map[string]time.Time{},
+		jwksRetireAt:  map[string]time.Time{},
+	}
+	usersDB := &fakeUsersDB{keys: map[string][]models.JWK{testRef: {{ID: "initial", PublicKey: publicKey}}}}
+	kube := &fakeJWKSKube{jwks: map[string]string{testRef: `{"keys":[` + publicKey + `]}`}}
+	s := &service{config: cfg, kube: kube, usersdb: usersDB, project: repo}
+	rotator := &JWKSRotator{config: cfg, project: repo, service: s}
+
+	rotate := func() error {
+		t.Helper()
+		in := &dto.RotateProjectJWKSInput{}
+		in.Body.Ref = testRef
+		if _, err := s.PrepareProjectJWKSRotation(ctx, in, "user"); err != nil {
+			return err
+		}
+		return s.RotateProjectJWKS(ctx, testRef)
+	}
+
+	///// 手動輪替 /////
+	if err := rotate(); err != nil {
+		t.Fatalf("rotate: %v", err)
+	}
+	signingKeys := usersDB.keyIDs(testRef)
+	if len(signingKeys) != 2 {
+		t.Fatalf("auth API keys = %v, want the old and the new key", signingKeys)
+	}
+	newKey := signingKeys[1]
+	if got := kube.keyIDs(t, testRef); !slices.Equal(got, []string{"initial", newKey}) {
+		t.Errorf("REST API keys = %v, want both keys", got)
+	}
+	// 舊金鑰移除前不能再次輪替
+	var statusErr huma.StatusError
+	if err := rotate(); !errors.As(err, &statusErr) || statusErr.GetStatus() != 409 {
+		t.Errorf("rotate before retirement error = %v, want 409", err)
+	}
+
+	///// 排程未啟用時仍移除到期的舊金鑰 /////
+	// Start 在背景立即執行第一次檢查
+	rotator.Start()
+	deadline := time.Now().Add(5 * time.Second)
+	for !slices.Equal(kube.keyIDs(t, testRef), []string{newKey}) {
+		if time.Now().After(deadline) {
+			t.Fatalf("REST API keys = %v, the old key was not retired", kube.keyIDs(t, testRef))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := usersDB.keyIDs(testRef); !slices.Equal(got, []string{newKey}) {
+		t.Errorf("auth API keys after retirement = %v, want %v", got, []string{newKey})
+	}
+	if retireAt, ok := repo.retireAt(testRef); ok {
+		t.Errorf("retirement is still scheduled at %v", retireAt)
+	}
+
+	// 未啟用時不自動輪替
+	_ = repo.UpdateJWKSRotatedAtByRef(ctx, testRef, time.Now().Add(-24*time.Hour))
+	rotator.runOnce(ctx)
+	if got := usersDB.keyIDs(testRef); len(got) != 1 {
+		t.Errorf("auth API keys = %v, scheduled rotation ran while disabled", got)
+	}
+
+	///// 移除舊金鑰後可以再次輪替 /////
+	if err := rotate(); err != nil {
+		t.Fatalf("rotate after retirement: %v", err)
+	}
+	if got := usersDB.keyIDs(testRef); len(got) != 2 || got[0] != newKey {
+		t.Errorf("auth API keys = %v, want %s and a new key", got, newKey)
+	}
+	rotator.runOnce(ctx)
+
+	///// 輪替失敗時釋放標記 /////
+	kube.failApply = true
+	if err := rotate(); err == nil {
+		t.Fatal("rotate succeeded while the REST API JWKS could not be applied")
+	}
+	if retireAt, ok := repo.retireAt(testRef); ok {
+		t.Errorf("failed rotation left the retirement scheduled at %v", retireAt)
+	}
+	kube.failApply = false
+	if err := rotate(); err != nil {
+		t.Errorf("rotate after a failed rotation: %v", err)
+	}
+}
@@ -0,0 +1,54 @@
+package project
+
+import (
+	"context"
+	"errors"
+
+	"baas-api/internal/dto"
+	"baas-api/internal/kubeproject"
+
+	"github.com/danielgtaylor/huma/v2"
+)
+
+// StreamProjectLogs 將專案 container 的 log (已遮蔽 secret) 以 dto.ProjectLogEvent 送到 c，直到 ctx 結束
+func (s *service) StreamProjectLogs(ctx context.Context, c chan any, in *dto.StreamProjectLogsInput, userID string) error {
+	if _, err := s.findOwnedProject(ctx, in.Ref, userID); err != nil {
+		return err
+	}
+
+	opt := kubeproject.LogStreamOption{
+		Container: in.Container,
+		TailLines: &in.TailLines,
+	}
+	if !in.SinceTime.IsZero() {
+		opt.SinceTime = &in.SinceTime
+	}
+
+	lines := make(chan kubeproject.LogLine)
+	errc := make(chan error, 1)
+	go func() {
+		defer close(lines)
+		errc <- s.kube.StreamProjectLogs(ctx, in.Ref, opt, lines)
+	}()
+
+	for line := range lines {
+		select {
+		case c <- dto.ProjectLogEvent{
+			Pod:       line.Pod,
+			Container: line.Container,
+			Timestamp: line.Timestamp,
+			Line:      line.Line,
+		}:
+		case <-ctx.Done():
+		}
+	}
+
+	err := <-errc
+	switch {
+	case errors.Is(err, kubeproject.ErrLogContainerNotFound):
+		return huma.Error422UnprocessableEntity(err.Error())
+	case errors.Is(err, context.Canceled):
+		return nil
+	}
+	return err
+}
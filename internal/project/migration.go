@@ -0,0 +1,59 @@
+package project
+
+import (
+	"context"
+	"errors"
+
+	"baas-api/internal/dto"
+	"baas-api/internal/kubeproject"
+
+	"github.com/danielgtaylor/huma/v2"
+)
+
+func (s *service) GetProjectMigration(ctx context.Context, in *dto.GetProjectMigrationInput, userID string) (*dto.GetProjectMigrationOutput, error) {
+	if _, err := s.findOwnedProject(ctx, in.Ref, userID); err != nil {
+		return nil, err
+	}
+
+	result, err := s.kube.FindMigrationResult(ctx, in.Ref)
+	if errors.Is(err, kubeproject.ErrMigrationNotFound) {
+		return nil, huma.Error404NotFound("Migration not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	out := &dto.GetProjectMigrationOutput{}
+	out.Body.Migration = migrationToDTO(result)
+	return out, nil
+}
+
+func (s *service) GetProjectMigrationLogs(ctx context.Context, in *dto.GetProjectMigrationLogsInput, userID string) (*dto.GetProjectMigrationLogsOutput, error) {
+	if _, err := s.findOwnedProject(ctx, in.Ref, userID); err != nil {
+		return nil, err
+	}
+
+	result, err := s.kube.FindMigrationLogs(ctx, in.Ref)
+	if errors.Is(err, kubeproject.ErrMigrationNotFound) {
+		return nil, huma.Error404NotFound("Migration not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	out := &dto.GetProjectMigrationLogsOutput{}
+	out.Body.Migration = migrationToDTO(result)
+	out.Body.Logs = result.Logs
+	return out, nil
+}
+
+func migrationToDTO(result *kubeproject.MigrationResult) dto.ProjectMigration {
+	return dto.ProjectMigration{
+		JobName:     result.JobName,
+		Action:      result.Action,
+		Status:      result.Status,
+		Message:     result.Message,
+		StartedAt:   result.StartedAt,
+		CompletedAt: result.CompletedAt,
+	}
+}
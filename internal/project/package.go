@@ -6,4 +6,5 @@ var Package = do.Package(
 	do.Lazy(NewRepository),
 	do.Lazy(NewService),
 	do.Lazy(NewController),
+	do.Lazy(NewJWKSRotator),
 )
@@ -0,0 +1,130 @@
+package project
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"baas-api/internal/dto"
+	"baas-api/internal/kubeproject"
+
+	"github.com/danielgtaylor/huma/v2"
+)
+
+// PITR 還原模式
+const (
+	PITRModeReplace = "replace"
+	PITRModeInspect = "inspect"
+)
+
+func (s *service) GetProjectRecoveryWindow(ctx context.Context, in *dto.GetProjectRecoveryWindowInput, userID string) (*dto.GetProjectRecoveryWindowOutput, error) {
+	if _, err := s.findOwnedProject(ctx, in.Ref, userID); err != nil {
+		return nil, err
+	}
+
+	window, err := s.findRecoveryWindow(ctx, in.Ref)
+	if err != nil {
+		return nil, err
+	}
+
+	out := &dto.GetProjectRecoveryWindowOutput{}
+	out.Body.From = window.From
+	out.Body.To = window.To
+	out.Body.ArchivingHealthy = window.ArchivingHealthy
+	return out, nil
+}
+
+// PrepareProjectPITR 驗證目標時間落在可還原範圍內並回傳還原選項
+func (s *service) PrepareProjectPITR(ctx context.Context, in *dto.RestoreProjectToPointInTimeInput, userID string) (*kubeproject.ClusterRecoveryOption, error) {
+	if _, err := s.findOwnedProject(ctx, in.Body.Ref, userID); err != nil {
+		return nil, err
+	}
+
+	window, err := s.findRecoveryWindow(ctx, in.Body.Ref)
+	if err != nil {
+		return nil, err
+	}
+
+	targetTime := in.Body.TargetTime.UTC()
+	if !window.Contains(targetTime) {
+		return nil, huma.Error422UnprocessableEntity(
+			"Target time is outside the recoverable window (" +
+				window.From.Format(time.RFC3339) + " - " + window.To.Format(time.RFC3339) + ")",
+		)
+	}
+
+	return &kubeproject.ClusterRecoveryOption{
+		SourceRef:  in.Body.Ref,
+		ServerName: window.ServerName,
+		TargetTime: &targetTime,
+	}, nil
+}
+
+// CreateProjectPITRCluster 建立並行的 PITR 檢視 cluster，已存在時需先刪除
+func (s *service) CreateProjectPITRCluster(ctx context.Context, ref string, opt *kubeproject.ClusterRecoveryOption) (string, error) {
+	_, err := s.kube.FindPITRCluster(ctx, ref)
+	if err == nil {
+		return "", huma.Error409Conflict("A point-in-time inspection cluster already exists, delete it first")
+	}
+	if !errors.Is(err, kubeproject.ErrPITRClusterNotFound) {
+		return "", err
+	}
+
+	err = s.kube.CreatePITRCluster(ctx, ref, opt)
+	if err != nil {
+		return "", err
+	}
+	return s.kube.GetPITRHost(ref), nil
+}
+
+func (s *service) GetProjectPITRCluster(ctx context.Context, in *dto.GetProjectPITRClusterInput, userID string) (*dto.GetProjectPITRClusterOutput, error) {
+	if _, err := s.findOwnedProject(ctx, in.Ref, userID); err != nil {
+		return nil, err
+	}
+
+	cluster, err := s.kube.FindPITRCluster(ctx, in.Ref)
+	if errors.Is(err, kubeproject.ErrPITRClusterNotFound) {
+		return nil, huma.Error404NotFound("Point-in-time inspection cluster not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	out := &dto.GetProjectPITRClusterOutput{}
+	out.Body.Cluster = dto.ProjectPITRCluster{
+		Name:       cluster.Name,
+		Phase:      cluster.Phase,
+		TargetTime: cluster.TargetTime,
+		Host:       cluster.Host,
+		Username:   cluster.Username,
+		Password:   cluster.Password,
+	}
+	return out, nil
+}
+
+func (s *service) DeleteProjectPITRCluster(ctx context.Context, in *dto.DeleteProjectPITRClusterInput, userID string) (*dto.DeleteProjectPITRClusterOutput, error) {
+	if _, err := s.findOwnedProject(ctx, in.Ref, userID); err != nil {
+		return nil, err
+	}
+
+	if err := s.kube.DeletePITRCluster(ctx, in.Ref); err != nil {
+		return nil, err
+	}
+
+	out := &dto.DeleteProjectPITRClusterOutput{}
+	out.Body.Success = true
+	return out, nil
+}
+
+func (s *service) findRecoveryWindow(ctx context.Context, ref string) (*kubeproject.RecoveryWindow, error) {
+	window, err := s.kube.FindRecoveryWindow(ctx, ref)
+	switch {
+	case errors.Is(err, kubeproject.ErrBackupNotEnabled):
+		return nil, huma.Error400BadRequest("Database backups are not enabled")
+	case errors.Is(err, kubeproject.ErrNoRecoverabilityPoint):
+		return nil, huma.Error409Conflict("No completed backup is available yet")
+	case err != nil:
+		return nil, err
+	}
+	return window, nil
+}
@@ -0,0 +1,69 @@
+package project
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"baas-api/internal/dto"
+	"baas-api/internal/kubeproject"
+	"baas-api/internal/models"
+
+	"github.com/danielgtaylor/huma/v2"
+)
+
+func TestPrepareProjectPITR(t *testing.T) {
+	from := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	window := &kubeproject.RecoveryWindow{ServerName: testRef + "-restored", From: from, To: from.Add(24 * time.Hour)}
+	utc8 := time.FixedZone("UTC+8", 8*60*60)
+
+	tests := []struct {
+		name       string
+		target     time.Time
+		userID     string
+		kubeErr    error
+		wantTarget time.Time
+		wantStatus int
+	}{
+		{name: "inside", target: from.Add(time.Hour), userID: "user", wantTarget: from.Add(time.Hour)},
+		{name: "at start", target: from, userID: "user", wantTarget: from},
+		{name: "at end", target: from.Add(24 * time.Hour), userID: "user", wantTarget: from.Add(24 * time.Hour)},
+		{name: "other time zone is converted to UTC", target: time.Date(2026, 1, 1, 9, 0, 0, 0, utc8), userID: "user", wantTarget: from.Add(time.Hour)},
+		{name: "before start", target: from.Add(-time.Second), userID: "user", wantStatus: 422},
+		{name: "after end", target: from.Add(24*time.Hour + time.Second), userID: "user", wantStatus: 422},
+		{name: "other time zone before start", target: time.Date(2026, 1, 1, 7, 59, 0, 0, utc8), userID: "user", wantStatus: 422},
+		{name: "backup not enabled", target: from.Add(time.Hour), userID: "user", kubeErr: kubeproject.ErrBackupNotEnabled, wantStatus: 400},
+		{name: "no completed backup", target: from.Add(time.Hour), userID: "user", kubeErr: kubeproject.ErrNoRecoverabilityPoint, wantStatus: 409},
+		{name: "not the owner", target: from.Add(time.Hour), userID: "other", wantStatus: 401},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &service{
+				kube:    &fakePITRKube{window: window, err: tt.kubeErr},
+				project: &fakeProjectRepository{projects: map[string]*models.ProjectView{testRef: {Reference: testRef, OwnerID: "user"}}},
+			}
+			in := &dto.RestoreProjectToPointInTimeInput{}
+			in.Body.Ref = testRef
+			in.Body.TargetTime = tt.target
+
+			opt, err := s.PrepareProjectPITR(context.Background(), in, tt.userID)
+			if tt.wantStatus != 0 {
+				var statusErr huma.StatusError
+				if !errors.As(err, &statusErr) || statusErr.GetStatus() != tt.wantStatus {
+					t.Fatalf("PrepareProjectPITR error = %v, want status %d", err, tt.wantStatus)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("PrepareProjectPITR: %v", err)
+			}
+			if opt.SourceRef != testRef || opt.ServerName != window.ServerName {
+				t.Errorf("recovery option = %+v, want source %s and server %s", opt, testRef, window.ServerName)
+			}
+			if opt.TargetTime == nil || !opt.TargetTime.Equal(tt.wantTarget) || opt.TargetTime.Location() != time.UTC {
+				t.Errorf("TargetTime = %v, want %v", opt.TargetTime, tt.wantTarget)
+			}
+		})
+	}
+}
@@ -0,0 +1,141 @@
+package project
+
+import (
+	"context"
+	"errors"
+
+	"baas-api/internal/dto"
+	"baas-api/internal/kubeproject"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/samber/lo"
+)
+
+// defaultPoolerOption 是第一次啟用 pooler 時未指定欄位的預設值
+var defaultPoolerOption = kubeproject.PoolerOption{
+	Instances:       1,
+	PoolMode:        kubeproject.PoolModeTransaction,
+	DefaultPoolSize: 20,
+	MaxClientConn:   500,
+}
+
+func (s *service) GetProjectPooler(ctx context.Context, in *dto.GetProjectPoolerInput, userID string) (*dto.GetProjectPoolerOutput, error) {
+	if _, err := s.findOwnedProject(ctx, in.Ref, userID); err != nil {
+		return nil, err
+	}
+
+	pooler, err := s.findPooler(ctx, in.Ref)
+	if err != nil {
+		return nil, err
+	}
+
+	out := &dto.GetProjectPoolerOutput{}
+	out.Body.Pooler = *pooler
+	return out, nil
+}
+
+// UpdateProjectPooler 啟用 (或調整) 與停用專案的 PgBouncer pooler，並切換 REST API 的連線目標
+func (s *service) UpdateProjectPooler(ctx context.Context, in *dto.UpdateProjectPoolerInput, userID string) (*dto.GetProjectPoolerOutput, error) {
+	ref := in.Body.Ref
+	if _, err := s.findOwnedProject(ctx, ref, userID); err != nil {
+		return nil, err
+	}
+
+	if !in.Body.Enabled {
+		// 先讓 REST API 改回直接連線，再移除 pooler
+		if err := s.kube.ApplyRESTAPIPooler(ctx, ref, false, ""); err != nil {
+			return nil, err
+		}
+		if err := s.kube.DeletePooler(ctx, ref); err != nil {
+			return nil, err
+		}
+		return s.GetProjectPooler(ctx, &dto.GetProjectPoolerInput{Ref: ref}, userID)
+	}
+
+	opt := defaultPoolerOption
+	current, err := s.kube.FindPooler(ctx, ref)
+	switch {
+	case err == nil:
+		opt = current.PoolerOption
+	case !errors.Is(err, kubeproject.ErrPoolerNotFound):
+		return nil, err
+	}
+	restAPI, err := s.kube.FindRESTAPIPooler(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	if in.Body.Instances != nil {
+		opt.Instances = *in.Body.Instances
+	}
+	if in.Body.PoolMode != nil {
+		opt.PoolMode = *in.Body.PoolMode
+	}
+	if in.Body.DefaultPoolSize != nil {
+		opt.DefaultPoolSize = *in.Body.DefaultPoolSize
+	}
+	if in.Body.MaxClientConn != nil {
+		opt.MaxClientConn = *in.Body.MaxClientConn
+	}
+	restAPI = lo.FromPtrOr(in.Body.RESTAPI, restAPI)
+
+	if err := kubeproject.ValidatePooler(opt); err != nil {
+		return nil, huma.Error422UnprocessableEntity(err.Error())
+	}
+
+	// 重新套用 NetworkPolicy，讓較早建立的專案也開放 pooler Pod
+	if err := s.kube.EnsureProjectNamespace(ctx, ref); err != nil {
+		return nil, err
+	}
+	if err := s.kube.ApplyPooler(ctx, ref, opt); err != nil {
+		return nil, err
+	}
+
+	if restAPI {
+		// 較早建立的 authenticator Secret 沒有 pooler-uri，以目前的密碼重新產生
+		password, err := s.kube.FindDatabaseRolePassword(ctx, ref, kubeproject.RoleAuthenticator)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.kube.UpdateDatabaseRoleSecret(ctx, ref, kubeproject.RoleAuthenticator, *password); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.kube.ApplyRESTAPIPooler(ctx, ref, restAPI, opt.PoolMode); err != nil {
+		return nil, err
+	}
+
+	return s.GetProjectPooler(ctx, &dto.GetProjectPoolerInput{Ref: ref}, userID)
+}
+
+func (s *service) findPooler(ctx context.Context, ref string) (*dto.ProjectPooler, error) {
+	connection, err := s.databaseConnection(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	restAPI, err := s.kube.FindRESTAPIPooler(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	out := &dto.ProjectPooler{
+		RESTAPI:    restAPI,
+		Connection: *connection,
+	}
+
+	pooler, err := s.kube.FindPooler(ctx, ref)
+	if errors.Is(err, kubeproject.ErrPoolerNotFound) {
+		return out, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	out.Enabled = true
+	out.Instances = pooler.Instances
+	out.ReadyInstances = pooler.ReadyInstances
+	out.PoolMode = pooler.PoolMode
+	out.DefaultPoolSize = pooler.DefaultPoolSize
+	out.MaxClientConn = pooler.MaxClientConn
+	return out, nil
+}
@@ -0,0 +1,120 @@
+package project
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"maps"
+	"slices"
+
+	"baas-api/internal/dto"
+	"baas-api/internal/kubeproject"
+	"baas-api/internal/models"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/samber/lo"
+)
+
+func (s *service) GetProjectPostgresParameters(ctx context.Context, jwt string, in *dto.GetProjectPostgresParametersInput, userID string) (*dto.GetProjectPostgresParametersOutput, error) {
+	if _, err := s.findOwnedProject(ctx, in.Ref, userID); err != nil {
+		return nil, err
+	}
+
+	desired, err := s.kube.FindPostgresParameters(ctx, in.Ref)
+	if err != nil {
+		return nil, err
+	}
+	phase, err := s.kube.FindClusterStatus(ctx, in.Ref)
+	if err != nil {
+		return nil, err
+	}
+
+	out := &dto.GetProjectPostgresParametersOutput{}
+	out.Body.Phase = lo.FromPtr(phase)
+	out.Body.Parameters = s.postgresParametersToDTO(ctx, jwt, in.Ref, desired)
+	return out, nil
+}
+
+// UpdateProjectPostgresParameters 合併變更後套用，值為 null 的參數回到預設值
+func (s *service) UpdateProjectPostgresParameters(ctx context.Context, jwt string, in *dto.UpdateProjectPostgresParametersInput, userID string) (*dto.UpdateProjectPostgresParametersOutput, error) {
+	ref := in.Body.Ref
+	if _, err := s.findOwnedProject(ctx, ref, userID); err != nil {
+		return nil, err
+	}
+
+	current, err := s.kube.FindPostgresParameters(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	next := maps.Clone(current)
+	restartRequired := false
+	for _, name := range slices.Sorted(maps.Keys(in.Body.Parameters)) {
+		spec, ok := kubeproject.PostgresParameterAllowlist[name]
+		if !ok {
+			return nil, huma.Error422UnprocessableEntity("Parameter is not allowed: " + name)
+		}
+
+		value := in.Body.Parameters[name]
+		if value == nil {
+			delete(next, name)
+		} else {
+			if err := kubeproject.ValidatePostgresParameter(name, *value); err != nil {
+				return nil, huma.Error422UnprocessableEntity(err.Error())
+			}
+			next[name] = *value
+		}
+
+		previous, hadPrevious := current[name]
+		changed := hadPrevious != (value != nil) ||
+			(value != nil && !kubeproject.PostgresParameterEqual(name, previous, *value))
+		if changed && spec.RestartRequired {
+			restartRequired = true
+		}
+	}
+
+	err = s.kube.ApplyPostgresParameters(ctx, ref, next)
+	if errors.Is(err, kubeproject.ErrPostgresParameterNotAllowed) {
+		return nil, huma.Error422UnprocessableEntity(err.Error())
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	out := &dto.UpdateProjectPostgresParametersOutput{}
+	out.Body.RestartRequired = restartRequired
+	out.Body.Parameters = s.postgresParametersToDTO(ctx, jwt, ref, next)
+	return out, nil
+}
+
+// postgresParametersToDTO 合併專案設定的值與資料庫目前生效的值
+func (s *service) postgresParametersToDTO(ctx context.Context, jwt, ref string, desired map[string]string) []dto.ProjectPostgresParameter {
+	names := slices.Sorted(maps.Keys(kubeproject.PostgresParameterAllowlist))
+
+	// 資料庫重新啟動中時無法讀取生效的值，仍然回傳專案設定的值
+	settings, err := s.usersdb.GetPostgresSettings(ctx, jwt, ref, names)
+	if err != nil {
+		slog.WarnContext(ctx, "Failed to read postgres settings", "error", err, "ref", ref)
+	}
+	applied := lo.SliceToMap(settings, func(setting models.PostgresSetting) (string, models.PostgresSetting) {
+		return setting.Name, setting
+	})
+
+	return lo.Map(names, func(name string, _ int) dto.ProjectPostgresParameter {
+		spec := kubeproject.PostgresParameterAllowlist[name]
+		parameter := dto.ProjectPostgresParameter{
+			Name:            name,
+			Type:            string(spec.Type),
+			Values:          spec.Values,
+			RestartRequired: spec.RestartRequired,
+			Desired:         desired[name],
+		}
+		if setting, ok := applied[name]; ok {
+			parameter.Applied = setting.Setting
+			parameter.PendingRestart = setting.PendingRestart
+			parameter.Pending = setting.PendingRestart ||
+				(parameter.Desired != "" && !kubeproject.PostgresParameterEqual(name, parameter.Desired, setting.Setting))
+		}
+		return parameter
+	})
+}
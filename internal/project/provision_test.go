@@ -0,0 +1,378 @@
+package project
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"baas-api/internal/authsetting"
+	"baas-api/internal/config"
+	"baas-api/internal/dto"
+	"baas-api/internal/encryption"
+	"baas-api/internal/kubeproject"
+	"baas-api/internal/minio"
+	"baas-api/internal/pgrest"
+	"baas-api/internal/usersdb"
+
+	"github.com/samber/do/v2"
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+)
+
+const testRef = "abcdefghijklmnopqrst"
+
+var (
+	ingressRouteGVR    = schema.GroupVersionResource{Group: "traefik.io", Version: "v1alpha1", Resource: "ingressroutes"}
+	ingressRouteTCPGVR = schema.GroupVersionResource{Group: "traefik.io", Version: "v1alpha1", Resource: "ingressroutetcps"}
+	httpRouteGVR       = schema.GroupVersionResource{Group: "gateway.networking.k8s.io", Version: "v1", Resource: "httproutes"}
+	tlsRouteGVR        = schema.GroupVersionResource{Group: "gateway.networking.k8s.io", Version: "v1alpha2", Resource: "tlsroutes"}
+	databaseGVR        = schema.GroupVersionResource{Group: "postgresql.cnpg.io", Version: "v1", Resource: "databases"}
+)
+
+// resourceNames 是 kubeproject service 產生資源名稱的方法，未列在 kubeproject.Service 中
+type resourceNames interface {
+	GetJWKSConfigMapName(ref string) string
+	GetMigrationResultConfigMapName(ref string) string
+	GetAPISecretName(ref string) string
+	GetAuthAPIDeploymentName(ref string) string
+	GetAuthAPIServiceName(ref string) string
+	GetRESTAPIDeploymentName(ref string) string
+	GetRESTAPIServiceName(ref string) string
+	GetAPIIngressRouteName(ref string) string
+	GetDBIngressRouteTCPName(ref string) string
+	GetDatabaseRoleSecretName(ref string, role string) string
+}
+
+// provisionEnv 是以 fake 依賴組成的 project.Service 與可供檢查的 fake
+type provisionEnv struct {
+	service     Service
+	kube        resourceNames
+	encryption  encryption.Service
+	clientset   *k8sfake.Clientset
+	dynamic     *dynamicfake.FakeDynamicClient
+	minio       *fakeMinIO
+	pgrest      *fakePostgREST
+	authSetting *fakeAuthSettingRepository
+	namespace   string
+	// backupBucket 是平台共用的 backup bucket
+	backupBucket string
+}
+
+func newProvisionEnv(t *testing.T, ingressProvider string) *provisionEnv {
+	t.Helper()
+
+	key := make([]byte, 32)
+	_, _ = rand.Read(key)
+
+	authSetting := newFakeAuthSettingRepository()
+	env := &provisionEnv{
+		minio: newFakeMinIO(t),
+		pgrest: newFakePostgREST(t, pgrest.CreateProjectOutput{
+			ID:                "0b6c4c2e-7a2d-4f0e-9a55-0c1f3c9f1d10",
+			Ref:               testRef,
+			AuthSecret:        "plain-auth-secret",
+			S3Bucket:          testRef,
+			S3AccessKeyID:     testRef + "-key",
+			S3SecretAccessKey: "s3-secret",
+		}, authSetting),
+		authSetting: authSetting,
+	}
+	env.clientset, env.dynamic = newFakeKube(t)
+
+	i := do.New()
+	config.Package(i)
+	cfg := do.MustInvoke[*config.Config](i)
+	pgrestURL, err := url.Parse(env.pgrest.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.PgREST.URL = pgrestURL
+	cfg.App.ExternalDomain = "example.com"
+	cfg.S3 = config.S3Config{
+		Endpoint:        env.minio.endpoint(),
+		AccessKeyID:     env.minio.accessKeyID,
+		SecretAccessKey: env.minio.secretKey,
+	}
+	cfg.Kube.Ingress.Provider = ingressProvider
+	cfg.Kube.Project.Backup.Enabled = true
+	cfg.Encryption = config.EncryptionConfig{
+		ActiveKeyID: "test",
+		Keys:        []config.EncryptionKeyConfig{{ID: "test", Key: base64.StdEncoding.EncodeToString(key)}},
+	}
+	env.namespace = cfg.Kube.Project.Namespace
+	env.backupBucket = cfg.Kube.Project.Backup.Bucket
+
+	do.ProvideValue[kubernetes.Interface](i, env.clientset)
+	do.ProvideValue[dynamic.Interface](i, env.dynamic)
+	do.Provide(i, kubeproject.NewService)
+	pgrest.Package(i)
+	encryption.Package(i)
+	minio.Package(i)
+	do.ProvideValue[authsetting.Repository](i, env.authSetting)
+	do.ProvideValue[Repository](i, &fakeProjectRepository{})
+	do.ProvideValue[usersdb.Service](i, &fakeUsersDB{})
+	do.Provide(i, NewService)
+
+	env.service = do.MustInvokeAs[Service](i)
+	env.kube = do.MustInvokeAs[kubeproject.Service](i).(resourceNames)
+	env.encryption = do.MustInvokeAs[encryption.Service](i)
+	return env
+}
+
+// exists 回傳 dynamic client 中是否有指定的資源
+func (env *provisionEnv) exists(t *testing.T, gvr schema.GroupVersionResource, name string) bool {
+	t.Helper()
+	_, err := env.dynamic.Resource(gvr).Namespace(env.namespace).Get(context.Background(), name, metav1.GetOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		t.Fatalf("get %s %s: %v", gvr.Resource, name, err)
+	}
+	return err == nil
+}
+
+func TestProjectProvisioning(t *testing.T) {
+	tests := []struct {
+		provider string
+		apiRoute schema.GroupVersionResource
+		dbRoute  schema.GroupVersionResource
+	}{
+		{provider: config.IngressProviderTraefik, apiRoute: ingressRouteGVR, dbRoute: ingressRouteTCPGVR},
+		{provider: config.IngressProviderGateway, apiRoute: httpRouteGVR, dbRoute: tlsRouteGVR},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.provider, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer cancel()
+
+			env := newProvisionEnv(t, tt.provider)
+			kube := env.kube
+			core := env.clientset.CoreV1()
+			apps := env.clientset.AppsV1()
+
+			///// CreateProject /////
+			in := &dto.CreateProjectInput{}
+			in.Body.Name = "My Project"
+			in.Body.Description = lo.ToPtr("")
+			in.Body.StorageSize = "1Gi"
+			out, internal, err := env.service.CreateProject(ctx, in, "jwt", lo.ToPtr("user"))
+			if err != nil {
+				t.Fatalf("CreateProject: %v", err)
+			}
+			if out.Body.Reference != testRef {
+				t.Fatalf("reference = %q, want %q", out.Body.Reference, testRef)
+			}
+
+			if !env.minio.hasBucket(testRef) || !env.minio.hasUser(testRef+"-key") {
+				t.Error("MinIO bucket or user was not created")
+			}
+			if quota, ok := env.minio.quota(testRef); !ok || quota.Size != 1<<30 {
+				t.Errorf("bucket quota = %+v, %v", quota, ok)
+			}
+			env.assertPolicyResource(t, testRef+"-key", "arn:aws:s3:::"+testRef+"/*")
+			backupUser := minio.GetBackupUserNameByRef(testRef)
+			if !env.minio.hasBucket(env.backupBucket) || !env.minio.hasUser(backupUser) {
+				t.Error("MinIO backup bucket or user was not created")
+			}
+			env.assertPolicyResource(t, backupUser, "arn:aws:s3:::"+env.backupBucket+"/"+minio.GetBackupPrefixByRef(testRef)+"*")
+
+			stored := env.authSetting.secret(out.Body.ID)
+			if !env.encryption.IsEncrypted(stored) {
+				t.Errorf("stored auth secret is not encrypted: %q", stored)
+			}
+			if plaintext, err := env.encryption.Decrypt(stored); err != nil || plaintext != "plain-auth-secret" {
+				t.Errorf("decrypted auth secret = %q, %v", plaintext, err)
+			}
+			if internal.AuthSecret != "plain-auth-secret" {
+				t.Errorf("internal auth secret = %q", internal.AuthSecret)
+			}
+
+			if _, err := core.ConfigMaps(env.namespace).Get(ctx, kube.GetJWKSConfigMapName(testRef), metav1.GetOptions{}); err != nil {
+				t.Errorf("JWKS ConfigMap: %v", err)
+			}
+			if _, err := core.Secrets(env.namespace).Get(ctx, kube.GetDatabaseRoleSecretName(testRef, kubeproject.RoleAuthenticator), metav1.GetOptions{}); err != nil {
+				t.Errorf("authenticator role Secret: %v", err)
+			}
+			if !env.exists(t, clusterGVR, testRef) {
+				t.Error("CNPG Cluster was not created")
+			}
+			databases, err := env.dynamic.Resource(databaseGVR).Namespace(env.namespace).List(ctx, metav1.ListOptions{})
+			if err != nil || len(databases.Items) == 0 {
+				t.Errorf("CNPG Database was not created: %v", err)
+			}
+
+			///// CreateProjectPostInstall /////
+			if err := env.service.CreateProjectPostInstall(ctx, testRef, internal); err != nil {
+				t.Fatalf("CreateProjectPostInstall: %v", err)
+			}
+
+			migration, err := core.ConfigMaps(env.namespace).Get(ctx, kube.GetMigrationResultConfigMapName(testRef), metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("migration result ConfigMap: %v", err)
+			}
+			if migration.Data["status"] != kubeproject.MigrationSucceeded {
+				t.Errorf("migration status = %q, want %q", migration.Data["status"], kubeproject.MigrationSucceeded)
+			}
+
+			for _, name := range []string{kube.GetAuthAPIDeploymentName(testRef), kube.GetRESTAPIDeploymentName(testRef)} {
+				if _, err := apps.Deployments(env.namespace).Get(ctx, name, metav1.GetOptions{}); err != nil {
+					t.Errorf("Deployment %s: %v", name, err)
+				}
+			}
+			for _, name := range []string{kube.GetAuthAPIServiceName(testRef), kube.GetRESTAPIServiceName(testRef)} {
+				if _, err := core.Services(env.namespace).Get(ctx, name, metav1.GetOptions{}); err != nil {
+					t.Errorf("Service %s: %v", name, err)
+				}
+			}
+			if !env.exists(t, tt.apiRoute, kube.GetAPIIngressRouteName(testRef)) {
+				t.Errorf("%s was not created", tt.apiRoute.Resource)
+			}
+			if !env.exists(t, tt.dbRoute, kube.GetDBIngressRouteTCPName(testRef)) {
+				t.Errorf("%s was not created", tt.dbRoute.Resource)
+			}
+
+			///// PatchProjectSettings /////
+			patch := &dto.UpdateProjectInput{}
+			patch.Body.ID = out.Body.ID
+			patch.Body.TrustedOrigins = []string{"https://app.example.com"}
+			patch.Body.Auth = map[string]dto.AuthProvider{
+				"google": {Enabled: true, ClientID: lo.ToPtr("client-id"), ClientSecret: lo.ToPtr("google-secret")},
+			}
+			if err := env.service.PatchProjectSettings(ctx, "jwt", patch, "user"); err != nil {
+				t.Fatalf("PatchProjectSettings: %v", err)
+			}
+
+			apiSecret, err := core.Secrets(env.namespace).Get(ctx, kube.GetAPISecretName(testRef), metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("API Secret: %v", err)
+			}
+			if got := apiSecret.Data["GOOGLE_CLIENT_SECRET"]; string(got) != "google-secret" {
+				t.Errorf("GOOGLE_CLIENT_SECRET = %q, want %q", got, "google-secret")
+			}
+
+			deployment, err := apps.Deployments(env.namespace).Get(ctx, kube.GetAuthAPIDeploymentName(testRef), metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("auth Deployment: %v", err)
+			}
+			env.assertSecretEnv(t, deployment.Spec.Template.Spec.Containers, "GOOGLE_CLIENT_SECRET", kube.GetAPISecretName(testRef))
+
+			var sent struct {
+				Payload pgrest.CreateOrUpdateAuthProviderPayload `json:"payload"`
+			}
+			if err := json.Unmarshal(env.pgrest.lastRequest("create_or_update_auth_providers"), &sent); err != nil {
+				t.Fatalf("create_or_update_auth_providers payload: %v", err)
+			}
+			google := sent.Payload.Providers["google"]
+			if google.ClientSecret == nil || !env.encryption.IsEncrypted(*google.ClientSecret) {
+				t.Errorf("client secret sent to PostgREST is not encrypted: %v", lo.FromPtr(google.ClientSecret))
+			}
+
+			// 平台資料庫中移除的 provider 在下次套用設定時從 Deployment 與 API Secret 移除
+			env.authSetting.deleteProvider(out.Body.ID, "google")
+			patch = &dto.UpdateProjectInput{}
+			patch.Body.ID = out.Body.ID
+			patch.Body.TrustedOrigins = []string{"https://app.example.com", "https://admin.example.com"}
+			if err := env.service.PatchProjectSettings(ctx, "jwt", patch, "user"); err != nil {
+				t.Fatalf("PatchProjectSettings: %v", err)
+			}
+
+			apiSecret, err = core.Secrets(env.namespace).Get(ctx, kube.GetAPISecretName(testRef), metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("API Secret: %v", err)
+			}
+			if _, ok := apiSecret.Data["GOOGLE_CLIENT_SECRET"]; ok {
+				t.Error("GOOGLE_CLIENT_SECRET remains in the API Secret after the provider was removed")
+			}
+			if _, ok := apiSecret.Data[kubeproject.SecretKeyBetterAuthSecret]; !ok {
+				t.Errorf("%s was removed from the API Secret", kubeproject.SecretKeyBetterAuthSecret)
+			}
+			deployment, err = apps.Deployments(env.namespace).Get(ctx, kube.GetAuthAPIDeploymentName(testRef), metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("auth Deployment: %v", err)
+			}
+			for _, container := range deployment.Spec.Template.Spec.Containers {
+				for _, e := range container.Env {
+					if strings.HasPrefix(e.Name, "GOOGLE_") {
+						t.Errorf("%s remains in the auth Deployment after the provider was removed", e.Name)
+					}
+					if e.Name == "TRUSTED_ORIGINS" && e.Value != "https://app.example.com,https://admin.example.com" {
+						t.Errorf("TRUSTED_ORIGINS = %q", e.Value)
+					}
+				}
+			}
+
+			///// DeleteProjectByID /////
+			// labels 導入前建立的資源沒有專案 labels，需依名稱刪除
+			deployment.Labels = nil
+			if _, err := apps.Deployments(env.namespace).Update(ctx, deployment, metav1.UpdateOptions{}); err != nil {
+				t.Fatalf("remove auth Deployment labels: %v", err)
+			}
+
+			if _, err := env.service.DeleteProjectByID(ctx, "jwt", &dto.DeleteProjectByIDInput{ID: out.Body.ID}, "user"); err != nil {
+				t.Fatalf("DeleteProjectByID: %v", err)
+			}
+
+			if env.minio.hasBucket(testRef) || env.minio.hasUser(testRef+"-key") {
+				t.Error("MinIO bucket or user was not removed")
+			}
+			if env.minio.hasUser(minio.GetBackupUserNameByRef(testRef)) {
+				t.Error("MinIO backup user was not removed")
+			}
+			if env.exists(t, clusterGVR, testRef) {
+				t.Error("CNPG Cluster was not removed")
+			}
+			for _, gvr := range []schema.GroupVersionResource{tt.apiRoute, tt.dbRoute} {
+				list, err := env.dynamic.Resource(gvr).Namespace(env.namespace).List(ctx, metav1.ListOptions{})
+				if err != nil || len(list.Items) != 0 {
+					t.Errorf("%s remain after delete: %v", gvr.Resource, err)
+				}
+			}
+			deployments, err := apps.Deployments(env.namespace).List(ctx, metav1.ListOptions{})
+			if err != nil || len(deployments.Items) != 0 {
+				t.Errorf("Deployments remain after delete: %v", err)
+			}
+		})
+	}
+}
+
+// assertPolicyResource 檢查 MinIO 使用者綁定的 policy 中有允許存取 resource 的 statement
+func (env *provisionEnv) assertPolicyResource(t *testing.T, accessKeyID string, resource string) {
+	t.Helper()
+	for _, policy := range env.minio.userPolicies(accessKeyID) {
+		for _, statement := range policy.Statement {
+			if statement.Effect == "Allow" && slices.Contains(statement.Resource, resource) {
+				return
+			}
+		}
+	}
+	t.Errorf("no policy attached to %s allows %s", accessKeyID, resource)
+}
+
+// assertSecretEnv 檢查環境變數以 secretKeyRef 引用 secretName，而不是以明文設定
+func (env *provisionEnv) assertSecretEnv(t *testing.T, containers []corev1.Container, name string, secretName string) {
+	t.Helper()
+	for _, container := range containers {
+		for _, e := range container.Env {
+			if e.Name != name {
+				continue
+			}
+			if e.Value != "" || e.ValueFrom == nil || e.ValueFrom.SecretKeyRef == nil || e.ValueFrom.SecretKeyRef.Name != secretName {
+				t.Errorf("%s is not a secretKeyRef to %s: %+v", name, secretName, e)
+			}
+			return
+		}
+	}
+	t.Errorf("%s is not set on the auth API container", name)
+}
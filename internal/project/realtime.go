@@ -0,0 +1,110 @@
+package project
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"baas-api/internal/dto"
+
+	"github.com/danielgtaylor/huma/v2"
+)
+
+var (
+	realtimeTableRegex = regexp.MustCompile(`^[a-z_][a-z0-9_]{0,62}\.[a-z_][a-z0-9_]{0,62}$`)
+	// auth 保存簽章金鑰，_realtime 與 realtime 是 Realtime 自己的 schema
+	realtimeReservedSchemas = []string{"information_schema", "auth", "baas_migrations", "_realtime", "realtime"}
+	// 類別與物件的變更一律推送
+	realtimeDefaultTables = []string{"dbo.classes", "dbo.objects"}
+)
+
+func (s *service) GetProjectRealtime(ctx context.Context, jwt string, in *dto.GetProjectRealtimeInput, userID string) (*dto.GetProjectRealtimeOutput, error) {
+	if _, err := s.findOwnedProject(ctx, in.Ref, userID); err != nil {
+		return nil, err
+	}
+	return s.projectRealtime(ctx, jwt, in.Ref)
+}
+
+// UpdateProjectRealtime 啟用或停用專案的 Realtime。
+//
+// 啟用時先設定 supabase_realtime publication 再部署 Realtime；停用時只移除 Realtime，publication 保留以便再次啟用。
+func (s *service) UpdateProjectRealtime(ctx context.Context, jwt string, in *dto.UpdateProjectRealtimeInput, userID string) (*dto.GetProjectRealtimeOutput, error) {
+	ref := in.Body.Ref
+	if _, err := s.findOwnedProject(ctx, ref, userID); err != nil {
+		return nil, err
+	}
+
+	if !in.Body.Enabled {
+		if err := s.kube.DeleteRealtime(ctx, ref); err != nil {
+			return nil, err
+		}
+		return s.projectRealtime(ctx, jwt, ref)
+	}
+
+	tables := in.Body.Tables
+	if tables == nil {
+		current, err := s.usersdb.GetRealtimeTables(ctx, jwt, ref)
+		if err != nil {
+			return nil, err
+		}
+		tables = current
+	}
+	tables, err := realtimeTables(tables)
+	if err != nil {
+		return nil, huma.Error422UnprocessableEntity(err.Error())
+	}
+
+	missing, err := s.usersdb.GetMissingTables(ctx, jwt, ref, tables)
+	if err != nil {
+		return nil, err
+	}
+	if len(missing) > 0 {
+		return nil, huma.Error422UnprocessableEntity("Tables do not exist: " + strings.Join(missing, ", "))
+	}
+	if err := s.usersdb.SetRealtimeTables(ctx, jwt, ref, tables); err != nil {
+		return nil, err
+	}
+
+	if err := s.kube.ApplyRealtime(ctx, ref); err != nil {
+		return nil, err
+	}
+	return s.projectRealtime(ctx, jwt, ref)
+}
+
+func (s *service) projectRealtime(ctx context.Context, jwt, ref string) (*dto.GetProjectRealtimeOutput, error) {
+	status, err := s.kube.FindRealtime(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	tables, err := s.usersdb.GetRealtimeTables(ctx, jwt, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	out := &dto.GetProjectRealtimeOutput{}
+	out.Body.Realtime = dto.ProjectRealtime{
+		Enabled: status.Enabled,
+		Ready:   status.ReadyReplicas > 0,
+		URL:     s.kube.GetRealtimeURL(ref),
+		Tables:  tables,
+	}
+	return out, nil
+}
+
+// realtimeTables 檢查資料表名稱並加入預設的資料表，回傳排序且不重複的清單
+func realtimeTables(tables []string) ([]string, error) {
+	for _, table := range tables {
+		if !realtimeTableRegex.MatchString(table) {
+			return nil, fmt.Errorf("invalid table name %q, expected schema.table", table)
+		}
+		schema, _, _ := strings.Cut(table, ".")
+		if strings.HasPrefix(schema, "pg_") || slices.Contains(realtimeReservedSchemas, schema) {
+			return nil, fmt.Errorf("tables of schema %q cannot be broadcast", schema)
+		}
+	}
+	tables = append(slices.Clone(realtimeDefaultTables), tables...)
+	slices.Sort(tables)
+	return slices.Compact(tables), nil
+}
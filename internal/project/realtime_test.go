@@ -0,0 +1,50 @@
+package project
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestRealtimeTables(t *testing.T) {
+	tests := []struct {
+		name    string
+		tables  []string
+		want    []string
+		wantErr string
+	}{
+		{name: "defaults only", tables: nil, want: []string{"dbo.classes", "dbo.objects"}},
+		{name: "sorted", tables: []string{"public.todos", "api.messages"}, want: []string{"api.messages", "dbo.classes", "dbo.objects", "public.todos"}},
+		{name: "duplicates and defaults removed", tables: []string{"public.todos", "dbo.objects", "public.todos"}, want: []string{"dbo.classes", "dbo.objects", "public.todos"}},
+		{name: "underscores and digits", tables: []string{"_app.table_2"}, want: []string{"_app.table_2", "dbo.classes", "dbo.objects"}},
+
+		{name: "missing schema", tables: []string{"todos"}, wantErr: "expected schema.table"},
+		{name: "upper case", tables: []string{"public.Todos"}, wantErr: "expected schema.table"},
+		{name: "quoted identifier", tables: []string{`public."todos"`}, wantErr: "expected schema.table"},
+		{name: "injection", tables: []string{"public.todos; DROP PUBLICATION x"}, wantErr: "expected schema.table"},
+		{name: "three parts", tables: []string{"db.public.todos"}, wantErr: "expected schema.table"},
+		{name: "identifier too long", tables: []string{"public." + strings.Repeat("t", 64)}, wantErr: "expected schema.table"},
+		{name: "auth schema", tables: []string{"auth.users"}, wantErr: `schema "auth" cannot be broadcast`},
+		{name: "realtime schema", tables: []string{"realtime.messages"}, wantErr: "cannot be broadcast"},
+		{name: "internal realtime schema", tables: []string{"_realtime.tenants"}, wantErr: "cannot be broadcast"},
+		{name: "system schema", tables: []string{"pg_catalog.pg_class"}, wantErr: "cannot be broadcast"},
+		{name: "one invalid table", tables: []string{"public.todos", "information_schema.tables"}, wantErr: "cannot be broadcast"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := realtimeTables(tt.tables)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("realtimeTables error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("realtimeTables: %v", err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("realtimeTables = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
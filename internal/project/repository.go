@@ -4,7 +4,9 @@ import (
 	"context"
 	"errors"
 	"log/slog"
+	"time"
 
+	"baas-api/internal/kubeproject"
 	"baas-api/internal/models"
 
 	gonanoid "github.com/matoous/go-nanoid/v2"
@@ -49,13 +51,44 @@ type Repository interface {
 	UpdateByRef(ctx context.Context, ref string, project any, object any) error
 	// IsOwner 檢查使用者是否為專案擁有者。
 	IsOwner(ctx context.Context, projectRef string, userID string) (bool, error)
+	// FindClusterByRef 取得專案所在的叢集，未記錄時為空字串，專案不存在時 found 為 false。
+	FindClusterByRef(ctx context.Context, ref string) (cluster string, found bool, err error)
+	// UpdateClusterByRef 記錄專案所在的叢集。
+	UpdateClusterByRef(ctx context.Context, ref string, cluster string) error
+	// FindPlanByRef 取得專案的方案，未記錄時為空字串。
+	FindPlanByRef(ctx context.Context, ref string) (string, error)
+	// UpdatePlanByRef 記錄專案的方案。
+	UpdatePlanByRef(ctx context.Context, ref string, plan string) error
+	// FindJWKSRotationByRef 取得專案最近一次輪替 JWKS 的時間與移除舊金鑰的時間。
+	FindJWKSRotationByRef(ctx context.Context, ref string) (rotatedAt *time.Time, retireAt *time.Time, err error)
+	// FindJWKSRotationsDue 取得需要輪替 JWKS (上次輪替早於 rotatedBefore) 與需要移除舊金鑰的專案。
+	FindJWKSRotationsDue(ctx context.Context, rotatedBefore time.Time, now time.Time) (rotate []string, retire []string, err error)
+	// ClaimJWKSRotation 開始輪替 JWKS 並預定 retireAt 移除舊金鑰，已有進行中的輪替時 claimed 為 false。
+	ClaimJWKSRotation(ctx context.Context, ref string, retireAt time.Time) (claimed bool, err error)
+	// ClaimJWKSRetirement 開始移除舊金鑰，尚未到期或沒有進行中的輪替時 claimed 為 false。
+	ClaimJWKSRetirement(ctx context.Context, ref string, now time.Time) (claimed bool, err error)
+	// UpdateJWKSRotatedAtByRef 記錄切換簽章金鑰的時間。
+	UpdateJWKSRotatedAtByRef(ctx context.Context, ref string, rotatedAt time.Time) error
+	// UpdateJWKSRetireAtByRef 重新預定移除舊金鑰的時間 (例如移除失敗時重試)。
+	UpdateJWKSRetireAtByRef(ctx context.Context, ref string, retireAt time.Time) error
+	// ReleaseJWKSRotation 取消預定的移除舊金鑰 (輪替失敗時)，之後可以再次輪替。
+	ReleaseJWKSRotation(ctx context.Context, ref string) error
+	// FindRestoreByRef 取得專案最近一次資料庫還原的狀態。
+	FindRestoreByRef(ctx context.Context, ref string) (*models.Project, error)
+	// ClaimRestore 開始還原專案資料庫，已有在 staleBefore 之後開始、進行中的還原時 claimed 為 false。
+	ClaimRestore(ctx context.Context, ref string, startedAt time.Time, staleBefore time.Time) (claimed bool, err error)
+	// FinishRestore 記錄資料庫還原的結果，restoreErr 為 nil 代表成功。
+	FinishRestore(ctx context.Context, ref string, finishedAt time.Time, restoreErr error) error
 }
 
 type repository struct {
 	db *gorm.DB
 }
 
-var _ Repository = (*repository)(nil)
+var (
+	_ Repository                           = (*repository)(nil)
+	_ kubeproject.ProjectClusterRepository = (*repository)(nil)
+)
 
 func NewRepository(i do.Injector) (*repository, error) {
 	db := do.MustInvoke[*gorm.DB](i)
@@ -331,3 +364,220 @@ func (r *repository) IsOwner(ctx context.Context, projectRef string, userID stri
 	}
 	return true, nil
 }
+
+func (r *repository) FindClusterByRef(ctx context.Context, ref string) (string, bool, error) {
+	var project models.Project
+	if err := r.db.WithContext(ctx).Select("cluster").First(&project, "reference = ?", ref).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", false, nil
+		}
+		slog.ErrorContext(ctx, "Failed to get project cluster by reference", "projectRef", ref, "error", err)
+		return "", false, errors.New("failed to get project cluster by reference")
+	}
+	return lo.FromPtr(project.Cluster), true, nil
+}
+
+func (r *repository) UpdateClusterByRef(ctx context.Context, ref string, cluster string) error {
+	result := r.db.WithContext(ctx).
+		Model(&models.Project{}).
+		Where("reference = ?", ref).
+		Update("cluster", cluster)
+	if result.Error != nil {
+		slog.ErrorContext(ctx, "Failed to update project cluster by reference", "projectRef", ref, "cluster", cluster, "error", result.Error)
+		return errors.New("failed to update project cluster by reference")
+	}
+	if result.RowsAffected == 0 {
+		slog.WarnContext(ctx, "Project not found for cluster update by reference", "projectRef", ref)
+		return ErrProjectNotFound
+	}
+	return nil
+}
+
+func (r *repository) FindPlanByRef(ctx context.Context, ref string) (string, error) {
+	var project models.Project
+	if err := r.db.WithContext(ctx).Select("plan").First(&project, "reference = ?", ref).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", ErrProjectNotFound
+		}
+		slog.ErrorContext(ctx, "Failed to get project plan by reference", "projectRef", ref, "error", err)
+		return "", errors.New("failed to get project plan by reference")
+	}
+	return lo.FromPtr(project.Plan), nil
+}
+
+func (r *repository) UpdatePlanByRef(ctx context.Context, ref string, plan string) error {
+	result := r.db.WithContext(ctx).
+		Model(&models.Project{}).
+		Where("reference = ?", ref).
+		Update("plan", plan)
+	if result.Error != nil {
+		slog.ErrorContext(ctx, "Failed to update project plan by reference", "projectRef", ref, "plan", plan, "error", result.Error)
+		return errors.New("failed to update project plan by reference")
+	}
+	if result.RowsAffected == 0 {
+		slog.WarnContext(ctx, "Project not found for plan update by reference", "projectRef", ref)
+		return ErrProjectNotFound
+	}
+	return nil
+}
+
+func (r *repository) FindJWKSRotationByRef(ctx context.Context, ref string) (*time.Time, *time.Time, error) {
+	var project models.Project
+	if err := r.db.WithContext(ctx).Select("jwks_rotated_at", "jwks_retire_at").First(&project, "reference = ?", ref).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, ErrProjectNotFound
+		}
+		slog.ErrorContext(ctx, "Failed to get project JWKS rotation by reference", "projectRef", ref, "error", err)
+		return nil, nil, errors.New("failed to get project JWKS rotation by reference")
+	}
+	return project.JWKSRotatedAt, project.JWKSRetireAt, nil
+}
+
+func (r *repository) FindJWKSRotationsDue(ctx context.Context, rotatedBefore time.Time, now time.Time) ([]string, []string, error) {
+	var rotate []string
+	err := r.db.WithContext(ctx).
+		Model(&models.Project{}).
+		Joins("JOIN dbo.objects ON dbo.objects.id = dbo.projects.id AND dbo.objects.deleted_at IS NULL").
+		Where("dbo.projects.jwks_retire_at IS NULL AND COALESCE(dbo.projects.jwks_rotated_at, dbo.objects.created_at) < ?", rotatedBefore).
+		Pluck("dbo.projects.reference", &rotate).Error
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to find projects due for JWKS rotation", "error", err)
+		return nil, nil, errors.New("failed to find projects due for JWKS rotation")
+	}
+
+	var retire []string
+	err = r.db.WithContext(ctx).
+		Model(&models.Project{}).
+		Where("jwks_retire_at <= ?", now).
+		Pluck("reference", &retire).Error
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to find projects due for JWKS retirement", "error", err)
+		return nil, nil, errors.New("failed to find projects due for JWKS retirement")
+	}
+	return rotate, retire, nil
+}
+
+func (r *repository) ClaimJWKSRotation(ctx context.Context, ref string, retireAt time.Time) (bool, error) {
+	// 以條件更新取得輪替權，多個 API 副本同時執行排程時只有一個會成功
+	result := r.db.WithContext(ctx).
+		Model(&models.Project{}).
+		Where("reference = ? AND jwks_retire_at IS NULL", ref).
+		Update("jwks_retire_at", retireAt)
+	if result.Error != nil {
+		slog.ErrorContext(ctx, "Failed to claim project JWKS rotation", "projectRef", ref, "error", result.Error)
+		return false, errors.New("failed to claim project JWKS rotation")
+	}
+	return result.RowsAffected > 0, nil
+}
+
+func (r *repository) ClaimJWKSRetirement(ctx context.Context, ref string, now time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Model(&models.Project{}).
+		Where("reference = ? AND jwks_retire_at <= ?", ref, now).
+		Update("jwks_retire_at", nil)
+	if result.Error != nil {
+		slog.ErrorContext(ctx, "Failed to claim project JWKS retirement", "projectRef", ref, "error", result.Error)
+		return false, errors.New("failed to claim project JWKS retirement")
+	}
+	return result.RowsAffected > 0, nil
+}
+
+func (r *repository) UpdateJWKSRotatedAtByRef(ctx context.Context, ref string, rotatedAt time.Time) error {
+	result := r.db.WithContext(ctx).
+		Model(&models.Project{}).
+		Where("reference = ?", ref).
+		Update("jwks_rotated_at", rotatedAt)
+	if result.Error != nil {
+		slog.ErrorContext(ctx, "Failed to update project JWKS rotation time by reference", "projectRef", ref, "error", result.Error)
+		return errors.New("failed to update project JWKS rotation time by reference")
+	}
+	if result.RowsAffected == 0 {
+		return ErrProjectNotFound
+	}
+	return nil
+}
+
+func (r *repository) UpdateJWKSRetireAtByRef(ctx context.Context, ref string, retireAt time.Time) error {
+	result := r.db.WithContext(ctx).
+		Model(&models.Project{}).
+		Where("reference = ?", ref).
+		Update("jwks_retire_at", retireAt)
+	if result.Error != nil {
+		slog.ErrorContext(ctx, "Failed to update project JWKS retirement time by reference", "projectRef", ref, "error", result.Error)
+		return errors.New("failed to update project JWKS retirement time by reference")
+	}
+	if result.RowsAffected == 0 {
+		return ErrProjectNotFound
+	}
+	return nil
+}
+
+func (r *repository) ReleaseJWKSRotation(ctx context.Context, ref string) error {
+	result := r.db.WithContext(ctx).
+		Model(&models.Project{}).
+		Where("reference = ?", ref).
+		Update("jwks_retire_at", nil)
+	if result.Error != nil {
+		slog.ErrorContext(ctx, "Failed to release project JWKS rotation by reference", "projectRef", ref, "error", result.Error)
+		return errors.New("failed to release project JWKS rotation by reference")
+	}
+	return nil
+}
+
+func (r *repository) FindRestoreByRef(ctx context.Context, ref string) (*models.Project, error) {
+	var project models.Project
+	err := r.db.WithContext(ctx).
+		Select("restore_status", "restore_started_at", "restore_finished_at", "restore_error").
+		First(&project, "reference = ?", ref).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrProjectNotFound
+		}
+		slog.ErrorContext(ctx, "Failed to get project restore by reference", "projectRef", ref, "error", err)
+		return nil, errors.New("failed to get project restore by reference")
+	}
+	return &project, nil
+}
+
+func (r *repository) ClaimRestore(ctx context.Context, ref string, startedAt time.Time, staleBefore time.Time) (bool, error) {
+	// 以條件更新取得還原權，同一個專案同時只會有一個還原；超過 staleBefore 的還原視為已中斷 (例如 API 重新啟動)
+	result := r.db.WithContext(ctx).
+		Model(&models.Project{}).
+		Where("reference = ? AND (restore_status IS DISTINCT FROM ? OR restore_started_at < ?)", ref, models.RestoreStatusRunning, staleBefore).
+		Updates(map[string]any{
+			"restore_status":      models.RestoreStatusRunning,
+			"restore_started_at":  startedAt,
+			"restore_finished_at": nil,
+			"restore_error":       nil,
+		})
+	if result.Error != nil {
+		slog.ErrorContext(ctx, "Failed to claim project restore", "projectRef", ref, "error", result.Error)
+		return false, errors.New("failed to claim project restore")
+	}
+	return result.RowsAffected > 0, nil
+}
+
+func (r *repository) FinishRestore(ctx context.Context, ref string, finishedAt time.Time, restoreErr error) error {
+	values := map[string]any{
+		"restore_status":      models.RestoreStatusSucceeded,
+		"restore_finished_at": finishedAt,
+		"restore_error":       nil,
+	}
+	if restoreErr != nil {
+		values["restore_status"] = models.RestoreStatusFailed
+		values["restore_error"] = restoreErr.Error()
+	}
+
+	result := r.db.WithContext(ctx).
+		Model(&models.Project{}).
+		Where("reference = ?", ref).
+		Updates(values)
+	if result.Error != nil {
+		slog.ErrorContext(ctx, "Failed to update project restore by reference", "projectRef", ref, "error", result.Error)
+		return errors.New("failed to update project restore by reference")
+	}
+	if result.RowsAffected == 0 {
+		return ErrProjectNotFound
+	}
+	return nil
+}
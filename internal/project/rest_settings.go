@@ -0,0 +1,109 @@
+package project
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+
+	"baas-api/internal/dto"
+	"baas-api/internal/kubeproject"
+
+	"github.com/danielgtaylor/huma/v2"
+)
+
+func (s *service) GetProjectRESTSettings(ctx context.Context, in *dto.GetProjectRESTSettingsInput, userID string) (*dto.GetProjectRESTSettingsOutput, error) {
+	if _, err := s.findOwnedProject(ctx, in.Ref, userID); err != nil {
+		return nil, err
+	}
+
+	settings, err := s.kube.FindRESTAPISettings(ctx, in.Ref)
+	if err != nil {
+		return nil, err
+	}
+
+	out := &dto.GetProjectRESTSettingsOutput{}
+	out.Body.Settings = restSettingsToDTO(settings)
+	return out, nil
+}
+
+// UpdateProjectRESTSettings 更新專案的 PostgREST 設定。
+//
+// 公開的 schema 寫入資料庫後以 NOTIFY 重新載入，不需重啟；其他設定變更時 REST API 會 rolling update。
+func (s *service) UpdateProjectRESTSettings(ctx context.Context, jwt string, in *dto.UpdateProjectRESTSettingsInput, userID string) (*dto.GetProjectRESTSettingsOutput, error) {
+	ref := in.Body.Ref
+	if _, err := s.findOwnedProject(ctx, ref, userID); err != nil {
+		return nil, err
+	}
+
+	current, err := s.kube.FindRESTAPISettings(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	settings := *current
+	if in.Body.Schemas != nil {
+		settings.Schemas = in.Body.Schemas
+	}
+	if in.Body.MaxRows != nil {
+		settings.MaxRows = in.Body.MaxRows
+		if *in.Body.MaxRows == 0 {
+			settings.MaxRows = nil
+		}
+	}
+	if in.Body.PreRequest != nil {
+		settings.PreRequest = *in.Body.PreRequest
+	}
+	if in.Body.PoolSize != nil {
+		settings.PoolSize = *in.Body.PoolSize
+	}
+	if in.Body.PoolAcquisitionTimeout != nil {
+		settings.PoolAcquisitionTimeout = *in.Body.PoolAcquisitionTimeout
+	}
+	if in.Body.JWTAudience != nil {
+		settings.JWTAudience = *in.Body.JWTAudience
+	}
+	if in.Body.OpenAPIMode != nil {
+		settings.OpenAPIMode = *in.Body.OpenAPIMode
+	}
+
+	if err := kubeproject.ValidateRESTAPISettings(settings); err != nil {
+		return nil, huma.Error422UnprocessableEntity(err.Error())
+	}
+
+	if !slices.Equal(settings.Schemas, current.Schemas) {
+		missing, err := s.usersdb.GetMissingSchemas(ctx, jwt, ref, settings.Schemas)
+		if err != nil {
+			return nil, err
+		}
+		if len(missing) > 0 {
+			return nil, huma.Error422UnprocessableEntity("Schemas do not exist: " + strings.Join(missing, ", "))
+		}
+		if err := s.usersdb.UpdateRESTAPISchemas(ctx, jwt, ref, settings.Schemas); err != nil {
+			return nil, err
+		}
+	}
+
+	err = s.kube.ApplyRESTAPISettings(ctx, ref, settings)
+	if errors.Is(err, kubeproject.ErrRESTAPIDeploymentNotFound) {
+		return nil, huma.Error409Conflict("The REST API of the project is not deployed yet")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	out := &dto.GetProjectRESTSettingsOutput{}
+	out.Body.Settings = restSettingsToDTO(&settings)
+	return out, nil
+}
+
+func restSettingsToDTO(settings *kubeproject.RESTAPISettings) dto.ProjectRESTSettings {
+	return dto.ProjectRESTSettings{
+		Schemas:                settings.Schemas,
+		MaxRows:                settings.MaxRows,
+		PreRequest:             settings.PreRequest,
+		PoolSize:               settings.PoolSize,
+		PoolAcquisitionTimeout: settings.PoolAcquisitionTimeout,
+		JWTAudience:            settings.JWTAudience,
+		OpenAPIMode:            settings.OpenAPIMode,
+	}
+}
@@ -0,0 +1,132 @@
+package project
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"time"
+
+	"baas-api/internal/dto"
+	"baas-api/internal/encryption"
+	"baas-api/internal/middlewares"
+	"baas-api/internal/models"
+	"baas-api/internal/utils"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/samber/lo"
+)
+
+// maskedSecretPrefix 是 utils.MaskSecret 的前綴，前端送回遮蔽後的值時視為未修改
+const maskedSecretPrefix = "********"
+
+// encryptSecret 加密要存入平台資料庫的 secret。
+//
+// 未設定 active key 時以明文保存 (與加密功能加入前相同)，設定 key 後可用 cmd/reencrypt 加密既有資料。
+func (s *service) encryptSecret(ctx context.Context, secret string) (string, error) {
+	encrypted, err := s.encryption.Encrypt(secret)
+	if errors.Is(err, encryption.ErrNoActiveKey) {
+		slog.WarnContext(ctx, "No active encryption key configured, storing secret unencrypted")
+		return secret, nil
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to encrypt secret", "error", err)
+		return "", huma.Error500InternalServerError("Failed to encrypt secret")
+	}
+	return encrypted, nil
+}
+
+// decryptSecret 解密平台資料庫中的 secret，尚未加密的舊資料原樣回傳
+func (s *service) decryptSecret(ctx context.Context, secret string) (string, error) {
+	plaintext, err := s.encryption.Decrypt(secret)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to decrypt secret", "error", err)
+		return "", huma.Error500InternalServerError("Failed to decrypt secret")
+	}
+	return plaintext, nil
+}
+
+// normalizeAuthProviders 將前端送回的遮蔽值視為未修改 (ClientSecret 為 nil)
+func normalizeAuthProviders(providers map[string]dto.AuthProvider) {
+	for name, provider := range providers {
+		if provider.ClientSecret != nil && strings.HasPrefix(*provider.ClientSecret, maskedSecretPrefix) {
+			provider.ClientSecret = nil
+			providers[name] = provider
+		}
+	}
+}
+
+// encryptAuthProviders 回傳 ClientSecret 已加密的複本，用於寫入平台資料庫
+func (s *service) encryptAuthProviders(ctx context.Context, providers map[string]dto.AuthProvider) (map[string]dto.AuthProvider, error) {
+	encrypted := make(map[string]dto.AuthProvider, len(providers))
+	for name, provider := range providers {
+		if provider.ClientSecret != nil && *provider.ClientSecret != "" {
+			secret, err := s.encryptSecret(ctx, *provider.ClientSecret)
+			if err != nil {
+				return nil, err
+			}
+			provider.ClientSecret = &secret
+		}
+		encrypted[name] = provider
+	}
+	return encrypted, nil
+}
+
+// maskedClientSecret 回傳遮蔽後的 client secret
+func (s *service) maskedClientSecret(ctx context.Context, secret *string) (*string, error) {
+	if secret == nil || *secret == "" {
+		return secret, nil
+	}
+	plaintext, err := s.decryptSecret(ctx, *secret)
+	if err != nil {
+		return nil, err
+	}
+	return lo.ToPtr(utils.MaskSecret(plaintext)), nil
+}
+
+// findRevealableProject 回傳可取得明文 secret 的專案：
+// 除了專案擁有者之外，還需要通過平台資料庫的權限檢查，且 session 必須是最近登入的。
+func (s *service) findRevealableProject(ctx context.Context, jwt string, session *middlewares.Session, ref string) (*models.ProjectView, error) {
+	project, err := s.findOwnedProject(ctx, ref, session.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.pgrest.CheckProjectPermissionByRef(ctx, jwt, ref); err != nil {
+		return nil, err
+	}
+	maxAge := time.Duration(s.config.Encryption.RevealMaxSessionAgeMinutes) * time.Minute
+	if maxAge > 0 && time.Since(session.CreatedAt) > maxAge {
+		return nil, huma.Error403Forbidden("Please sign in again to reveal secrets")
+	}
+	return project, nil
+}
+
+// RevealProjectAuthSecret 回傳 OAuth provider 的明文 client secret，權限要求見 findRevealableProject
+func (s *service) RevealProjectAuthSecret(ctx context.Context, jwt string, session *middlewares.Session, in *dto.RevealProjectAuthSecretInput) (*dto.RevealProjectAuthSecretOutput, error) {
+	project, err := s.findRevealableProject(ctx, jwt, session, in.Body.Ref)
+	if err != nil {
+		return nil, err
+	}
+
+	providers, err := s.authSetting.FindAllOAuthProviders(ctx, project.ID)
+	if err != nil {
+		return nil, err
+	}
+	provider, ok := lo.Find(providers, func(p *models.ProjectAuthProvider) bool {
+		return p.Name == in.Body.Provider
+	})
+	if !ok || provider.ClientSecret == nil || *provider.ClientSecret == "" {
+		return nil, huma.Error404NotFound("Client secret not found")
+	}
+	secret, err := s.decryptSecret(ctx, *provider.ClientSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	slog.InfoContext(ctx, "Revealed OAuth client secret", "ref", in.Body.Ref, "provider", in.Body.Provider, "user", session.UserID)
+
+	out := &dto.RevealProjectAuthSecretOutput{}
+	out.Body.Provider = provider.Name
+	out.Body.ClientSecret = secret
+	return out, nil
+}
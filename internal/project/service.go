@@ -3,6 +3,7 @@ package project
 import (
 	"context"
 	"errors"
+	"fmt"
 	"io"
 	"log/slog"
 	"net/http"
@@ -12,10 +13,13 @@ import (
 	"baas-api/internal/authsetting"
 	"baas-api/internal/config"
 	"baas-api/internal/dto"
+	"baas-api/internal/encryption"
 	"baas-api/internal/kubeproject"
+	"baas-api/internal/middlewares"
 	"baas-api/internal/minio"
 	"baas-api/internal/models"
 	"baas-api/internal/pgrest"
+	"baas-api/internal/usersdb"
 	"baas-api/internal/utils"
 
 	"github.com/danielgtaylor/huma/v2"
@@ -27,6 +31,7 @@ import (
 type CreateProjectInternalOutput struct {
 	AuthSecret    string
 	JWKSPublicKey string
+	Plan          *config.PlanConfig
 }
 
 type Service interface {
@@ -35,7 +40,7 @@ type Service interface {
 	// Returns dto.CreateProjectOutput, project's auth-secret, error
 	CreateProject(ctx context.Context, in *dto.CreateProjectInput, jwt string, userID *string) (*dto.CreateProjectOutput, *CreateProjectInternalOutput, error)
 	// CreateProjectPostInstall performs post-installation steps after the project's cluster is read.
-	CreateProjectPostInstall(ctx context.Context, ref string, authSecret string, jwks string) error
+	CreateProjectPostInstall(ctx context.Context, ref string, internal *CreateProjectInternalOutput) error
 	GetProjectJWKS(ctx context.Context, ref string) (*string, error)
 	DeleteProjectByID(ctx context.Context, jwt string, in *dto.DeleteProjectByIDInput, userID string) (*dto.DeleteProjectByIDOutput, error)
 	PatchProjectSettings(ctx context.Context, jwt string, in *dto.UpdateProjectInput, userID string) error
@@ -44,14 +49,67 @@ type Service interface {
 	GetUserProjectStatusByRef(ctx context.Context, c chan any, ref, userID string) error
 	GetProjectSettings(ctx context.Context, in *dto.GetProjectSettingsInput, userID string) (*dto.GetProjectSettingsOutput, error)
 	ResetDatabasePassword(ctx context.Context, in *dto.ResetDatabasePasswordInput, userID string) (*dto.ResetDatabasePasswordOutput, error)
+	GetProjectWorkload(ctx context.Context, in *dto.GetProjectWorkloadInput, userID string) (*dto.GetProjectWorkloadOutput, error)
+	UpdateProjectWorkload(ctx context.Context, in *dto.UpdateProjectWorkloadInput, userID string) (*dto.GetProjectWorkloadOutput, error)
+	GetProjectDatabaseCluster(ctx context.Context, in *dto.GetProjectDatabaseClusterInput, userID string) (*dto.GetProjectDatabaseClusterOutput, error)
+	UpdateProjectDatabaseCluster(ctx context.Context, in *dto.UpdateProjectDatabaseClusterInput, userID string) (*dto.GetProjectDatabaseClusterOutput, error)
+	GetProjectPostgresParameters(ctx context.Context, jwt string, in *dto.GetProjectPostgresParametersInput, userID string) (*dto.GetProjectPostgresParametersOutput, error)
+	UpdateProjectPostgresParameters(ctx context.Context, jwt string, in *dto.UpdateProjectPostgresParametersInput, userID string) (*dto.UpdateProjectPostgresParametersOutput, error)
+	GetProjectPooler(ctx context.Context, in *dto.GetProjectPoolerInput, userID string) (*dto.GetProjectPoolerOutput, error)
+	UpdateProjectPooler(ctx context.Context, in *dto.UpdateProjectPoolerInput, userID string) (*dto.GetProjectPoolerOutput, error)
+	GetProjectRESTSettings(ctx context.Context, in *dto.GetProjectRESTSettingsInput, userID string) (*dto.GetProjectRESTSettingsOutput, error)
+	UpdateProjectRESTSettings(ctx context.Context, jwt string, in *dto.UpdateProjectRESTSettingsInput, userID string) (*dto.GetProjectRESTSettingsOutput, error)
+	ListProjectDatabaseExtensions(ctx context.Context, jwt string, in *dto.ListProjectDatabaseExtensionsInput, userID string) (*dto.ListProjectDatabaseExtensionsOutput, error)
+	UpdateProjectDatabaseExtension(ctx context.Context, jwt string, in *dto.UpdateProjectDatabaseExtensionInput, userID string) (*dto.UpdateProjectDatabaseExtensionOutput, error)
+	ListProjectBackups(ctx context.Context, in *dto.ListProjectBackupsInput, userID string) (*dto.ListProjectBackupsOutput, error)
+	CreateProjectBackup(ctx context.Context, in *dto.CreateProjectBackupInput, userID string) (*dto.CreateProjectBackupOutput, error)
+	// PrepareProjectRestore 驗證還原請求並回傳還原選項，實際還原由 RestoreProjectDatabase 執行
+	PrepareProjectRestore(ctx context.Context, in *dto.RestoreProjectBackupInput, userID string) (*kubeproject.ClusterRecoveryOption, error)
+	// StartProjectRestore 取得專案的還原鎖，已有進行中的還原時回傳 409
+	StartProjectRestore(ctx context.Context, ref string) error
+	// RestoreProjectDatabase 執行還原並記錄結果，呼叫前需先以 StartProjectRestore 取得還原鎖
+	RestoreProjectDatabase(ctx context.Context, ref string, opt *kubeproject.ClusterRecoveryOption) error
+	GetProjectRestoreStatus(ctx context.Context, in *dto.GetProjectRestoreStatusInput, userID string) (*dto.GetProjectRestoreStatusOutput, error)
+	GetProjectRecoveryWindow(ctx context.Context, in *dto.GetProjectRecoveryWindowInput, userID string) (*dto.GetProjectRecoveryWindowOutput, error)
+	// PrepareProjectPITR 驗證時間點還原請求，replace 模式交給 RestoreProjectDatabase，inspect 模式交給 CreateProjectPITRCluster
+	PrepareProjectPITR(ctx context.Context, in *dto.RestoreProjectToPointInTimeInput, userID string) (*kubeproject.ClusterRecoveryOption, error)
+	CreateProjectPITRCluster(ctx context.Context, ref string, opt *kubeproject.ClusterRecoveryOption) (string, error)
+	GetProjectPITRCluster(ctx context.Context, in *dto.GetProjectPITRClusterInput, userID string) (*dto.GetProjectPITRClusterOutput, error)
+	DeleteProjectPITRCluster(ctx context.Context, in *dto.DeleteProjectPITRClusterInput, userID string) (*dto.DeleteProjectPITRClusterOutput, error)
+	GetProjectMigration(ctx context.Context, in *dto.GetProjectMigrationInput, userID string) (*dto.GetProjectMigrationOutput, error)
+	GetProjectMigrationLogs(ctx context.Context, in *dto.GetProjectMigrationLogsInput, userID string) (*dto.GetProjectMigrationLogsOutput, error)
+	ListProjectUserMigrations(ctx context.Context, jwt string, in *dto.ListProjectUserMigrationsInput, userID string) (*dto.ListProjectUserMigrationsOutput, error)
+	UploadProjectUserMigration(ctx context.Context, jwt string, in *dto.UploadProjectUserMigrationInput, userID string) (*dto.UploadProjectUserMigrationOutput, error)
+	DeleteProjectUserMigration(ctx context.Context, jwt string, in *dto.DeleteProjectUserMigrationInput, userID string) (*dto.DeleteProjectUserMigrationOutput, error)
+	RunProjectUserMigrations(ctx context.Context, jwt string, ref string, action string, userID string) (*kubeproject.MigrationResult, error)
+	ListProjectCronJobs(ctx context.Context, in *dto.ListProjectCronJobsInput, userID string) (*dto.ListProjectCronJobsOutput, error)
+	PutProjectCronJob(ctx context.Context, jwt string, in *dto.PutProjectCronJobInput, userID string) (*dto.PutProjectCronJobOutput, error)
+	DeleteProjectCronJob(ctx context.Context, in *dto.DeleteProjectCronJobInput, userID string) (*dto.DeleteProjectCronJobOutput, error)
+	ListProjectCronJobRuns(ctx context.Context, in *dto.ListProjectCronJobRunsInput, userID string) (*dto.ListProjectCronJobRunsOutput, error)
+	TriggerProjectCronJob(ctx context.Context, in *dto.TriggerProjectCronJobInput, userID string) (*dto.TriggerProjectCronJobOutput, error)
+	GetProjectCronJobRun(ctx context.Context, in *dto.GetProjectCronJobRunInput, userID string) (*dto.GetProjectCronJobRunOutput, error)
+	GetProjectRealtime(ctx context.Context, jwt string, in *dto.GetProjectRealtimeInput, userID string) (*dto.GetProjectRealtimeOutput, error)
+	UpdateProjectRealtime(ctx context.Context, jwt string, in *dto.UpdateProjectRealtimeInput, userID string) (*dto.GetProjectRealtimeOutput, error)
+	GetProjectJWKSRotation(ctx context.Context, in *dto.GetProjectJWKSRotationInput, userID string) (*dto.GetProjectJWKSRotationOutput, error)
+	PrepareProjectJWKSRotation(ctx context.Context, in *dto.RotateProjectJWKSInput, userID string) (*dto.RotateProjectJWKSOutput, error)
+	RotateProjectJWKS(ctx context.Context, ref string) error
+	RetireProjectJWKS(ctx context.Context, ref string) error
+	GetProjectUserMigrationRun(ctx context.Context, in *dto.GetProjectUserMigrationRunInput, userID string) (*dto.GetProjectUserMigrationRunOutput, error)
+	StreamProjectLogs(ctx context.Context, c chan any, in *dto.StreamProjectLogsInput, userID string) error
+	ListProjectEvents(ctx context.Context, in *dto.ListProjectEventsInput, userID string) (*dto.ListProjectEventsOutput, error)
+	RevealProjectAuthSecret(ctx context.Context, jwt string, session *middlewares.Session, in *dto.RevealProjectAuthSecretInput) (*dto.RevealProjectAuthSecretOutput, error)
+	GetProjectDatabaseConnection(ctx context.Context, in *dto.GetProjectDatabaseConnectionInput, userID string) (*dto.GetProjectDatabaseConnectionOutput, error)
+	RevealProjectDatabaseConnection(ctx context.Context, jwt string, session *middlewares.Session, in *dto.RevealProjectDatabaseConnectionInput) (*dto.GetProjectDatabaseConnectionOutput, error)
 }
 
 type service struct {
 	config *config.Config
 	// Services
-	kube   kubeproject.Service
-	pgrest pgrest.Service
-	minio  minio.Service
+	kube       kubeproject.Service
+	pgrest     pgrest.Service
+	minio      minio.Service
+	usersdb    usersdb.Service
+	encryption encryption.Service
 	// Repositories
 	// entity             repo.EntityRepositoryInterface             `do:""`
 	project     Repository
@@ -66,6 +124,8 @@ func NewService(i do.Injector) (*service, error) {
 		kube:        do.MustInvokeAs[kubeproject.Service](i),
 		pgrest:      do.MustInvokeAs[pgrest.Service](i),
 		minio:       do.MustInvokeAs[minio.Service](i),
+		usersdb:     do.MustInvokeAs[usersdb.Service](i),
+		encryption:  do.MustInvokeAs[encryption.Service](i),
 		project:     do.MustInvokeAs[Repository](i),
 		authSetting: do.MustInvokeAs[authsetting.Repository](i),
 	}
@@ -87,11 +147,56 @@ func (s *service) CreateProject(ctx context.Context, in *dto.CreateProjectInput,
 		}
 	}()
 
+	plan, err := s.selectablePlan(in.Body.Plan, "")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// 選擇專案所在的叢集，之後的 kubeproject 呼叫依記錄的叢集轉發
+	cluster, err := s.kube.PlaceProject(ctx, in.Body.Region)
+	switch {
+	case errors.Is(err, kubeproject.ErrNoClusterInRegion):
+		return nil, nil, huma.Error422UnprocessableEntity("No cluster in region: " + in.Body.Region)
+	case errors.Is(err, kubeproject.ErrNoClusterAvailable):
+		return nil, nil, huma.Error503ServiceUnavailable("No cluster has capacity for a new project")
+	case err != nil:
+		return nil, nil, huma.Error500InternalServerError("Failed to place project")
+	}
+
 	///// Create database records /////
 	project, err := s.pgrest.CreateProject(ctx, jwt, in.Body.Name, *in.Body.Description)
 	if err != nil {
 		return nil, nil, err
 	}
+	cleanupFuncs = append(cleanupFuncs, func() {
+		_, _ = s.pgrest.DeleteProject(ctx, jwt, project.ID)
+	})
+	// 清除時的 kubeproject 呼叫會快取專案的叢集，ref 之後可能由其他專案使用
+	cleanupFuncs = append(cleanupFuncs, func() {
+		s.kube.ForgetProjectCluster(project.Ref)
+	})
+
+	// 單一叢集時不記錄，之後加入其他叢集時以 config.Kube.DefaultCluster 對應
+	if cluster != "" {
+		if err := s.project.UpdateClusterByRef(ctx, project.Ref, cluster); err != nil {
+			return nil, nil, huma.Error500InternalServerError("Failed to record project cluster")
+		}
+		// 記錄前已讀取的叢集 (未記錄時為預設叢集) 不再正確
+		s.kube.ForgetProjectCluster(project.Ref)
+	}
+
+	if err := s.project.UpdatePlanByRef(ctx, project.Ref, s.config.Kube.PlanName(in.Body.Plan)); err != nil {
+		return nil, nil, huma.Error500InternalServerError("Failed to record project plan")
+	}
+
+	// 資料庫預設產生的 auth secret 是明文，加密後寫回；Deployment 使用明文的 project.AuthSecret
+	encryptedAuthSecret, err := s.encryptSecret(ctx, project.AuthSecret)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := s.authSetting.UpdateSecret(ctx, project.ID, encryptedAuthSecret); err != nil {
+		return nil, nil, err
+	}
 
 	////// Create S3 resources /////
 	err = s.minio.CreateBucket(ctx, project.S3Bucket)
@@ -110,7 +215,7 @@ func (s *service) CreateProject(ctx context.Context, in *dto.CreateProjectInput,
 		_ = s.minio.DeleteBucketUser(ctx, project.S3AccessKeyID)
 	})
 
-	err = s.minio.CreateBucketPolicy(ctx, project.Ref, project.S3Bucket)
+	err = s.minio.CreateBucketPolicy(ctx, project.S3AccessKeyID, project.S3Bucket)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -120,6 +225,14 @@ func (s *service) CreateProject(ctx context.Context, in *dto.CreateProjectInput,
 
 	///// Create Kubernetes resources /////
 	ref := project.Ref
+	err = s.kube.EnsureProjectNamespace(ctx, ref)
+	if err != nil {
+		return nil, nil, err
+	}
+	cleanupFuncs = append(cleanupFuncs, func() {
+		_ = s.kube.DeleteProjectNamespace(ctx, ref)
+	})
+
 	jwkID, err := uuid.NewV7()
 	if err != nil {
 		return nil, nil, errors.New("failed to generate JWK ID")
@@ -151,7 +264,25 @@ func (s *service) CreateProject(ctx context.Context, in *dto.CreateProjectInput,
 		_ = s.kube.DeleteDatabaseRoleSecret(ctx, ref, kubeproject.RoleAuthenticator)
 	})
 
-	err = s.kube.CreateCluster(ctx, ref, in.Body.StorageSize)
+	if s.config.Kube.Project.Backup.Enabled {
+		err = s.minio.EnsureBackupBucket(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+		accessKeyID, secretAccessKey, err := s.minio.CreateBackupUser(ctx, ref)
+		if err != nil {
+			return nil, nil, err
+		}
+		cleanupFuncs = append(cleanupFuncs, func() {
+			_ = s.minio.DeleteBackupUser(ctx, ref)
+		})
+		err = s.kube.CreateBackupCredentialsSecret(ctx, ref, accessKeyID, secretAccessKey)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	err = s.kube.CreateCluster(ctx, ref, &kubeproject.ClusterOption{StorageSize: in.Body.StorageSize})
 	if err != nil {
 		return nil, nil, err
 	}
@@ -159,6 +290,11 @@ func (s *service) CreateProject(ctx context.Context, in *dto.CreateProjectInput,
 		_ = s.kube.DeleteCluster(ctx, ref)
 	})
 
+	err = s.kube.ApplyDatabaseCluster(ctx, ref, plan.Database)
+	if err != nil {
+		return nil, nil, err
+	}
+
 	err = s.kube.CreateDatabase(ctx, ref)
 	if err != nil {
 		return nil, nil, err
@@ -175,20 +311,33 @@ func (s *service) CreateProject(ctx context.Context, in *dto.CreateProjectInput,
 	internalOut := &CreateProjectInternalOutput{}
 	internalOut.AuthSecret = project.AuthSecret
 	internalOut.JWKSPublicKey = publicKey
+	internalOut.Plan = plan
 
 	return out, internalOut, nil
 }
 
-func (s *service) CreateProjectPostInstall(ctx context.Context, ref string, authSecret string, jwks string) error {
+func (s *service) CreateProjectPostInstall(ctx context.Context, ref string, internal *CreateProjectInternalOutput) error {
 	var err error
 	err = s.kube.CreateMigrationJob(ctx, ref)
 	if err != nil {
 		return err
 	}
 
+	// 等待 migration 結束並保存 dbmate 的輸出，失敗時依設定中止後續步驟
+	migration, err := s.kube.WaitMigrationJob(ctx, ref)
+	if err != nil {
+		return err
+	}
+	if migration.Status == kubeproject.MigrationFailed {
+		slog.ErrorContext(ctx, "Project migration failed", "ref", ref, "message", migration.Message)
+		if s.config.Kube.Project.Migration.BlockOnFailure {
+			return fmt.Errorf("%w: %s", kubeproject.ErrMigrationFailed, migration.Message)
+		}
+	}
+
 	err = s.kube.CreateAuthAPIDeployment(ctx, ref,
 		&kubeproject.APIDeploymentOption{
-			BetterAuthSecret: &authSecret,
+			BetterAuthSecret: &internal.AuthSecret,
 			TrustedOrigins:   []string{"*"},
 			AuthProviders: map[string]dto.AuthProvider{
 				"email": {
@@ -201,7 +350,17 @@ func (s *service) CreateProjectPostInstall(ctx context.Context, ref string, auth
 		return err
 	}
 
-	err = s.kube.CreateRESTAPIDeployment(ctx, ref, jwks)
+	err = s.kube.CreateRESTAPIDeployment(ctx, ref, internal.JWKSPublicKey)
+	if err != nil {
+		return err
+	}
+
+	err = s.kube.ApplyAPIWorkload(ctx, ref, kubeproject.AuthAPIComponent, internal.Plan.AuthAPI)
+	if err != nil {
+		return err
+	}
+
+	err = s.kube.ApplyAPIWorkload(ctx, ref, kubeproject.RestAPIComponent, internal.Plan.RESTAPI)
 	if err != nil {
 		return err
 	}
@@ -226,6 +385,11 @@ func (s *service) CreateProjectPostInstall(ctx context.Context, ref string, auth
 		return err
 	}
 
+	err = s.kube.CreateScheduledBackup(ctx, ref)
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -283,52 +447,16 @@ func (s *service) DeleteProjectByID(ctx context.Context, jwt string, in *dto.Del
 		errors = append(errors, err)
 	}
 
-	///// Delete Kubernetes resources /////
-	err = s.kube.DeleteCluster(ctx, deleted.Ref)
-	if err != nil {
-		errors = append(errors, err)
-	}
-
-	err = s.kube.DeleteDatabase(ctx, deleted.Ref)
-	if err != nil {
-		errors = append(errors, err)
-	}
-
-	err = s.kube.DeleteIngressRouteTCP(ctx, deleted.Ref)
-	if err != nil {
-		errors = append(errors, err)
-	}
-
-	err = s.kube.DeleteIngressRoute(ctx, deleted.Ref)
-	if err != nil {
-		errors = append(errors, err)
-	}
-
-	err = s.kube.DeleteAuthAPIDeployment(ctx, deleted.Ref)
-	if err != nil {
-		errors = append(errors, err)
-	}
-
-	err = s.kube.DeleteRESTAPIDeployment(ctx, deleted.Ref)
-	if err != nil {
-		errors = append(errors, err)
-	}
-
-	err = s.kube.DeleteAuthAPIService(ctx, deleted.Ref)
-	if err != nil {
-		errors = append(errors, err)
-	}
-	err = s.kube.DeleteRESTAPIService(ctx, deleted.Ref)
-	if err != nil {
-		errors = append(errors, err)
-	}
-
-	err = s.kube.DeleteDatabaseRoleSecret(ctx, deleted.Ref, kubeproject.RoleAuthenticator)
-	if err != nil {
-		errors = append(errors, err)
+	// 已封存的備份保留在 backup bucket，只移除存取用的使用者
+	if s.config.Kube.Project.Backup.Enabled {
+		err = s.minio.DeleteBackupUser(ctx, deleted.Ref)
+		if err != nil {
+			errors = append(errors, err)
+		}
 	}
 
-	err = s.kube.DeleteJWKSConfigMap(ctx, deleted.Ref)
+	///// Delete Kubernetes resources /////
+	err = s.kube.DeleteAllForProject(ctx, deleted.Ref)
 	if err != nil {
 		errors = append(errors, err)
 	}
@@ -357,42 +485,65 @@ func (s *service) PatchProjectSettings(ctx context.Context, jwt string, in *dto.
 
 	slog.Debug("Patch Project", "input", in.Body)
 
-	needPatchDeployment := false
-	opt := &kubeproject.APIDeploymentOption{}
-	if in.Body.TrustedOrigins != nil {
-		needPatchDeployment = true
-		opt.TrustedOrigins = in.Body.TrustedOrigins
-	}
-
-	if in.Body.ProxyURL != nil {
-		needPatchDeployment = true
-		opt.ProxyURL = in.Body.ProxyURL
-	}
-
+	// Update Database Auth Providers
 	if in.Body.Auth != nil {
-		needPatchDeployment = true
-		opt.AuthProviders = in.Body.Auth
-	}
-
-	if needPatchDeployment {
-		err = s.kube.PatchAuthAPIDeployment(ctx, updated.Ref, opt)
+		normalizeAuthProviders(in.Body.Auth)
+		providers, err := s.encryptAuthProviders(ctx, in.Body.Auth)
 		if err != nil {
 			return err
 		}
-	}
-
-	// Update Database Auth Providers
-	if in.Body.Auth != nil {
-		err := s.pgrest.CreateOrUpdateAuthProvider(ctx, jwt, pgrest.CreateOrUpdateAuthProviderPayload{
+		err = s.pgrest.CreateOrUpdateAuthProvider(ctx, jwt, pgrest.CreateOrUpdateAuthProviderPayload{
 			ProjectID: in.Body.ID,
-			Providers: in.Body.Auth,
+			Providers: providers,
 		})
 		if err != nil {
 			return err
 		}
 	}
 
-	return nil
+	if in.Body.TrustedOrigins == nil && in.Body.ProxyURL == nil && in.Body.Auth == nil {
+		return nil
+	}
+
+	// Deployment 以平台資料庫中的完整設定套用，已移除的 provider 會從 Deployment 與 API Secret 移除
+	opt, err := s.authDeploymentOption(ctx, in.Body.ID)
+	if err != nil {
+		return err
+	}
+	return s.kube.PatchAuthAPIDeployment(ctx, updated.Ref, opt)
+}
+
+// authDeploymentOption 讀取平台資料庫中專案的 auth 設定與所有 provider，client secret 解密後回傳
+func (s *service) authDeploymentOption(ctx context.Context, projectID string) (*kubeproject.APIDeploymentOption, error) {
+	settings, err := s.authSetting.FindByProjectID(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	providers, err := s.authSetting.FindAllOAuthProviders(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	opt := &kubeproject.APIDeploymentOption{
+		TrustedOrigins: settings.TrustedOrigins,
+		ProxyURL:       settings.ProxyURL,
+		AuthProviders:  make(map[string]dto.AuthProvider, len(providers)),
+	}
+	for _, provider := range providers {
+		authProvider := dto.AuthProvider{
+			Enabled:  provider.Enabled,
+			ClientID: provider.ClientID,
+		}
+		if provider.ClientSecret != nil && *provider.ClientSecret != "" {
+			secret, err := s.decryptSecret(ctx, *provider.ClientSecret)
+			if err != nil {
+				return nil, err
+			}
+			authProvider.ClientSecret = &secret
+		}
+		opt.AuthProviders[provider.Name] = authProvider
+	}
+	return opt, nil
 }
 
 func (s *service) GetUsersProjects(ctx context.Context, userID string) ([]*models.ProjectView, error) {
@@ -416,42 +567,67 @@ func (s *service) GetUserProjectStatusByRef(ctx context.Context, c chan any, ref
 		return huma.Error400BadRequest("Project already initialized")
 	}
 
-	ticker := time.NewTicker(1 * time.Second)
-	defer ticker.Stop()
+	// 狀態變更由 kubeproject 共用的 informer 通知，不再對每個連線輪詢 API server
+	changes, err := s.kube.WatchProject(ctx, ref)
+	if err != nil {
+		return err
+	}
 
+	totalStep := 4
+	var lastEvent *dto.ProjectStatusEvent
+	var eventsSince time.Time
 	for {
 		select {
 		case <-ctx.Done():
-			c <- dto.ProjectStatusEvent{Message: "Operation cancelled by client.", Step: -1, TotalStep: 4}
+			c <- dto.ProjectStatusEvent{Message: "Operation cancelled by client.", Step: -1, TotalStep: totalStep}
 			return ctx.Err()
-		case <-ticker.C:
-			totalStep := 4
+		case _, ok := <-changes:
+			if !ok {
+				c <- dto.ProjectStatusEvent{Message: "Operation cancelled by client.", Step: -1, TotalStep: totalStep}
+				return ctx.Err()
+			}
+			// image 無法拉取、PVC 無法 bind 等問題只會出現在 Kubernetes Event 中
+			eventsSince, err = s.sendProjectEvents(ctx, c, ref, eventsSince)
+			if err != nil {
+				return err
+			}
+
 			status, err := s.kube.FindClusterStatus(ctx, ref)
 			if err != nil {
 				return err
 			}
+
+			var event dto.ProjectStatusEvent
 			if status == nil {
-				c <- dto.ProjectStatusEvent{Message: "Postgres cluster is not ready yet.", Step: 0, TotalStep: totalStep}
-				continue
+				event = dto.ProjectStatusEvent{Message: "Postgres cluster is not ready yet.", Step: 0, TotalStep: totalStep}
+			} else {
+				switch *status {
+				case "Initializing Postgres cluster":
+					event = dto.ProjectStatusEvent{Message: "Postgres cluster is initializing...", Step: 1, TotalStep: totalStep}
+				case "Setting up primary":
+					event = dto.ProjectStatusEvent{Message: "Postgres cluster is setting up primary...", Step: 2, TotalStep: totalStep}
+				case "Waiting for the instances to become active":
+					event = dto.ProjectStatusEvent{Message: "Postgres Waiting for the instances to become active", Step: 3, TotalStep: totalStep}
+				case kubeproject.ClusterHealthyPhase:
+					s.project.UpdateByRef(ctx, ref, &models.Project{
+						InitializedAt: lo.ToPtr(time.Now()),
+					}, models.Object{
+						UpdatedAt: time.Now(),
+					})
+					c <- dto.ProjectStatusEvent{Message: "Postgres cluster is ready.", Step: 4, TotalStep: totalStep}
+					return nil
+				default:
+					slog.Warn("Unknown Postgres cluster status", "status", *status)
+					continue
+				}
 			}
-			switch *status {
-			case "Initializing Postgres cluster":
-				c <- dto.ProjectStatusEvent{Message: "Postgres cluster is initializing...", Step: 1, TotalStep: totalStep}
-			case "Setting up primary":
-				c <- dto.ProjectStatusEvent{Message: "Postgres cluster is setting up primary...", Step: 2, TotalStep: totalStep}
-			case "Waiting for the instances to become active":
-				c <- dto.ProjectStatusEvent{Message: "Postgres Waiting for the instances to become active", Step: 3, TotalStep: totalStep}
-			case "Cluster in healthy state":
-				s.project.UpdateByRef(ctx, ref, &models.Project{
-					InitializedAt: lo.ToPtr(time.Now()),
-				}, models.Object{
-					UpdatedAt: time.Now(),
-				})
-				c <- dto.ProjectStatusEvent{Message: "Postgres cluster is ready.", Step: 4, TotalStep: totalStep}
-				return nil
-			default:
-				slog.Warn("Unknown Postgres cluster status", "status", *status)
+
+			// Deployment 與 Job 的變更也會觸發通知，只在狀態改變時送出事件
+			if lastEvent != nil && *lastEvent == event {
+				continue
 			}
+			lastEvent = &event
+			c <- event
 		}
 	}
 }
@@ -497,10 +673,15 @@ func (s *service) GetProjectSettings(ctx context.Context, in *dto.GetProjectSett
 	out.Body.UpdatedAt = project.UpdatedAt.Format(time.RFC3339)
 
 	for _, provider := range oauthProviders {
+		// 明文需透過 RevealProjectAuthSecret 取得
+		clientSecret, err := s.maskedClientSecret(ctx, provider.ClientSecret)
+		if err != nil {
+			return nil, err
+		}
 		providerInfo := &dto.ProjectAuthProviderInfo{
 			Enabled:      provider.Enabled,
 			ClientID:     provider.ClientID,
-			ClientSecret: provider.ClientSecret,
+			ClientSecret: clientSecret,
 		}
 
 		switch provider.Name {
@@ -0,0 +1,241 @@
+package project
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"slices"
+	"strings"
+
+	"baas-api/internal/dto"
+	"baas-api/internal/kubeproject"
+
+	"github.com/danielgtaylor/huma/v2"
+)
+
+// userMigrationFilename 是 dbmate 的 migration 檔名 (<version>_<name>.sql)
+var userMigrationFilename = regexp.MustCompile(`^([0-9]+)_([a-z0-9_]+)\.sql$`)
+
+const (
+	migrateUpMarker   = "-- migrate:up"
+	migrateDownMarker = "-- migrate:down"
+)
+
+// userMigrationFile 是解析檔名後的 migration 檔案
+type userMigrationFile struct {
+	version  string
+	name     string
+	filename string
+	content  string
+}
+
+func parseUserMigrationFiles(files map[string]string) map[string]userMigrationFile {
+	parsed := map[string]userMigrationFile{}
+	for filename, content := range files {
+		match := userMigrationFilename.FindStringSubmatch(filename)
+		if match == nil {
+			continue
+		}
+		parsed[match[1]] = userMigrationFile{version: match[1], name: match[2], filename: filename, content: content}
+	}
+	return parsed
+}
+
+func (f userMigrationFile) hasDown() bool {
+	return strings.Contains(f.content, migrateDownMarker)
+}
+
+func (s *service) ListProjectUserMigrations(ctx context.Context, jwt string, in *dto.ListProjectUserMigrationsInput, userID string) (*dto.ListProjectUserMigrationsOutput, error) {
+	if _, err := s.findOwnedProject(ctx, in.Ref, userID); err != nil {
+		return nil, err
+	}
+
+	files, err := s.kube.ListUserMigrationFiles(ctx, in.Ref)
+	if err != nil {
+		return nil, err
+	}
+	applied, err := s.usersdb.GetAppliedMigrations(ctx, jwt, in.Ref)
+	if err != nil {
+		return nil, err
+	}
+
+	parsed := parseUserMigrationFiles(files)
+	migrations := map[string]dto.ProjectUserMigration{}
+	for version, file := range parsed {
+		migrations[version] = dto.ProjectUserMigration{
+			Version:  version,
+			Name:     file.name,
+			Filename: file.filename,
+			HasDown:  file.hasDown(),
+		}
+	}
+	for _, version := range applied {
+		migration := migrations[version]
+		migration.Version = version
+		migration.Applied = true
+		migrations[version] = migration
+	}
+
+	out := &dto.ListProjectUserMigrationsOutput{}
+	out.Body.Migrations = make([]dto.ProjectUserMigration, 0, len(migrations))
+	for _, migration := range migrations {
+		out.Body.Migrations = append(out.Body.Migrations, migration)
+	}
+	// 版本為數字，長度不同時以長度比較
+	slices.SortFunc(out.Body.Migrations, func(a, b dto.ProjectUserMigration) int {
+		if len(a.Version) != len(b.Version) {
+			return len(a.Version) - len(b.Version)
+		}
+		return strings.Compare(a.Version, b.Version)
+	})
+
+	result, err := s.kube.FindUserMigrationResult(ctx, in.Ref)
+	switch {
+	case err == nil:
+		lastRun := migrationToDTO(result)
+		out.Body.LastRun = &lastRun
+	case !errors.Is(err, kubeproject.ErrMigrationNotFound):
+		return nil, err
+	}
+	return out, nil
+}
+
+func (s *service) UploadProjectUserMigration(ctx context.Context, jwt string, in *dto.UploadProjectUserMigrationInput, userID string) (*dto.UploadProjectUserMigrationOutput, error) {
+	ref := in.Body.Ref
+	if _, err := s.findOwnedProject(ctx, ref, userID); err != nil {
+		return nil, err
+	}
+
+	match := userMigrationFilename.FindStringSubmatch(in.Body.Filename)
+	if match == nil {
+		return nil, huma.Error422UnprocessableEntity("Filename must be <version>_<name>.sql")
+	}
+	if !strings.Contains(in.Body.Content, migrateUpMarker) {
+		return nil, huma.Error422UnprocessableEntity("Migration must contain a " + migrateUpMarker + " section")
+	}
+	file := userMigrationFile{version: match[1], name: match[2], filename: in.Body.Filename, content: in.Body.Content}
+
+	files, err := s.kube.ListUserMigrationFiles(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	if existing, ok := parseUserMigrationFiles(files)[file.version]; ok && existing.filename != file.filename {
+		return nil, huma.Error409Conflict("Version " + file.version + " is already used by " + existing.filename)
+	}
+	applied, err := s.usersdb.GetAppliedMigrations(ctx, jwt, ref)
+	if err != nil {
+		return nil, err
+	}
+	if slices.Contains(applied, file.version) {
+		return nil, huma.Error409Conflict("Version " + file.version + " is already applied")
+	}
+
+	err = s.kube.PutUserMigrationFile(ctx, ref, file.filename, file.content)
+	if errors.Is(err, kubeproject.ErrUserMigrationsTooLarge) {
+		return nil, huma.Error422UnprocessableEntity("Migrations exceed the size limit")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	out := &dto.UploadProjectUserMigrationOutput{}
+	out.Body.Migration = dto.ProjectUserMigration{
+		Version:  file.version,
+		Name:     file.name,
+		Filename: file.filename,
+		HasDown:  file.hasDown(),
+	}
+	return out, nil
+}
+
+func (s *service) DeleteProjectUserMigration(ctx context.Context, jwt string, in *dto.DeleteProjectUserMigrationInput, userID string) (*dto.DeleteProjectUserMigrationOutput, error) {
+	if _, err := s.findOwnedProject(ctx, in.Ref, userID); err != nil {
+		return nil, err
+	}
+
+	files, err := s.kube.ListUserMigrationFiles(ctx, in.Ref)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := files[in.Filename]; !ok {
+		return nil, huma.Error404NotFound("Migration not found")
+	}
+	// 已套用的版本需先 rollback，否則 rollback 時找不到 down migration
+	if match := userMigrationFilename.FindStringSubmatch(in.Filename); match != nil {
+		applied, err := s.usersdb.GetAppliedMigrations(ctx, jwt, in.Ref)
+		if err != nil {
+			return nil, err
+		}
+		if slices.Contains(applied, match[1]) {
+			return nil, huma.Error409Conflict("Migration is applied, roll it back before removing it")
+		}
+	}
+
+	if err := s.kube.DeleteUserMigrationFile(ctx, in.Ref, in.Filename); err != nil {
+		return nil, err
+	}
+
+	out := &dto.DeleteProjectUserMigrationOutput{}
+	out.Body.Success = true
+	return out, nil
+}
+
+// RunProjectUserMigrations 建立執行 dbmate 的 Job，Job 由呼叫端在背景等待 (kubeproject.Service.WaitUserMigrationJob)
+func (s *service) RunProjectUserMigrations(ctx context.Context, jwt string, ref string, action string, userID string) (*kubeproject.MigrationResult, error) {
+	if _, err := s.findOwnedProject(ctx, ref, userID); err != nil {
+		return nil, err
+	}
+
+	// dbmate rollback 回滾最新的版本，檔案缺少 down migration 時會失敗或什麼都不做
+	if action == kubeproject.MigrationActionRollback {
+		applied, err := s.usersdb.GetAppliedMigrations(ctx, jwt, ref)
+		if err != nil {
+			return nil, err
+		}
+		if len(applied) == 0 {
+			return nil, huma.Error409Conflict("No migration has been applied")
+		}
+		files, err := s.kube.ListUserMigrationFiles(ctx, ref)
+		if err != nil {
+			return nil, err
+		}
+		latest := applied[len(applied)-1]
+		file, ok := parseUserMigrationFiles(files)[latest]
+		if !ok {
+			return nil, huma.Error409Conflict("The file of the latest applied version " + latest + " was removed")
+		}
+		if !file.hasDown() {
+			return nil, huma.Error409Conflict("Migration " + file.filename + " has no " + migrateDownMarker + " section")
+		}
+	}
+
+	result, err := s.kube.RunUserMigrations(ctx, ref, action)
+	switch {
+	case errors.Is(err, kubeproject.ErrNoUserMigrations):
+		return nil, huma.Error409Conflict("No migration has been uploaded")
+	case errors.Is(err, kubeproject.ErrMigrationRunning):
+		return nil, huma.Error409Conflict("A migration is already running")
+	case err != nil:
+		return nil, err
+	}
+	return result, nil
+}
+
+func (s *service) GetProjectUserMigrationRun(ctx context.Context, in *dto.GetProjectUserMigrationRunInput, userID string) (*dto.GetProjectUserMigrationRunOutput, error) {
+	if _, err := s.findOwnedProject(ctx, in.Ref, userID); err != nil {
+		return nil, err
+	}
+
+	result, err := s.kube.FindUserMigrationLogs(ctx, in.Ref)
+	if errors.Is(err, kubeproject.ErrMigrationNotFound) {
+		return nil, huma.Error404NotFound("Migration not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	out := &dto.GetProjectUserMigrationRunOutput{}
+	out.Body.Migration = migrationToDTO(result)
+	out.Body.Logs = result.Logs
+	return out, nil
+}
@@ -0,0 +1,137 @@
+package project
+
+import (
+	"context"
+	"log/slog"
+
+	"baas-api/internal/config"
+	"baas-api/internal/dto"
+	"baas-api/internal/kubeproject"
+	"baas-api/internal/models"
+
+	"github.com/danielgtaylor/huma/v2"
+)
+
+// findOwnedProject 取得專案並確認使用者為擁有者
+func (s *service) findOwnedProject(ctx context.Context, ref, userID string) (*models.ProjectView, error) {
+	project, err := s.project.FindByRef(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	if project.OwnerID != userID {
+		return nil, huma.Error401Unauthorized("Unauthorized")
+	}
+	return project, nil
+}
+
+func (s *service) GetProjectWorkload(ctx context.Context, in *dto.GetProjectWorkloadInput, userID string) (*dto.GetProjectWorkloadOutput, error) {
+	if _, err := s.findOwnedProject(ctx, in.Ref, userID); err != nil {
+		return nil, err
+	}
+
+	workload, err := s.kube.FindAPIWorkload(ctx, in.Ref, in.Component)
+	if err != nil {
+		return nil, err
+	}
+
+	out := &dto.GetProjectWorkloadOutput{}
+	out.Body.Component = in.Component
+	out.Body.Workload = workloadToDTO(workload)
+	return out, nil
+}
+
+// UpdateProjectWorkload 以目前生效的設定 (或指定方案的預設值) 為基礎，覆寫有提供的區塊後套用
+func (s *service) UpdateProjectWorkload(ctx context.Context, in *dto.UpdateProjectWorkloadInput, userID string) (*dto.GetProjectWorkloadOutput, error) {
+	ref := in.Body.Ref
+	component := in.Body.Component
+	if _, err := s.findOwnedProject(ctx, ref, userID); err != nil {
+		return nil, err
+	}
+	projectPlan, projectPlanName, err := s.projectPlan(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	var workload config.WorkloadConfig
+	if in.Body.Plan != nil {
+		plan, err := s.selectablePlan(*in.Body.Plan, projectPlanName)
+		if err != nil {
+			return nil, err
+		}
+		workload = plan.AuthAPI
+		if component == kubeproject.RestAPIComponent {
+			workload = plan.RESTAPI
+		}
+	} else {
+		current, err := s.kube.FindAPIWorkload(ctx, ref, component)
+		if err != nil {
+			return nil, err
+		}
+		workload = *current
+	}
+
+	if in.Body.Replicas != nil {
+		workload.Replicas = *in.Body.Replicas
+	}
+	if in.Body.Resources != nil {
+		workload.Resources = config.WorkloadResources(*in.Body.Resources)
+	}
+	if in.Body.Autoscaling != nil {
+		workload.Autoscaling = config.WorkloadAutoscaling(*in.Body.Autoscaling)
+	}
+	if in.Body.PodDisruptionBudget != nil {
+		workload.PodDisruptionBudget = config.WorkloadPodDisruptionBudget(*in.Body.PodDisruptionBudget)
+	}
+
+	if err := kubeproject.ValidateWorkload(workload); err != nil {
+		return nil, huma.Error422UnprocessableEntity(err.Error())
+	}
+	if err := kubeproject.ValidateWorkloadLimits(workload, projectPlan.Limits); err != nil {
+		return nil, huma.Error403Forbidden("Exceeds the " + projectPlanName + " plan: " + err.Error())
+	}
+	if err := s.kube.ApplyAPIWorkload(ctx, ref, component, workload); err != nil {
+		return nil, err
+	}
+
+	out := &dto.GetProjectWorkloadOutput{}
+	out.Body.Component = component
+	out.Body.Workload = workloadToDTO(&workload)
+	return out, nil
+}
+
+// projectPlan 回傳專案的方案與其名稱，未記錄方案的專案使用預設方案
+func (s *service) projectPlan(ctx context.Context, ref string) (*config.PlanConfig, string, error) {
+	name, err := s.project.FindPlanByRef(ctx, ref)
+	if err != nil {
+		return nil, "", err
+	}
+	name = s.config.Kube.PlanName(name)
+	plan, ok := s.config.Kube.Plan(name)
+	if !ok {
+		slog.ErrorContext(ctx, "Project plan is not configured", "ref", ref, "plan", name)
+		return nil, "", huma.Error500InternalServerError("Project plan is not configured: " + name)
+	}
+	return plan, name, nil
+}
+
+// selectablePlan 回傳使用者可以選擇的方案：預設方案、Selectable 的方案或專案目前的方案 (current)
+func (s *service) selectablePlan(name string, current string) (*config.PlanConfig, error) {
+	plan, ok := s.config.Kube.Plan(name)
+	if !ok {
+		return nil, huma.Error422UnprocessableEntity("Unknown plan: " + name)
+	}
+	name = s.config.Kube.PlanName(name)
+	if !plan.Selectable && name != s.config.Kube.PlanName("") && name != current {
+		return nil, huma.Error403Forbidden("Plan is not available: " + name)
+	}
+	return plan, nil
+}
+
+func workloadToDTO(workload *config.WorkloadConfig) dto.ProjectWorkload {
+	return dto.ProjectWorkload{
+		Replicas:            workload.Replicas,
+		Resources:           dto.ProjectWorkloadResources(workload.Resources),
+		Autoscaling:         dto.ProjectWorkloadAutoscaling(workload.Autoscaling),
+		PodDisruptionBudget: dto.ProjectWorkloadPodDisruptionBudget(workload.PodDisruptionBudget),
+	}
+}
@@ -0,0 +1,21 @@
+package usersdb
+
+import "context"
+
+// HasFunction 回傳 schema 中是否有名稱為 name 的函式 (不論參數)
+func (s *service) HasFunction(ctx context.Context, jwt, ref, schema, name string) (bool, error) {
+	db, err := s.GetDB(ctx, jwt, ref, "superuser")
+	if err != nil {
+		return false, err
+	}
+
+	var exists bool
+	err = db.WithContext(ctx).
+		Raw("SELECT EXISTS (SELECT 1 FROM pg_proc p JOIN pg_namespace n ON n.oid = p.pronamespace WHERE n.nspname = ? AND p.proname = ?)", schema, name).
+		Scan(&exists).Error
+	if err != nil {
+		return false, err
+	}
+
+	return exists, nil
+}
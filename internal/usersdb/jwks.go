@@ -0,0 +1,48 @@
+package usersdb
+
+import (
+	"context"
+
+	"baas-api/internal/models"
+)
+
+// 以下方法供平台的背景作業 (例如 JWKS 輪替) 使用，不經過使用者的權限檢查，呼叫端須自行確認權限
+
+func (s *service) GetJWKSKeys(ctx context.Context, ref string) ([]models.JWK, error) {
+	db, err := s.openDB(ctx, ref, "superuser")
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []models.JWK
+	err = db.WithContext(ctx).
+		Raw("SELECT id, public_key, created_at FROM auth.jwks ORDER BY created_at, id").
+		Scan(&keys).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+func (s *service) InsertJWKSKey(ctx context.Context, ref, kid, publicKey, privateKey string) error {
+	db, err := s.openDB(ctx, ref, "superuser")
+	if err != nil {
+		return err
+	}
+
+	return db.WithContext(ctx).
+		Exec("INSERT INTO auth.jwks (id, public_key, private_key) VALUES (?, ?, ?)", kid, publicKey, privateKey).
+		Error
+}
+
+func (s *service) DeleteJWKSKeysExcept(ctx context.Context, ref, kid string) error {
+	db, err := s.openDB(ctx, ref, "superuser")
+	if err != nil {
+		return err
+	}
+
+	return db.WithContext(ctx).
+		Exec("DELETE FROM auth.jwks WHERE id <> ?", kid).
+		Error
+}
@@ -0,0 +1,37 @@
+package usersdb
+
+import (
+	"context"
+
+	"baas-api/internal/kubeproject"
+)
+
+// GetAppliedMigrations 讀取使用者 migration 已套用的版本 (由舊到新)，尚未執行過時回傳空的列表
+func (s *service) GetAppliedMigrations(ctx context.Context, jwt, ref string) ([]string, error) {
+	db, err := s.GetDB(ctx, jwt, ref, "superuser")
+	if err != nil {
+		return nil, err
+	}
+
+	// dbmate 第一次執行時才會建立紀錄表
+	var exists bool
+	err = db.WithContext(ctx).
+		Raw("SELECT to_regclass(?) IS NOT NULL", kubeproject.UserMigrationsTable).
+		Scan(&exists).Error
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return []string{}, nil
+	}
+
+	var versions []string
+	err = db.WithContext(ctx).
+		Raw("SELECT version FROM " + kubeproject.UserMigrationsTable + " ORDER BY version").
+		Scan(&versions).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return versions, nil
+}
@@ -0,0 +1,82 @@
+package usersdb
+
+import (
+	"context"
+	"slices"
+	"strings"
+)
+
+// RealtimePublication 是 Realtime 讀取變更的 publication
+const RealtimePublication = "supabase_realtime"
+
+// GetRealtimeTables 回傳 Realtime publication 中的資料表 (schema.table)，publication 不存在時回傳空的清單
+func (s *service) GetRealtimeTables(ctx context.Context, jwt, ref string) ([]string, error) {
+	db, err := s.GetDB(ctx, jwt, ref, "superuser")
+	if err != nil {
+		return nil, err
+	}
+
+	tables := []string{}
+	err = db.WithContext(ctx).
+		Raw("SELECT schemaname || '.' || tablename FROM pg_publication_tables WHERE pubname = ? ORDER BY 1", RealtimePublication).
+		Scan(&tables).Error
+	if err != nil {
+		return nil, err
+	}
+	return tables, nil
+}
+
+// GetMissingTables 回傳 names (schema.table) 中不存在於資料庫的資料表
+func (s *service) GetMissingTables(ctx context.Context, jwt, ref string, names []string) ([]string, error) {
+	db, err := s.GetDB(ctx, jwt, ref, "superuser")
+	if err != nil {
+		return nil, err
+	}
+
+	var existing []string
+	err = db.WithContext(ctx).
+		Raw(`SELECT n.nspname || '.' || c.relname FROM pg_class c
+			JOIN pg_namespace n ON n.oid = c.relnamespace
+			WHERE c.relkind IN ('r', 'p') AND n.nspname || '.' || c.relname IN ?`, names).
+		Scan(&existing).Error
+	if err != nil {
+		return nil, err
+	}
+
+	missing := []string{}
+	for _, name := range names {
+		if !slices.Contains(existing, name) {
+			missing = append(missing, name)
+		}
+	}
+	return missing, nil
+}
+
+// SetRealtimeTables 將 Realtime publication 的資料表設為 tables，publication 不存在時建立。
+//
+// tables 須先檢查為 schema.table 格式的合法識別字 (CREATE/ALTER PUBLICATION 無法使用參數)。
+func (s *service) SetRealtimeTables(ctx context.Context, jwt, ref string, tables []string) error {
+	db, err := s.GetDB(ctx, jwt, ref, "superuser")
+	if err != nil {
+		return err
+	}
+
+	quoted := make([]string, 0, len(tables))
+	for _, table := range tables {
+		schema, name, _ := strings.Cut(table, ".")
+		quoted = append(quoted, `"`+schema+`"."`+name+`"`)
+	}
+	list := strings.Join(quoted, ", ")
+
+	var exists bool
+	err = db.WithContext(ctx).
+		Raw("SELECT EXISTS (SELECT 1 FROM pg_publication WHERE pubname = ?)", RealtimePublication).
+		Scan(&exists).Error
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return db.WithContext(ctx).Exec("CREATE PUBLICATION " + RealtimePublication + " FOR TABLE " + list).Error
+	}
+	return db.WithContext(ctx).Exec("ALTER PUBLICATION " + RealtimePublication + " SET TABLE " + list).Error
+}
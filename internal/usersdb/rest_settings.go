@@ -0,0 +1,55 @@
+package usersdb
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	"baas-api/internal/kubeproject"
+)
+
+// GetMissingSchemas 回傳 names 中不存在於資料庫的 schema
+func (s *service) GetMissingSchemas(ctx context.Context, jwt, ref string, names []string) ([]string, error) {
+	db, err := s.GetDB(ctx, jwt, ref, "superuser")
+	if err != nil {
+		return nil, err
+	}
+
+	var existing []string
+	err = db.WithContext(ctx).
+		Raw("SELECT nspname FROM pg_namespace WHERE nspname IN ?", names).
+		Scan(&existing).Error
+	if err != nil {
+		return nil, err
+	}
+
+	missing := []string{}
+	for _, name := range names {
+		if !slices.Contains(existing, name) {
+			missing = append(missing, name)
+		}
+	}
+	return missing, nil
+}
+
+// UpdateRESTAPISchemas 以 in-database configuration 設定 PostgREST 公開的 schema，並通知 PostgREST 重新載入設定與 schema cache。
+//
+// schemas 須先經過 kubeproject.ValidateRESTAPISettings 檢查 (ALTER ROLE ... SET 無法使用參數)。
+func (s *service) UpdateRESTAPISchemas(ctx context.Context, jwt, ref string, schemas []string) error {
+	db, err := s.GetDB(ctx, jwt, ref, "superuser")
+	if err != nil {
+		return err
+	}
+
+	statements := []string{
+		"ALTER ROLE " + kubeproject.RoleAuthenticator + " SET pgrst.db_schemas = '" + strings.Join(schemas, ", ") + "'",
+		"NOTIFY pgrst, 'reload config'",
+		"NOTIFY pgrst, 'reload schema'",
+	}
+	for _, statement := range statements {
+		if err := db.WithContext(ctx).Exec(statement).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -23,6 +23,8 @@ import (
 type Service interface {
 	// GetDB by baas-project ref
 	GetDB(ctx context.Context, jwt, ref, role string) (*gorm.DB, error)
+	// InvalidateDB 關閉並移除專案快取的連線 (例如資料庫還原、密碼變更後)
+	InvalidateDB(ref string)
 	GetRootClass(ctx context.Context, jwt, ref string) (*models.Class, error)
 	GetRootClasses(ctx context.Context, jwt, ref string) ([]models.Class, error)
 	GetClassesChild(ctx context.Context, jwt, ref string, classIDs []string) ([]models.ClassWithPCID, error)
@@ -34,6 +36,32 @@ type Service interface {
 	DeleteClass(ctx context.Context, jwt string, in *dto.DeleteClassInput) error
 	GetUsers(ctx context.Context, jwt string, in *dto.GetRolesInput) ([]models.User, error)
 	GetGroups(ctx context.Context, jwt string, in *dto.GetRolesInput) ([]models.Group, error)
+	// GetPostgresSettings 讀取資料庫目前生效的設定 (pg_settings)
+	GetPostgresSettings(ctx context.Context, jwt, ref string, names []string) ([]models.PostgresSetting, error)
+	// GetAvailableExtensions 讀取 Postgres image 中可安裝的 extension (pg_available_extensions)
+	GetAvailableExtensions(ctx context.Context, jwt, ref string, names []string) ([]models.PostgresAvailableExtension, error)
+	// GetAvailableExtensionVersions 讀取 Postgres image 中 extension 可安裝的版本 (pg_available_extension_versions)
+	GetAvailableExtensionVersions(ctx context.Context, jwt, ref string, name string) ([]string, error)
+	// GetAppliedMigrations 讀取使用者 migration 已套用的版本 (dbmate 的版本紀錄表)
+	GetAppliedMigrations(ctx context.Context, jwt, ref string) ([]string, error)
+	// GetMissingSchemas 回傳不存在於資料庫的 schema
+	GetMissingSchemas(ctx context.Context, jwt, ref string, names []string) ([]string, error)
+	// UpdateRESTAPISchemas 設定 PostgREST 公開的 schema (authenticator 的 pgrst.db_schemas) 並 NOTIFY 重新載入
+	UpdateRESTAPISchemas(ctx context.Context, jwt, ref string, schemas []string) error
+	// GetRealtimeTables 讀取 Realtime publication (supabase_realtime) 中的資料表
+	GetRealtimeTables(ctx context.Context, jwt, ref string) ([]string, error)
+	// GetMissingTables 回傳不存在於資料庫的資料表 (schema.table)
+	GetMissingTables(ctx context.Context, jwt, ref string, names []string) ([]string, error)
+	// SetRealtimeTables 設定 Realtime publication 的資料表，publication 不存在時建立
+	SetRealtimeTables(ctx context.Context, jwt, ref string, tables []string) error
+	// HasFunction 回傳 schema 中是否有該名稱的函式
+	HasFunction(ctx context.Context, jwt, ref, schema, name string) (bool, error)
+	// GetJWKSKeys 讀取 auth API 的簽章金鑰 (auth.jwks，由舊到新)，不檢查使用者權限
+	GetJWKSKeys(ctx context.Context, ref string) ([]models.JWK, error)
+	// InsertJWKSKey 新增簽章金鑰，auth API 之後以這把金鑰簽發 JWT
+	InsertJWKSKey(ctx context.Context, ref, kid, publicKey, privateKey string) error
+	// DeleteJWKSKeysExcept 移除 kid 以外的簽章金鑰
+	DeleteJWKSKeysExcept(ctx context.Context, ref, kid string) error
 }
 
 type service struct {
@@ -60,6 +88,11 @@ func (s *service) GetDB(ctx context.Context, jwt, ref, role string) (*gorm.DB, e
 		return nil, err
 	}
 
+	return s.openDB(ctx, ref, role)
+}
+
+// openDB 取得專案資料庫的連線 (有快取)，不檢查權限
+func (s *service) openDB(ctx context.Context, ref, role string) (*gorm.DB, error) {
 	// 2. 生成緩存鍵
 	cacheKey := "usersdb:" + ref + ":" + role
 
@@ -98,3 +131,18 @@ func (s *service) GetDB(ctx context.Context, jwt, ref, role string) (*gorm.DB, e
 
 	return db, nil
 }
+
+func (s *service) InvalidateDB(ref string) {
+	prefix := "usersdb:" + ref + ":"
+	for key, item := range s.cache.Items() {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		s.cache.Delete(key)
+		if db, ok := item.Object.(*gorm.DB); ok {
+			if sqlDB, err := db.DB(); err == nil {
+				_ = sqlDB.Close()
+			}
+		}
+	}
+}
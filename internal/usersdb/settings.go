@@ -0,0 +1,58 @@
+package usersdb
+
+import (
+	"context"
+
+	"baas-api/internal/models"
+)
+
+func (s *service) GetPostgresSettings(ctx context.Context, jwt, ref string, names []string) ([]models.PostgresSetting, error) {
+	db, err := s.GetDB(ctx, jwt, ref, "superuser")
+	if err != nil {
+		return nil, err
+	}
+
+	var settings []models.PostgresSetting
+	err = db.WithContext(ctx).
+		Raw("SELECT name, current_setting(name) AS setting, pending_restart FROM pg_settings WHERE name IN ?", names).
+		Scan(&settings).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return settings, nil
+}
+
+func (s *service) GetAvailableExtensions(ctx context.Context, jwt, ref string, names []string) ([]models.PostgresAvailableExtension, error) {
+	db, err := s.GetDB(ctx, jwt, ref, "superuser")
+	if err != nil {
+		return nil, err
+	}
+
+	var extensions []models.PostgresAvailableExtension
+	err = db.WithContext(ctx).
+		Raw("SELECT name, default_version, installed_version, comment FROM pg_available_extensions WHERE name IN ? ORDER BY name", names).
+		Scan(&extensions).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return extensions, nil
+}
+
+func (s *service) GetAvailableExtensionVersions(ctx context.Context, jwt, ref string, name string) ([]string, error) {
+	db, err := s.GetDB(ctx, jwt, ref, "superuser")
+	if err != nil {
+		return nil, err
+	}
+
+	var versions []string
+	err = db.WithContext(ctx).
+		Raw("SELECT version FROM pg_available_extension_versions WHERE name = ? ORDER BY version", name).
+		Scan(&versions).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return versions, nil
+}
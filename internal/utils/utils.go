@@ -8,3 +8,12 @@ func BoolToString(b bool) string {
 	}
 	return "false"
 }
+
+// MaskSecret 遮蔽 secret，只保留最後 4 個字元方便辨識；太短的值完全遮蔽
+func MaskSecret(secret string) string {
+	const visible = 4
+	if len(secret) < 12 {
+		return "********"
+	}
+	return "********" + secret[len(secret)-visible:]
+}
@@ -6,6 +6,7 @@ import (
 	"baas-api/internal/classfunc"
 	"baas-api/internal/config"
 	"baas-api/internal/database"
+	"baas-api/internal/encryption"
 	"baas-api/internal/kubeproject"
 	"baas-api/internal/middlewares"
 	"baas-api/internal/minio"
@@ -24,6 +25,7 @@ func main() {
 	config.Package(i)
 	cache.Package(i)
 	database.Package(i)
+	encryption.Package(i)
 
 	// Services
 	minio.Package(i)
@@ -42,6 +44,9 @@ func main() {
 	// Router
 	router.Package(i)
 
+	do.MustInvoke[*project.JWKSRotator](i).Start()
+	do.MustInvokeAs[kubeproject.Service](i).StartTLSSecretSync()
+
 	router := do.MustInvoke[*router.BaaSRouter](i)
 	router.RegisterControllers()
 	router.Start()